@@ -4,7 +4,6 @@ import (
 	"net/http"
 	"project/examples/jwt_auth/model"
 	srv "project/examples/jwt_auth/service"
-	"project/pkg/json"
 	"project/pkg/logger"
 	"project/pkg/response"
 
@@ -29,21 +28,17 @@ func init() {
 func Register(c *gin.Context) {
 	var req model.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		logger.Sugar.Error(err)
+		logger.FromGin(c).Error(err)
 		response.BadRequest(c)
 		return
 	}
 
-	logger.Sugar.Info("Register 请求参数：", string(json.JSONMarshal(req)))
-
 	authResp, err := service.Register(&req)
 	if err != nil {
 		response.Failed(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	logger.Sugar.Info("Register 响应参数：", string(json.JSONMarshal(authResp)))
-
 	response.Success(c, authResp)
 }
 
@@ -63,16 +58,12 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	logger.Sugar.Info("Login 请求参数：", string(json.JSONMarshal(req)))
-
 	authResp, err := service.Login(&req)
 	if err != nil {
 		response.Failed(c, http.StatusUnauthorized, err.Error())
 		return
 	}
 
-	logger.Sugar.Info("Login 响应参数：", string(json.JSONMarshal(authResp)))
-
 	response.Success(c, authResp)
 }
 
@@ -92,16 +83,12 @@ func RefreshToken(c *gin.Context) {
 		return
 	}
 
-	logger.Sugar.Info("Login 请求参数：", string(json.JSONMarshal(req)))
-
 	tokenPair, err := service.RefreshToken(req.RefreshToken)
 	if err != nil {
 		response.Failed(c, http.StatusUnauthorized, err.Error())
 		return
 	}
 
-	logger.Sugar.Info("Login 响应参数：", string(json.JSONMarshal(tokenPair)))
-
 	response.Success(c, tokenPair)
 }
 
@@ -121,15 +108,11 @@ func Logout(c *gin.Context) {
 		return
 	}
 
-	logger.Sugar.Info("Logout 请求参数：", string(json.JSONMarshal(req)))
-
 	if err := service.Logout(req.RefreshToken); err != nil {
 		response.ServerError(c, err.Error())
 		return
 	}
 
-	logger.Sugar.Info("Logout 响应参数：", string(json.JSONMarshal("登出成功")))
-
 	response.Success(c, "登出成功")
 }
 
@@ -149,15 +132,11 @@ func LogoutAllDevices(c *gin.Context) {
 		return
 	}
 
-	logger.Sugar.Info("LogoutAllDevices 请求参数：", string(json.JSONMarshal(userID)))
-
 	if err := service.LogoutAllDevices(userID.(string)); err != nil {
 		response.ServerError(c, err.Error())
 		return
 	}
 
-	logger.Sugar.Info("LogoutAllDevices 响应参数：", string(json.JSONMarshal("已登出所有设备")))
-
 	response.Success(c, "已登出所有设备")
 }
 
@@ -177,16 +156,12 @@ func GetUserInfo(c *gin.Context) {
 		return
 	}
 
-	logger.Sugar.Info("GetUserInfo 请求参数：", string(json.JSONMarshal("userID")))
-
 	userInfo, err := service.GetUserInfo(userID.(string))
 	if err != nil {
 		response.ServerError(c, err.Error())
 		return
 	}
 
-	logger.Sugar.Info("GetUserInfo 响应参数：", string(json.JSONMarshal(userInfo)))
-
 	response.Success(c, userInfo)
 }
 
@@ -216,14 +191,10 @@ func ChangePassword(c *gin.Context) {
 
 	req.UserID = userID.(string)
 
-	logger.Sugar.Info("ChangePassword 请求参数：", string(json.JSONMarshal(req)))
-
 	if err := service.ChangePassword(&req); err != nil {
 		response.Failed(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	logger.Sugar.Info("ChangePassword 响应参数：", string(json.JSONMarshal("密码修改成功，请重新登录")))
-
 	response.Success(c, "密码修改成功，请重新登录")
 }
@@ -2,8 +2,8 @@ package model
 
 // AuthResponse 认证响应
 type AuthResponse struct {
-	AccessToken  string    `json:"access_token"`
-	RefreshToken string    `json:"refresh_token"`
+	AccessToken  string    `json:"access_token" log:"redact"`
+	RefreshToken string    `json:"refresh_token" log:"redact"`
 	TokenType    string    `json:"token_type"` // Bearer
 	ExpiresIn    int64     `json:"expires_in"` // 秒
 	User         *UserInfo `json:"user"`
@@ -13,12 +13,12 @@ type AuthResponse struct {
 type UserInfo struct {
 	ID       string `json:"id"`
 	Username string `json:"username"`
-	Email    string `json:"email"`
+	Email    string `json:"email" log:"mask"`
 	Role     string `json:"role"`
 }
 
 // TokenPair Token 对
 type TokenPair struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	AccessToken  string `json:"access_token" log:"redact"`
+	RefreshToken string `json:"refresh_token" log:"redact"`
 }
@@ -17,7 +17,7 @@ type User struct {
 type RefreshToken struct {
 	ID        string    `db:"id" json:"id"`
 	UserID    string    `db:"user_id" json:"user_id"`
-	Token     string    `db:"token" json:"token"`
+	Token     string    `db:"token" json:"token" log:"redact"`
 	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 	IsRevoked bool      `db:"is_revoked" json:"is_revoked"` // 是否已撤销
@@ -2,16 +2,24 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"project/internal/middleware"
+	"project/pkg/cache"
 	"project/pkg/config"
+	"project/pkg/database"
 	"project/pkg/logger"
+	"project/pkg/metrics"
+	"project/pkg/ratelimit"
+	"project/pkg/redact"
+	"project/pkg/requestid"
 	"project/pkg/response"
 
 	"github.com/gin-contrib/pprof"
@@ -24,6 +32,7 @@ type App interface {
 	LoadComponents() error
 	GetRouter() *gin.Engine
 	InitPProf()
+	RegisterWorker(workers ...Worker)
 	Run() error
 	Shutdown(ctx context.Context) error
 }
@@ -37,6 +46,8 @@ type DefaultApp struct {
 	configPath string
 	version    string
 	server     *http.Server
+	monitor    *statsCollector
+	workers    []Worker
 }
 
 // localhost页面参数
@@ -80,7 +91,30 @@ func InitApp() (App, error) {
 	appConfig := config.Get()
 
 	// 初始化日志
-	logger.InitLogger(opts.LogPath, appConfig.Log.Level)
+	logCfg := appConfig.Log
+	redact.SetKeys(logCfg.RedactKeys...)
+	sinks := make([]logger.SinkOptions, 0, len(logCfg.Sinks))
+	for i := range logCfg.Sinks {
+		sinks = append(sinks, logCfg.Sinks[i].Options())
+	}
+	err := logger.Init(logger.Options{
+		Dir:          opts.LogPath,
+		Level:        logCfg.Level,
+		Format:       logCfg.Format,
+		StdoutLevel:  logCfg.StdoutLevel,
+		FileLevel:    logCfg.FileLevel,
+		ErrorFile:    logCfg.ErrorFile,
+		Pattern:      logCfg.FilePattern,
+		MaxSize:      logCfg.MaxSize,
+		MaxBackups:   logCfg.MaxBackups,
+		MaxAge:       logCfg.MaxAge,
+		MaxTotalSize: logCfg.MaxTotalSize,
+		Compress:     logCfg.Compress,
+		Sinks:        sinks,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to init logger: %w", err)
+	}
 
 	app := &DefaultApp{
 		name:       appConfig.Server.Name,
@@ -125,7 +159,27 @@ func (d *DefaultApp) GetRouter() *gin.Engine {
 	d.router = gin.New()
 
 	// 注册中间件
-	middleware.RegisterDefaultMiddlewares(d.router, d.version)
+	serverCfg := config.Get().Server
+	// 生成器名称已在配置校验时检查
+	generator, _ := requestid.NewGenerator(serverCfg.RequestIDGenerator)
+	accessCfg := config.Get().Log.Access
+	middleware.RegisterDefaultMiddlewares(d.router, d.version, middleware.Options{
+		RequestID: middleware.RequestIDOptions{
+			Header:    serverCfg.RequestIDHeader,
+			Generator: generator,
+		},
+		AccessLog: middleware.AccessLogOptions{
+			SkipPaths:     accessCfg.SkipPaths,
+			SampleRates:   accessCfg.SampleRates,
+			SlowThreshold: accessCfg.SlowThreshold,
+			RequestBody:   accessCfg.RequestBody,
+			ResponseBody:  accessCfg.ResponseBody,
+			MaxBodySize:   accessCfg.MaxBodySize,
+		},
+		CORS:      corsOptions(config.Get().CORS),
+		RateLimit: rateLimitOptions(config.Get()),
+		Timeout:   timeoutOptions(serverCfg),
+	})
 
 	// 设置信任的代理
 	if err := d.router.SetTrustedProxies(nil); err != nil {
@@ -144,9 +198,40 @@ func (d *DefaultApp) GetRouter() *gin.Engine {
 		})
 	})
 
+	// 就绪检查（组件状态与连接池统计）
+	d.router.GET("/readyz", d.readiness)
+
+	// 指标导出
+	if mc := config.Get().Monitor; mc != nil && mc.MetricsPath != "" {
+		if admin := d.adminRoutes(mc.MetricsPath); admin != nil {
+			admin.GET(mc.MetricsPath, gin.WrapH(metrics.Handler()))
+		}
+	}
+
+	// 运行时日志级别
+	if dc := config.Get().Debug; dc != nil && dc.EnableLogLevel {
+		if admin := d.adminRoutes("/debug/log/level"); admin != nil {
+			levelHandler := gin.WrapH(logger.LevelHandler())
+			admin.GET("/debug/log/level", levelHandler)
+			admin.PUT("/debug/log/level", levelHandler)
+			logger.Sugar.Info("\t[app] log level api enabled, visit /debug/log/level")
+		}
+	}
+
 	return d.router
 }
 
+// adminRoutes 返回需要 debug.adminToken 认证的管理接口路由组（指标导出、pprof、日志级别）；
+// 未配置令牌时返回 nil，管理接口不注册
+func (d *DefaultApp) adminRoutes(name string) *gin.RouterGroup {
+	dc := config.Get().Debug
+	if dc == nil || dc.AdminToken == "" {
+		logger.Sugar.Warnf("\t[app] debug.adminToken is not set, %s is disabled", name)
+		return nil
+	}
+	return d.router.Group("", middleware.AdminAuth(dc.AdminToken))
+}
+
 // InitPProf 初始化性能分析工具
 func (d *DefaultApp) InitPProf() {
 	if !config.Get().Debug.EnablePProf {
@@ -154,8 +239,12 @@ func (d *DefaultApp) InitPProf() {
 		return
 	}
 
-	router := d.GetRouter()
-	pprof.Register(router)
+	d.GetRouter()
+	admin := d.adminRoutes("/debug/pprof/")
+	if admin == nil {
+		return
+	}
+	pprof.Register(admin)
 	logger.Sugar.Info("[app] pprof enabled, visit /debug/pprof/")
 }
 
@@ -170,6 +259,25 @@ func (d *DefaultApp) Run() error {
 		return fmt.Errorf("failed to load components: %w", err)
 	}
 
+	// 启动连接池统计采集
+	if mc := config.Get().Monitor; mc != nil {
+		d.monitor = newStatsCollector(d.components, mc)
+		d.monitor.Start()
+	}
+
+	// 默认缓存的失效广播订阅需在 Redis 组件加载后启动，随其他后台任务一起停止
+	if cfg := config.Get(); cfg.Db != nil && cfg.Db.Redis != nil {
+		d.RegisterWorker(cache.Default())
+	}
+
+	// 启动后台任务
+	if err := d.startWorkers(); err != nil {
+		if d.monitor != nil {
+			d.monitor.Stop()
+		}
+		return err
+	}
+
 	addr := fmt.Sprintf(":%d", d.port)
 
 	// 创建 HTTP 服务器
@@ -177,10 +285,14 @@ func (d *DefaultApp) Run() error {
 		Addr:           addr,
 		Handler:        d.router,
 		ReadTimeout:    30 * time.Second,
-		WriteTimeout:   30 * time.Second,
+		WriteTimeout:   writeTimeout(config.Get().Server),
 		MaxHeaderBytes: 1 << 20, // 1MB
 	}
 
+	// SIGUSR1 切换 debug 级别
+	stopWatch := logger.WatchSignal()
+	defer stopWatch()
+
 	// 监听系统信号
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -202,17 +314,30 @@ func (d *DefaultApp) Run() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	// HTTP 服务关闭失败（如等待请求超时）时仍继续停止后台任务与关闭组件
+	var errs []error
 	if err := d.Shutdown(ctx); err != nil {
-		return fmt.Errorf("server shutdown error: %w", err)
+		errs = append(errs, fmt.Errorf("server shutdown error: %w", err))
 	}
 
-	// 加载组件
+	// 停止后台任务
+	d.stopWorkers(ctx, d.workers)
+
+	if d.monitor != nil {
+		d.monitor.Stop()
+	}
+
+	// 关闭组件
 	if err := d.CloseComponents(); err != nil {
-		return fmt.Errorf("failed to load components: %w", err)
+		logger.Sugar.Errorf("\t[app] failed to close components: %v", err)
+		errs = append(errs, fmt.Errorf("failed to close components: %w", err))
 	}
 
-	logger.Sugar.Info("[app] server exited gracefully")
-	return nil
+	if len(errs) == 0 {
+		logger.Sugar.Info("[app] server exited gracefully")
+	}
+	_ = logger.Close()
+	return errors.Join(errs...)
 }
 
 // Shutdown 优雅关闭应用
@@ -232,3 +357,113 @@ func (d *DefaultApp) Shutdown(ctx context.Context) error {
 	logger.Sugar.Info("\t[app] shutdown completed")
 	return nil
 }
+
+// corsOptions 转换跨域配置，未配置时允许任意来源
+func corsOptions(cfg *config.CORS) middleware.CORSOptions {
+	if cfg == nil {
+		return middleware.CORSOptions{Policy: middleware.CORSPolicy{AllowOrigins: []string{"*"}}}
+	}
+	opts := middleware.CORSOptions{Policy: corsPolicy(cfg.CORSPolicy)}
+	for _, g := range cfg.Groups {
+		opts.Groups = append(opts.Groups, middleware.CORSGroup{Prefix: g.Prefix, Policy: corsPolicy(g.CORSPolicy)})
+	}
+	return opts
+}
+
+func corsPolicy(p config.CORSPolicy) middleware.CORSPolicy {
+	return middleware.CORSPolicy{
+		AllowOrigins:     p.AllowOrigins,
+		AllowOriginRegex: p.AllowOriginRegex,
+		AllowMethods:     p.AllowMethods,
+		AllowHeaders:     p.AllowHeaders,
+		ExposeHeaders:    p.ExposeHeaders,
+		AllowCredentials: p.AllowCredentials,
+		MaxAge:           p.MaxAge,
+	}
+}
+
+// timeoutOptions 转换请求处理超时配置
+func timeoutOptions(cfg *config.Server) middleware.TimeoutOptions {
+	opts := middleware.TimeoutOptions{Timeout: cfg.Timeout}
+	for _, r := range cfg.TimeoutRoutes {
+		opts.Routes = append(opts.Routes, middleware.TimeoutRoute{Prefix: r.Prefix, Timeout: r.Timeout})
+	}
+	return opts
+}
+
+// writeTimeout 连接写超时需长于请求处理超时，否则 504 无法写出；存在不限时的路由时不设置
+func writeTimeout(cfg *config.Server) time.Duration {
+	longest := cfg.Timeout
+	for _, r := range cfg.TimeoutRoutes {
+		if r.Timeout == 0 {
+			return 0
+		}
+		if r.Timeout > longest {
+			longest = r.Timeout
+		}
+	}
+	if cfg.Timeout == 0 {
+		return 0
+	}
+	return longest + 5*time.Second
+}
+
+// rateLimitOptions 按配置为每条规则创建限流器，未启用时不限流
+func rateLimitOptions(cfg *config.App) middleware.RateLimitOptions {
+	rl := cfg.RateLimit
+	if rl == nil || !rl.Enabled {
+		return middleware.RateLimitOptions{}
+	}
+	store := rl.Store
+	if store == "redis" && (cfg.Db == nil || cfg.Db.Redis == nil) {
+		logger.Sugar.Errorf("\t[app] rate limit store is redis but redis is not configured, fallback to memory")
+		store = "memory"
+	}
+
+	opts := middleware.RateLimitOptions{APIKeyHeader: rl.APIKeyHeader}
+	if len(rl.APIKeys) > 0 {
+		keys := make(map[string]struct{}, len(rl.APIKeys))
+		for _, k := range rl.APIKeys {
+			keys[k] = struct{}{}
+		}
+		opts.APIKeyValidator = func(_ *gin.Context, key string) bool {
+			_, ok := keys[key]
+			return ok
+		}
+	}
+	for _, r := range rl.Rules {
+		var limiter ratelimit.Limiter
+		if store == "redis" {
+			// 每条规则独立计数，键前缀包含路由前缀
+			name := r.Prefix
+			if name == "" {
+				name = "/"
+			}
+			limiter = &lazyRedisLimiter{prefix: rl.Prefix + name + ":", rule: r.Rule()}
+		} else {
+			limiter = ratelimit.NewMemoryLimiter(r.Rule())
+		}
+		opts.Rules = append(opts.Rules, middleware.RateLimitRule{Prefix: r.Prefix, Key: r.Key, Limiter: limiter})
+	}
+	return opts
+}
+
+// lazyRedisLimiter 路由在组件加载前创建，Redis 初始化后才创建 Redis 限流器；未就绪时返回错误，由中间件放行
+type lazyRedisLimiter struct {
+	prefix  string
+	rule    ratelimit.Rule
+	limiter atomic.Pointer[ratelimit.RedisLimiter]
+}
+
+func (l *lazyRedisLimiter) Allow(ctx context.Context, key string) (ratelimit.Result, error) {
+	limiter := l.limiter.Load()
+	if limiter == nil {
+		r := database.GetRedis()
+		if r == nil || !r.IsInitialize() {
+			return ratelimit.Result{}, errors.New("ratelimit: redis is not initialized")
+		}
+		limiter = ratelimit.NewRedisLimiter(r.GetClient(), l.prefix, l.rule)
+		l.limiter.Store(limiter)
+	}
+	return limiter.Allow(ctx, key)
+}
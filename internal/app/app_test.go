@@ -0,0 +1,64 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetRouter_AdminRoutes(t *testing.T) {
+	const (
+		withoutToken = "debug:\n  enablePProf: true\n  enableLogLevel: true\n"
+		withToken    = withoutToken + "  adminToken: s3cret-admin\n"
+	)
+	paths := []string{"/debug/log/level", "/metrics", "/debug/pprof/"}
+
+	tests := []struct {
+		name   string
+		debug  string
+		header string
+		want   int
+	}{
+		{
+			name:  "未配置令牌时不注册",
+			debug: withoutToken,
+			want:  http.StatusNotFound,
+		},
+		{
+			name:  "未携带令牌",
+			debug: withToken,
+			want:  http.StatusUnauthorized,
+		},
+		{
+			name:   "令牌错误",
+			debug:  withToken,
+			header: "Bearer wrong",
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name:   "令牌正确",
+			debug:  withToken,
+			header: "Bearer s3cret-admin",
+			want:   http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			initConfig(t, "\n"+tt.debug)
+			d := &DefaultApp{}
+			d.InitPProf()
+
+			for _, path := range paths {
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				if tt.header != "" {
+					req.Header.Set("Authorization", tt.header)
+				}
+				w := httptest.NewRecorder()
+				d.GetRouter().ServeHTTP(w, req)
+				assert.Equal(t, tt.want, w.Code, path)
+			}
+		})
+	}
+}
@@ -84,6 +84,20 @@ func (d *DefaultApp) loadComponent(comp string) error {
 		}
 		t.InitComponent()
 
+	case "sqlite":
+		sl := database.GetSqlite()
+		if sl == nil {
+			return fmt.Errorf("sqlite component is nil")
+		}
+		sl.InitComponent()
+
+	case "postgres":
+		pg := database.GetPostgres()
+		if pg == nil {
+			return fmt.Errorf("postgres component is nil")
+		}
+		pg.InitComponent()
+
 	case "rabbitmq":
 		rb := queue.GetRabbitMQ()
 		if rb == nil {
@@ -132,6 +146,20 @@ func (d *DefaultApp) closeComponent(comp string) error {
 		}
 		t.Close()
 
+	case "sqlite":
+		sl := database.GetSqlite()
+		if sl == nil {
+			return fmt.Errorf("sqlite component is nil")
+		}
+		sl.Close()
+
+	case "postgres":
+		pg := database.GetPostgres()
+		if pg == nil {
+			return fmt.Errorf("postgres component is nil")
+		}
+		pg.Close()
+
 	case "rabbitmq":
 		rb := queue.GetRabbitMQ()
 		if rb == nil {
@@ -0,0 +1,283 @@
+package app
+
+import (
+	"database/sql"
+	"net/http"
+	"sync"
+	"time"
+
+	"project/pkg/config"
+	"project/pkg/database"
+	"project/pkg/logger"
+	"project/pkg/metrics"
+	"project/pkg/queue"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	sqlMaxOpenGauge      = metrics.NewGaugeVec("db_sql_max_open_connections", "Maximum number of open connections to the database.", "component")
+	sqlOpenGauge         = metrics.NewGaugeVec("db_sql_open_connections", "The number of established connections both in use and idle.", "component")
+	sqlInUseGauge        = metrics.NewGaugeVec("db_sql_in_use_connections", "The number of connections currently in use.", "component")
+	sqlIdleGauge         = metrics.NewGaugeVec("db_sql_idle_connections", "The number of idle connections.", "component")
+	sqlWaitCount         = metrics.NewCounterVec("db_sql_wait_count_total", "The total number of connections waited for.", "component")
+	sqlWaitSeconds       = metrics.NewCounterVec("db_sql_wait_duration_seconds_total", "The total time blocked waiting for a new connection.", "component")
+	sqlIdleClosed        = metrics.NewCounterVec("db_sql_max_idle_closed_total", "The total number of connections closed due to SetMaxIdleConns.", "component")
+	sqlIdleTimeClosed    = metrics.NewCounterVec("db_sql_max_idle_time_closed_total", "The total number of connections closed due to SetConnMaxIdleTime.", "component")
+	sqlLifetimeClosed    = metrics.NewCounterVec("db_sql_max_lifetime_closed_total", "The total number of connections closed due to SetConnMaxLifetime.", "component")
+	redisHits            = metrics.NewCounterVec("redis_pool_hits_total", "Number of times free connection was found in the pool.", "component")
+	redisMisses          = metrics.NewCounterVec("redis_pool_misses_total", "Number of times free connection was NOT found in the pool.", "component")
+	redisTimeouts        = metrics.NewCounterVec("redis_pool_timeouts_total", "Number of times a wait timeout occurred.", "component")
+	redisTotalConns      = metrics.NewGaugeVec("redis_pool_total_connections", "Number of total connections in the pool.", "component")
+	redisIdleConns       = metrics.NewGaugeVec("redis_pool_idle_connections", "Number of idle connections in the pool.", "component")
+	redisStaleConns      = metrics.NewCounterVec("redis_pool_stale_connections_total", "Number of stale connections removed from the pool.", "component")
+	rabbitmqChannelGauge = metrics.NewGaugeVec("rabbitmq_channels", "Number of open AMQP channels.", "component")
+	componentReadyGauge  = metrics.NewGaugeVec("component_ready", "Whether the component is initialized (1) or not (0).", "component")
+)
+
+// SQLPoolStats SQL 连接池快照
+type SQLPoolStats struct {
+	MaxOpenConnections int    `json:"max_open_connections"`
+	OpenConnections    int    `json:"open_connections"`
+	InUse              int    `json:"in_use"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"wait_count"`
+	WaitDuration       string `json:"wait_duration"`
+	MaxIdleClosed      int64  `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64  `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64  `json:"max_lifetime_closed"`
+}
+
+// RedisPoolStats Redis 连接池快照
+type RedisPoolStats struct {
+	Hits       uint32 `json:"hits"`
+	Misses     uint32 `json:"misses"`
+	Timeouts   uint32 `json:"timeouts"`
+	TotalConns uint32 `json:"total_conns"`
+	IdleConns  uint32 `json:"idle_conns"`
+	StaleConns uint32 `json:"stale_conns"`
+}
+
+// AMQPStats RabbitMQ 统计快照
+type AMQPStats struct {
+	Channels int `json:"channels"`
+}
+
+// ComponentStatus 组件就绪状态（readiness 返回内容）
+type ComponentStatus struct {
+	Ready bool        `json:"ready"`
+	Stats interface{} `json:"stats,omitempty"`
+}
+
+// ReadinessData readiness 接口返回内容
+type ReadinessData struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+// componentStats 获取单个组件的就绪状态与原始统计信息
+func componentStats(comp string) (bool, interface{}) {
+	switch comp {
+	case "mysql":
+		m := database.GetMysql()
+		if m == nil {
+			return false, nil
+		}
+		return m.IsInitialize(), m.Stats()
+
+	case "gorm":
+		gm := database.GetGormMysql()
+		if gm == nil {
+			return false, nil
+		}
+		return gm.IsInitialize(), gm.Stats()
+
+	case "redis":
+		r := database.GetRedis()
+		if r == nil {
+			return false, nil
+		}
+		return r.IsInitialize(), r.PoolStats()
+
+	case "tdengine":
+		t := database.GetTdengine()
+		if t == nil {
+			return false, nil
+		}
+		return t.IsInitialize(), t.Stats()
+
+	case "sqlite":
+		sl := database.GetSqlite()
+		if sl == nil {
+			return false, nil
+		}
+		return sl.IsInitialize(), sl.Stats()
+
+	case "postgres":
+		pg := database.GetPostgres()
+		if pg == nil {
+			return false, nil
+		}
+		return pg.IsInitialize(), pg.Stats()
+
+	case "rabbitmq":
+		rb := queue.GetRabbitMQ()
+		if rb == nil {
+			return false, nil
+		}
+		return rb.IsInitialize(), AMQPStats{Channels: rb.ChannelCount()}
+	}
+
+	return false, nil
+}
+
+// snapshot 将原始统计信息转换为可序列化的快照
+func snapshot(raw interface{}) interface{} {
+	switch s := raw.(type) {
+	case sql.DBStats:
+		return SQLPoolStats{
+			MaxOpenConnections: s.MaxOpenConnections,
+			OpenConnections:    s.OpenConnections,
+			InUse:              s.InUse,
+			Idle:               s.Idle,
+			WaitCount:          s.WaitCount,
+			WaitDuration:       s.WaitDuration.String(),
+			MaxIdleClosed:      s.MaxIdleClosed,
+			MaxIdleTimeClosed:  s.MaxIdleTimeClosed,
+			MaxLifetimeClosed:  s.MaxLifetimeClosed,
+		}
+	case *redis.PoolStats:
+		return RedisPoolStats{
+			Hits:       s.Hits,
+			Misses:     s.Misses,
+			Timeouts:   s.Timeouts,
+			TotalConns: s.TotalConns,
+			IdleConns:  s.IdleConns,
+			StaleConns: s.StaleConns,
+		}
+	}
+	return raw
+}
+
+// statsCollector 周期性采集组件连接池统计并导出为指标
+type statsCollector struct {
+	components []string
+	interval   time.Duration
+	threshold  time.Duration
+
+	// 上一次采集时各组件的累计等待耗时
+	lastWait map[string]time.Duration
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func newStatsCollector(components []string, cfg *config.Monitor) *statsCollector {
+	return &statsCollector{
+		components: components,
+		interval:   cfg.StatsInterval,
+		threshold:  cfg.WaitWarnThreshold,
+		lastWait:   make(map[string]time.Duration),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start 启动采集协程
+func (s *statsCollector) Start() {
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		s.collect()
+		for {
+			select {
+			case <-ticker.C:
+				s.collect()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	logger.Sugar.Infof("\t[monitor] stats collector started, interval: %v", s.interval)
+}
+
+// Stop 停止采集并等待协程退出
+func (s *statsCollector) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+		logger.Sugar.Info("\t[monitor] stats collector stopped")
+	})
+}
+
+func (s *statsCollector) collect() {
+	for _, comp := range s.components {
+		ready, raw := componentStats(comp)
+		if ready {
+			componentReadyGauge.WithLabelValues(comp).Set(1)
+		} else {
+			componentReadyGauge.WithLabelValues(comp).Set(0)
+		}
+
+		switch st := raw.(type) {
+		case sql.DBStats:
+			s.recordSQL(comp, st)
+		case *redis.PoolStats:
+			redisHits.WithLabelValues(comp).Set(float64(st.Hits))
+			redisMisses.WithLabelValues(comp).Set(float64(st.Misses))
+			redisTimeouts.WithLabelValues(comp).Set(float64(st.Timeouts))
+			redisTotalConns.WithLabelValues(comp).Set(float64(st.TotalConns))
+			redisIdleConns.WithLabelValues(comp).Set(float64(st.IdleConns))
+			redisStaleConns.WithLabelValues(comp).Set(float64(st.StaleConns))
+		case AMQPStats:
+			rabbitmqChannelGauge.WithLabelValues(comp).Set(float64(st.Channels))
+		}
+	}
+}
+
+func (s *statsCollector) recordSQL(comp string, st sql.DBStats) {
+	sqlMaxOpenGauge.WithLabelValues(comp).Set(float64(st.MaxOpenConnections))
+	sqlOpenGauge.WithLabelValues(comp).Set(float64(st.OpenConnections))
+	sqlInUseGauge.WithLabelValues(comp).Set(float64(st.InUse))
+	sqlIdleGauge.WithLabelValues(comp).Set(float64(st.Idle))
+	sqlWaitCount.WithLabelValues(comp).Set(float64(st.WaitCount))
+	sqlWaitSeconds.WithLabelValues(comp).Set(st.WaitDuration.Seconds())
+	sqlIdleClosed.WithLabelValues(comp).Set(float64(st.MaxIdleClosed))
+	sqlIdleTimeClosed.WithLabelValues(comp).Set(float64(st.MaxIdleTimeClosed))
+	sqlLifetimeClosed.WithLabelValues(comp).Set(float64(st.MaxLifetimeClosed))
+
+	// 等待耗时为累计值，按采集周期内的增量判断是否告警
+	waited := st.WaitDuration - s.lastWait[comp]
+	s.lastWait[comp] = st.WaitDuration
+	if s.threshold > 0 && waited >= s.threshold {
+		logger.Sugar.Warnf("\t[monitor] %s waited %v for connections in the last %v (in use: %d, max open: %d)",
+			comp, waited, s.interval, st.InUse, st.MaxOpenConnections)
+	}
+}
+
+// readiness 返回各组件就绪状态与连接池统计
+func (d *DefaultApp) readiness(c *gin.Context) {
+	data := ReadinessData{
+		Status:     "ok",
+		Components: make(map[string]ComponentStatus, len(d.components)),
+	}
+
+	for _, comp := range d.components {
+		ready, raw := componentStats(comp)
+		if !ready {
+			data.Status = "unavailable"
+		}
+		data.Components[comp] = ComponentStatus{Ready: ready, Stats: snapshot(raw)}
+	}
+
+	if data.Status != "ok" {
+		c.JSON(http.StatusServiceUnavailable, data)
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
@@ -0,0 +1,227 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"project/pkg/config"
+	"project/pkg/database"
+	"project/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	logger.Logger = zap.NewNop()
+	logger.Sugar = logger.Logger.Sugar()
+	os.Exit(m.Run())
+}
+
+// initConfig 加载只包含必填项的配置，组件不初始化
+func initConfig(t *testing.T, extra string) {
+	t.Helper()
+
+	configContent := `
+server:
+  name: test-app
+  port: 8080
+
+db:
+  mysql:
+    url: "user:pass@tcp(localhost:3306)/dbname"
+` + extra + `
+storage:
+  accessKey: "admin"
+  secretKey: "admin123"
+  bucketName: "test-bucket"
+  endpoint: "localhost:9000"
+  region: "us-west-1"
+`
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(configContent), 0644))
+	config.ResetForTesting()
+	require.NoError(t, config.Init(path))
+	t.Cleanup(config.ResetForTesting)
+}
+
+// initSqlite 加载启用内存 SQLite 的配置并初始化组件
+func initSqlite(t *testing.T) *database.Sqlite {
+	t.Helper()
+
+	initConfig(t, `  sqlite:
+    path: ":memory:"
+`)
+	s := database.GetSqlite()
+	require.NotNil(t, s)
+	require.True(t, s.InitComponent())
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// observeLogs 替换全局 logger 以捕获 warn 及以上的日志
+func observeLogs(t *testing.T) *observer.ObservedLogs {
+	t.Helper()
+
+	core, logs := observer.New(zapcore.WarnLevel)
+	prev := logger.Sugar
+	logger.Sugar = zap.New(core).Sugar()
+	t.Cleanup(func() { logger.Sugar = prev })
+	return logs
+}
+
+func TestStatsCollector_RecordSQL(t *testing.T) {
+	logs := observeLogs(t)
+	collector := newStatsCollector(nil, &config.Monitor{
+		StatsInterval:     time.Second,
+		WaitWarnThreshold: 100 * time.Millisecond,
+	})
+
+	collector.recordSQL("test-db", sql.DBStats{
+		MaxOpenConnections: 10,
+		OpenConnections:    4,
+		InUse:              3,
+		Idle:               1,
+		WaitCount:          2,
+		WaitDuration:       50 * time.Millisecond,
+	})
+	assert.Equal(t, float64(10), sqlMaxOpenGauge.WithLabelValues("test-db").Value())
+	assert.Equal(t, float64(4), sqlOpenGauge.WithLabelValues("test-db").Value())
+	assert.Equal(t, float64(3), sqlInUseGauge.WithLabelValues("test-db").Value())
+	assert.Equal(t, float64(1), sqlIdleGauge.WithLabelValues("test-db").Value())
+	assert.Equal(t, float64(2), sqlWaitCount.WithLabelValues("test-db").Value())
+	assert.InDelta(t, 0.05, sqlWaitSeconds.WithLabelValues("test-db").Value(), 1e-9)
+	assert.Zero(t, logs.Len())
+
+	// 等待耗时按增量判断：本周期新增 150ms 超过阈值
+	collector.recordSQL("test-db", sql.DBStats{MaxOpenConnections: 10, InUse: 10, WaitCount: 5, WaitDuration: 200 * time.Millisecond})
+	require.Equal(t, 1, logs.Len())
+	assert.Contains(t, logs.All()[0].Message, "[monitor] test-db waited 150ms")
+
+	// 没有新的等待时不再告警
+	collector.recordSQL("test-db", sql.DBStats{MaxOpenConnections: 10, WaitCount: 5, WaitDuration: 200 * time.Millisecond})
+	assert.Equal(t, 1, logs.Len())
+}
+
+func TestStatsCollector_CollectNotInitialized(t *testing.T) {
+	initConfig(t, "")
+
+	collector := newStatsCollector([]string{"mysql", "unknown"}, &config.Monitor{StatsInterval: time.Second})
+	componentReadyGauge.WithLabelValues("mysql").Set(1)
+	collector.collect()
+	assert.Equal(t, float64(0), componentReadyGauge.WithLabelValues("mysql").Value())
+	assert.Equal(t, float64(0), componentReadyGauge.WithLabelValues("unknown").Value())
+}
+
+func TestReadiness_NotInitialized(t *testing.T) {
+	initConfig(t, "")
+
+	get := func(components ...string) (int, ReadinessData) {
+		d := &DefaultApp{components: components}
+		engine := gin.New()
+		engine.GET("/readyz", d.readiness)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var data ReadinessData
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &data))
+		return w.Code, data
+	}
+
+	code, data := get()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", data.Status)
+	assert.Empty(t, data.Components)
+
+	// 已配置但未初始化的组件返回 503，并附带连接池快照
+	code, data = get("mysql")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "unavailable", data.Status)
+	require.Contains(t, data.Components, "mysql")
+	assert.False(t, data.Components["mysql"].Ready)
+	stats, ok := data.Components["mysql"].Stats.(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, float64(0), stats["max_open_connections"])
+	assert.Equal(t, "0s", stats["wait_duration"])
+}
+
+func TestStatsCollector_CollectSqlite(t *testing.T) {
+	s := initSqlite(t)
+	logs := observeLogs(t)
+
+	collector := newStatsCollector([]string{"sqlite"}, &config.Monitor{
+		StatsInterval:     time.Second,
+		WaitWarnThreshold: 10 * time.Millisecond,
+	})
+	collector.collect()
+
+	assert.Equal(t, float64(1), componentReadyGauge.WithLabelValues("sqlite").Value())
+	assert.Equal(t, float64(1), sqlMaxOpenGauge.WithLabelValues("sqlite").Value())
+	assert.Equal(t, float64(1), sqlOpenGauge.WithLabelValues("sqlite").Value())
+	assert.Zero(t, logs.Len())
+
+	// 内存库只有一条连接，占用期间的查询需要等待
+	ctx := context.Background()
+	conn, err := s.GetSqlDb().Conn(ctx)
+	require.NoError(t, err)
+	queried := make(chan error, 1)
+	go func() {
+		_, err := s.GetSqlDb().ExecContext(ctx, "SELECT 1")
+		queried <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, conn.Close())
+	require.NoError(t, <-queried)
+
+	collector.collect()
+	assert.Equal(t, float64(1), sqlWaitCount.WithLabelValues("sqlite").Value())
+	assert.GreaterOrEqual(t, sqlWaitSeconds.WithLabelValues("sqlite").Value(), 0.04)
+	require.Equal(t, 1, logs.Len())
+	assert.Contains(t, logs.All()[0].Message, "[monitor] sqlite waited")
+
+	require.NoError(t, s.Close())
+	collector.collect()
+	assert.Equal(t, float64(0), componentReadyGauge.WithLabelValues("sqlite").Value())
+}
+
+func TestReadiness_Sqlite(t *testing.T) {
+	s := initSqlite(t)
+
+	d := &DefaultApp{components: []string{"sqlite"}}
+	engine := gin.New()
+	engine.GET("/readyz", d.readiness)
+
+	get := func() (int, ReadinessData) {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var data ReadinessData
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &data))
+		return w.Code, data
+	}
+
+	code, data := get()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", data.Status)
+	require.Contains(t, data.Components, "sqlite")
+	assert.True(t, data.Components["sqlite"].Ready)
+	stats, ok := data.Components["sqlite"].Stats.(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, float64(1), stats["max_open_connections"])
+
+	require.NoError(t, s.Close())
+	code, data = get()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "unavailable", data.Status)
+	assert.False(t, data.Components["sqlite"].Ready)
+}
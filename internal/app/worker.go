@@ -0,0 +1,47 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"project/pkg/logger"
+)
+
+// Worker 随应用启动和停止的后台任务，例如消息消费者、主节点选举器
+//
+// 应用在组件加载完成后按注册顺序启动 Worker，收到退出信号并关闭 HTTP 服务后按相反顺序停止，
+// 之后才关闭组件，因此 Worker 在整个生命周期内都可以使用组件。
+type Worker interface {
+	Name() string
+	Start() error
+	Stop(ctx context.Context) error
+}
+
+// RegisterWorker 注册后台任务，需在 Run 之前调用
+func (d *DefaultApp) RegisterWorker(workers ...Worker) {
+	d.workers = append(d.workers, workers...)
+}
+
+// startWorkers 启动全部 Worker，任一启动失败时停止已启动的 Worker
+func (d *DefaultApp) startWorkers() error {
+	for i, w := range d.workers {
+		if err := w.Start(); err != nil {
+			d.stopWorkers(context.Background(), d.workers[:i])
+			return fmt.Errorf("failed to start worker '%s': %w", w.Name(), err)
+		}
+		logger.Sugar.Infof("\t[worker] %s started", w.Name())
+	}
+	return nil
+}
+
+// stopWorkers 按注册的相反顺序停止 Worker
+func (d *DefaultApp) stopWorkers(ctx context.Context, workers []Worker) {
+	for i := len(workers) - 1; i >= 0; i-- {
+		w := workers[i]
+		if err := w.Stop(ctx); err != nil {
+			logger.Sugar.Errorf("\t[worker] %s stop failed: %v", w.Name(), err)
+			continue
+		}
+		logger.Sugar.Infof("\t[worker] %s stopped", w.Name())
+	}
+}
@@ -0,0 +1,272 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"mime"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/pkg/logger"
+	"project/pkg/redact"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// AccessLogOptions 访问日志配置
+type AccessLogOptions struct {
+	// SkipPaths 不记录的路径，精确匹配；以 * 结尾时按前缀匹配，如 /debug/*
+	SkipPaths []string
+	// SampleRates 按状态码采样的比例（0-1），键为状态码（404）或状态码类别（2xx），精确状态码优先；
+	// 未配置的状态码全部记录。慢请求与 5xx 不采样
+	SampleRates map[string]float64
+	// SlowThreshold 超过该耗时的请求以 warn 级别记录，0 不区分
+	SlowThreshold time.Duration
+	// RequestBody 记录请求体
+	RequestBody bool
+	// ResponseBody 记录响应体
+	ResponseBody bool
+	// MaxBodySize 记录的请求体、响应体的最大字节数，默认 4096
+	MaxBodySize int
+}
+
+// Logger 访问日志中间件，请求结束后输出一条结构化日志。
+//
+// 方法、路由模板、客户端 IP、请求 ID 与用户 ID 由 RequestContext 写入上下文 logger，需在其之后注册；
+// 本中间件追加状态码、耗时、请求与响应字节数、User-Agent 以及可选的请求体与响应体。
+// 只记录 JSON 与表单类型的请求体/响应体，名称敏感的字段按 redact 规则脱敏；文本等其他类型无法按字段脱敏，不记录。
+// 超过 MaxBodySize 时无法完整解析，只记录长度
+func Logger(opts AccessLogOptions) gin.HandlerFunc {
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = 4096
+	}
+	sampler := newStatusSampler(opts.SampleRates)
+
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if skipPath(opts.SkipPaths, path) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		var reqBody *bodyCapture
+		if opts.RequestBody && c.Request.Body != nil && capturable(c.ContentType()) {
+			reqBody = captureRequestBody(c, opts.MaxBodySize)
+		}
+		var respBody *bodyWriter
+		if opts.ResponseBody {
+			respBody = &bodyWriter{ResponseWriter: c.Writer, capture: bodyCapture{limit: opts.MaxBodySize}}
+			c.Writer = respBody
+		}
+
+		c.Next()
+
+		latency := time.Since(start)
+		status := c.Writer.Status()
+		slow := opts.SlowThreshold > 0 && latency >= opts.SlowThreshold
+		if !slow && status < 500 && !sampler.sample(status) {
+			return
+		}
+
+		bytesIn := c.Request.ContentLength
+		if bytesIn < 0 {
+			bytesIn = 0
+		}
+		bytesOut := c.Writer.Size()
+		if bytesOut < 0 {
+			bytesOut = 0
+		}
+		fields := []interface{}{
+			zap.Int("status", status),
+			zap.Duration("latency", latency),
+			zap.String("path", redactedPath(c.Request.URL)),
+			zap.Int64("bytes_in", bytesIn),
+			zap.Int("bytes_out", bytesOut),
+			zap.String("user_agent", c.Request.UserAgent()),
+		}
+		if errs := c.Errors.ByType(gin.ErrorTypePrivate).String(); errs != "" {
+			fields = append(fields, zap.String("errors", errs))
+		}
+		if reqBody != nil {
+			fields = append(fields, zap.String("request_body", reqBody.String(c.ContentType())))
+		}
+		if respBody != nil && capturable(respBody.Header().Get("Content-Type")) {
+			fields = append(fields, zap.String("response_body", respBody.capture.String(respBody.Header().Get("Content-Type"))))
+		}
+
+		log := logger.FromGin(c).Named("http")
+		msg := fmt.Sprintf("\t[http] %s %s %d %v", c.Request.Method, path, status, latency)
+		switch {
+		case status >= 500:
+			log.Errorw(msg, fields...)
+		case slow:
+			log.Warnw(msg, append(fields, zap.Bool("slow", true))...)
+		default:
+			log.Infow(msg, fields...)
+		}
+	}
+}
+
+// skipPath 路径是否在跳过列表中
+func skipPath(patterns []string, path string) bool {
+	for _, p := range patterns {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// statusSampler 按状态码采样
+type statusSampler struct {
+	rates map[string]float64
+	rand  func() float64
+}
+
+func newStatusSampler(rates map[string]float64) *statusSampler {
+	normalized := make(map[string]float64, len(rates))
+	for k, v := range rates {
+		normalized[strings.ToLower(k)] = v
+	}
+	return &statusSampler{rates: normalized, rand: rand.Float64}
+}
+
+func (s *statusSampler) sample(status int) bool {
+	rate, ok := s.rates[strconv.Itoa(status)]
+	if !ok {
+		rate, ok = s.rates[strconv.Itoa(status/100)+"xx"]
+	}
+	if !ok || rate >= 1 {
+		return true
+	}
+	return rate > 0 && s.rand() < rate
+}
+
+// redactedPath 返回路径与查询参数，敏感参数的值替换为 [REDACTED]
+func redactedPath(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.Path
+	}
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return u.Path
+	}
+	return u.Path + "?" + redactValues(query).Encode()
+}
+
+func redactValues(values url.Values) url.Values {
+	for k, vs := range values {
+		for i := range vs {
+			vs[i] = redact.String(k, vs[i])
+		}
+	}
+	return values
+}
+
+// capturable 只记录 JSON 与表单类型
+func capturable(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return isJSON(mediaType) || mediaType == gin.MIMEPOSTForm
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == gin.MIMEJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+// bodyCapture 保存请求体或响应体的前 limit 字节
+type bodyCapture struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+	size      int
+}
+
+func (b *bodyCapture) write(p []byte) {
+	b.size += len(p)
+	if room := b.limit - b.buf.Len(); room > 0 {
+		if len(p) > room {
+			p = p[:room]
+		}
+		b.buf.Write(p)
+	}
+	if b.size > b.limit {
+		b.truncated = true
+	}
+}
+
+// String 按内容类型脱敏后返回
+func (b *bodyCapture) String(contentType string) string {
+	if b.size == 0 {
+		return ""
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	// 截断的 JSON 与表单无法解析，不能确定是否包含敏感字段
+	if b.truncated {
+		return fmt.Sprintf("[TRUNCATED %d bytes]", b.size)
+	}
+	if isJSON(mediaType) {
+		var v interface{}
+		dec := json.NewDecoder(bytes.NewReader(b.buf.Bytes()))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			return fmt.Sprintf("[INVALID JSON %d bytes]", b.size)
+		}
+		out, err := redact.JSON(v)
+		if err != nil {
+			return fmt.Sprintf("[INVALID JSON %d bytes]", b.size)
+		}
+		return string(out)
+	}
+	values, err := url.ParseQuery(b.buf.String())
+	if err != nil {
+		return fmt.Sprintf("[INVALID FORM %d bytes]", b.size)
+	}
+	return redactValues(values).Encode()
+}
+
+// captureRequestBody 读取请求体的前 limit+1 字节用于记录，并把读取的部分放回请求体，不影响后续绑定
+func captureRequestBody(c *gin.Context, limit int) *bodyCapture {
+	capture := &bodyCapture{limit: limit}
+	head, err := io.ReadAll(io.LimitReader(c.Request.Body, int64(limit)+1))
+	capture.write(head)
+	if err == nil && len(head) > limit && c.Request.ContentLength > 0 {
+		// 只读取了前 limit+1 字节，实际长度取 Content-Length
+		capture.size = int(c.Request.ContentLength)
+	}
+	c.Request.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(head), c.Request.Body), Closer: c.Request.Body}
+	return capture
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyWriter 在写出响应的同时保存响应体
+type bodyWriter struct {
+	gin.ResponseWriter
+	capture bodyCapture
+}
+
+func (w *bodyWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.capture.write(p[:n])
+	return n, err
+}
+
+func (w *bodyWriter) WriteString(s string) (int, error) {
+	n, err := w.ResponseWriter.WriteString(s)
+	w.capture.write([]byte(s[:n]))
+	return n, err
+}
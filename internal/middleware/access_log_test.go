@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+// newAccessLogEngine 注册访问日志中间件与测试路由
+func newAccessLogEngine(opts AccessLogOptions) *gin.Engine {
+	engine := gin.New()
+	engine.Use(Logger(opts))
+	engine.GET("/status/:code", func(c *gin.Context) {
+		var code int
+		fmt.Sscan(c.Param("code"), &code)
+		c.String(code, "ok")
+	})
+	engine.GET("/slow", func(c *gin.Context) {
+		time.Sleep(20 * time.Millisecond)
+		c.String(http.StatusOK, "ok")
+	})
+	engine.POST("/echo", func(c *gin.Context) {
+		// 记录请求体后处理函数仍能读取完整的请求体
+		data, err := c.GetRawData()
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Data(http.StatusOK, c.ContentType(), data)
+	})
+	return engine
+}
+
+func serve(engine *gin.Engine, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestLogger_SkipPaths(t *testing.T) {
+	logs := observeLogs(t)
+	engine := newAccessLogEngine(AccessLogOptions{SkipPaths: []string{"/status/204", "/slow*"}})
+
+	tests := []struct {
+		path   string
+		logged bool
+	}{
+		{"/status/204", false},
+		{"/slow", false},
+		{"/status/200", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			logs.TakeAll()
+			serve(engine, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.logged, logs.Len() == 1)
+		})
+	}
+}
+
+func TestStatusSampler(t *testing.T) {
+	s := newStatusSampler(map[string]float64{"2XX": 0.5, "204": 0, "404": 1})
+	s.rand = func() float64 { return 0.4 }
+
+	tests := []struct {
+		status int
+		want   bool
+	}{
+		{http.StatusOK, true},
+		{http.StatusNoContent, false},
+		{http.StatusNotFound, true},
+		{http.StatusBadRequest, true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.status), func(t *testing.T) {
+			assert.Equal(t, tt.want, s.sample(tt.status))
+		})
+	}
+
+	s.rand = func() float64 { return 0.6 }
+	assert.False(t, s.sample(http.StatusOK))
+}
+
+func TestLogger_SampleRatesExemptSlowAndServerErrors(t *testing.T) {
+	logs := observeLogs(t)
+	engine := newAccessLogEngine(AccessLogOptions{
+		SampleRates:   map[string]float64{"2xx": 0, "5xx": 0},
+		SlowThreshold: 10 * time.Millisecond,
+	})
+
+	serve(engine, httptest.NewRequest(http.MethodGet, "/status/200", nil))
+	assert.Zero(t, logs.Len())
+
+	serve(engine, httptest.NewRequest(http.MethodGet, "/status/503", nil))
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, zapcore.ErrorLevel, logs.All()[0].Level)
+
+	serve(engine, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	require.Equal(t, 2, logs.Len())
+	entry := logs.All()[1]
+	assert.Equal(t, zapcore.WarnLevel, entry.Level)
+	assert.Equal(t, true, entry.ContextMap()["slow"])
+	assert.Contains(t, entry.Message, "[http] GET /slow 200")
+}
+
+func TestLogger_Bodies(t *testing.T) {
+	logs := observeLogs(t)
+	engine := newAccessLogEngine(AccessLogOptions{RequestBody: true, ResponseBody: true, MaxBodySize: 64})
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{
+			name:        "JSON 脱敏",
+			contentType: "application/json",
+			body:        `{"username":"alice","password":"p@ss","auth":{"token":"t0k"}}`,
+			want:        `{"auth":{"token":"[REDACTED]"},"password":"[REDACTED]","username":"alice"}`,
+		},
+		{
+			name:        "表单脱敏",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "username=alice&password=p%40ss",
+			want:        "password=%5BREDACTED%5D&username=alice",
+		},
+		{
+			name:        "超过上限只记录长度",
+			contentType: "application/json",
+			body:        `{"password":"p@ss","padding":"` + strings.Repeat("x", 100) + `"}`,
+			want:        "[TRUNCATED 132 bytes]",
+		},
+		{
+			name:        "无效 JSON",
+			contentType: "application/json",
+			body:        `{"password":`,
+			want:        "[INVALID JSON 12 bytes]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.TakeAll()
+			req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := serve(engine, req)
+			assert.Equal(t, tt.body, w.Body.String())
+
+			require.Equal(t, 1, logs.Len())
+			fields := logs.All()[0].ContextMap()
+			assert.Equal(t, tt.want, fields["request_body"])
+			assert.Equal(t, tt.want, fields["response_body"])
+		})
+	}
+}
+
+func TestLogger_DoesNotLogSecrets(t *testing.T) {
+	logs := observeLogs(t)
+	engine := newAccessLogEngine(AccessLogOptions{RequestBody: true, ResponseBody: true})
+
+	// 文本类型无法按字段脱敏，不记录请求体与响应体
+	req := httptest.NewRequest(http.MethodPost, "/echo?token=q-secret&page=2", strings.NewReader("password=plain-secret"))
+	req.Header.Set("Content-Type", "text/plain")
+	serve(engine, req)
+
+	req = httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{"api_key":"json-secret"}`))
+	req.Header.Set("Content-Type", "application/json")
+	serve(engine, req)
+
+	require.Equal(t, 2, logs.Len())
+	text := logs.All()[0].ContextMap()
+	assert.NotContains(t, text, "request_body")
+	assert.NotContains(t, text, "response_body")
+	assert.Equal(t, "/echo?page=2&token=%5BREDACTED%5D", text["path"])
+
+	for _, entry := range logs.All() {
+		line := entry.Message + fmt.Sprint(entry.ContextMap())
+		for _, secret := range []string{"q-secret", "plain-secret", "json-secret"} {
+			assert.NotContains(t, line, secret)
+		}
+	}
+}
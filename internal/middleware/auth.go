@@ -1,12 +1,15 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"strings"
 
 	"project/pkg/jwt"
+	"project/pkg/logger"
 	"project/pkg/response"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 // JWT 认证中间件
@@ -54,11 +57,25 @@ func JWTAuth() gin.HandlerFunc {
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("role", claims.Role)
+		logger.WithGin(c, zap.String(logger.FieldUserID, claims.UserID))
 
 		c.Next()
 	}
 }
 
+// AdminAuth 管理接口认证中间件，请求需携带 Authorization: Bearer <token>
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			response.Unauthorized(c, "管理令牌无效")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
 // JWTAuthOptional 可选的 JWT 认证（不强制要求 token）
 func JWTAuthOptional() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -75,6 +92,7 @@ func JWTAuthOptional() gin.HandlerFunc {
 				c.Set("user_id", claims.UserID)
 				c.Set("username", claims.Username)
 				c.Set("role", claims.Role)
+				logger.WithGin(c, zap.String(logger.FieldUserID, claims.UserID))
 			}
 		}
 
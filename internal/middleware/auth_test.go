@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminAuth(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		want   int
+	}{
+		{name: "令牌正确", token: "s3cret-admin", header: "Bearer s3cret-admin", want: http.StatusOK},
+		{name: "未携带令牌", token: "s3cret-admin", want: http.StatusUnauthorized},
+		{name: "令牌错误", token: "s3cret-admin", header: "Bearer wrong", want: http.StatusUnauthorized},
+		{name: "缺少 Bearer 前缀", token: "s3cret-admin", header: "s3cret-admin", want: http.StatusUnauthorized},
+		{name: "未配置令牌时拒绝所有请求", header: "Bearer ", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := gin.New()
+			engine.GET("/debug/log/level", AdminAuth(tt.token), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodGet, "/debug/log/level", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
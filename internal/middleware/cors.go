@@ -2,28 +2,280 @@ package middleware
 
 import (
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// 跨域策略的默认值
+var (
+	DefaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead, http.MethodOptions}
+	DefaultCORSHeaders = []string{"Origin", "Accept", "Content-Type", "Authorization", "X-Requested-With", "X-Request-ID"}
+	DefaultCORSExpose  = []string{"Content-Length", "Content-Type", "X-Request-ID", "X-Service-Version"}
+)
+
+// CORSPolicy 跨域策略
+type CORSPolicy struct {
+	// AllowOrigins 允许的来源：精确匹配（https://app.example.com）；*.example.com 或 https://*.example.com 匹配任意子域名；
+	// * 允许任意来源，此时响应 Allow-Origin: *；与 AllowCredentials 同时使用时忽略 *
+	AllowOrigins []string
+	// AllowOriginRegex 正则匹配整个 Origin（自动添加 ^ 与 $），如 https://pr-\d+\.preview\.example\.com
+	AllowOriginRegex []string
+	// AllowMethods 预检允许的方法，默认 DefaultCORSMethods
+	AllowMethods []string
+	// AllowHeaders 预检允许的请求头（不区分大小写），默认 DefaultCORSHeaders；* 允许任意请求头
+	AllowHeaders []string
+	// ExposeHeaders 浏览器可读取的响应头，默认 DefaultCORSExpose
+	ExposeHeaders []string
+	// AllowCredentials 允许携带 Cookie 等凭证
+	AllowCredentials bool
+	// MaxAge 预检结果缓存时间，0 不发送
+	MaxAge time.Duration
+}
+
+// CORSGroup 路由前缀的跨域策略，完整替换默认策略
+type CORSGroup struct {
+	Prefix string
+	Policy CORSPolicy
+}
+
+// CORSOptions 跨域中间件配置
+type CORSOptions struct {
+	// Policy 默认策略
+	Policy CORSPolicy
+	// Groups 按路由前缀覆盖，最长前缀优先。预检请求通常没有注册路由，因此按路径前缀而不是在路由组上注册中间件
+	Groups []CORSGroup
+}
+
 // CORS 跨域中间件
-func CORS(version string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		method := c.Request.Method
+//
+// 来源匹配时回写该来源并添加 Vary: Origin；不匹配时不添加跨域头，由浏览器拦截。
+// 预检请求（OPTIONS 且带 Access-Control-Request-Method）校验来源、方法与请求头，通过返回 204，否则返回 403。
+// AllowOriginRegex 不合法时 panic，配置已在加载时校验
+func CORS(version string, opts CORSOptions) gin.HandlerFunc {
+	def := newCORSPolicy(opts.Policy)
+	groups := make([]corsGroup, 0, len(opts.Groups))
+	for _, g := range opts.Groups {
+		groups = append(groups, corsGroup{prefix: g.Prefix, policy: newCORSPolicy(g.Policy)})
+	}
 
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE, PATCH")
-		c.Header("Access-Control-Allow-Headers", "Authorization, Content-Length, X-CSRF-Token, Token, session, X-Requested-With, Accept, Origin, Host, Connection, Accept-Encoding, Accept-Language, DNT, X-CustomHeader, Keep-Alive, User-Agent, If-Modified-Since, Cache-Control, Content-Type, X-Jwt, X-Signature, X-Time-Stamp")
-		c.Header("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers, Cache-Control, Content-Language, Content-Type, Expires, Last-Modified, Pragma")
-		c.Header("Access-Control-Max-Age", "172800")
-		c.Header("Access-Control-Allow-Credentials", "true")
+	return func(c *gin.Context) {
 		c.Header("X-Service-Version", version)
 
-		if method == http.MethodOptions {
-			c.AbortWithStatus(http.StatusNoContent)
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
 			return
 		}
+		p := def
+		if g := matchCORSGroup(groups, c.Request.URL.Path); g != nil {
+			p = g
+		}
 
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if preflight {
+			p.preflight(c, origin)
+			return
+		}
+		p.setOrigin(c, origin)
 		c.Next()
 	}
 }
+
+type corsGroup struct {
+	prefix string
+	policy *corsPolicy
+}
+
+// matchCORSGroup 返回最长前缀匹配的路由组策略
+func matchCORSGroup(groups []corsGroup, path string) *corsPolicy {
+	var match *corsGroup
+	for i := range groups {
+		g := &groups[i]
+		if pathHasPrefix(path, g.prefix) && (match == nil || len(g.prefix) > len(match.prefix)) {
+			match = g
+		}
+	}
+	if match == nil {
+		return nil
+	}
+	return match.policy
+}
+
+// pathHasPrefix 按路径段匹配，/api 匹配 /api 与 /api/users，不匹配 /apis
+func pathHasPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/") || prefix == ""
+}
+
+// corsPolicy 预处理后的跨域策略
+type corsPolicy struct {
+	anyOrigin bool
+	origins   map[string]bool
+	wildcards []wildcardOrigin
+	regexps   []*regexp.Regexp
+
+	methods     map[string]bool
+	anyHeader   bool
+	headers     map[string]bool
+	credentials bool
+
+	allowMethods  string
+	allowHeaders  string
+	exposeHeaders string
+	maxAge        string
+}
+
+// wildcardOrigin *.example.com 形式的来源，scheme 为空时匹配任意协议
+type wildcardOrigin struct {
+	scheme string
+	suffix string
+}
+
+func newCORSPolicy(p CORSPolicy) *corsPolicy {
+	methods := p.AllowMethods
+	if len(methods) == 0 {
+		methods = DefaultCORSMethods
+	}
+	headers := p.AllowHeaders
+	if len(headers) == 0 {
+		headers = DefaultCORSHeaders
+	}
+	expose := p.ExposeHeaders
+	if len(expose) == 0 {
+		expose = DefaultCORSExpose
+	}
+
+	cp := &corsPolicy{
+		origins:       make(map[string]bool),
+		methods:       make(map[string]bool),
+		headers:       make(map[string]bool),
+		credentials:   p.AllowCredentials,
+		allowMethods:  strings.Join(methods, ", "),
+		allowHeaders:  strings.Join(headers, ", "),
+		exposeHeaders: strings.Join(expose, ", "),
+	}
+	for _, o := range p.AllowOrigins {
+		o = strings.ToLower(o)
+		switch {
+		case o == "*":
+			// 携带凭证时回写任意来源等同于关闭同源策略，忽略 *
+			cp.anyOrigin = !p.AllowCredentials
+		case strings.Contains(o, "*"):
+			scheme, host, ok := strings.Cut(o, "://")
+			if !ok {
+				scheme, host = "", o
+			}
+			cp.wildcards = append(cp.wildcards, wildcardOrigin{scheme: scheme, suffix: strings.TrimPrefix(host, "*")})
+		default:
+			cp.origins[o] = true
+		}
+	}
+	for _, expr := range p.AllowOriginRegex {
+		cp.regexps = append(cp.regexps, regexp.MustCompile("^(?:"+expr+")$"))
+	}
+	for _, m := range methods {
+		cp.methods[strings.ToUpper(m)] = true
+	}
+	for _, h := range headers {
+		if h == "*" {
+			cp.anyHeader = true
+		}
+		cp.headers[strings.ToLower(h)] = true
+	}
+	if p.MaxAge > 0 {
+		cp.maxAge = strconv.Itoa(int(p.MaxAge.Seconds()))
+	}
+	return cp
+}
+
+func (p *corsPolicy) allowOrigin(origin string) bool {
+	o := strings.ToLower(origin)
+	if p.origins[o] {
+		return true
+	}
+	if scheme, host, ok := strings.Cut(o, "://"); ok {
+		for _, w := range p.wildcards {
+			if (w.scheme == "" || w.scheme == scheme) && len(host) > len(w.suffix) && strings.HasSuffix(host, w.suffix) {
+				return true
+			}
+		}
+	}
+	for _, re := range p.regexps {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// originAllowed 来源是否允许；除 * 以外响应都随 Origin 变化，添加 Vary: Origin 供缓存区分
+func (p *corsPolicy) originAllowed(c *gin.Context, origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	c.Writer.Header().Add("Vary", "Origin")
+	return p.allowOrigin(origin)
+}
+
+// setOrigin 设置 Allow-Origin 与凭证头，来源不允许时不设置
+func (p *corsPolicy) setOrigin(c *gin.Context, origin string) {
+	if !p.originAllowed(c, origin) {
+		return
+	}
+	p.writeOrigin(c, origin)
+	c.Header("Access-Control-Expose-Headers", p.exposeHeaders)
+}
+
+func (p *corsPolicy) writeOrigin(c *gin.Context, origin string) {
+	if p.anyOrigin {
+		c.Header("Access-Control-Allow-Origin", "*")
+	} else {
+		c.Header("Access-Control-Allow-Origin", origin)
+	}
+	if p.credentials {
+		c.Header("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// preflight 校验预检请求的来源、方法与请求头，通过返回 204，否则返回 403 且不设置跨域头
+func (p *corsPolicy) preflight(c *gin.Context, origin string) {
+	c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+	c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+	requested := c.GetHeader("Access-Control-Request-Headers")
+	if !p.originAllowed(c, origin) ||
+		!p.methods[strings.ToUpper(c.GetHeader("Access-Control-Request-Method"))] ||
+		!p.headersAllowed(requested) {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+
+	p.writeOrigin(c, origin)
+	c.Header("Access-Control-Allow-Methods", p.allowMethods)
+	if p.anyHeader && requested != "" {
+		// 携带凭证时浏览器不认可 *，回写请求的头
+		c.Header("Access-Control-Allow-Headers", requested)
+	} else {
+		c.Header("Access-Control-Allow-Headers", p.allowHeaders)
+	}
+	if p.maxAge != "" {
+		c.Header("Access-Control-Max-Age", p.maxAge)
+	}
+	c.AbortWithStatus(http.StatusNoContent)
+}
+
+func (p *corsPolicy) headersAllowed(requested string) bool {
+	if p.anyHeader {
+		return true
+	}
+	for _, h := range strings.Split(requested, ",") {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h != "" && !p.headers[h] {
+			return false
+		}
+	}
+	return true
+}
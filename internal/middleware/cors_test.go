@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// newCORSEngine 注册跨域中间件与 /api、/public 路由
+func newCORSEngine(opts CORSOptions) *gin.Engine {
+	engine := gin.New()
+	engine.Use(CORS("v1", opts))
+	for _, path := range []string{"/api/users", "/public/files"} {
+		engine.GET(path, func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	}
+	return engine
+}
+
+func corsRequest(engine *gin.Engine, method, path, origin string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORS_OriginMatching(t *testing.T) {
+	engine := newCORSEngine(CORSOptions{Policy: CORSPolicy{
+		AllowOrigins:     []string{"https://app.example.com", "https://*.example.org", "*.example.net"},
+		AllowOriginRegex: []string{`https://pr-\d+\.preview\.example\.com`},
+	}})
+
+	tests := []struct {
+		name    string
+		origin  string
+		allowed bool
+	}{
+		{"精确匹配", "https://app.example.com", true},
+		{"精确匹配不区分大小写", "https://APP.example.com", true},
+		{"精确匹配不含端口", "https://app.example.com:8443", false},
+		{"子域名通配", "https://a.b.example.org", true},
+		{"子域名通配限定协议", "http://a.example.org", false},
+		{"子域名通配不匹配主域名", "https://example.org", false},
+		{"子域名通配不匹配后缀相同的域名", "https://evilexample.org", false},
+		{"子域名通配任意协议", "http://a.example.net", true},
+		{"正则", "https://pr-42.preview.example.com", true},
+		{"正则匹配整个来源：前缀", "https://evil.com?https://pr-1.preview.example.com", false},
+		{"正则匹配整个来源：后缀", "https://pr-1.preview.example.com.evil.com", false},
+		{"未配置", "https://evil.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := corsRequest(engine, http.MethodGet, "/api/users", tt.origin, nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "v1", w.Header().Get("X-Service-Version"))
+			assert.Equal(t, []string{"Origin"}, w.Header().Values("Vary"))
+			if tt.allowed {
+				assert.Equal(t, tt.origin, w.Header().Get("Access-Control-Allow-Origin"))
+				assert.NotEmpty(t, w.Header().Get("Access-Control-Expose-Headers"))
+			} else {
+				assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+			}
+		})
+	}
+
+	// 非跨域请求不添加跨域头
+	w := corsRequest(engine, http.MethodGet, "/api/users", "", nil)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Values("Vary"))
+}
+
+func TestCORS_AnyOrigin(t *testing.T) {
+	// 任意来源的响应不随 Origin 变化，不添加 Vary
+	engine := newCORSEngine(CORSOptions{Policy: CORSPolicy{AllowOrigins: []string{"*"}}})
+	w := corsRequest(engine, http.MethodGet, "/api/users", "https://any.com", nil)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Empty(t, w.Header().Values("Vary"))
+
+	// 携带凭证时忽略 *，只允许列出的来源
+	engine = newCORSEngine(CORSOptions{Policy: CORSPolicy{
+		AllowOrigins:     []string{"*", "https://app.example.com"},
+		AllowCredentials: true,
+	}})
+	w = corsRequest(engine, http.MethodGet, "/api/users", "https://evil.com", nil)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, []string{"Origin"}, w.Header().Values("Vary"))
+
+	w = corsRequest(engine, http.MethodGet, "/api/users", "https://app.example.com", nil)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	engine := newCORSEngine(CORSOptions{Policy: CORSPolicy{
+		AllowOrigins:     []string{"https://app.example.com"},
+		AllowMethods:     []string{http.MethodGet, http.MethodPost},
+		AllowHeaders:     []string{"Content-Type", "Authorization"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}})
+
+	tests := []struct {
+		name    string
+		origin  string
+		method  string
+		headers string
+		want    int
+	}{
+		{"通过", "https://app.example.com", http.MethodPost, "content-type, Authorization", http.StatusNoContent},
+		{"来源不允许", "https://evil.com", http.MethodPost, "", http.StatusForbidden},
+		{"方法不允许", "https://app.example.com", http.MethodDelete, "", http.StatusForbidden},
+		{"请求头不允许", "https://app.example.com", http.MethodPost, "X-Custom", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := corsRequest(engine, http.MethodOptions, "/api/users", tt.origin, map[string]string{
+				"Access-Control-Request-Method":  tt.method,
+				"Access-Control-Request-Headers": tt.headers,
+			})
+			assert.Equal(t, tt.want, w.Code)
+			assert.ElementsMatch(t, []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}, w.Header().Values("Vary"))
+			if tt.want != http.StatusNoContent {
+				assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+				assert.Empty(t, w.Header().Get("Access-Control-Allow-Methods"))
+				return
+			}
+			assert.Equal(t, tt.origin, w.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+			assert.Equal(t, "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+			assert.Equal(t, "Content-Type, Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+			assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+		})
+	}
+
+	// 没有 Access-Control-Request-Method 的 OPTIONS 不是预检请求
+	w := corsRequest(engine, http.MethodOptions, "/api/users", "https://app.example.com", nil)
+	assert.NotEqual(t, http.StatusNoContent, w.Code)
+}
+
+func TestCORS_PreflightAnyHeader(t *testing.T) {
+	engine := newCORSEngine(CORSOptions{Policy: CORSPolicy{
+		AllowOrigins: []string{"https://app.example.com"},
+		AllowHeaders: []string{"*"},
+	}})
+	w := corsRequest(engine, http.MethodOptions, "/api/users", "https://app.example.com", map[string]string{
+		"Access-Control-Request-Method":  http.MethodGet,
+		"Access-Control-Request-Headers": "X-Custom",
+	})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "X-Custom", w.Header().Get("Access-Control-Allow-Headers"))
+}
+
+func TestCORS_Groups(t *testing.T) {
+	engine := newCORSEngine(CORSOptions{
+		Policy: CORSPolicy{AllowOrigins: []string{"https://app.example.com"}, AllowCredentials: true},
+		Groups: []CORSGroup{
+			{Prefix: "/public", Policy: CORSPolicy{AllowOrigins: []string{"*"}}},
+			{Prefix: "/public/files/private", Policy: CORSPolicy{AllowOrigins: []string{"https://admin.example.com"}}},
+		},
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		origin string
+		want   string
+	}{
+		{"默认策略", "/api/users", "https://app.example.com", "https://app.example.com"},
+		{"默认策略不允许", "/api/users", "https://other.com", ""},
+		{"路由组替换默认策略", "/public/files", "https://other.com", "*"},
+		{"按路径段匹配前缀", "/publicity", "https://other.com", ""},
+		{"最长前缀优先", "/public/files/private/1", "https://other.com", ""},
+		{"最长前缀优先：允许", "/public/files/private/1", "https://admin.example.com", "https://admin.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := corsRequest(engine, http.MethodGet, tt.path, tt.origin, nil)
+			assert.Equal(t, tt.want, w.Header().Get("Access-Control-Allow-Origin"))
+		})
+	}
+
+	// 路由组策略完整替换默认策略，不继承凭证设置
+	w := corsRequest(engine, http.MethodGet, "/public/files", "https://other.com", nil)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+
+	// 预检请求没有注册路由，同样按路径前缀选择策略
+	w = corsRequest(engine, http.MethodOptions, "/public/files", "https://other.com", map[string]string{
+		"Access-Control-Request-Method": http.MethodGet,
+	})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+}
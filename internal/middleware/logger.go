@@ -1,38 +1,33 @@
 package middleware
 
 import (
-	"time"
-
 	"project/pkg/logger"
+	"project/pkg/requestid"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
-// Logger 日志中间件
-func Logger() gin.HandlerFunc {
+// RequestContext 为请求创建上下文 logger，携带请求 ID、客户端 IP、方法、路由与 trace 字段，
+// 之后通过 logger.FromGin(c) 或 logger.FromContext(c.Request.Context()) 记录的日志都会带上这些字段；
+// 请求 ID 由 RequestID 中间件写入，需在其之后注册
+func RequestContext() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		start := time.Now()
-		path := c.Request.URL.Path
-		query := c.Request.URL.RawQuery
-
-		c.Next()
+		requestID := requestid.FromGin(c)
 
-		latency := time.Since(start)
-		statusCode := c.Writer.Status()
-		method := c.Request.Method
-		clientIP := c.ClientIP()
-
-		if query != "" {
-			path = path + "?" + query
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		fields := []zap.Field{
+			zap.String(logger.FieldRequestID, requestID),
+			zap.String(logger.FieldClientIP, c.ClientIP()),
+			zap.String(logger.FieldMethod, c.Request.Method),
+			zap.String(logger.FieldRoute, route),
 		}
+		fields = append(fields, logger.TraceFields(c.GetHeader("traceparent"))...)
+		logger.WithGin(c, fields...)
 
-		logger.Sugar.Infof("[GIN] %s | %3d | %13v | %15s | %-7s %s",
-			time.Now().Format("2006-01-02 15:04:05"),
-			statusCode,
-			latency,
-			clientIP,
-			method,
-			path,
-		)
+		c.Next()
 	}
 }
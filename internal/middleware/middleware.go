@@ -1,17 +1,31 @@
 package middleware
 
 import (
-	"time"
-
 	"github.com/gin-gonic/gin"
 )
 
+// Options 默认中间件配置
+type Options struct {
+	RequestID RequestIDOptions
+	AccessLog AccessLogOptions
+	CORS      CORSOptions
+	RateLimit RateLimitOptions
+	Timeout   TimeoutOptions
+}
+
 // RegisterDefaultMiddlewares 注册默认中间件
-func RegisterDefaultMiddlewares(engine *gin.Engine, version string) {
+func RegisterDefaultMiddlewares(engine *gin.Engine, version string, opts Options) {
+	// gin.Context 作为 context.Context 传递时使用请求 context 的截止时间与取消信号
+	engine.ContextWithFallback = true
+
 	// 核心中间件（按顺序）
 	engine.Use(Recovery())                // Panic 恢复
-	engine.Use(Logger())                  // 日志记录
-	engine.Use(CORS(version))             // 跨域处理
-	engine.Use(Timeout(30 * time.Second)) // 请求超时
-	// engine.Use(RateLimit())                  // 限流（可选）
+	engine.Use(RequestID(opts.RequestID)) // 请求 ID
+	engine.Use(RequestContext())          // 请求上下文 logger
+	engine.Use(Logger(opts.AccessLog))    // 访问日志
+	engine.Use(CORS(version, opts.CORS))  // 跨域处理，预检请求不计入限流
+	if len(opts.RateLimit.Rules) > 0 {
+		engine.Use(RateLimit(opts.RateLimit)) // 限流
+	}
+	engine.Use(Timeout(opts.Timeout)) // 请求超时，缓冲响应，超时返回 504
 }
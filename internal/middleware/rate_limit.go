@@ -1,69 +1,97 @@
 package middleware
 
 import (
-	"sync"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 
-	"project/pkg/errcode"
+	"project/pkg/jwt"
+	"project/pkg/logger"
+	"project/pkg/ratelimit"
 	"project/pkg/response"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
-// 简单的令牌桶限流器
-type rateLimiter struct {
-	tokens     int
-	maxTokens  int
-	refillRate time.Duration
-	lastRefill time.Time
-	mu         sync.Mutex
-}
-
-func newRateLimiter(maxTokens int, refillRate time.Duration) *rateLimiter {
-	return &rateLimiter{
-		tokens:     maxTokens,
-		maxTokens:  maxTokens,
-		refillRate: refillRate,
-		lastRefill: time.Now(),
-	}
-}
-
-func (r *rateLimiter) allow() bool {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+// 限流键类型
+const (
+	// RateLimitByIP 按客户端 IP
+	RateLimitByIP = "ip"
+	// RateLimitByUser 按用户 ID，未登录时按 IP
+	RateLimitByUser = "user"
+	// RateLimitByAPIKey 按 API Key 请求头，未携带或未通过 APIKeyValidator 校验时按 IP
+	RateLimitByAPIKey = "api_key"
+	// RateLimitByRoute 按路由模板，同一路由的所有请求共享配额
+	RateLimitByRoute = "route"
+)
 
-	now := time.Now()
-	elapsed := now.Sub(r.lastRefill)
+// RateLimitKeys 支持的限流键类型
+var RateLimitKeys = []string{RateLimitByIP, RateLimitByUser, RateLimitByAPIKey, RateLimitByRoute}
 
-	// 补充令牌
-	tokensToAdd := int(elapsed / r.refillRate)
-	if tokensToAdd > 0 {
-		r.tokens = min(r.tokens+tokensToAdd, r.maxTokens)
-		r.lastRefill = now
-	}
+// DefaultAPIKeyHeader 默认的 API Key 请求头
+const DefaultAPIKeyHeader = "X-API-Key"
 
-	// 检查是否有可用令牌
-	if r.tokens > 0 {
-		r.tokens--
-		return true
-	}
-	return false
+// RateLimitRule 路由前缀的限流规则
+type RateLimitRule struct {
+	// Prefix 路由前缀，按路径段匹配，最长前缀优先；为空匹配所有请求
+	Prefix string
+	// Key 限流键类型，默认 ip
+	Key string
+	// Limiter 限流器，每条规则独立计数
+	Limiter ratelimit.Limiter
 }
 
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
+// RateLimitOptions 限流中间件配置
+type RateLimitOptions struct {
+	// Rules 限流规则，每个请求只按最长前缀匹配的一条规则限流，没有匹配的规则时不限流
+	Rules []RateLimitRule
+	// APIKeyHeader api_key 类型读取的请求头，默认 X-API-Key
+	APIKeyHeader string
+	// APIKeyValidator 校验 API Key 是否有效，只有有效的 Key 按 Key 限流；为空时 api_key 类型总是按 IP，
+	// 避免客户端伪造任意 Key 绕过限流
+	APIKeyValidator func(c *gin.Context, key string) bool
 }
 
-var globalLimiter = newRateLimiter(100, time.Second) // 每秒 100 个请求
-
 // RateLimit 限流中间件
-func RateLimit() gin.HandlerFunc {
+//
+// 按匹配规则的键消耗配额，响应 X-RateLimit-Limit、X-RateLimit-Remaining、X-RateLimit-Reset（秒）；
+// 超出配额时返回 429 与 Retry-After（秒）。限流器出错（如 Redis 不可用）时记录日志并放行。
+// user 类型优先取 JWTAuth 写入的 user_id，在全局注册时从 Bearer 令牌中解析，令牌无效时按 IP；
+// api_key 类型的键为 Key 的哈希，原始 Key 不会写入限流存储与日志
+func RateLimit(opts RateLimitOptions) gin.HandlerFunc {
+	if opts.APIKeyHeader == "" {
+		opts.APIKeyHeader = DefaultAPIKeyHeader
+	}
+	rules := make([]RateLimitRule, len(opts.Rules))
+	copy(rules, opts.Rules)
+
 	return func(c *gin.Context) {
-		if !globalLimiter.allow() {
-			response.Failed(c, errcode.TooManyRequest, errcode.ErrorMessage[errcode.TooManyRequest])
+		rule := matchRateLimitRule(rules, c.Request.URL.Path)
+		if rule == nil {
+			c.Next()
+			return
+		}
+
+		key := rateLimitKey(c, rule.Key, opts)
+		res, err := rule.Limiter.Allow(c.Request.Context(), key)
+		if err != nil {
+			logger.FromGin(c).Named("ratelimit").Warnw(fmt.Sprintf("\t[ratelimit] %s 限流失败，放行请求", rule.Prefix),
+				zap.String("key", key), zap.Error(err))
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("X-RateLimit-Reset", ceilSeconds(res.ResetAfter))
+		if !res.Allowed {
+			c.Header("Retry-After", ceilSeconds(res.RetryAfter))
+			response.TooManyRequests(c)
 			c.Abort()
 			return
 		}
@@ -71,15 +99,59 @@ func RateLimit() gin.HandlerFunc {
 	}
 }
 
-// RateLimitWithConfig 自定义配置的限流中间件
-func RateLimitWithConfig(maxTokens int, refillRate time.Duration) gin.HandlerFunc {
-	limiter := newRateLimiter(maxTokens, refillRate)
-	return func(c *gin.Context) {
-		if !limiter.allow() {
-			response.Failed(c, errcode.TooManyRequest, errcode.ErrorMessage[errcode.TooManyRequest])
-			c.Abort()
-			return
+// matchRateLimitRule 返回最长前缀匹配的规则
+func matchRateLimitRule(rules []RateLimitRule, path string) *RateLimitRule {
+	var match *RateLimitRule
+	for i := range rules {
+		r := &rules[i]
+		if pathHasPrefix(path, r.Prefix) && (match == nil || len(r.Prefix) > len(match.Prefix)) {
+			match = r
 		}
-		c.Next()
 	}
+	return match
+}
+
+// rateLimitKey 返回限流键，不同类型的键带类型前缀，回退到 IP 的请求与按 IP 限流的请求共享配额
+func rateLimitKey(c *gin.Context, kind string, opts RateLimitOptions) string {
+	switch kind {
+	case RateLimitByUser:
+		if id := rateLimitUserID(c); id != "" {
+			return "user:" + id
+		}
+	case RateLimitByAPIKey:
+		if key := c.GetHeader(opts.APIKeyHeader); key != "" && opts.APIKeyValidator != nil && opts.APIKeyValidator(c, key) {
+			return "api_key:" + hashAPIKey(key)
+		}
+	case RateLimitByRoute:
+		// 未匹配路由的请求共享一个键，避免任意路径产生大量键
+		return "route:" + c.Request.Method + " " + c.FullPath()
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// hashAPIKey 返回 API Key 的 SHA-256 前 16 位十六进制，用于限流键与日志
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// rateLimitUserID 优先取 JWTAuth 写入的用户 ID，否则解析 Bearer 令牌（只用于识别用户，不做鉴权）
+func rateLimitUserID(c *gin.Context) string {
+	if id, ok := c.Get("user_id"); ok {
+		return fmt.Sprint(id)
+	}
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		return ""
+	}
+	claims, err := jwt.ParseToken(token)
+	if err != nil {
+		return ""
+	}
+	return claims.UserID
+}
+
+// ceilSeconds 向上取整到秒
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
 }
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"project/pkg/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordLimiter 记录限流键并返回错误，中间件记录日志后放行
+type recordLimiter struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+func (l *recordLimiter) Allow(_ context.Context, key string) (ratelimit.Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.keys = append(l.keys, key)
+	return ratelimit.Result{}, errors.New("redis unavailable")
+}
+
+func TestRateLimit_APIKey(t *testing.T) {
+	const validKey = "sk-live-0123456789abcdef"
+
+	tests := []struct {
+		name      string
+		key       string
+		validator func(c *gin.Context, key string) bool
+		want      string
+	}{
+		{
+			name:      "有效的 Key 按哈希计数",
+			key:       validKey,
+			validator: func(_ *gin.Context, key string) bool { return key == validKey },
+			want:      "api_key:" + hashAPIKey(validKey),
+		},
+		{
+			name:      "无效的 Key 按 IP",
+			key:       "random-forged-key",
+			validator: func(_ *gin.Context, key string) bool { return key == validKey },
+			want:      "ip:192.0.2.1",
+		},
+		{
+			name: "未配置校验时按 IP",
+			key:  validKey,
+			want: "ip:192.0.2.1",
+		},
+		{
+			name:      "未携带 Key 按 IP",
+			validator: func(_ *gin.Context, key string) bool { return true },
+			want:      "ip:192.0.2.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := observeLogs(t)
+			limiter := &recordLimiter{}
+			engine := gin.New()
+			engine.Use(RateLimit(RateLimitOptions{
+				Rules:           []RateLimitRule{{Key: RateLimitByAPIKey, Limiter: limiter}},
+				APIKeyValidator: tt.validator,
+			}))
+			engine.GET("/api/orders", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+			req := httptest.NewRequest(http.MethodGet, "/api/orders", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			if tt.key != "" {
+				req.Header.Set(DefaultAPIKeyHeader, tt.key)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusNoContent, w.Code)
+
+			require.Equal(t, []string{tt.want}, limiter.keys)
+			// 原始 Key 不出现在限流键与日志中
+			require.Equal(t, 1, logs.Len())
+			entry := logs.All()[0]
+			assert.Equal(t, tt.want, entry.ContextMap()["key"])
+			if tt.key != "" {
+				assert.NotContains(t, entry.ContextMap()["key"], tt.key)
+			}
+		})
+	}
+}
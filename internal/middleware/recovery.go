@@ -17,7 +17,7 @@ func Recovery() gin.HandlerFunc {
 			if err := recover(); err != nil {
 				// 记录堆栈信息
 				stack := string(debug.Stack())
-				logger.Sugar.Errorf("[Recovery] panic recovered:\n%v\n%s", err, stack)
+				logger.FromGin(c).Errorf("[Recovery] panic recovered:\n%v\n%s", err, stack)
 
 				// 返回统一错误响应
 				response.Failed(c, errcode.ServerError, errcode.ErrorMessage[errcode.ServerError])
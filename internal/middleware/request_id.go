@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"project/pkg/requestid"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDOptions 请求 ID 中间件配置
+type RequestIDOptions struct {
+	// Header 请求 ID 请求头，默认 X-Request-ID
+	Header string
+	// Generator 上游未携带或携带的值不合法时生成请求 ID，默认 UUID
+	Generator requestid.Generator
+}
+
+// RequestID 读取或生成请求 ID，写入 gin.Context、请求的 context 与响应头。
+// 之后 requestid.FromGin(c) / requestid.FromContext(ctx) 可取得该 ID，
+// 通过 httpclient 发出的请求与发布到队列的消息会携带该 ID
+func RequestID(opts RequestIDOptions) gin.HandlerFunc {
+	header := opts.Header
+	if header == "" {
+		header = requestid.Header
+	}
+	generate := opts.Generator
+	if generate == nil {
+		generate = requestid.UUID
+	}
+
+	return func(c *gin.Context) {
+		id := c.GetHeader(header)
+		if !requestid.Valid(id) {
+			id = generate()
+		}
+		c.Set(requestid.ContextKey, id)
+		c.Request = c.Request.WithContext(requestid.NewContext(c.Request.Context(), id))
+		c.Header(header, id)
+
+		c.Next()
+	}
+}
@@ -1,40 +1,261 @@
 package middleware
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"project/pkg/errcode"
+	"project/pkg/logger"
+	"project/pkg/requestid"
 	"project/pkg/response"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
+// TimeoutRoute 路由前缀的超时
+type TimeoutRoute struct {
+	// Prefix 路由前缀，按路径段匹配，最长前缀优先
+	Prefix string
+	// Timeout 超时，0 不限制（如 SSE、文件下载等流式路由）
+	Timeout time.Duration
+}
+
+// TimeoutOptions 超时中间件配置
+type TimeoutOptions struct {
+	// Timeout 默认超时，0 不限制
+	Timeout time.Duration
+	// Routes 按路由前缀覆盖超时
+	Routes []TimeoutRoute
+}
+
+// ErrHandlerTimeout 超时响应已写出后处理函数继续写入响应时返回
+var ErrHandlerTimeout = errors.New("middleware: handler write after timeout")
+
 // Timeout 超时中间件
-func Timeout(timeout time.Duration) gin.HandlerFunc {
+//
+// 处理函数在当前协程中执行，截止时间写入请求 context（c.Request.Context() 与 c.Deadline()），
+// 数据库、HTTP 客户端等下游调用应传递该 context 以便超时后尽快返回。
+// 响应先写入缓冲区：处理完成时原样写出；超时时立即写出 504 与统一响应结构，之后的写入被丢弃。
+// 两者通过锁保证只写出一次，处理函数仍在执行时不会并发访问 gin.Context。
+// WebSocket 升级与 text/event-stream 请求不缓冲也不限时
+func Timeout(opts TimeoutOptions) gin.HandlerFunc {
+	routes := make([]TimeoutRoute, len(opts.Routes))
+	copy(routes, opts.Routes)
+
 	return func(c *gin.Context) {
+		timeout := opts.Timeout
+		if r := matchTimeoutRoute(routes, c.Request.URL.Path); r != nil {
+			timeout = r.Timeout
+		}
+		if timeout <= 0 || streaming(c.Request) {
+			c.Next()
+			return
+		}
+
 		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
 		defer cancel()
-
 		c.Request = c.Request.WithContext(ctx)
 
-		// 使用 channel 来检测处理是否完成
-		finished := make(chan struct{})
-		go func() {
-			c.Next()
-			close(finished)
+		w := newTimeoutWriter(c.Writer, requestid.FromGin(c))
+		c.Writer = w
+		stop := context.AfterFunc(ctx, func() {
+			// 客户端断开时无需响应
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				w.timeout()
+			}
+		})
+		completed := false
+		defer func() {
+			stop()
+			// 处理函数 panic 时丢弃缓冲的响应，由 Recovery 写出 500
+			w.finish(completed)
+			c.Writer = w.ResponseWriter
 		}()
 
-		select {
-		case <-finished:
-			// 请求正常完成
-			return
-		case <-ctx.Done():
-			// 请求超时
-			if ctx.Err() == context.DeadlineExceeded {
-				response.Failed(c, http.StatusRequestTimeout, "Request timeout")
-				c.Abort()
-			}
+		start := time.Now()
+		c.Next()
+		completed = true
+		if w.timedOut() {
+			logger.FromGin(c).Named("http").Warnw(fmt.Sprintf("\t[http] %s %s 处理超时", c.Request.Method, c.Request.URL.Path),
+				zap.Duration("timeout", timeout), zap.Duration("elapsed", time.Since(start)))
+		}
+	}
+}
+
+// matchTimeoutRoute 返回最长前缀匹配的路由超时
+func matchTimeoutRoute(routes []TimeoutRoute, path string) *TimeoutRoute {
+	var match *TimeoutRoute
+	for i := range routes {
+		r := &routes[i]
+		if pathHasPrefix(path, r.Prefix) && (match == nil || len(r.Prefix) > len(match.Prefix)) {
+			match = r
 		}
 	}
+	return match
+}
+
+// streaming WebSocket 与 SSE 请求需要直接写出响应
+func streaming(r *http.Request) bool {
+	return r.Header.Get("Upgrade") != "" || strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// timeoutWriter 缓冲处理函数的响应，嵌入的原始 writer 只在持有锁时写入
+type timeoutWriter struct {
+	gin.ResponseWriter
+	requestID string
+
+	mu      sync.Mutex
+	header  http.Header
+	buf     bytes.Buffer
+	status  int
+	size    int
+	done    bool
+	expired bool
+}
+
+func newTimeoutWriter(w gin.ResponseWriter, requestID string) *timeoutWriter {
+	return &timeoutWriter{
+		ResponseWriter: w,
+		requestID:      requestID,
+		header:         w.Header().Clone(),
+		status:         w.Status(), // 继承 gin 已设置的状态码，如未匹配路由时的 404
+		size:           -1,
+	}
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if code > 0 && w.size < 0 {
+		w.status = code
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.size < 0 {
+		w.size = 0
+	}
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.expired {
+		return 0, ErrHandlerTimeout
+	}
+	if w.size < 0 {
+		w.size = 0
+	}
+	n, err := w.buf.Write(data)
+	w.size += n
+	return n, err
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.expired {
+		return http.StatusGatewayTimeout
+	}
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.size
+}
+
+func (w *timeoutWriter) Written() bool {
+	return w.Size() >= 0
+}
+
+// Flush 响应完成前不写出，流式路由应配置为不限时
+func (w *timeoutWriter) Flush() {}
+
+func (w *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errors.New("middleware: hijack is not supported by timeout middleware")
+}
+
+func (w *timeoutWriter) Pusher() http.Pusher {
+	return nil
+}
+
+func (w *timeoutWriter) timedOut() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.expired
+}
+
+// timeout 写出 504，处理函数已完成时忽略
+func (w *timeoutWriter) timeout() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.done {
+		return
+	}
+	w.done = true
+	w.expired = true
+
+	body, _ := json.Marshal(response.Response{
+		RequestID: w.requestID,
+		Code:      errcode.GatewayTimeout,
+		Message:   errcode.ErrorMessage[errcode.GatewayTimeout],
+	})
+	// 处理函数可能仍在修改 w.header，只保留本中间件之前已设置的响应头（请求 ID、跨域、限流等）
+	dst := w.ResponseWriter.Header()
+	dst.Set("Content-Type", "application/json; charset=utf-8")
+	// 明确长度，客户端读完响应即可返回，无需等待处理函数结束
+	dst.Set("Content-Length", strconv.Itoa(len(body)))
+	w.ResponseWriter.WriteHeader(http.StatusGatewayTimeout)
+	_, _ = w.ResponseWriter.Write(body)
+	w.ResponseWriter.Flush()
+}
+
+// finish 处理函数返回后写出缓冲的响应；flush 为 false 时只丢弃
+func (w *timeoutWriter) finish(flush bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.done {
+		return
+	}
+	w.done = true
+	if !flush {
+		return
+	}
+
+	// 以处理函数看到的响应头为准，包括其删除的响应头
+	dst := w.ResponseWriter.Header()
+	for k := range dst {
+		delete(dst, k)
+	}
+	for k, v := range w.header {
+		dst[k] = v
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.size >= 0 {
+		w.ResponseWriter.WriteHeaderNow()
+		_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+	}
 }
@@ -0,0 +1,197 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"project/pkg/errcode"
+	"project/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeout_PassThrough(t *testing.T) {
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		c.Header("X-Before", "kept")
+		c.Header("X-Removed", "1")
+		c.Next()
+	})
+	engine.Use(Timeout(TimeoutOptions{Timeout: time.Second}))
+	engine.GET("/users", func(c *gin.Context) {
+		_, ok := c.Request.Context().Deadline()
+		assert.True(t, ok)
+		c.Writer.Header().Del("X-Removed")
+		c.Header("X-Handler", "set")
+		c.String(http.StatusCreated, "hello ")
+		c.Writer.Flush()
+		c.String(http.StatusOK, "world")
+		assert.Equal(t, http.StatusCreated, c.Writer.Status())
+		assert.Equal(t, len("hello world"), c.Writer.Size())
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "hello world", w.Body.String())
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "kept", w.Header().Get("X-Before"))
+	assert.Equal(t, "set", w.Header().Get("X-Handler"))
+	assert.Empty(t, w.Header().Values("X-Removed"))
+}
+
+func TestTimeout_WritesGatewayTimeoutOnce(t *testing.T) {
+	const handlerDelay = 2 * time.Second
+	release := make(chan struct{})
+	lateErrs := make(chan error, 2)
+
+	engine := gin.New()
+	engine.Use(RequestID(RequestIDOptions{}))
+	engine.Use(Timeout(TimeoutOptions{Timeout: 50 * time.Millisecond}))
+	engine.GET("/slow", func(c *gin.Context) {
+		c.Header("X-Handler", "set")
+		<-c.Request.Context().Done()
+		// 超时响应已写出，处理函数之后的写入都被丢弃
+		select {
+		case <-release:
+		case <-time.After(handlerDelay):
+		}
+		_, err := c.Writer.Write([]byte("late"))
+		lateErrs <- err
+		c.JSON(http.StatusOK, gin.H{"late": true})
+		lateErrs <- c.Errors.Last()
+	})
+	srv := httptest.NewServer(engine)
+	t.Cleanup(srv.Close)
+
+	// 处理函数仍在执行时客户端已读完整个 504 响应
+	start := time.Now()
+	resp, err := http.Get(srv.URL + "/slow")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Less(t, time.Since(start), handlerDelay)
+	close(release)
+
+	assert.Equal(t, int64(len(body)), resp.ContentLength)
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	assert.Equal(t, "application/json; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Empty(t, resp.Header.Get("X-Handler"))
+	requestID := resp.Header.Get("X-Request-ID")
+	require.NotEmpty(t, requestID)
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	var got response.Response
+	require.NoError(t, dec.Decode(&got))
+	assert.Equal(t, response.Response{Code: errcode.GatewayTimeout, Message: "请求处理超时", RequestID: requestID}, got)
+	assert.False(t, dec.More(), "unexpected data after timeout response: %s", body)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-lateErrs:
+			assert.ErrorIs(t, err, ErrHandlerTimeout)
+		case <-time.After(time.Second):
+			t.Fatal("handler did not finish")
+		}
+	}
+}
+
+func TestTimeout_NotFound(t *testing.T) {
+	engine := gin.New()
+	engine.Use(Timeout(TimeoutOptions{Timeout: time.Second}))
+	engine.GET("/users", func(c *gin.Context) {})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "404 page not found", w.Body.String())
+}
+
+func TestTimeout_Bypass(t *testing.T) {
+	engine := gin.New()
+	engine.Use(Timeout(TimeoutOptions{
+		Timeout: 50 * time.Millisecond,
+		Routes: []TimeoutRoute{
+			{Prefix: "/stream", Timeout: 0},
+			{Prefix: "/export", Timeout: time.Hour},
+		},
+	}))
+	type seen struct {
+		deadline time.Duration
+		buffered bool
+	}
+	var got seen
+	handler := func(c *gin.Context) {
+		got = seen{}
+		if d, ok := c.Request.Context().Deadline(); ok {
+			got.deadline = time.Until(d)
+		}
+		_, got.buffered = c.Writer.(*timeoutWriter)
+		c.Status(http.StatusOK)
+	}
+	for _, path := range []string{"/users", "/stream/events", "/export/orders"} {
+		engine.GET(path, handler)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		header   string
+		value    string
+		buffered bool
+		minimum  time.Duration
+	}{
+		{name: "默认超时", path: "/users", buffered: true},
+		{name: "路由覆盖为不限时", path: "/stream/events"},
+		{name: "路由覆盖为更长的超时", path: "/export/orders", buffered: true, minimum: time.Minute},
+		{name: "SSE", path: "/users", header: "Accept", value: "text/event-stream"},
+		{name: "WebSocket 升级", path: "/users", header: "Upgrade", value: "websocket"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.buffered, got.buffered)
+			if !tt.buffered {
+				assert.Zero(t, got.deadline)
+				return
+			}
+			assert.Positive(t, got.deadline)
+			assert.GreaterOrEqual(t, got.deadline, tt.minimum)
+		})
+	}
+}
+
+func TestTimeout_PanicDiscardsBuffer(t *testing.T) {
+	engine := gin.New()
+	engine.Use(Recovery())
+	engine.Use(Timeout(TimeoutOptions{Timeout: time.Second}))
+	engine.GET("/panic", func(c *gin.Context) {
+		c.Header("X-Handler", "set")
+		c.String(http.StatusCreated, "partial")
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "partial")
+	assert.Empty(t, w.Header().Get("X-Handler"))
+	var got response.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, errcode.ServerError, got.Code)
+}
@@ -0,0 +1,437 @@
+// Package cache 提供两级缓存（进程内 LRU + Redis）的 cache-aside 封装。
+//
+// 读取时依次查询本地缓存、Redis，均未命中时调用 loader 加载并回写两级缓存；
+// 同一 key 的并发加载只会执行一次 loader：
+//
+//	user, err := cache.GetOrLoad(ctx, "user:"+id, 10*time.Minute, func(ctx context.Context) (*model.User, error) {
+//		user, err := repository.GetUser(ctx, id)
+//		if errors.Is(err, gorm.ErrRecordNotFound) {
+//			return nil, cache.ErrNotFound // 空结果同样会被缓存，防止缓存穿透
+//		}
+//		return user, err
+//	}, cache.WithTags("users"))
+//
+//	// 数据变更后按 key 或标签失效，其他副本的本地缓存通过 Redis pub/sub 同步失效
+//	cache.Default().InvalidateTags(ctx, "users")
+//
+// 本地缓存的有效期受 LocalTTL 限制，失效广播丢失时副本间最多在该时长内不一致。
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"project/pkg/config"
+	"project/pkg/logger"
+	"project/pkg/utils/idgen"
+)
+
+// ErrNotFound loader 返回该错误表示数据不存在，结果会按 NegativeTTL 缓存
+var ErrNotFound = errors.New("cache: not found")
+
+const (
+	flagValue    byte = 'v'
+	flagNegative byte = 'n'
+)
+
+// Options 缓存配置
+type Options struct {
+	// Prefix Redis 键前缀
+	Prefix string
+	// LocalSize 本地 LRU 容量（条），0 表示不启用本地缓存
+	LocalSize int
+	// LocalTTL 本地缓存最长有效期，0 表示与写入时的 ttl 一致
+	LocalTTL time.Duration
+	// Jitter 过期时间随机抖动比例，避免大量缓存同时过期
+	Jitter float64
+	// NegativeTTL 空结果缓存时间，0 表示不缓存空结果
+	NegativeTTL time.Duration
+	// Channel 失效广播频道，为空则不广播
+	Channel string
+	// LoadTimeout 单次加载（查询 Redis、调用 loader、回写缓存）的超时，0 使用 defaultLoadTimeout
+	LoadTimeout time.Duration
+}
+
+// defaultLoadTimeout 未配置 LoadTimeout 时的加载超时
+const defaultLoadTimeout = 10 * time.Second
+
+// Cache 两级缓存
+type Cache struct {
+	opts   Options
+	id     string
+	local  *localStore
+	remote Remote
+	group  flightGroup
+
+	mu   sync.Mutex
+	stop func() error
+	done chan struct{}
+}
+
+// New 创建缓存，remote 为 nil 时只使用本地缓存
+func New(remote Remote, opts Options) *Cache {
+	id, err := idgen.GenerateUUID(22)
+	if err != nil {
+		id = fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+
+	c := &Cache{opts: opts, id: id, remote: remote}
+	if opts.LocalSize > 0 {
+		c.local = newLocalStore(opts.LocalSize)
+	}
+	return c
+}
+
+var (
+	defaultOnce  sync.Once
+	defaultCache *Cache
+)
+
+// Default 返回按配置文件创建的默认缓存
+//
+// 配置了 Redis 时每次访问都从 Redis 组件获取客户端，组件加载前创建的缓存在 Redis 初始化后自动启用 Redis 层；
+// 未初始化期间按 Redis 不可用降级。失效广播的订阅由 app 在组件加载后作为 Worker 启动，并在退出时停止
+func Default() *Cache {
+	defaultOnce.Do(func() {
+		opts := Options{
+			Prefix:      "cache:",
+			LocalSize:   10000,
+			LocalTTL:    time.Minute,
+			Jitter:      0.1,
+			NegativeTTL: 30 * time.Second,
+			Channel:     "cache:invalidate",
+			LoadTimeout: defaultLoadTimeout,
+		}
+		cfg := config.Get()
+		if cfg != nil && cfg.Cache != nil {
+			opts = Options{
+				Prefix:      cfg.Cache.Prefix,
+				LocalSize:   cfg.Cache.LocalSize,
+				LocalTTL:    cfg.Cache.LocalTTL,
+				Jitter:      cfg.Cache.Jitter,
+				NegativeTTL: cfg.Cache.NegativeTTL,
+				Channel:     cfg.Cache.Channel,
+				LoadTimeout: cfg.Cache.LoadTimeout,
+			}
+		}
+
+		var remote Remote
+		if cfg != nil && cfg.Db != nil && cfg.Db.Redis != nil {
+			remote = componentRemote{}
+		} else {
+			logger.Sugar.Infof("\t[cache] redis is not configured, default cache is local only")
+		}
+		defaultCache = New(remote, opts)
+	})
+	return defaultCache
+}
+
+// LoadOption 加载选项
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	tags []string
+}
+
+// WithTags 为缓存打上标签，之后可通过 InvalidateTags 批量失效
+func WithTags(tags ...string) LoadOption {
+	return func(o *loadOptions) {
+		o.tags = append(o.tags, tags...)
+	}
+}
+
+// GetOrLoad 使用默认缓存读取 key，未命中时调用 loader 加载并缓存 ttl
+func GetOrLoad[T any](ctx context.Context, key string, ttl time.Duration, loader func(ctx context.Context) (T, error), opts ...LoadOption) (T, error) {
+	return Load(ctx, Default(), key, ttl, loader, opts...)
+}
+
+// Load 使用指定缓存读取 key，未命中时调用 loader 加载并缓存 ttl；值以 JSON 编码存储
+func Load[T any](ctx context.Context, c *Cache, key string, ttl time.Duration, loader func(ctx context.Context) (T, error), opts ...LoadOption) (T, error) {
+	var zero T
+
+	data, err := c.getOrLoad(ctx, key, ttl, func(ctx context.Context) ([]byte, error) {
+		v, err := loader(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(v)
+	}, opts)
+	if err != nil {
+		return zero, err
+	}
+
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return zero, fmt.Errorf("cache: decode %s: %w", key, err)
+	}
+	return v, nil
+}
+
+func (c *Cache) getOrLoad(ctx context.Context, key string, ttl time.Duration, load func(ctx context.Context) ([]byte, error), opts []LoadOption) ([]byte, error) {
+	var o loadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	fullKey := c.opts.Prefix + key
+
+	if c.local != nil {
+		if raw, ok := c.local.get(fullKey); ok {
+			return decodeEntry(raw)
+		}
+	}
+
+	raw, err, _ := c.group.do(ctx, fullKey, func() ([]byte, error) {
+		// 加载由所有等待者共享，不随第一个调用者取消，只受 LoadTimeout 限制
+		timeout := c.opts.LoadTimeout
+		if timeout <= 0 {
+			timeout = defaultLoadTimeout
+		}
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		defer cancel()
+
+		if c.remote != nil {
+			raw, ok, err := c.remote.Get(ctx, fullKey)
+			switch {
+			case err != nil:
+				// Redis 不可用时降级为直接加载
+				logger.Sugar.Warnf("\t[cache] get %s failed: %v", fullKey, err)
+			case ok:
+				c.setLocal(fullKey, raw, ttl, o.tags)
+				return raw, nil
+			}
+		}
+
+		data, err := load(ctx)
+		var raw []byte
+		switch {
+		case errors.Is(err, ErrNotFound):
+			if c.opts.NegativeTTL <= 0 {
+				return nil, err
+			}
+			raw, ttl = []byte{flagNegative}, c.opts.NegativeTTL
+		case err != nil:
+			return nil, err
+		default:
+			raw = append([]byte{flagValue}, data...)
+		}
+
+		c.store(ctx, fullKey, raw, c.jitter(ttl), o.tags)
+		return raw, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeEntry(raw)
+}
+
+// Set 直接写入缓存，并通知其他副本失效本地旧值
+func (c *Cache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration, opts ...LoadOption) error {
+	var o loadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	fullKey := c.opts.Prefix + key
+	if err := c.store(ctx, fullKey, append([]byte{flagValue}, data...), c.jitter(ttl), o.tags); err != nil {
+		return err
+	}
+	return c.broadcast(ctx, invalidation{Keys: []string{fullKey}})
+}
+
+// Delete 删除缓存
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	fullKeys := make([]string, len(keys))
+	for i, key := range keys {
+		fullKeys[i] = c.opts.Prefix + key
+	}
+
+	if c.local != nil {
+		c.local.delete(fullKeys...)
+	}
+	if c.remote != nil {
+		if err := c.remote.Delete(ctx, fullKeys...); err != nil {
+			return err
+		}
+	}
+	return c.broadcast(ctx, invalidation{Keys: fullKeys})
+}
+
+// InvalidateTags 删除带有任一标签的缓存
+func (c *Cache) InvalidateTags(ctx context.Context, tags ...string) error {
+	if c.local != nil {
+		c.local.deleteTags(tags...)
+	}
+
+	msg := invalidation{Tags: tags}
+	if c.remote != nil {
+		tagKeys := make([]string, len(tags))
+		for i, tag := range tags {
+			tagKeys[i] = c.tagKey(tag)
+		}
+		keys, err := c.remote.DeleteTags(ctx, tagKeys...)
+		if err != nil {
+			return err
+		}
+		// 其他副本可能以不同标签缓存了同一 key，按 key 一并失效
+		msg.Keys = keys
+	}
+	return c.broadcast(ctx, msg)
+}
+
+// store 写入两级缓存，Redis 写入失败时记录日志并返回错误
+func (c *Cache) store(ctx context.Context, fullKey string, raw []byte, ttl time.Duration, tags []string) error {
+	c.setLocal(fullKey, raw, ttl, tags)
+
+	if c.remote == nil {
+		return nil
+	}
+	tagKeys := make([]string, len(tags))
+	for i, tag := range tags {
+		tagKeys[i] = c.tagKey(tag)
+	}
+	if err := c.remote.Set(ctx, fullKey, raw, ttl, tagKeys); err != nil {
+		logger.Sugar.Errorf("\t[cache] set %s failed: %v", fullKey, err)
+		return err
+	}
+	return nil
+}
+
+func (c *Cache) setLocal(fullKey string, raw []byte, ttl time.Duration, tags []string) {
+	if c.local == nil {
+		return
+	}
+	if c.opts.LocalTTL > 0 && ttl > c.opts.LocalTTL {
+		ttl = c.opts.LocalTTL
+	}
+	c.local.set(fullKey, raw, ttl, tags)
+}
+
+func (c *Cache) tagKey(tag string) string {
+	return c.opts.Prefix + "tag:" + tag
+}
+
+// jitter 在 ttl 基础上随机浮动 ±Jitter
+func (c *Cache) jitter(ttl time.Duration) time.Duration {
+	if c.opts.Jitter <= 0 || ttl <= 0 {
+		return ttl
+	}
+	delta := (rand.Float64()*2 - 1) * c.opts.Jitter * float64(ttl)
+	return ttl + time.Duration(delta)
+}
+
+func decodeEntry(raw []byte) ([]byte, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("cache: empty entry")
+	}
+	switch raw[0] {
+	case flagValue:
+		return raw[1:], nil
+	case flagNegative:
+		return nil, ErrNotFound
+	}
+	return nil, fmt.Errorf("cache: unknown entry flag %q", raw[0])
+}
+
+// invalidation 副本间的失效广播消息
+type invalidation struct {
+	Origin string   `json:"origin"`
+	Keys   []string `json:"keys,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+}
+
+func (c *Cache) broadcast(ctx context.Context, msg invalidation) error {
+	if c.remote == nil || c.opts.Channel == "" {
+		return nil
+	}
+	msg.Origin = c.id
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return c.remote.Publish(ctx, c.opts.Channel, payload)
+}
+
+// Name 返回名称
+func (c *Cache) Name() string {
+	return "cache"
+}
+
+// Start 订阅失效广播，收到其他副本的消息后失效本地缓存（重复调用无效）
+func (c *Cache) Start() error {
+	if c.remote == nil || c.local == nil || c.opts.Channel == "" {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.done != nil {
+		return nil
+	}
+
+	messages, stop, err := c.remote.Subscribe(context.Background(), c.opts.Channel)
+	if errors.Is(err, ErrRedisNotReady) {
+		// 配置了 Redis 但未加载 redis 组件，各副本只能依赖 LocalTTL 收敛
+		logger.Sugar.Warnf("\t[cache] redis is not initialized, invalidation channel %s is not subscribed", c.opts.Channel)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	c.stop = stop
+	c.done = make(chan struct{})
+
+	go func(done chan struct{}) {
+		defer close(done)
+		for payload := range messages {
+			c.apply(payload)
+		}
+	}(c.done)
+
+	logger.Sugar.Infof("\t[cache] subscribed invalidation channel: %s", c.opts.Channel)
+	return nil
+}
+
+// Stop 取消订阅失效广播
+func (c *Cache) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	stop, done := c.stop, c.done
+	c.stop, c.done = nil, nil
+	c.mu.Unlock()
+
+	if done == nil {
+		return nil
+	}
+	if err := stop(); err != nil {
+		return err
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Cache) apply(payload []byte) {
+	var msg invalidation
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		logger.Sugar.Warnf("\t[cache] invalid invalidation message: %v", err)
+		return
+	}
+	if msg.Origin == c.id {
+		return
+	}
+	c.local.delete(msg.Keys...)
+	c.local.deleteTags(msg.Tags...)
+}
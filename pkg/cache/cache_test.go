@@ -0,0 +1,332 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"project/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = zap.NewNop()
+	logger.Sugar = logger.Logger.Sugar()
+	os.Exit(m.Run())
+}
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func testOptions() Options {
+	return Options{
+		Prefix:      "test:",
+		LocalSize:   100,
+		LocalTTL:    time.Minute,
+		NegativeTTL: time.Minute,
+		Channel:     "test:invalidate",
+	}
+}
+
+func TestLoad_CachesValue(t *testing.T) {
+	ctx := context.Background()
+	remote := NewMemoryRemote()
+	c := New(remote, testOptions())
+
+	var calls int32
+	loader := func(ctx context.Context) (user, error) {
+		atomic.AddInt32(&calls, 1)
+		return user{ID: 1, Name: "alice"}, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		u, err := Load(ctx, c, "user:1", time.Minute, loader)
+		require.NoError(t, err)
+		assert.Equal(t, user{ID: 1, Name: "alice"}, u)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// 本地缓存被清空后从 Redis 层读取，不再调用 loader
+	c.local.delete("test:user:1")
+	_, err := Load(ctx, c, "user:1", time.Minute, loader)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	_, ok, _ := remote.Get(ctx, "test:user:1")
+	assert.True(t, ok)
+}
+
+func TestLoad_Singleflight(t *testing.T) {
+	ctx := context.Background()
+	c := New(nil, testOptions())
+
+	var calls int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]int, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := Load(ctx, c, "answer", time.Minute, loader)
+			assert.NoError(t, err)
+			results[i] = v
+		}(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, v := range results {
+		assert.Equal(t, 42, v)
+	}
+}
+
+func TestLoad_SingleflightFirstCallerCancelled(t *testing.T) {
+	c := New(NewMemoryRemote(), testOptions())
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	loaderErr := make(chan error, 1)
+	loader := func(ctx context.Context) (int, error) {
+		close(started)
+		<-release
+		loaderErr <- ctx.Err()
+		return 42, nil
+	}
+
+	// 第一个调用者发起加载后取消，只放弃自己的等待
+	ctx1, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := Load(ctx1, c, "answer", time.Minute, loader)
+		firstErr <- err
+	}()
+	<-started
+
+	second := make(chan int, 1)
+	go func() {
+		v, err := Load(context.Background(), c, "answer", time.Minute, loader)
+		assert.NoError(t, err)
+		second <- v
+	}()
+
+	cancel()
+	select {
+	case err := <-firstErr:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("first caller did not return after cancel")
+	}
+
+	// 加载不受第一个调用者取消的影响，第二个调用者拿到结果
+	close(release)
+	select {
+	case v := <-second:
+		assert.Equal(t, 42, v)
+	case <-time.After(time.Second):
+		t.Fatal("second caller did not get the value")
+	}
+	assert.NoError(t, <-loaderErr)
+
+	v, err := Load(context.Background(), c, "answer", time.Minute, func(ctx context.Context) (int, error) {
+		return 0, errors.New("should be cached")
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 42, v)
+}
+
+func TestLoad_NegativeCaching(t *testing.T) {
+	ctx := context.Background()
+	c := New(NewMemoryRemote(), testOptions())
+
+	var calls int32
+	loader := func(ctx context.Context) (*user, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, ErrNotFound
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := Load(ctx, c, "user:404", time.Minute, loader)
+		assert.ErrorIs(t, err, ErrNotFound)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// 关闭空结果缓存后每次都会调用 loader
+	opts := testOptions()
+	opts.NegativeTTL = 0
+	c = New(nil, opts)
+	for i := 0; i < 2; i++ {
+		_, err := Load(ctx, c, "user:404", time.Minute, loader)
+		assert.ErrorIs(t, err, ErrNotFound)
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestLoad_ErrorNotCached(t *testing.T) {
+	ctx := context.Background()
+	c := New(nil, testOptions())
+
+	boom := errors.New("boom")
+	_, err := Load(ctx, c, "k", time.Minute, func(ctx context.Context) (int, error) {
+		return 0, boom
+	})
+	assert.ErrorIs(t, err, boom)
+
+	v, err := Load(ctx, c, "k", time.Minute, func(ctx context.Context) (int, error) {
+		return 7, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 7, v)
+}
+
+func TestInvalidateTags(t *testing.T) {
+	ctx := context.Background()
+	remote := NewMemoryRemote()
+	c := New(remote, testOptions())
+
+	var calls int32
+	loader := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "v", nil
+	}
+
+	_, err := Load(ctx, c, "a", time.Minute, loader, WithTags("users"))
+	require.NoError(t, err)
+	_, err = Load(ctx, c, "b", time.Minute, loader, WithTags("users", "vip"))
+	require.NoError(t, err)
+	_, err = Load(ctx, c, "c", time.Minute, loader, WithTags("orders"))
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	require.NoError(t, c.InvalidateTags(ctx, "users"))
+
+	_, ok, _ := remote.Get(ctx, "test:a")
+	assert.False(t, ok)
+	_, ok, _ = remote.Get(ctx, "test:c")
+	assert.True(t, ok)
+
+	for _, key := range []string{"a", "b", "c"} {
+		_, err := Load(ctx, c, key, time.Minute, loader)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(5), atomic.LoadInt32(&calls))
+}
+
+func TestBroadcastInvalidation(t *testing.T) {
+	ctx := context.Background()
+	remote := NewMemoryRemote()
+
+	// 两个副本共用同一 Redis
+	a := New(remote, testOptions())
+	b := New(remote, testOptions())
+	require.NoError(t, a.Start())
+	require.NoError(t, b.Start())
+	defer a.Stop(ctx)
+	defer b.Stop(ctx)
+
+	require.NoError(t, a.Set(ctx, "k", "old", time.Minute, WithTags("t")))
+	v, err := Load(ctx, b, "k", time.Minute, func(ctx context.Context) (string, error) {
+		return "unused", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "old", v)
+
+	// a 更新后 b 的本地缓存被失效，重新从 Redis 读取新值
+	require.NoError(t, a.Set(ctx, "k", "new", time.Minute))
+	require.Eventually(t, func() bool {
+		_, ok := b.local.get("test:k")
+		return !ok
+	}, time.Second, 5*time.Millisecond)
+
+	v, err = Load(ctx, b, "k", time.Minute, func(ctx context.Context) (string, error) {
+		return "unused", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "new", v)
+
+	// 标签失效同样同步到其他副本
+	_, err = Load(ctx, b, "tagged", time.Minute, func(ctx context.Context) (int, error) {
+		return 1, nil
+	}, WithTags("t"))
+	require.NoError(t, err)
+	require.NoError(t, a.InvalidateTags(ctx, "t"))
+	require.Eventually(t, func() bool {
+		_, ok := b.local.get("test:tagged")
+		return !ok
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestComponentRemote_NotReady(t *testing.T) {
+	ctx := context.Background()
+
+	// Redis 组件未初始化时降级为本地缓存，订阅不阻止启动
+	c := New(componentRemote{}, testOptions())
+	require.NoError(t, c.Start())
+	defer c.Stop(ctx)
+
+	var loads int32
+	load := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&loads, 1)
+		return "v", nil
+	}
+	for i := 0; i < 2; i++ {
+		v, err := Load(ctx, c, "k", time.Minute, load)
+		require.NoError(t, err)
+		assert.Equal(t, "v", v)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+	assert.ErrorIs(t, c.Delete(ctx, "k"), ErrRedisNotReady)
+}
+
+func TestLocalStore_LRU(t *testing.T) {
+	s := newLocalStore(2)
+	s.set("a", []byte("1"), time.Minute, []string{"t"})
+	s.set("b", []byte("2"), time.Minute, nil)
+
+	// 访问 a 后 b 成为最久未使用
+	_, ok := s.get("a")
+	require.True(t, ok)
+	s.set("c", []byte("3"), time.Minute, nil)
+
+	_, ok = s.get("b")
+	assert.False(t, ok)
+	assert.Equal(t, 2, s.len())
+
+	s.set("d", []byte("4"), 10*time.Millisecond, nil)
+	time.Sleep(20 * time.Millisecond)
+	_, ok = s.get("d")
+	assert.False(t, ok)
+
+	s.deleteTags("t")
+	_, ok = s.get("a")
+	assert.False(t, ok)
+	assert.Empty(t, s.tags)
+}
+
+func TestJitter(t *testing.T) {
+	c := New(nil, Options{Jitter: 0.2})
+	for i := 0; i < 100; i++ {
+		d := c.jitter(time.Minute)
+		assert.GreaterOrEqual(t, d, 48*time.Second)
+		assert.LessOrEqual(t, d, 72*time.Second)
+	}
+	assert.Equal(t, time.Minute, New(nil, Options{}).jitter(time.Minute))
+}
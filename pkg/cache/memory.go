@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryRemote 进程内的共享缓存层，用于单元测试中模拟多个副本共用的 Redis
+type memoryRemote struct {
+	mu     sync.Mutex
+	values map[string]memoryValue
+	tags   map[string]map[string]struct{}
+	subs   map[string][]chan []byte
+}
+
+type memoryValue struct {
+	value    []byte
+	expireAt time.Time
+}
+
+// NewMemoryRemote 创建进程内共享缓存层，多个 Cache 共用同一实例即可模拟多副本部署
+func NewMemoryRemote() Remote {
+	return &memoryRemote{
+		values: make(map[string]memoryValue),
+		tags:   make(map[string]map[string]struct{}),
+		subs:   make(map[string][]chan []byte),
+	}
+}
+
+func (m *memoryRemote) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	v, ok := m.values[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if time.Now().After(v.expireAt) {
+		delete(m.values, key)
+		return nil, false, nil
+	}
+	return v.value, true, nil
+}
+
+func (m *memoryRemote) Set(_ context.Context, key string, value []byte, ttl time.Duration, tagKeys []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.values[key] = memoryValue{value: value, expireAt: time.Now().Add(ttl)}
+	for _, tagKey := range tagKeys {
+		keys, ok := m.tags[tagKey]
+		if !ok {
+			keys = make(map[string]struct{})
+			m.tags[tagKey] = keys
+		}
+		keys[key] = struct{}{}
+	}
+	return nil
+}
+
+func (m *memoryRemote) Delete(_ context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		delete(m.values, key)
+	}
+	return nil
+}
+
+func (m *memoryRemote) DeleteTags(_ context.Context, tagKeys ...string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var keys []string
+	for _, tagKey := range tagKeys {
+		for key := range m.tags[tagKey] {
+			keys = append(keys, key)
+			delete(m.values, key)
+		}
+		delete(m.tags, tagKey)
+	}
+	return keys, nil
+}
+
+func (m *memoryRemote) Publish(_ context.Context, channel string, payload []byte) error {
+	// 持锁发送，避免与取消订阅时关闭 channel 并发
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, ch := range m.subs[channel] {
+		ch <- payload
+	}
+	return nil
+}
+
+func (m *memoryRemote) Subscribe(_ context.Context, channel string) (<-chan []byte, func() error, error) {
+	ch := make(chan []byte, 64)
+
+	m.mu.Lock()
+	m.subs[channel] = append(m.subs[channel], ch)
+	m.mu.Unlock()
+
+	var once sync.Once
+	stop := func() error {
+		once.Do(func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			subs := m.subs[channel]
+			for i, c := range subs {
+				if c == ch {
+					m.subs[channel] = append(subs[:i], subs[i+1:]...)
+					break
+				}
+			}
+			close(ch)
+		})
+		return nil
+	}
+	return ch, stop, nil
+}
@@ -0,0 +1,190 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"project/pkg/config"
+	"project/pkg/database"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Remote 共享缓存层，由所有副本共用，同时承载副本间的失效广播
+type Remote interface {
+	// Get 读取缓存，不存在时 ok 为 false
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	// Set 写入缓存，并把 key 记录到各标签集合中
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration, tagKeys []string) error
+	// Delete 删除缓存
+	Delete(ctx context.Context, keys ...string) error
+	// DeleteTags 删除标签集合中记录的全部缓存及标签集合本身，返回被删除的 key
+	DeleteTags(ctx context.Context, tagKeys ...string) ([]string, error)
+	// Publish 发布失效消息
+	Publish(ctx context.Context, channel string, payload []byte) error
+	// Subscribe 订阅失效消息，返回的 stop 用于取消订阅
+	Subscribe(ctx context.Context, channel string) (messages <-chan []byte, stop func() error, err error)
+}
+
+// tagScript 把 key 加入标签集合，并保证集合至少与其中的缓存存活同样久
+var tagScript = redis.NewScript(`
+redis.call('SADD', KEYS[1], ARGV[1])
+if redis.call('TTL', KEYS[1]) < tonumber(ARGV[2]) then
+	redis.call('EXPIRE', KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+// redisRemote 基于 Redis 组件的共享缓存层
+type redisRemote struct {
+	client redis.UniversalClient
+}
+
+// NewRedisRemote 使用 Redis 客户端创建共享缓存层
+func NewRedisRemote(client redis.UniversalClient) Remote {
+	return &redisRemote{client: client}
+}
+
+func (r *redisRemote) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := r.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (r *redisRemote) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tagKeys []string) error {
+	if len(tagKeys) == 0 {
+		return r.client.Set(ctx, key, value, ttl).Err()
+	}
+
+	// 集群模式下 key 与标签集合可能位于不同槽位，使用普通 pipeline 而非事务
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, value, ttl)
+		for _, tagKey := range tagKeys {
+			tagScript.Eval(ctx, pipe, []string{tagKey}, key, int64(ttl/time.Second)+1)
+		}
+		return nil
+	})
+	return err
+}
+
+func (r *redisRemote) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(ctx, key)
+		}
+		return nil
+	})
+	return err
+}
+
+func (r *redisRemote) DeleteTags(ctx context.Context, tagKeys ...string) ([]string, error) {
+	var keys []string
+	for _, tagKey := range tagKeys {
+		members, err := r.client.SMembers(ctx, tagKey).Result()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, members...)
+	}
+
+	if err := r.Delete(ctx, append(keys, tagKeys...)...); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *redisRemote) Publish(ctx context.Context, channel string, payload []byte) error {
+	return r.client.Publish(ctx, channel, payload).Err()
+}
+
+func (r *redisRemote) Subscribe(ctx context.Context, channel string) (<-chan []byte, func() error, error) {
+	pubsub := r.client.Subscribe(ctx, channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, nil, err
+	}
+
+	// go-redis 在连接断开后会自动重连并重新订阅
+	out := make(chan []byte, 64)
+	go func() {
+		defer close(out)
+		for msg := range pubsub.Channel() {
+			out <- []byte(msg.Payload)
+		}
+	}()
+	return out, pubsub.Close, nil
+}
+
+// ErrRedisNotReady Redis 组件尚未初始化
+var ErrRedisNotReady = errors.New("cache: redis is not initialized")
+
+// componentRemote 每次访问时从 Redis 组件获取客户端，组件未初始化时返回 ErrRedisNotReady
+type componentRemote struct{}
+
+func (componentRemote) remote() (Remote, error) {
+	if !config.IsInitialized() {
+		return nil, ErrRedisNotReady
+	}
+	r := database.GetRedis()
+	if r == nil || !r.IsInitialize() {
+		return nil, ErrRedisNotReady
+	}
+	return NewRedisRemote(r.GetClient()), nil
+}
+
+func (c componentRemote) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	r, err := c.remote()
+	if err != nil {
+		return nil, false, err
+	}
+	return r.Get(ctx, key)
+}
+
+func (c componentRemote) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tagKeys []string) error {
+	r, err := c.remote()
+	if err != nil {
+		return err
+	}
+	return r.Set(ctx, key, value, ttl, tagKeys)
+}
+
+func (c componentRemote) Delete(ctx context.Context, keys ...string) error {
+	r, err := c.remote()
+	if err != nil {
+		return err
+	}
+	return r.Delete(ctx, keys...)
+}
+
+func (c componentRemote) DeleteTags(ctx context.Context, tagKeys ...string) ([]string, error) {
+	r, err := c.remote()
+	if err != nil {
+		return nil, err
+	}
+	return r.DeleteTags(ctx, tagKeys...)
+}
+
+func (c componentRemote) Publish(ctx context.Context, channel string, payload []byte) error {
+	r, err := c.remote()
+	if err != nil {
+		return err
+	}
+	return r.Publish(ctx, channel, payload)
+}
+
+func (c componentRemote) Subscribe(ctx context.Context, channel string) (<-chan []byte, func() error, error) {
+	r, err := c.remote()
+	if err != nil {
+		return nil, nil, err
+	}
+	return r.Subscribe(ctx, channel)
+}
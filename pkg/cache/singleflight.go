@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"project/pkg/logger"
+)
+
+// flightGroup 合并同一 key 的并发加载，fn 只执行一次，所有调用者等待其结果
+//
+// fn 在独立协程中执行，不受任何调用者取消的影响；调用者的 ctx 结束时只放弃等待，
+// 加载仍会完成并写入缓存，其余调用者照常拿到结果
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done chan struct{}
+	val  []byte
+	err  error
+}
+
+// do 执行 fn 并等待结果，shared 表示结果是否来自其他调用者发起的加载
+func (g *flightGroup) do(ctx context.Context, key string, fn func() ([]byte, error)) (val []byte, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		return c.wait(ctx, true)
+	}
+	c := &flightCall{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	go g.call(key, c, fn)
+	return c.wait(ctx, false)
+}
+
+func (g *flightGroup) call(key string, c *flightCall, fn func() ([]byte, error)) {
+	defer func() {
+		// loader panic 时所有调用者收到错误，不能让独立协程的 panic 终止进程
+		if r := recover(); r != nil {
+			c.err = fmt.Errorf("cache: loader panic: %v", r)
+			logger.Sugar.Errorf("\t[cache] load %s panic: %v\n%s", key, r, debug.Stack())
+		}
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(c.done)
+	}()
+
+	c.val, c.err = fn()
+}
+
+func (c *flightCall) wait(ctx context.Context, shared bool) ([]byte, error, bool) {
+	select {
+	case <-c.done:
+		return c.val, c.err, shared
+	case <-ctx.Done():
+		return nil, ctx.Err(), shared
+	}
+}
@@ -4,8 +4,15 @@ package config
 import (
 	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 	"sync"
+	"time"
+
+	"project/pkg/logger"
+	"project/pkg/ratelimit"
+	"project/pkg/redact"
+	"project/pkg/requestid"
 
 	"github.com/spf13/viper"
 )
@@ -18,15 +25,20 @@ var (
 
 // App 是顶层配置结构。
 type App struct {
-	Server     *Server   `mapstructure:"server"`
-	Db         *Db       `mapstructure:"db"`
-	Log        *Log      `mapstructure:"log"`
-	JWT        *JWT      `mapstructure:"jwt"`
-	Storage    *Storage  `mapstructure:"storage"`
-	RabbitMQ   *RabbitMQ `mapstructure:"rabbitmq"`
-	Public     *Public   `mapstructure:"public"`
-	Debug      *Debug    `mapstructure:"debug"`
-	Components []string  `mapstructure:"components"`
+	Server     *Server            `mapstructure:"server"`
+	Db         *Db                `mapstructure:"db"`
+	Log        *Log               `mapstructure:"log"`
+	JWT        *JWT               `mapstructure:"jwt"`
+	Storage    *Storage           `mapstructure:"storage"`
+	RabbitMQ   *RabbitMQ          `mapstructure:"rabbitmq"`
+	Public     *Public            `mapstructure:"public"`
+	Debug      *Debug             `mapstructure:"debug"`
+	Monitor    *Monitor           `mapstructure:"monitor"`
+	Cache      *Cache             `mapstructure:"cache"`
+	CORS       *CORS              `mapstructure:"cors"`
+	RateLimit  *RateLimit         `mapstructure:"rateLimit"`
+	Brokers    map[string]*Broker `mapstructure:"brokers"`
+	Components []string           `mapstructure:"components"`
 }
 
 // Server 配置。
@@ -35,6 +47,20 @@ type Server struct {
 	Port        int    `mapstructure:"port"`
 	Version     string `mapstructure:"version"`
 	Environment string `mapstructure:"environment"`
+	// RequestIDHeader 请求 ID 请求头，上游携带时沿用，否则生成；同时写入响应头
+	RequestIDHeader string `mapstructure:"requestIdHeader"`
+	// RequestIDGenerator 请求 ID 生成器：uuid、snowflake、random
+	RequestIDGenerator string `mapstructure:"requestIdGenerator"`
+	// Timeout 请求处理超时，超时返回 504，0 不限制
+	Timeout time.Duration `mapstructure:"timeout"`
+	// TimeoutRoutes 按路由前缀覆盖超时，最长前缀优先；timeout 为 0 不限制，用于 SSE、文件下载等流式路由
+	TimeoutRoutes []TimeoutRoute `mapstructure:"timeoutRoutes"`
+}
+
+// TimeoutRoute 路由前缀的请求处理超时。
+type TimeoutRoute struct {
+	Prefix  string        `mapstructure:"prefix"`  // 路由前缀，如 /api/export
+	Timeout time.Duration `mapstructure:"timeout"` // 0 不限制
 }
 
 // JWT JWT 配置
@@ -50,15 +76,122 @@ type Db struct {
 	Mysql    *Mysql    `mapstructure:"mysql"`
 	Redis    *Redis    `mapstructure:"redis"`
 	Tdengine *Tdengine `mapstructure:"tdengine"`
+	Sqlite   *Sqlite   `mapstructure:"sqlite"`
+	Postgres *Postgres `mapstructure:"postgres"`
 }
 
 // Log 日志配置。
 type Log struct {
-	Path       string `mapstructure:"path"`
-	Level      string `mapstructure:"level"`
-	MaxSize    int    `mapstructure:"maxSize"`    // MB
-	MaxBackups int    `mapstructure:"maxBackups"` // 文件数
-	MaxAge     int    `mapstructure:"maxAge"`     // 天
+	Path         string `mapstructure:"path"`
+	Level        string `mapstructure:"level"`
+	Format       string `mapstructure:"format"`       // console/json/logfmt
+	StdoutLevel  string `mapstructure:"stdoutLevel"`  // 为空时同 level，off 关闭
+	FileLevel    string `mapstructure:"fileLevel"`    // 为空时同 level，off 关闭
+	ErrorFile    bool   `mapstructure:"errorFile"`    // error 及以上另写一份，文件名在扩展名前加 .error
+	FilePattern  string `mapstructure:"filePattern"`  // 文件名模式，{date} 替换为日期
+	MaxSize      int    `mapstructure:"maxSize"`      // MB
+	MaxBackups   int    `mapstructure:"maxBackups"`   // 文件数
+	MaxAge       int    `mapstructure:"maxAge"`       // 天
+	MaxTotalSize int    `mapstructure:"maxTotalSize"` // MB，0 不限制
+	Compress     bool   `mapstructure:"compress"`     // gzip 压缩旧文件
+	// RedactKeys 敏感字段名模式，名称包含任一模式的日志字段输出为 [REDACTED]，为空时使用默认列表
+	RedactKeys []string `mapstructure:"redactKeys"`
+	// Sinks 额外的输出：syslog、单独的文件（如只写 error）、HTTP 推送到 Loki/Elasticsearch
+	Sinks []LogSink `mapstructure:"sinks"`
+	// Access HTTP 访问日志
+	Access AccessLog `mapstructure:"access"`
+}
+
+// AccessLog HTTP 访问日志配置。
+type AccessLog struct {
+	SkipPaths     []string           `mapstructure:"skipPaths"`     // 不记录的路径，以 * 结尾时按前缀匹配
+	SampleRates   map[string]float64 `mapstructure:"sampleRates"`   // 按状态码（404）或类别（2xx）采样的比例 0-1，慢请求与 5xx 不采样
+	SlowThreshold time.Duration      `mapstructure:"slowThreshold"` // 超过该耗时以 warn 记录，0 不区分
+	RequestBody   bool               `mapstructure:"requestBody"`   // 记录请求体（JSON/表单，脱敏）
+	ResponseBody  bool               `mapstructure:"responseBody"`  // 记录响应体（JSON/表单，脱敏）
+	MaxBodySize   int                `mapstructure:"maxBodySize"`   // 记录的请求体/响应体最大字节数
+}
+
+// Validate 验证 AccessLog 配置。
+func (a *AccessLog) Validate() error {
+	for k, rate := range a.SampleRates {
+		if !validStatusKey(k) {
+			return fmt.Errorf("invalid sampleRates key: %s (must be a status code like 404 or a class like 2xx)", k)
+		}
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("sampleRates[%s] must be between 0 and 1", k)
+		}
+	}
+	if a.SlowThreshold < 0 {
+		return errors.New("slowThreshold must be >= 0")
+	}
+	if a.MaxBodySize < 0 {
+		return errors.New("maxBodySize must be >= 0")
+	}
+	return nil
+}
+
+// validStatusKey 状态码（100-599）或类别（1xx-5xx）
+func validStatusKey(k string) bool {
+	k = strings.ToLower(k)
+	if len(k) != 3 || k[0] < '1' || k[0] > '5' {
+		return false
+	}
+	if k[1:] == "xx" {
+		return true
+	}
+	return k[1] >= '0' && k[1] <= '9' && k[2] >= '0' && k[2] <= '9'
+}
+
+// LogSink 额外的日志输出配置，字段含义见 logger.SinkOptions。
+type LogSink struct {
+	Type   string `mapstructure:"type"`   // file/syslog/http
+	Level  string `mapstructure:"level"`  // 为空时同 log.level，off 关闭
+	Format string `mapstructure:"format"` // 为空时同 log.format
+
+	Pattern string `mapstructure:"pattern"` // file：文件名模式
+
+	Network  string `mapstructure:"network"`  // syslog：udp/tcp，为空写本机
+	Address  string `mapstructure:"address"`  // syslog：地址
+	Tag      string `mapstructure:"tag"`      // syslog：标识
+	Facility string `mapstructure:"facility"` // syslog：默认 user
+
+	URL           string            `mapstructure:"url"`           // http：推送地址
+	Protocol      string            `mapstructure:"protocol"`      // http：loki/elasticsearch
+	Labels        map[string]string `mapstructure:"labels"`        // http：Loki stream 标签
+	Index         string            `mapstructure:"index"`         // http：Elasticsearch 索引
+	Headers       map[string]string `mapstructure:"headers"`       // http：附加请求头
+	BatchSize     int               `mapstructure:"batchSize"`     // http：默认 100
+	FlushInterval time.Duration     `mapstructure:"flushInterval"` // http：默认 1s
+	BufferSize    int               `mapstructure:"bufferSize"`    // http：默认 10000
+	DropPolicy    string            `mapstructure:"dropPolicy"`    // http：newest/oldest
+	MaxRetries    int               `mapstructure:"maxRetries"`    // http：默认 3，小于 0 不重试
+	Timeout       time.Duration     `mapstructure:"timeout"`       // http：默认 5s
+}
+
+// Options 转换为 logger.SinkOptions。
+func (s *LogSink) Options() logger.SinkOptions {
+	return logger.SinkOptions{
+		Type:          s.Type,
+		Level:         s.Level,
+		Format:        s.Format,
+		Pattern:       s.Pattern,
+		Network:       s.Network,
+		Address:       s.Address,
+		Tag:           s.Tag,
+		Facility:      s.Facility,
+		URL:           s.URL,
+		Protocol:      s.Protocol,
+		Labels:        s.Labels,
+		Index:         s.Index,
+		Headers:       s.Headers,
+		BatchSize:     s.BatchSize,
+		FlushInterval: s.FlushInterval,
+		BufferSize:    s.BufferSize,
+		DropPolicy:    s.DropPolicy,
+		MaxRetries:    s.MaxRetries,
+		Timeout:       s.Timeout,
+	}
 }
 
 // Mysql 配置。
@@ -68,6 +201,20 @@ type Mysql struct {
 	MaxOpenConnection int    `mapstructure:"maxOpenConnection"`
 }
 
+// Sqlite 配置。
+type Sqlite struct {
+	Path              string `mapstructure:"path"` // 数据库文件路径，":memory:" 为内存库
+	MaxIdleConnection int    `mapstructure:"maxIdleConnection"`
+	MaxOpenConnection int    `mapstructure:"maxOpenConnection"`
+}
+
+// Postgres 配置。
+type Postgres struct {
+	URL               string `mapstructure:"url"`
+	MaxIdleConnection int    `mapstructure:"maxIdleConnection"`
+	MaxOpenConnection int    `mapstructure:"maxOpenConnection"`
+}
+
 // Storage 配置。
 type Storage struct {
 	AccessKey  string `mapstructure:"accessKey"`
@@ -78,13 +225,49 @@ type Storage struct {
 	Region     string `mapstructure:"region"`
 }
 
+// Redis 部署模式
+const (
+	RedisModeStandalone = "standalone"
+	RedisModeSentinel   = "sentinel"
+	RedisModeCluster    = "cluster"
+)
+
 // Redis 配置。
 type Redis struct {
-	DB       int    `mapstructure:"db"`
-	Addr     string `mapstructure:"addr"`
-	Network  string `mapstructure:"network"`
-	Username string `mapstructure:"username"`
-	Password string `mapstructure:"password"`
+	Mode     string   `mapstructure:"mode"`  // standalone / sentinel / cluster
+	DB       int      `mapstructure:"db"`    // cluster 模式不支持选库
+	Addr     string   `mapstructure:"addr"`  // standalone 地址
+	Addrs    []string `mapstructure:"addrs"` // sentinel 或 cluster 节点地址
+	Network  string   `mapstructure:"network"`
+	Username string   `mapstructure:"username"`
+	Password string   `mapstructure:"password"`
+
+	// sentinel
+	MasterName       string `mapstructure:"masterName"`
+	SentinelUsername string `mapstructure:"sentinelUsername"`
+	SentinelPassword string `mapstructure:"sentinelPassword"`
+
+	// 连接池
+	PoolSize     int           `mapstructure:"poolSize"` // 0 表示使用驱动默认值（10 * CPU 核数）
+	MinIdleConns int           `mapstructure:"minIdleConns"`
+	PoolTimeout  time.Duration `mapstructure:"poolTimeout"`
+	IdleTimeout  time.Duration `mapstructure:"idleTimeout"`
+	DialTimeout  time.Duration `mapstructure:"dialTimeout"`
+	ReadTimeout  time.Duration `mapstructure:"readTimeout"`
+	WriteTimeout time.Duration `mapstructure:"writeTimeout"`
+	MaxRetries   int           `mapstructure:"maxRetries"` // -1 表示不重试
+
+	TLS *TLS `mapstructure:"tls"`
+}
+
+// TLS 客户端 TLS 配置。
+type TLS struct {
+	Enabled            bool   `mapstructure:"enabled"`
+	CAFile             string `mapstructure:"caFile"`
+	CertFile           string `mapstructure:"certFile"`
+	KeyFile            string `mapstructure:"keyFile"`
+	ServerName         string `mapstructure:"serverName"`
+	InsecureSkipVerify bool   `mapstructure:"insecureSkipVerify"`
 }
 
 // Tdengine 配置。
@@ -99,12 +282,141 @@ type Public struct {
 
 // Debug 调试配置。
 type Debug struct {
-	EnablePProf bool `mapstructure:"enablePProf"`
+	EnablePProf    bool   `mapstructure:"enablePProf"`
+	EnableLogLevel bool   `mapstructure:"enableLogLevel"` // 开启 /debug/log/level 运行时调整日志级别
+	AdminToken     string `mapstructure:"adminToken"`     // 管理接口（pprof、日志级别、指标导出）的访问令牌（Authorization: Bearer <token>），为空时不注册管理接口
+}
+
+// Monitor 监控配置。
+type Monitor struct {
+	StatsInterval     time.Duration `mapstructure:"statsInterval"`     // 连接池统计采集间隔
+	WaitWarnThreshold time.Duration `mapstructure:"waitWarnThreshold"` // 单个采集周期内等待连接总耗时的告警阈值
+	MetricsPath       string        `mapstructure:"metricsPath"`       // 指标导出路径，为空则不导出；需配置 debug.adminToken
+}
+
+// Cache 缓存配置。
+type Cache struct {
+	Prefix      string        `mapstructure:"prefix"`      // Redis 键前缀
+	LocalSize   int           `mapstructure:"localSize"`   // 进程内 LRU 容量（条），0 表示不启用本地缓存
+	LocalTTL    time.Duration `mapstructure:"localTTL"`    // 本地缓存最长有效期，用于限制副本间的不一致时间
+	Jitter      float64       `mapstructure:"jitter"`      // 过期时间随机抖动比例，0.1 表示 ±10%
+	NegativeTTL time.Duration `mapstructure:"negativeTTL"` // 空结果缓存时间，0 表示不缓存空结果
+	Channel     string        `mapstructure:"channel"`     // 副本间失效广播的 pub/sub 频道，为空则不广播
+	LoadTimeout time.Duration `mapstructure:"loadTimeout"` // 单次加载的超时，加载不随调用方取消，由该超时兜底
+}
+
+// CORS 跨域配置，顶层为默认策略，Groups 按路由前缀覆盖。
+type CORS struct {
+	CORSPolicy `mapstructure:",squash"`
+	Groups     []CORSGroup `mapstructure:"groups"` // 按路由前缀覆盖，最长前缀优先
+}
+
+// CORSGroup 路由组的跨域策略，完整替换默认策略。
+type CORSGroup struct {
+	Prefix     string `mapstructure:"prefix"` // 路由前缀，如 /api/admin
+	CORSPolicy `mapstructure:",squash"`
+}
+
+// CORSPolicy 跨域策略，列表为空时使用中间件的默认值。
+type CORSPolicy struct {
+	AllowOrigins     []string      `mapstructure:"allowOrigins"`     // 精确匹配，*.example.com 匹配子域名，* 允许任意来源
+	AllowOriginRegex []string      `mapstructure:"allowOriginRegex"` // 正则匹配整个 Origin（自动添加 ^ 与 $）
+	AllowMethods     []string      `mapstructure:"allowMethods"`     // 预检允许的方法
+	AllowHeaders     []string      `mapstructure:"allowHeaders"`     // 预检允许的请求头，* 允许任意请求头
+	ExposeHeaders    []string      `mapstructure:"exposeHeaders"`    // 浏览器可读取的响应头
+	AllowCredentials bool          `mapstructure:"allowCredentials"` // 允许携带 Cookie 等凭证
+	MaxAge           time.Duration `mapstructure:"maxAge"`           // 预检结果缓存时间
+}
+
+// RateLimit 限流配置，每个请求按最长前缀匹配的一条规则限流。
+type RateLimit struct {
+	Enabled      bool            `mapstructure:"enabled"`
+	Store        string          `mapstructure:"store"`        // memory（每个副本单独计数）/ redis（所有副本共享计数，需启用 redis 组件）
+	Prefix       string          `mapstructure:"prefix"`       // Redis 键前缀
+	APIKeyHeader string          `mapstructure:"apiKeyHeader"` // api_key 类型读取的请求头
+	APIKeys      []string        `mapstructure:"apiKeys"`      // 有效的 API Key，api_key 类型只对其中的 Key 单独计数，其余按 ip
+	Rules        []RateLimitRule `mapstructure:"rules"`
+}
+
+// RateLimitRule 路由前缀的限流规则。
+type RateLimitRule struct {
+	Prefix    string        `mapstructure:"prefix"`    // 路由前缀，如 /api/auth，为空匹配所有请求
+	Key       string        `mapstructure:"key"`       // ip / user / api_key / route，默认 ip
+	Algorithm string        `mapstructure:"algorithm"` // token_bucket / sliding_window，默认 token_bucket
+	Limit     int           `mapstructure:"limit"`     // 每个 period 允许的请求数
+	Period    time.Duration `mapstructure:"period"`    // 默认 1s
+	Burst     int           `mapstructure:"burst"`     // 令牌桶容量，默认同 limit
+}
+
+// Rule 转换为限流规则。
+func (r *RateLimitRule) Rule() ratelimit.Rule {
+	return ratelimit.Rule{Algorithm: r.Algorithm, Limit: r.Limit, Period: r.Period, Burst: r.Burst}
 }
 
 // RabbitMQ 配置。
 type RabbitMQ struct {
-	URL string `mapstructure:"url"`
+	URL                  string        `mapstructure:"url"`
+	ReconnectInterval    time.Duration `mapstructure:"reconnectInterval"`    // 断线重连初始间隔，按指数退避，默认 1s
+	MaxReconnectInterval time.Duration `mapstructure:"maxReconnectInterval"` // 断线重连最大间隔，默认 30s
+	ChannelPoolSize      int           `mapstructure:"channelPoolSize"`      // 发布者 channel 池大小，默认 8
+	PublishBuffer        int           `mapstructure:"publishBuffer"`        // 断线期间允许等待重连的发布数，超过后立即返回错误，默认 1000
+	PublishTimeout       time.Duration `mapstructure:"publishTimeout"`       // ctx 未设置截止时间时的发布超时（含等待确认），默认 5s
+	Topology             *Topology     `mapstructure:"topology"`             // 启动时声明的交换机、队列与绑定
+}
+
+// Broker 按名称配置的消息队列驱动，通过 queue.Open(name) 获取；名称不区分大小写。
+type Broker struct {
+	Driver      string        `mapstructure:"driver"`      // rabbitmq / redis / memory
+	Exchange    string        `mapstructure:"exchange"`    // rabbitmq：发布使用的交换机，为空时直接投递到与主题同名的队列
+	Prefix      string        `mapstructure:"prefix"`      // redis：Stream 键前缀
+	Group       string        `mapstructure:"group"`       // redis：消费组，默认 default
+	MaxLen      int64         `mapstructure:"maxLen"`      // redis：Stream 近似最大长度，0 表示不裁剪
+	Buffer      int           `mapstructure:"buffer"`      // memory：每个主题的缓冲消息数，默认 1024
+	Concurrency int           `mapstructure:"concurrency"` // 每个订阅的并发处理数，默认 1
+	MaxAttempts int           `mapstructure:"maxAttempts"` // 最大处理次数（含首次），默认 5
+	RetryDelay  time.Duration `mapstructure:"retryDelay"`  // 失败后的重试延迟，rabbitmq 默认 1s，redis 为回收未确认消息的空闲时间（默认 1m），memory 默认立即重试
+}
+
+// Topology AMQP 拓扑，组件初始化时幂等声明。
+type Topology struct {
+	DryRun    bool       `mapstructure:"dryRun"` // 只检查与 broker 现有状态的差异并记录日志，不做任何声明
+	Exchanges []Exchange `mapstructure:"exchanges"`
+	Queues    []Queue    `mapstructure:"queues"`
+	Bindings  []Binding  `mapstructure:"bindings"`
+}
+
+// Exchange 交换机。
+type Exchange struct {
+	Name       string                 `mapstructure:"name"`
+	Type       string                 `mapstructure:"type"` // direct / fanout / topic / headers 或插件类型（x- 开头）
+	Durable    bool                   `mapstructure:"durable"`
+	AutoDelete bool                   `mapstructure:"autoDelete"`
+	Internal   bool                   `mapstructure:"internal"`
+	Arguments  map[string]interface{} `mapstructure:"arguments"`
+}
+
+// Queue 队列，常用参数有独立字段，其余通过 Arguments 设置。
+type Queue struct {
+	Name                 string                 `mapstructure:"name"`
+	Durable              bool                   `mapstructure:"durable"`
+	AutoDelete           bool                   `mapstructure:"autoDelete"`
+	Exclusive            bool                   `mapstructure:"exclusive"`
+	Quorum               bool                   `mapstructure:"quorum"`               // x-queue-type=quorum，要求 durable
+	DeadLetterExchange   string                 `mapstructure:"deadLetterExchange"`   // x-dead-letter-exchange
+	DeadLetterRoutingKey string                 `mapstructure:"deadLetterRoutingKey"` // x-dead-letter-routing-key
+	MessageTTL           time.Duration          `mapstructure:"messageTTL"`           // x-message-ttl
+	MaxLength            int64                  `mapstructure:"maxLength"`            // x-max-length
+	MaxLengthBytes       int64                  `mapstructure:"maxLengthBytes"`       // x-max-length-bytes
+	Overflow             string                 `mapstructure:"overflow"`             // x-overflow：drop-head / reject-publish / reject-publish-dlx
+	Arguments            map[string]interface{} `mapstructure:"arguments"`
+}
+
+// Binding 队列绑定。
+type Binding struct {
+	Exchange   string                 `mapstructure:"exchange"`
+	Queue      string                 `mapstructure:"queue"`
+	RoutingKey string                 `mapstructure:"routingKey"`
+	Arguments  map[string]interface{} `mapstructure:"arguments"`
 }
 
 // Get 返回当前加载的配置（只读）。
@@ -137,6 +449,7 @@ func (a *App) SafeCopy() *App {
 	// 深拷贝嵌套结构
 	if a.Server != nil {
 		server := *a.Server
+		server.TimeoutRoutes = append([]TimeoutRoute(nil), a.Server.TimeoutRoutes...)
 		cp.Server = &server
 	}
 
@@ -153,6 +466,14 @@ func (a *App) SafeCopy() *App {
 		if a.Db.Redis != nil {
 			redis := *a.Db.Redis
 			redis.Password = "***REDACTED***"
+			if redis.SentinelPassword != "" {
+				redis.SentinelPassword = "***REDACTED***"
+			}
+			redis.Addrs = append([]string(nil), a.Db.Redis.Addrs...)
+			if a.Db.Redis.TLS != nil {
+				tls := *a.Db.Redis.TLS
+				redis.TLS = &tls
+			}
 			cp.Db.Redis = &redis
 		}
 
@@ -167,6 +488,17 @@ func (a *App) SafeCopy() *App {
 			td.URL = redactDSN(td.URL)
 			cp.Db.Tdengine = &td
 		}
+
+		if a.Db.Sqlite != nil {
+			sqlite := *a.Db.Sqlite
+			cp.Db.Sqlite = &sqlite
+		}
+
+		if a.Db.Postgres != nil {
+			pg := *a.Db.Postgres
+			pg.URL = redactDSN(pg.URL)
+			cp.Db.Postgres = &pg
+		}
 	}
 
 	if a.Storage != nil {
@@ -179,11 +511,37 @@ func (a *App) SafeCopy() *App {
 	if a.RabbitMQ != nil {
 		rmq := *a.RabbitMQ
 		rmq.URL = redactDSN(rmq.URL)
+		if a.RabbitMQ.Topology != nil {
+			topology := *a.RabbitMQ.Topology
+			topology.Exchanges = append([]Exchange(nil), topology.Exchanges...)
+			topology.Queues = append([]Queue(nil), topology.Queues...)
+			topology.Bindings = append([]Binding(nil), topology.Bindings...)
+			rmq.Topology = &topology
+		}
 		cp.RabbitMQ = &rmq
 	}
 
 	if a.Log != nil {
 		log := *a.Log
+		log.RedactKeys = append([]string(nil), a.Log.RedactKeys...)
+		log.Sinks = append([]LogSink(nil), a.Log.Sinks...)
+		log.Access.SkipPaths = append([]string(nil), a.Log.Access.SkipPaths...)
+		if a.Log.Access.SampleRates != nil {
+			log.Access.SampleRates = make(map[string]float64, len(a.Log.Access.SampleRates))
+			for k, v := range a.Log.Access.SampleRates {
+				log.Access.SampleRates[k] = v
+			}
+		}
+		for i := range log.Sinks {
+			// 请求头可能包含凭证
+			if len(log.Sinks[i].Headers) > 0 {
+				headers := make(map[string]string, len(log.Sinks[i].Headers))
+				for k := range log.Sinks[i].Headers {
+					headers[k] = "***REDACTED***"
+				}
+				log.Sinks[i].Headers = headers
+			}
+		}
 		cp.Log = &log
 	}
 
@@ -194,18 +552,66 @@ func (a *App) SafeCopy() *App {
 
 	if a.Debug != nil {
 		debug := *a.Debug
+		if debug.AdminToken != "" {
+			debug.AdminToken = redactKey(debug.AdminToken)
+		}
 		cp.Debug = &debug
 	}
 
+	if a.Monitor != nil {
+		monitor := *a.Monitor
+		cp.Monitor = &monitor
+	}
+
+	if a.Cache != nil {
+		cache := *a.Cache
+		cp.Cache = &cache
+	}
+
+	if a.CORS != nil {
+		cors := *a.CORS
+		cors.Groups = append([]CORSGroup(nil), a.CORS.Groups...)
+		cp.CORS = &cors
+	}
+
+	if a.RateLimit != nil {
+		rateLimit := *a.RateLimit
+		rateLimit.Rules = append([]RateLimitRule(nil), a.RateLimit.Rules...)
+		rateLimit.APIKeys = nil
+		for _, key := range a.RateLimit.APIKeys {
+			rateLimit.APIKeys = append(rateLimit.APIKeys, redactKey(key))
+		}
+		cp.RateLimit = &rateLimit
+	}
+
+	if a.Brokers != nil {
+		cp.Brokers = make(map[string]*Broker, len(a.Brokers))
+		for name, b := range a.Brokers {
+			if b != nil {
+				broker := *b
+				b = &broker
+			}
+			cp.Brokers[name] = b
+		}
+	}
+
 	return &cp
 }
 
+// dsnPasswordPattern 匹配 key=value 形式 DSN（如 PostgreSQL 的 "host=... password=secret"）中的密码，
+// 值可以用单引号包裹并以反斜杠转义。
+var dsnPasswordPattern = regexp.MustCompile(`(?i)(^|\s)(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S*)`)
+
 // redactDSN 脱敏 DSN 字符串中的密码部分。
 func redactDSN(dsn string) string {
 	if dsn == "" {
 		return ""
 	}
 
+	if dsnPasswordPattern.MatchString(dsn) {
+		return dsnPasswordPattern.ReplaceAllString(dsn, "${1}${2}***REDACTED***")
+	}
+
 	// 简单处理：查找 ":" 和 "@" 之间的内容
 	if idx1 := strings.Index(dsn, "://"); idx1 != -1 {
 		rest := dsn[idx1+3:]
@@ -261,6 +667,45 @@ func (a *App) Validate() error {
 		}
 	}
 
+	if a.Monitor != nil {
+		if err := a.Monitor.Validate(); err != nil {
+			return fmt.Errorf("monitor config: %w", err)
+		}
+	}
+
+	if a.Cache != nil {
+		if err := a.Cache.Validate(); err != nil {
+			return fmt.Errorf("cache config: %w", err)
+		}
+	}
+
+	if a.CORS != nil {
+		if err := a.CORS.Validate(); err != nil {
+			return fmt.Errorf("cors config: %w", err)
+		}
+	}
+
+	if a.RateLimit != nil {
+		if err := a.RateLimit.Validate(); err != nil {
+			return fmt.Errorf("rateLimit config: %w", err)
+		}
+	}
+
+	for name, b := range a.Brokers {
+		if b == nil {
+			return fmt.Errorf("brokers.%s config: can`t null", name)
+		}
+		if err := b.Validate(); err != nil {
+			return fmt.Errorf("brokers.%s config: %w", name, err)
+		}
+	}
+
+	if a.RabbitMQ != nil {
+		if err := a.RabbitMQ.Validate(); err != nil {
+			return fmt.Errorf("rabbitmq config: %w", err)
+		}
+	}
+
 	if a.Components != nil {
 		if len(a.Components) <= 0 {
 			return fmt.Errorf("components config: can`t null")
@@ -278,6 +723,20 @@ func (s *Server) Validate() error {
 	if s.Name == "" {
 		return errors.New("server name is required")
 	}
+	if _, err := requestid.NewGenerator(s.RequestIDGenerator); err != nil {
+		return err
+	}
+	if s.Timeout < 0 {
+		return errors.New("timeout must be >= 0")
+	}
+	for i, r := range s.TimeoutRoutes {
+		if !strings.HasPrefix(r.Prefix, "/") {
+			return fmt.Errorf("timeoutRoutes[%d]: prefix must start with '/'", i)
+		}
+		if r.Timeout < 0 {
+			return fmt.Errorf("timeoutRoutes[%d]: timeout must be >= 0", i)
+		}
+	}
 	return nil
 }
 
@@ -315,14 +774,74 @@ func (d *Db) Validate() error {
 		}
 	}
 
-	if d.Redis != nil && d.Redis.Addr == "" {
-		return errors.New("redis.addr is required")
+	if d.Redis != nil {
+		if err := d.Redis.Validate(); err != nil {
+			return err
+		}
 	}
 
 	if d.Tdengine != nil && d.Tdengine.URL == "" {
 		return errors.New("tdengine.url is required")
 	}
 
+	if d.Sqlite != nil {
+		if d.Sqlite.Path == "" {
+			return errors.New("sqlite.path is required")
+		}
+		if d.Sqlite.MaxOpenConnection < 0 {
+			return errors.New("sqlite.maxOpenConnection must be >= 0")
+		}
+	}
+
+	if d.Postgres != nil {
+		if d.Postgres.URL == "" {
+			return errors.New("postgres.url is required")
+		}
+		if d.Postgres.MaxIdleConnection < 0 {
+			return errors.New("postgres.maxIdleConnection must be >= 0")
+		}
+		if d.Postgres.MaxOpenConnection < 0 {
+			return errors.New("postgres.maxOpenConnection must be >= 0")
+		}
+	}
+
+	return nil
+}
+
+// Validate 验证 Redis 配置。
+func (r *Redis) Validate() error {
+	switch strings.ToLower(r.Mode) {
+	case "", RedisModeStandalone:
+		if r.Addr == "" {
+			return errors.New("redis.addr is required")
+		}
+	case RedisModeSentinel:
+		if r.MasterName == "" {
+			return errors.New("redis.masterName is required in sentinel mode")
+		}
+		if len(r.Addrs) == 0 {
+			return errors.New("redis.addrs is required in sentinel mode")
+		}
+	case RedisModeCluster:
+		if len(r.Addrs) == 0 {
+			return errors.New("redis.addrs is required in cluster mode")
+		}
+		if r.DB != 0 {
+			return errors.New("redis.db must be 0 in cluster mode")
+		}
+	default:
+		return fmt.Errorf("invalid redis mode: %s (must be standalone/sentinel/cluster)", r.Mode)
+	}
+
+	if r.PoolSize < 0 {
+		return errors.New("redis.poolSize must be >= 0")
+	}
+	if r.MinIdleConns < 0 {
+		return errors.New("redis.minIdleConns must be >= 0")
+	}
+	if r.TLS != nil && r.TLS.Enabled && (r.TLS.CertFile == "") != (r.TLS.KeyFile == "") {
+		return errors.New("redis.tls.certFile and redis.tls.keyFile must be set together")
+	}
 	return nil
 }
 
@@ -345,11 +864,20 @@ func (s *Storage) Validate() error {
 
 // Validate 验证 Log 配置。
 func (l *Log) Validate() error {
-	validLevels := map[string]bool{
-		"debug": true, "info": true, "warn": true, "error": true, "fatal": true,
+	// 级别与格式由 logger 包统一定义，避免两处不一致
+	if _, err := logger.ParseLevel(l.Level); err != nil {
+		return err
+	}
+	for _, level := range []string{l.StdoutLevel, l.FileLevel} {
+		if level == "" || level == logger.LevelOff {
+			continue
+		}
+		if _, err := logger.ParseLevel(level); err != nil {
+			return err
+		}
 	}
-	if !validLevels[strings.ToLower(l.Level)] {
-		return fmt.Errorf("invalid log level: %s (must be debug/info/warn/error/fatal)", l.Level)
+	if _, err := logger.ParseFormat(l.Format); err != nil {
+		return err
 	}
 	if l.MaxSize <= 0 {
 		return errors.New("maxSize must be > 0")
@@ -360,6 +888,193 @@ func (l *Log) Validate() error {
 	if l.MaxAge < 0 {
 		return errors.New("maxAge must be >= 0")
 	}
+	if l.MaxTotalSize < 0 {
+		return errors.New("maxTotalSize must be >= 0")
+	}
+	if l.FilePattern != "" && (!strings.Contains(l.FilePattern, "{date}") || strings.ContainsAny(l.FilePattern, `/\`)) {
+		return fmt.Errorf("invalid log filePattern: %s (must contain {date} and no path separators)", l.FilePattern)
+	}
+	for i := range l.Sinks {
+		opts := l.Sinks[i].Options()
+		if err := opts.Validate(); err != nil {
+			return fmt.Errorf("sinks[%d]: %w", i, err)
+		}
+	}
+	if err := l.Access.Validate(); err != nil {
+		return fmt.Errorf("access: %w", err)
+	}
+	return nil
+}
+
+// Validate 验证 Monitor 配置。
+func (m *Monitor) Validate() error {
+	if m.StatsInterval <= 0 {
+		return errors.New("statsInterval must be > 0")
+	}
+	if m.WaitWarnThreshold < 0 {
+		return errors.New("waitWarnThreshold must be >= 0")
+	}
+	if m.MetricsPath != "" && !strings.HasPrefix(m.MetricsPath, "/") {
+		return errors.New("metricsPath must start with '/'")
+	}
+	return nil
+}
+
+// Validate 验证 CORS 配置。
+func (c *CORS) Validate() error {
+	if err := c.CORSPolicy.Validate(); err != nil {
+		return err
+	}
+	for i, g := range c.Groups {
+		if !strings.HasPrefix(g.Prefix, "/") {
+			return fmt.Errorf("groups[%d]: prefix must start with '/'", i)
+		}
+		if err := g.CORSPolicy.Validate(); err != nil {
+			return fmt.Errorf("groups[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Validate 验证 CORSPolicy 配置。
+func (p *CORSPolicy) Validate() error {
+	for _, origin := range p.AllowOrigins {
+		if origin == "*" {
+			// 浏览器拒绝 Allow-Origin: * 与 Allow-Credentials: true 同时出现
+			if p.AllowCredentials {
+				return errors.New("allowOrigins '*' cannot be used with allowCredentials, list the origins or use allowOriginRegex")
+			}
+			continue
+		}
+		host := origin
+		if i := strings.Index(origin, "://"); i >= 0 {
+			host = origin[i+3:]
+		}
+		if strings.Contains(host, "*") && (!strings.HasPrefix(host, "*.") || strings.Count(origin, "*") > 1) {
+			return fmt.Errorf("invalid allowOrigins %s (wildcard is only allowed as the first subdomain, like *.example.com)", origin)
+		}
+	}
+	for _, expr := range p.AllowOriginRegex {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("invalid allowOriginRegex %s: %w", expr, err)
+		}
+	}
+	for _, method := range p.AllowMethods {
+		if method == "" || strings.ToUpper(method) != method {
+			return fmt.Errorf("invalid allowMethods %q (must be uppercase)", method)
+		}
+	}
+	if p.MaxAge < 0 {
+		return errors.New("maxAge must be >= 0")
+	}
+	return nil
+}
+
+// Validate 验证 RateLimit 配置。
+func (r *RateLimit) Validate() error {
+	if r.Store != "" && r.Store != "memory" && r.Store != "redis" {
+		return fmt.Errorf("invalid store: %s (must be memory/redis)", r.Store)
+	}
+	prefixes := make(map[string]bool, len(r.Rules))
+	for i, rule := range r.Rules {
+		if rule.Prefix != "" && !strings.HasPrefix(rule.Prefix, "/") {
+			return fmt.Errorf("rules[%d]: prefix must start with '/'", i)
+		}
+		if prefixes[rule.Prefix] {
+			return fmt.Errorf("rules[%d]: duplicate prefix %q", i, rule.Prefix)
+		}
+		prefixes[rule.Prefix] = true
+		switch rule.Key {
+		case "", "ip", "user", "api_key", "route":
+		default:
+			return fmt.Errorf("rules[%d]: invalid key: %s (must be ip/user/api_key/route)", i, rule.Key)
+		}
+		ratelimitRule := rule.Rule()
+		if err := ratelimitRule.Validate(); err != nil {
+			return fmt.Errorf("rules[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Validate 验证 Cache 配置。
+func (c *Cache) Validate() error {
+	if c.LocalSize < 0 {
+		return errors.New("localSize must be >= 0")
+	}
+	if c.LocalTTL < 0 || c.NegativeTTL < 0 || c.LoadTimeout < 0 {
+		return errors.New("localTTL, negativeTTL and loadTimeout must be >= 0")
+	}
+	if c.Jitter < 0 || c.Jitter >= 1 {
+		return errors.New("jitter must be in [0, 1)")
+	}
+	return nil
+}
+
+// Validate 验证 RabbitMQ 配置，未设置的项由组件使用默认值。
+func (r *RabbitMQ) Validate() error {
+	if r.ReconnectInterval < 0 || r.MaxReconnectInterval < 0 || r.PublishTimeout < 0 {
+		return errors.New("reconnectInterval, maxReconnectInterval and publishTimeout must be >= 0")
+	}
+	if r.ChannelPoolSize < 0 || r.PublishBuffer < 0 {
+		return errors.New("channelPoolSize and publishBuffer must be >= 0")
+	}
+	if r.Topology != nil {
+		if err := r.Topology.Validate(); err != nil {
+			return fmt.Errorf("topology: %w", err)
+		}
+	}
+	return nil
+}
+
+// Validate 验证消息队列驱动配置。
+func (b *Broker) Validate() error {
+	switch b.Driver {
+	case "rabbitmq", "redis", "memory":
+	default:
+		return fmt.Errorf("unsupported driver %q", b.Driver)
+	}
+	if b.MaxLen < 0 || b.Buffer < 0 || b.Concurrency < 0 || b.MaxAttempts < 0 || b.RetryDelay < 0 {
+		return errors.New("maxLen, buffer, concurrency, maxAttempts and retryDelay must be >= 0")
+	}
+	return nil
+}
+
+// Validate 验证拓扑配置。
+func (t *Topology) Validate() error {
+	for _, e := range t.Exchanges {
+		if e.Name == "" {
+			return errors.New("exchange name can`t null")
+		}
+		switch e.Type {
+		case "direct", "fanout", "topic", "headers":
+		default:
+			if !strings.HasPrefix(e.Type, "x-") {
+				return fmt.Errorf("exchange %s: unsupported type %q", e.Name, e.Type)
+			}
+		}
+	}
+	for _, q := range t.Queues {
+		if q.Name == "" {
+			return errors.New("queue name can`t null")
+		}
+		if q.Quorum && (!q.Durable || q.AutoDelete || q.Exclusive) {
+			return fmt.Errorf("queue %s: quorum queue must be durable, non-exclusive and not auto-delete", q.Name)
+		}
+		if q.MessageTTL < 0 || q.MaxLength < 0 || q.MaxLengthBytes < 0 {
+			return fmt.Errorf("queue %s: messageTTL, maxLength and maxLengthBytes must be >= 0", q.Name)
+		}
+		switch q.Overflow {
+		case "", "drop-head", "reject-publish", "reject-publish-dlx":
+		default:
+			return fmt.Errorf("queue %s: unsupported overflow %q", q.Name, q.Overflow)
+		}
+	}
+	for _, b := range t.Bindings {
+		if b.Exchange == "" || b.Queue == "" {
+			return errors.New("binding exchange and queue can`t null")
+		}
+	}
 	return nil
 }
 
@@ -409,6 +1124,9 @@ func setViperDefaults(v *viper.Viper) {
 	v.SetDefault("server.name", "my-app")
 	v.SetDefault("server.port", 8080)
 	v.SetDefault("server.version", "v1.0.0")
+	v.SetDefault("server.requestIdHeader", requestid.Header)
+	v.SetDefault("server.requestIdGenerator", requestid.GeneratorUUID)
+	v.SetDefault("server.timeout", "30s")
 
 	// JWT 默认值
 	v.SetDefault("jwt.secret", "woaifcll")
@@ -419,6 +1137,12 @@ func setViperDefaults(v *viper.Viper) {
 	// Log 默认值
 	v.SetDefault("log.path", "./log/")
 	v.SetDefault("log.level", "info")
+	v.SetDefault("log.format", "console")
+	v.SetDefault("log.filePattern", "{date}.log")
+	v.SetDefault("log.redactKeys", redact.DefaultKeys)
+	v.SetDefault("log.access.skipPaths", []string{"/readyz", "/metrics"})
+	v.SetDefault("log.access.slowThreshold", time.Second)
+	v.SetDefault("log.access.maxBodySize", 4096)
 	v.SetDefault("log.maxSize", 100)
 	v.SetDefault("log.maxBackups", 5)
 	v.SetDefault("log.maxAge", 7)
@@ -427,6 +1151,7 @@ func setViperDefaults(v *viper.Viper) {
 	v.SetDefault("db.redis.db", 0)
 	v.SetDefault("db.redis.addr", "localhost:6379")
 	v.SetDefault("db.redis.network", "tcp")
+	v.SetDefault("db.redis.mode", "standalone")
 
 	// Mysql 默认值
 	v.SetDefault("db.mysql.maxIdleConnection", 10)
@@ -438,6 +1163,30 @@ func setViperDefaults(v *viper.Viper) {
 
 	// Debug 默认值
 	v.SetDefault("debug.enablePProf", false)
+	v.SetDefault("debug.enableLogLevel", false)
+
+	// Cache 默认值
+	v.SetDefault("cache.prefix", "cache:")
+	v.SetDefault("cache.localSize", 10000)
+	v.SetDefault("cache.localTTL", "1m")
+	v.SetDefault("cache.jitter", 0.1)
+	v.SetDefault("cache.negativeTTL", "30s")
+	v.SetDefault("cache.channel", "cache:invalidate")
+	v.SetDefault("cache.loadTimeout", "10s")
+
+	// CORS 默认值
+	v.SetDefault("cors.allowOrigins", []string{"*"})
+	v.SetDefault("cors.maxAge", "12h")
+
+	// RateLimit 默认值
+	v.SetDefault("rateLimit.store", "memory")
+	v.SetDefault("rateLimit.prefix", "ratelimit:")
+	v.SetDefault("rateLimit.apiKeyHeader", "X-API-Key")
+
+	// Monitor 默认值
+	v.SetDefault("monitor.statsInterval", "15s")
+	v.SetDefault("monitor.waitWarnThreshold", "1s")
+	v.SetDefault("monitor.metricsPath", "/metrics")
 }
 
 // ResetForTesting 重置配置状态，仅供测试使用。
@@ -0,0 +1,246 @@
+package database
+
+import (
+	"strings"
+)
+
+// SelectBuilder SELECT 语句构建器
+//
+// 条件中统一使用 ? 作为占位符，Build 时按方言转换：
+//
+//	query, args := database.Select(database.DialectPostgres, "users", "id", "name").
+//		Where("status = ?", 1).
+//		OrderBy("id DESC").
+//		Limit(10).
+//		Build()
+type SelectBuilder struct {
+	dialect  Dialect
+	table    string
+	columns  []string
+	where    []string
+	args     []interface{}
+	orderBy  []string
+	limit    int
+	offset   int
+	lock     bool
+	skipLock bool
+}
+
+// Select 创建 SELECT 构建器，未指定列时查询全部列
+func Select(d Dialect, table string, columns ...string) *SelectBuilder {
+	return &SelectBuilder{dialect: d, table: table, columns: columns}
+}
+
+// Where 追加条件，多个条件之间为 AND
+func (b *SelectBuilder) Where(cond string, args ...interface{}) *SelectBuilder {
+	b.where = append(b.where, cond)
+	b.args = append(b.args, args...)
+	return b
+}
+
+// OrderBy 追加排序表达式
+func (b *SelectBuilder) OrderBy(exprs ...string) *SelectBuilder {
+	b.orderBy = append(b.orderBy, exprs...)
+	return b
+}
+
+// Limit 设置返回条数
+func (b *SelectBuilder) Limit(n int) *SelectBuilder {
+	b.limit = n
+	return b
+}
+
+// Offset 设置偏移量
+func (b *SelectBuilder) Offset(n int) *SelectBuilder {
+	b.offset = n
+	return b
+}
+
+// ForUpdate 加行锁，skipLocked 为 true 时跳过已被锁定的行
+func (b *SelectBuilder) ForUpdate(skipLocked bool) *SelectBuilder {
+	b.lock = true
+	b.skipLock = skipLocked
+	return b
+}
+
+// Build 生成 SQL 与参数
+func (b *SelectBuilder) Build() (string, []interface{}) {
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
+	if len(b.columns) == 0 {
+		sb.WriteString("*")
+	} else {
+		sb.WriteString(quoteList(b.dialect, b.columns))
+	}
+	sb.WriteString(" FROM ")
+	sb.WriteString(b.dialect.Quote(b.table))
+	writeWhere(&sb, b.where)
+	if len(b.orderBy) > 0 {
+		sb.WriteString(" ORDER BY ")
+		sb.WriteString(strings.Join(b.orderBy, ", "))
+	}
+	sb.WriteString(b.dialect.LimitOffset(b.limit, b.offset))
+	if b.lock {
+		sb.WriteString(b.dialect.ForUpdate(b.skipLock))
+	}
+	return Rebind(b.dialect, sb.String()), b.args
+}
+
+// InsertBuilder INSERT 语句构建器
+type InsertBuilder struct {
+	dialect Dialect
+	table   string
+	columns []string
+	rows    [][]interface{}
+	ignore  bool
+}
+
+// Insert 创建 INSERT 构建器
+func Insert(d Dialect, table string, columns ...string) *InsertBuilder {
+	return &InsertBuilder{dialect: d, table: table, columns: columns}
+}
+
+// Values 追加一行数据，数量需与列一致
+func (b *InsertBuilder) Values(values ...interface{}) *InsertBuilder {
+	b.rows = append(b.rows, values)
+	return b
+}
+
+// Ignore 唯一键冲突时忽略该行
+func (b *InsertBuilder) Ignore() *InsertBuilder {
+	b.ignore = true
+	return b
+}
+
+// Build 生成 SQL 与参数
+func (b *InsertBuilder) Build() (string, []interface{}) {
+	prefix, suffix := "INSERT INTO", ""
+	if b.ignore {
+		prefix, suffix = b.dialect.InsertIgnore()
+	}
+
+	var sb strings.Builder
+	sb.WriteString(prefix)
+	sb.WriteByte(' ')
+	sb.WriteString(b.dialect.Quote(b.table))
+	sb.WriteString(" (")
+	sb.WriteString(quoteList(b.dialect, b.columns))
+	sb.WriteString(") VALUES ")
+
+	args := make([]interface{}, 0, len(b.rows)*len(b.columns))
+	row := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(b.columns)), ", ") + ")"
+	for i, values := range b.rows {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(row)
+		args = append(args, values...)
+	}
+	sb.WriteString(suffix)
+	return Rebind(b.dialect, sb.String()), args
+}
+
+// UpdateBuilder UPDATE 语句构建器
+type UpdateBuilder struct {
+	dialect Dialect
+	table   string
+	sets    []string
+	setArgs []interface{}
+	where   []string
+	args    []interface{}
+}
+
+// Update 创建 UPDATE 构建器
+func Update(d Dialect, table string) *UpdateBuilder {
+	return &UpdateBuilder{dialect: d, table: table}
+}
+
+// Set 设置列值
+func (b *UpdateBuilder) Set(column string, value interface{}) *UpdateBuilder {
+	b.sets = append(b.sets, b.dialect.Quote(column)+" = ?")
+	b.setArgs = append(b.setArgs, value)
+	return b
+}
+
+// SetExpr 以表达式设置列值，例如 SetExpr("attempts", "attempts + 1")
+func (b *UpdateBuilder) SetExpr(column, expr string, args ...interface{}) *UpdateBuilder {
+	b.sets = append(b.sets, b.dialect.Quote(column)+" = "+expr)
+	b.setArgs = append(b.setArgs, args...)
+	return b
+}
+
+// Where 追加条件，多个条件之间为 AND
+func (b *UpdateBuilder) Where(cond string, args ...interface{}) *UpdateBuilder {
+	b.where = append(b.where, cond)
+	b.args = append(b.args, args...)
+	return b
+}
+
+// Build 生成 SQL 与参数
+func (b *UpdateBuilder) Build() (string, []interface{}) {
+	var sb strings.Builder
+	sb.WriteString("UPDATE ")
+	sb.WriteString(b.dialect.Quote(b.table))
+	sb.WriteString(" SET ")
+	sb.WriteString(strings.Join(b.sets, ", "))
+	writeWhere(&sb, b.where)
+
+	args := make([]interface{}, 0, len(b.setArgs)+len(b.args))
+	args = append(args, b.setArgs...)
+	args = append(args, b.args...)
+	return Rebind(b.dialect, sb.String()), args
+}
+
+// DeleteBuilder DELETE 语句构建器
+type DeleteBuilder struct {
+	dialect Dialect
+	table   string
+	where   []string
+	args    []interface{}
+}
+
+// Delete 创建 DELETE 构建器
+func Delete(d Dialect, table string) *DeleteBuilder {
+	return &DeleteBuilder{dialect: d, table: table}
+}
+
+// Where 追加条件，多个条件之间为 AND
+func (b *DeleteBuilder) Where(cond string, args ...interface{}) *DeleteBuilder {
+	b.where = append(b.where, cond)
+	b.args = append(b.args, args...)
+	return b
+}
+
+// Build 生成 SQL 与参数
+func (b *DeleteBuilder) Build() (string, []interface{}) {
+	var sb strings.Builder
+	sb.WriteString("DELETE FROM ")
+	sb.WriteString(b.dialect.Quote(b.table))
+	writeWhere(&sb, b.where)
+	return Rebind(b.dialect, sb.String()), b.args
+}
+
+func writeWhere(sb *strings.Builder, where []string) {
+	if len(where) == 0 {
+		return
+	}
+	sb.WriteString(" WHERE ")
+	for i, cond := range where {
+		if i > 0 {
+			sb.WriteString(" AND ")
+		}
+		if len(where) > 1 {
+			sb.WriteString("(" + cond + ")")
+		} else {
+			sb.WriteString(cond)
+		}
+	}
+}
+
+func quoteList(d Dialect, idents []string) string {
+	quoted := make([]string, len(idents))
+	for i, ident := range idents {
+		quoted[i] = d.Quote(ident)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectBuilder(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		want    string
+	}{
+		{
+			name:    "mysql",
+			dialect: DialectMySQL,
+			want:    "SELECT `id`, `name` FROM `users` WHERE (status = ?) AND (name LIKE ?) ORDER BY id DESC LIMIT 10 OFFSET 20 FOR UPDATE SKIP LOCKED",
+		},
+		{
+			name:    "postgres",
+			dialect: DialectPostgres,
+			want:    `SELECT "id", "name" FROM "users" WHERE (status = $1) AND (name LIKE $2) ORDER BY id DESC LIMIT 10 OFFSET 20 FOR UPDATE SKIP LOCKED`,
+		},
+		{
+			name:    "sqlite",
+			dialect: DialectSQLite,
+			want:    `SELECT "id", "name" FROM "users" WHERE (status = ?) AND (name LIKE ?) ORDER BY id DESC LIMIT 10 OFFSET 20`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args := Select(tt.dialect, "users", "id", "name").
+				Where("status = ?", 1).
+				Where("name LIKE ?", "a%").
+				OrderBy("id DESC").
+				Limit(10).
+				Offset(20).
+				ForUpdate(true).
+				Build()
+
+			assert.Equal(t, tt.want, query)
+			assert.Equal(t, []interface{}{1, "a%"}, args)
+		})
+	}
+}
+
+func TestInsertBuilder_Ignore(t *testing.T) {
+	query, args := Insert(DialectPostgres, "inbox", "id", "consumer").Values("m1", "c").Ignore().Build()
+	assert.Equal(t, `INSERT INTO "inbox" ("id", "consumer") VALUES ($1, $2) ON CONFLICT DO NOTHING`, query)
+	assert.Equal(t, []interface{}{"m1", "c"}, args)
+
+	query, _ = Insert(DialectMySQL, "inbox", "id").Values("m1").Ignore().Build()
+	assert.Equal(t, "INSERT IGNORE INTO `inbox` (`id`) VALUES (?)", query)
+
+	query, _ = Insert(DialectSQLite, "inbox", "id").Values("m1").Ignore().Build()
+	assert.Equal(t, `INSERT OR IGNORE INTO "inbox" ("id") VALUES (?)`, query)
+}
+
+func TestUpdateDeleteBuilder(t *testing.T) {
+	query, args := Update(DialectPostgres, "outbox").
+		Set("status", 1).
+		SetExpr("attempts", "attempts + ?", 1).
+		Where("id = ?", 7).
+		Build()
+	assert.Equal(t, `UPDATE "outbox" SET "status" = $1, "attempts" = attempts + $2 WHERE id = $3`, query)
+	assert.Equal(t, []interface{}{1, 1, 7}, args)
+
+	query, args = Delete(DialectPostgres, "outbox").Where("created_at < ?", "t").Build()
+	assert.Equal(t, `DELETE FROM "outbox" WHERE created_at < $1`, query)
+	assert.Equal(t, []interface{}{"t"}, args)
+}
+
+func TestRebind(t *testing.T) {
+	assert.Equal(t, "a = $1 AND b = '?' AND c = $2", Rebind(DialectPostgres, "a = ? AND b = '?' AND c = ?"))
+	assert.Equal(t, "a = ?", Rebind(DialectMySQL, "a = ?"))
+}
+
+func TestLimitOffset(t *testing.T) {
+	assert.Equal(t, " LIMIT -1 OFFSET 5", DialectSQLite.LimitOffset(0, 5))
+	assert.Equal(t, " LIMIT 18446744073709551615 OFFSET 5", DialectMySQL.LimitOffset(0, 5))
+	assert.Equal(t, " OFFSET 5", DialectPostgres.LimitOffset(0, 5))
+	assert.Equal(t, "", DialectPostgres.LimitOffset(0, 0))
+}
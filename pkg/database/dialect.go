@@ -0,0 +1,173 @@
+package database
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Dialect SQL 方言，屏蔽不同数据库在占位符、标识符引用、分页等方面的差异
+type Dialect interface {
+	// Name 方言名称：mysql / postgres / sqlite
+	Name() string
+	// Placeholder 返回第 n 个参数（从 1 开始）的占位符
+	Placeholder(n int) string
+	// Quote 引用标识符（表名、列名）
+	Quote(ident string) string
+	// LimitOffset 生成分页子句，limit <= 0 表示不限制
+	LimitOffset(limit, offset int) string
+	// InsertIgnore 生成遇到唯一键冲突时忽略的插入语句前缀与后缀
+	InsertIgnore() (prefix, suffix string)
+	// AutoIncrementPK 自增主键列类型定义
+	AutoIncrementPK() string
+	// TextType 长文本列类型
+	TextType() string
+	// BlobType 二进制列类型
+	BlobType() string
+	// TimestampType 时间列类型
+	TimestampType() string
+	// ForUpdate 行锁子句，不支持行锁的方言返回空字符串
+	ForUpdate(skipLocked bool) string
+}
+
+var (
+	DialectMySQL    Dialect = mysqlDialect{}
+	DialectPostgres Dialect = postgresDialect{}
+	DialectSQLite   Dialect = sqliteDialect{}
+)
+
+// DialectOf 根据名称获取方言，未知名称返回 nil
+func DialectOf(name string) Dialect {
+	switch strings.ToLower(name) {
+	case "mysql", "gorm", "gorm_mysql":
+		return DialectMySQL
+	case "postgres", "postgresql", "pgx":
+		return DialectPostgres
+	case "sqlite", "sqlite3":
+		return DialectSQLite
+	}
+	return nil
+}
+
+// Rebind 将 ? 占位符转换为指定方言的占位符（跳过引号内的内容）
+func Rebind(d Dialect, query string) string {
+	if d.Placeholder(1) == "?" {
+		return query
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(query) + 8)
+
+	n := 0
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '?':
+			n++
+			sb.WriteString(d.Placeholder(n))
+			continue
+		}
+		sb.WriteByte(ch)
+	}
+	return sb.String()
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string              { return "mysql" }
+func (mysqlDialect) Placeholder(int) string    { return "?" }
+func (mysqlDialect) Quote(ident string) string { return quoteWith(ident, '`') }
+func (mysqlDialect) LimitOffset(limit, offset int) string {
+	// MySQL 同样要求 OFFSET 前必须有 LIMIT
+	if limit <= 0 && offset > 0 {
+		return " LIMIT 18446744073709551615 OFFSET " + strconv.Itoa(offset)
+	}
+	return limitOffset(limit, offset)
+}
+func (mysqlDialect) InsertIgnore() (string, string)   { return "INSERT IGNORE INTO", "" }
+func (mysqlDialect) AutoIncrementPK() string          { return "BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY" }
+func (mysqlDialect) TextType() string                 { return "LONGTEXT" }
+func (mysqlDialect) BlobType() string                 { return "LONGBLOB" }
+func (mysqlDialect) TimestampType() string            { return "DATETIME(6)" }
+func (mysqlDialect) ForUpdate(skipLocked bool) string { return forUpdate(skipLocked) }
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string              { return "postgres" }
+func (postgresDialect) Placeholder(n int) string  { return "$" + strconv.Itoa(n) }
+func (postgresDialect) Quote(ident string) string { return quoteWith(ident, '"') }
+func (postgresDialect) LimitOffset(limit, offset int) string {
+	return limitOffset(limit, offset)
+}
+func (postgresDialect) InsertIgnore() (string, string) {
+	return "INSERT INTO", " ON CONFLICT DO NOTHING"
+}
+func (postgresDialect) AutoIncrementPK() string { return "BIGSERIAL PRIMARY KEY" }
+func (postgresDialect) TextType() string        { return "TEXT" }
+func (postgresDialect) BlobType() string        { return "BYTEA" }
+func (postgresDialect) TimestampType() string   { return "TIMESTAMPTZ" }
+func (postgresDialect) ForUpdate(skipLocked bool) string {
+	return forUpdate(skipLocked)
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string              { return "sqlite" }
+func (sqliteDialect) Placeholder(int) string    { return "?" }
+func (sqliteDialect) Quote(ident string) string { return quoteWith(ident, '"') }
+func (sqliteDialect) LimitOffset(limit, offset int) string {
+	// SQLite 不支持只有 OFFSET 没有 LIMIT 的写法
+	if limit <= 0 && offset > 0 {
+		return " LIMIT -1 OFFSET " + strconv.Itoa(offset)
+	}
+	return limitOffset(limit, offset)
+}
+func (sqliteDialect) InsertIgnore() (string, string) { return "INSERT OR IGNORE INTO", "" }
+func (sqliteDialect) AutoIncrementPK() string        { return "INTEGER PRIMARY KEY AUTOINCREMENT" }
+func (sqliteDialect) TextType() string               { return "TEXT" }
+func (sqliteDialect) BlobType() string               { return "BLOB" }
+func (sqliteDialect) TimestampType() string          { return "DATETIME" }
+
+// SQLite 写事务本身是串行的，没有行锁
+func (sqliteDialect) ForUpdate(bool) string { return "" }
+
+func limitOffset(limit, offset int) string {
+	var sb strings.Builder
+	if limit > 0 {
+		sb.WriteString(" LIMIT ")
+		sb.WriteString(strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		sb.WriteString(" OFFSET ")
+		sb.WriteString(strconv.Itoa(offset))
+	}
+	return sb.String()
+}
+
+func forUpdate(skipLocked bool) string {
+	if skipLocked {
+		return " FOR UPDATE SKIP LOCKED"
+	}
+	return " FOR UPDATE"
+}
+
+// quoteWith 引用标识符，支持 schema.table 形式，已引用或含表达式的内容原样返回
+func quoteWith(ident string, q byte) string {
+	if ident == "*" || strings.ContainsAny(ident, "`\"() ") {
+		return ident
+	}
+
+	parts := strings.Split(ident, ".")
+	for i, p := range parts {
+		if p != "*" {
+			parts[i] = string(q) + p + string(q)
+		}
+	}
+	return strings.Join(parts, ".")
+}
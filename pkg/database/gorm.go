@@ -1,11 +1,11 @@
 package database
 
 import (
+	"database/sql"
 	"time"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
 
 	"project/pkg/config"
@@ -59,7 +59,7 @@ func (g *GormMysql) InitComponent() bool {
 
 	// 构建 GORM 配置
 	gormConfig := &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Silent),
+		Logger: NewGormLogger(),
 		NamingStrategy: schema.NamingStrategy{
 			SingularTable: true, // 禁用表名复数化：gorm默认会把结构体名称+s得到的结果映射为数据库中的表名，设置该项后则不加s
 		},
@@ -119,3 +119,20 @@ func (g *GormMysql) IsInitialize() bool {
 func (g *GormMysql) GetDb() *gorm.DB {
 	return g.db
 }
+
+// 获取底层 sql.DB 连接池统计信息
+func (g *GormMysql) Stats() sql.DBStats {
+	if g.db == nil {
+		return sql.DBStats{}
+	}
+	sqlDB, err := g.db.DB()
+	if err != nil {
+		return sql.DBStats{}
+	}
+	return sqlDB.Stats()
+}
+
+// Dialect 返回 SQL 方言
+func (g *GormMysql) Dialect() Dialect {
+	return DialectMySQL
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	local_logger "project/pkg/logger"
+)
+
+// DefaultSlowThreshold 默认慢查询阈值
+const DefaultSlowThreshold = 200 * time.Millisecond
+
+// GormLogger 通过 logger.FromContext 输出 GORM 日志，使用 db.WithContext(ctx) 时 SQL 日志带上请求 ID 等字段
+//
+// 失败的 SQL 记录为 error（记录不存在除外），超过 SlowThreshold 的记录为 warn，其余 SQL 仅在 debug 级别输出。
+type GormLogger struct {
+	// Level GORM 日志级别，默认 Warn
+	Level gormlogger.LogLevel
+	// SlowThreshold 慢查询阈值，默认 200ms，小于 0 时不记录慢查询
+	SlowThreshold time.Duration
+}
+
+// NewGormLogger 创建默认配置的 GORM 日志
+func NewGormLogger() *GormLogger {
+	return &GormLogger{Level: gormlogger.Warn, SlowThreshold: DefaultSlowThreshold}
+}
+
+// LogMode 返回指定级别的副本
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	cp := *l
+	cp.Level = level
+	return &cp
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.Level >= gormlogger.Info {
+		local_logger.FromContext(ctx).Named("gorm").Infof("\t[gorm] "+msg, args...)
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.Level >= gormlogger.Warn {
+		local_logger.FromContext(ctx).Named("gorm").Warnf("\t[gorm] "+msg, args...)
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.Level >= gormlogger.Error {
+		local_logger.FromContext(ctx).Named("gorm").Errorf("\t[gorm] "+msg, args...)
+	}
+}
+
+// Trace 记录一条 SQL 的执行结果
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.Level <= gormlogger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	log := local_logger.FromContext(ctx).Named("gorm")
+
+	switch {
+	case err != nil && l.Level >= gormlogger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		log.Errorw("\t[gorm] query failed", "sql", sql, "rows", rows, "elapsed", elapsed, "error", err)
+	case l.SlowThreshold > 0 && elapsed > l.SlowThreshold && l.Level >= gormlogger.Warn:
+		sql, rows := fc()
+		log.Warnw(fmt.Sprintf("\t[gorm] slow query >= %s", l.SlowThreshold), "sql", sql, "rows", rows, "elapsed", elapsed)
+	case log.Desugar().Core().Enabled(zapcore.DebugLevel):
+		sql, rows := fc()
+		log.Debugw("\t[gorm] query", "sql", sql, "rows", rows, "elapsed", elapsed)
+	}
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"project/pkg/logger"
+)
+
+// MigrationTable 迁移记录表名
+const MigrationTable = "schema_migrations"
+
+// Migration 数据库迁移
+type Migration struct {
+	// Version 版本号，按升序执行，不可重复
+	Version int64
+	// Name 迁移说明
+	Name string
+	// Up 返回该迁移要执行的语句，可根据方言生成不同的 DDL
+	Up func(d Dialect) []string
+}
+
+// Migrate 按版本号顺序执行尚未执行过的迁移
+//
+// 每个迁移在单独的事务中执行（MySQL 的 DDL 会隐式提交，失败时需人工处理）
+func Migrate(ctx context.Context, db *sql.DB, d Dialect, migrations []Migration) error {
+	// 先校验整个列表，避免部分迁移执行后才发现版本重复
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i-1].Version == sorted[i].Version {
+			return fmt.Errorf("duplicate migration version %d", sorted[i].Version)
+		}
+	}
+
+	create := fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (%s BIGINT NOT NULL PRIMARY KEY, %s VARCHAR(255) NOT NULL, %s %s NOT NULL)",
+		d.Quote(MigrationTable), d.Quote("version"), d.Quote("name"), d.Quote("applied_at"), d.TimestampType(),
+	)
+	if _, err := db.ExecContext(ctx, create); err != nil {
+		return fmt.Errorf("create %s: %w", MigrationTable, err)
+	}
+
+	applied, err := AppliedMigrations(ctx, db, d)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range sorted {
+		if applied[m.Version] {
+			continue
+		}
+		if err := applyMigration(ctx, db, d, m); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		logger.FromContext(ctx).Infof("\t[migrate] applied %d: %s", m.Version, m.Name)
+	}
+
+	return nil
+}
+
+// AppliedMigrations 返回已执行的迁移版本
+func AppliedMigrations(ctx context.Context, db *sql.DB, d Dialect) (map[int64]bool, error) {
+	query, _ := Select(d, MigrationTable, "version").Build()
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query %s: %w", MigrationTable, err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var v int64
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied[v] = true
+	}
+	return applied, rows.Err()
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, d Dialect, m Migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if m.Up != nil {
+		for _, stmt := range m.Up(d) {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+	}
+
+	query, args := Insert(d, MigrationTable, "version", "name", "applied_at").
+		Values(m.Version, m.Name, time.Now().UTC()).
+		Build()
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -117,3 +117,16 @@ func (m *Mysql) GetUrl() string {
 func (m *Mysql) GetDb() *sql.DB {
 	return m.dB
 }
+
+// 获取连接池统计信息
+func (m *Mysql) Stats() sql.DBStats {
+	if m.dB == nil {
+		return sql.DBStats{}
+	}
+	return m.dB.Stats()
+}
+
+// Dialect 返回 SQL 方言
+func (m *Mysql) Dialect() Dialect {
+	return DialectMySQL
+}
@@ -0,0 +1,155 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"project/pkg/config"
+	local_logger "project/pkg/logger"
+)
+
+var postgresEntity *Postgres
+
+type Postgres struct {
+	//组件名称
+	name string
+	//postgres url（DSN 或 postgres:// 形式）
+	url string
+	//初始化标识
+	isInit bool
+	//最大闲置数
+	maxIdleConnection int
+	//最大连接数
+	maxOpenConnection int
+	//db
+	db *gorm.DB
+}
+
+// NewPostgres 根据配置创建 PostgreSQL 组件
+func NewPostgres(cfg *config.Postgres) *Postgres {
+	return &Postgres{
+		name:              "postgres",
+		url:               cfg.URL,
+		maxIdleConnection: cfg.MaxIdleConnection,
+		maxOpenConnection: cfg.MaxOpenConnection,
+	}
+}
+
+func GetPostgres() *Postgres {
+	if postgresEntity != nil {
+		return postgresEntity
+	}
+
+	if config.Get() == nil || config.Get().Db.Postgres == nil {
+		local_logger.Sugar.Errorf("\t[component] postgres config load failed")
+		return nil
+	}
+
+	postgresEntity = NewPostgres(config.Get().Db.Postgres)
+	return postgresEntity
+}
+
+func (p *Postgres) GetName() string {
+	return p.name
+}
+
+func (p *Postgres) InitComponent() bool {
+	if p.isInit {
+		local_logger.Sugar.Infof("\t[component] %s is inited", p.name)
+		return true
+	}
+
+	gormConfig := &gorm.Config{
+		Logger: NewGormLogger(),
+		NamingStrategy: schema.NamingStrategy{
+			SingularTable: true,
+		},
+	}
+
+	var err error
+	p.db, err = gorm.Open(postgres.Open(p.url), gormConfig)
+	if err != nil {
+		local_logger.Sugar.Errorf("\t[component] %s init failed: %v", p.name, err)
+		return false
+	}
+
+	sqlDB, err := p.db.DB()
+	if err != nil {
+		local_logger.Sugar.Errorf("\t[component] %s get raw db failed: %v", p.name, err)
+		return false
+	}
+
+	sqlDB.SetMaxOpenConns(p.maxOpenConnection)
+	sqlDB.SetMaxIdleConns(p.maxIdleConnection)
+	sqlDB.SetConnMaxLifetime(time.Hour)
+
+	if err = sqlDB.Ping(); err != nil {
+		local_logger.Sugar.Errorf("\t[component] %s connect failed: %v", p.name, err)
+		return false
+	}
+
+	p.isInit = true
+	local_logger.Sugar.Infof("\t[component] %s init success", p.name)
+	return true
+}
+
+// 关闭 PostgreSQL 连接
+func (p *Postgres) Close() error {
+	if p.db == nil {
+		return nil
+	}
+	sqlDB, err := p.db.DB()
+	if err != nil {
+		local_logger.Sugar.Errorf("\t[component] %s get raw db failed on close: %v", p.name, err)
+		return err
+	}
+	err = sqlDB.Close()
+	if err != nil {
+		local_logger.Sugar.Errorf("\t[component] %s close failed: %v", p.name, err)
+		return err
+	}
+	p.isInit = false
+	return nil
+}
+
+func (p *Postgres) IsInitialize() bool {
+	return p.isInit
+}
+
+func (p *Postgres) GetUrl() string {
+	return p.url
+}
+
+// 获取 gorm db
+func (p *Postgres) GetDb() *gorm.DB {
+	return p.db
+}
+
+// 获取底层 sql.DB，供原生 SQL 使用
+func (p *Postgres) GetSqlDb() *sql.DB {
+	if p.db == nil {
+		return nil
+	}
+	sqlDB, err := p.db.DB()
+	if err != nil {
+		return nil
+	}
+	return sqlDB
+}
+
+// Dialect 返回 SQL 方言
+func (p *Postgres) Dialect() Dialect {
+	return DialectPostgres
+}
+
+// 获取连接池统计信息
+func (p *Postgres) Stats() sql.DBStats {
+	if sqlDB := p.GetSqlDb(); sqlDB != nil {
+		return sqlDB.Stats()
+	}
+	return sql.DBStats{}
+}
@@ -2,6 +2,14 @@ package database
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
 	"project/pkg/config"
 	"project/pkg/logger"
 
@@ -14,24 +22,65 @@ type Redis struct {
 	name string
 
 	isInit   bool
+	mode     string
 	addr     string
+	addrs    []string
 	network  string
 	username string
 	password string
 	db       int
 
-	redisDB *redis.Client
+	masterName       string
+	sentinelUsername string
+	sentinelPassword string
+
+	poolSize     int
+	minIdleConns int
+	poolTimeout  time.Duration
+	idleTimeout  time.Duration
+	dialTimeout  time.Duration
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	maxRetries   int
+
+	tls *config.TLS
+
+	redisDB redis.UniversalClient
 }
 
 func newRedis() *Redis {
+	return NewRedis(config.Get().Db.Redis)
+}
+
+// NewRedis 根据配置创建 Redis 组件
+func NewRedis(cfg *config.Redis) *Redis {
+	mode := strings.ToLower(cfg.Mode)
+	if mode == "" {
+		mode = config.RedisModeStandalone
+	}
+
 	return &Redis{
-		name:     "redis",
-		isInit:   false,
-		db:       config.Get().Db.Redis.DB,
-		addr:     config.Get().Db.Redis.Addr,
-		network:  config.Get().Db.Redis.Network,
-		username: config.Get().Db.Redis.Username,
-		password: config.Get().Db.Redis.Password,
+		name:             "redis",
+		isInit:           false,
+		mode:             mode,
+		db:               cfg.DB,
+		addr:             cfg.Addr,
+		addrs:            cfg.Addrs,
+		network:          cfg.Network,
+		username:         cfg.Username,
+		password:         cfg.Password,
+		masterName:       cfg.MasterName,
+		sentinelUsername: cfg.SentinelUsername,
+		sentinelPassword: cfg.SentinelPassword,
+		poolSize:         cfg.PoolSize,
+		minIdleConns:     cfg.MinIdleConns,
+		poolTimeout:      cfg.PoolTimeout,
+		idleTimeout:      cfg.IdleTimeout,
+		dialTimeout:      cfg.DialTimeout,
+		readTimeout:      cfg.ReadTimeout,
+		writeTimeout:     cfg.WriteTimeout,
+		maxRetries:       cfg.MaxRetries,
+		tls:              cfg.TLS,
 	}
 }
 
@@ -53,7 +102,8 @@ func GetRedis() *Redis {
 	return redisEntity
 }
 
-func (r *Redis) GetClient() *redis.Client {
+// GetClient 返回通用客户端，standalone / sentinel / cluster 模式下用法一致
+func (r *Redis) GetClient() redis.UniversalClient {
 	return r.redisDB
 }
 
@@ -61,6 +111,11 @@ func (r *Redis) GetName() string {
 	return r.name
 }
 
+// GetMode 返回部署模式
+func (r *Redis) GetMode() string {
+	return r.mode
+}
+
 func (r *Redis) InitComponent() bool {
 
 	//判断是否初始化
@@ -69,19 +124,16 @@ func (r *Redis) InitComponent() bool {
 		return true
 	}
 
-	options := &redis.Options{
-		Addr:     r.addr,
-		Network:  r.network,
-		Username: r.username,
-		Password: r.password,
-		DB:       r.db,
+	client, err := r.newClient()
+	if err != nil {
+		logger.Sugar.Errorf("\t[component] %s init failed: %s", r.name, err)
+		return false
 	}
+	r.redisDB = client
 
-	r.redisDB = redis.NewClient(options)
-
-	//通过 *redis.Client.Ping() 来检查是否成功连接到了redis服务器
+	//通过 Ping() 来检查是否成功连接到了redis服务器
 	ctx := context.Background()
-	_, err := r.redisDB.Ping(ctx).Result()
+	_, err = r.redisDB.Ping(ctx).Result()
 	if err != nil {
 		logger.Sugar.Infof("\t[component] %s init failed: %s", r.name, err)
 		return false
@@ -89,11 +141,89 @@ func (r *Redis) InitComponent() bool {
 
 	r.isInit = true
 
-	logger.Sugar.Infof("\t[component] %s init success", r.name)
+	logger.Sugar.Infof("\t[component] %s init success, mode: %s", r.name, r.mode)
 
 	return true
 }
 
+// newClient 按部署模式创建客户端
+func (r *Redis) newClient() (redis.UniversalClient, error) {
+	tlsConfig, err := buildTLSConfig(r.tls)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &redis.UniversalOptions{
+		Addrs:            r.addrs,
+		DB:               r.db,
+		Username:         r.username,
+		Password:         r.password,
+		SentinelUsername: r.sentinelUsername,
+		SentinelPassword: r.sentinelPassword,
+		MasterName:       r.masterName,
+		MaxRetries:       r.maxRetries,
+		DialTimeout:      r.dialTimeout,
+		ReadTimeout:      r.readTimeout,
+		WriteTimeout:     r.writeTimeout,
+		PoolSize:         r.poolSize,
+		MinIdleConns:     r.minIdleConns,
+		PoolTimeout:      r.poolTimeout,
+		IdleTimeout:      r.idleTimeout,
+		TLSConfig:        tlsConfig,
+	}
+
+	switch r.mode {
+	case config.RedisModeSentinel:
+		return redis.NewFailoverClient(options.Failover()), nil
+
+	case config.RedisModeCluster:
+		return redis.NewClusterClient(options.Cluster()), nil
+
+	case config.RedisModeStandalone:
+		options.Addrs = []string{r.addr}
+		simple := options.Simple()
+		simple.Network = r.network
+		return redis.NewClient(simple), nil
+	}
+
+	return nil, fmt.Errorf("unknown redis mode: %s", r.mode)
+}
+
+// buildTLSConfig 根据配置构建 TLS 配置，未启用时返回 nil
+func buildTLSConfig(cfg *config.TLS) (*tls.Config, error) {
+	if cfg == nil || !cfg.Enabled {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CAFile != "" {
+		ca, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("no valid certificate found in ca file")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" && cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
 // 关闭 Redis 连接
 func (r *Redis) Close() error {
 	if r.redisDB == nil {
@@ -112,3 +242,11 @@ func (r *Redis) Close() error {
 func (r *Redis) IsInitialize() bool {
 	return r.isInit
 }
+
+// 获取连接池统计信息
+func (r *Redis) PoolStats() *redis.PoolStats {
+	if r.redisDB == nil {
+		return &redis.PoolStats{}
+	}
+	return r.redisDB.PoolStats()
+}
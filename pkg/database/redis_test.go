@@ -0,0 +1,74 @@
+package database
+
+import (
+	"testing"
+
+	"project/pkg/config"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedis_NewClientByMode(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *config.Redis
+		want interface{}
+	}{
+		{
+			name: "standalone",
+			cfg:  &config.Redis{Addr: "127.0.0.1:6379", Network: "tcp"},
+			want: &redis.Client{},
+		},
+		{
+			name: "sentinel",
+			cfg:  &config.Redis{Mode: "sentinel", MasterName: "mymaster", Addrs: []string{"127.0.0.1:26379"}},
+			want: &redis.Client{},
+		},
+		{
+			name: "cluster",
+			cfg:  &config.Redis{Mode: "Cluster", Addrs: []string{"127.0.0.1:7000", "127.0.0.1:7001"}, PoolSize: 20},
+			want: &redis.ClusterClient{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRedis(tt.cfg)
+			client, err := r.newClient()
+			require.NoError(t, err)
+			defer client.Close()
+
+			assert.IsType(t, tt.want, client)
+		})
+	}
+}
+
+func TestRedis_StandaloneOptions(t *testing.T) {
+	r := NewRedis(&config.Redis{Addr: "10.0.0.1:6380", Network: "tcp", DB: 3, PoolSize: 7, MaxRetries: -1})
+	client, err := r.newClient()
+	require.NoError(t, err)
+	defer client.Close()
+
+	opts := client.(*redis.Client).Options()
+	assert.Equal(t, "10.0.0.1:6380", opts.Addr)
+	assert.Equal(t, 3, opts.DB)
+	assert.Equal(t, 7, opts.PoolSize)
+	// 驱动将 -1 归一为 0，即不重试
+	assert.Equal(t, 0, opts.MaxRetries)
+	assert.Equal(t, config.RedisModeStandalone, r.GetMode())
+}
+
+func TestBuildTLSConfig(t *testing.T) {
+	cfg, err := buildTLSConfig(nil)
+	require.NoError(t, err)
+	assert.Nil(t, cfg)
+
+	cfg, err = buildTLSConfig(&config.TLS{Enabled: true, ServerName: "redis.local"})
+	require.NoError(t, err)
+	assert.Equal(t, "redis.local", cfg.ServerName)
+
+	_, err = buildTLSConfig(&config.TLS{Enabled: true, CAFile: "/nonexistent/ca.pem"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,183 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"project/pkg/config"
+	local_logger "project/pkg/logger"
+)
+
+// sqliteMemory 内存库路径
+const sqliteMemory = ":memory:"
+
+var sqliteEntity *Sqlite
+
+type Sqlite struct {
+	//组件名称
+	name string
+	//数据库文件路径
+	path string
+	//初始化标识
+	isInit bool
+	//最大闲置数
+	maxIdleConnection int
+	//最大连接数
+	maxOpenConnection int
+	//db
+	db *gorm.DB
+}
+
+// NewSqlite 根据配置创建 SQLite 组件
+//
+// 不经过全局单例，单元测试可以用 &config.Sqlite{Path: ":memory:"} 创建互相隔离的内存库
+func NewSqlite(cfg *config.Sqlite) *Sqlite {
+	return &Sqlite{
+		name:              "sqlite",
+		path:              cfg.Path,
+		maxIdleConnection: cfg.MaxIdleConnection,
+		maxOpenConnection: cfg.MaxOpenConnection,
+	}
+}
+
+func GetSqlite() *Sqlite {
+	if sqliteEntity != nil {
+		return sqliteEntity
+	}
+
+	if config.Get() == nil || config.Get().Db.Sqlite == nil {
+		local_logger.Sugar.Errorf("\t[component] sqlite config load failed")
+		return nil
+	}
+
+	sqliteEntity = NewSqlite(config.Get().Db.Sqlite)
+	return sqliteEntity
+}
+
+func (s *Sqlite) GetName() string {
+	return s.name
+}
+
+func (s *Sqlite) InitComponent() bool {
+	if s.isInit {
+		local_logger.Sugar.Infof("\t[component] %s is inited", s.name)
+		return true
+	}
+
+	gormConfig := &gorm.Config{
+		Logger: NewGormLogger(),
+		NamingStrategy: schema.NamingStrategy{
+			SingularTable: true,
+		},
+	}
+
+	var err error
+	s.db, err = gorm.Open(sqlite.Open(s.dsn()), gormConfig)
+	if err != nil {
+		local_logger.Sugar.Errorf("\t[component] %s init failed: %v", s.name, err)
+		return false
+	}
+
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		local_logger.Sugar.Errorf("\t[component] %s get raw db failed: %v", s.name, err)
+		return false
+	}
+
+	if s.IsMemory() {
+		// 内存库随连接销毁而消失，只保留一条永不过期的连接
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+		sqlDB.SetConnMaxIdleTime(0)
+	} else {
+		sqlDB.SetMaxOpenConns(s.maxOpenConnection)
+		sqlDB.SetMaxIdleConns(s.maxIdleConnection)
+	}
+
+	if err = sqlDB.Ping(); err != nil {
+		local_logger.Sugar.Errorf("\t[component] %s connect failed: %v", s.name, err)
+		return false
+	}
+
+	s.isInit = true
+	local_logger.Sugar.Infof("\t[component] %s init success", s.name)
+	return true
+}
+
+// dsn 拼接驱动参数：开启外键约束；文件库额外开启 WAL 与忙等待
+func (s *Sqlite) dsn() string {
+	if strings.Contains(s.path, "?") {
+		return s.path
+	}
+	if s.IsMemory() {
+		return s.path + "?_pragma=foreign_keys(1)"
+	}
+	return s.path + "?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"
+}
+
+// 关闭 SQLite 连接
+func (s *Sqlite) Close() error {
+	if s.db == nil {
+		return nil
+	}
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		local_logger.Sugar.Errorf("\t[component] %s get raw db failed on close: %v", s.name, err)
+		return err
+	}
+	err = sqlDB.Close()
+	if err != nil {
+		local_logger.Sugar.Errorf("\t[component] %s close failed: %v", s.name, err)
+		return err
+	}
+	s.isInit = false
+	return nil
+}
+
+func (s *Sqlite) IsInitialize() bool {
+	return s.isInit
+}
+
+// IsMemory 是否为内存库
+func (s *Sqlite) IsMemory() bool {
+	return strings.HasPrefix(s.path, sqliteMemory) || strings.Contains(s.path, "mode=memory")
+}
+
+func (s *Sqlite) GetPath() string {
+	return s.path
+}
+
+// 获取 gorm db
+func (s *Sqlite) GetDb() *gorm.DB {
+	return s.db
+}
+
+// 获取底层 sql.DB，供原生 SQL 使用
+func (s *Sqlite) GetSqlDb() *sql.DB {
+	if s.db == nil {
+		return nil
+	}
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return nil
+	}
+	return sqlDB
+}
+
+// Dialect 返回 SQL 方言
+func (s *Sqlite) Dialect() Dialect {
+	return DialectSQLite
+}
+
+// 获取连接池统计信息
+func (s *Sqlite) Stats() sql.DBStats {
+	if sqlDB := s.GetSqlDb(); sqlDB != nil {
+		return sqlDB.Stats()
+	}
+	return sql.DBStats{}
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"project/pkg/config"
+	"project/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = zap.NewNop()
+	logger.Sugar = logger.Logger.Sugar()
+	os.Exit(m.Run())
+}
+
+// newMemorySqlite 创建一个独立的内存库
+func newMemorySqlite(t *testing.T) *Sqlite {
+	t.Helper()
+
+	s := NewSqlite(&config.Sqlite{Path: ":memory:"})
+	require.True(t, s.InitComponent())
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSqlite_Lifecycle(t *testing.T) {
+	s := NewSqlite(&config.Sqlite{Path: ":memory:"})
+	assert.False(t, s.IsInitialize())
+	assert.True(t, s.IsMemory())
+
+	require.True(t, s.InitComponent())
+	assert.True(t, s.IsInitialize())
+	assert.NotNil(t, s.GetDb())
+	assert.NotNil(t, s.GetSqlDb())
+	assert.Equal(t, "sqlite", s.Dialect().Name())
+	assert.Equal(t, 1, s.Stats().MaxOpenConnections)
+
+	// 重复初始化直接返回
+	assert.True(t, s.InitComponent())
+
+	require.NoError(t, s.Close())
+	assert.False(t, s.IsInitialize())
+}
+
+func TestSqlite_Gorm(t *testing.T) {
+	type Item struct {
+		ID   int64 `gorm:"primaryKey"`
+		Name string
+	}
+
+	s := newMemorySqlite(t)
+	db := s.GetDb()
+
+	require.NoError(t, db.AutoMigrate(&Item{}))
+	require.NoError(t, db.Create(&Item{Name: "a"}).Error)
+
+	var got Item
+	require.NoError(t, db.First(&got).Error)
+	assert.Equal(t, "a", got.Name)
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	s := newMemorySqlite(t)
+	db := s.GetSqlDb()
+	d := s.Dialect()
+
+	migrations := []Migration{
+		{
+			Version: 2,
+			Name:    "add items.note",
+			Up: func(d Dialect) []string {
+				return []string{"ALTER TABLE " + d.Quote("items") + " ADD COLUMN " + d.Quote("note") + " " + d.TextType()}
+			},
+		},
+		{
+			Version: 1,
+			Name:    "create items",
+			Up: func(d Dialect) []string {
+				return []string{"CREATE TABLE " + d.Quote("items") + " (" + d.Quote("id") + " " + d.AutoIncrementPK() + ", " + d.Quote("name") + " VARCHAR(64) NOT NULL)"}
+			},
+		},
+	}
+
+	require.NoError(t, Migrate(ctx, db, d, migrations))
+	// 再次执行不会重复迁移
+	require.NoError(t, Migrate(ctx, db, d, migrations))
+
+	applied, err := AppliedMigrations(ctx, db, d)
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]bool{1: true, 2: true}, applied)
+
+	query, args := Insert(d, "items", "name", "note").Values("a", "x").Values("b", "y").Build()
+	_, err = db.ExecContext(ctx, query, args...)
+	require.NoError(t, err)
+
+	query, args = Select(d, "items", "name").Where("note = ?", "y").Build()
+	var name string
+	require.NoError(t, db.QueryRowContext(ctx, query, args...).Scan(&name))
+	assert.Equal(t, "b", name)
+}
+
+func TestMigrate_DuplicateVersion(t *testing.T) {
+	s := newMemorySqlite(t)
+	applied := false
+	up := func(d Dialect) []string {
+		applied = true
+		return nil
+	}
+	err := Migrate(context.Background(), s.GetSqlDb(), s.Dialect(), []Migration{
+		{Version: 1, Name: "a", Up: up},
+		{Version: 2, Name: "b", Up: up},
+		{Version: 2, Name: "c", Up: up},
+	})
+	assert.ErrorContains(t, err, "duplicate migration version 2")
+	// 校验在执行任何迁移之前完成
+	assert.False(t, applied)
+}
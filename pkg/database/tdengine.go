@@ -1,7 +1,11 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"slices"
+	"time"
+
 	"project/pkg/config"
 	"project/pkg/logger"
 )
@@ -15,8 +19,10 @@ type Tdengine struct {
 	url string
 	//初始化标识
 	isInit bool
-	//conn
+	//conn（仅在导入 taosRestful 驱动时可用）
 	taos *sql.DB
+	//REST 执行器
+	rest *TdengineREST
 }
 
 func newTdengine() *Tdengine {
@@ -60,15 +66,31 @@ func (t *Tdengine) InitComponent() bool {
 	logger.Sugar.Infof("\t[component] %s is initiating...", t.name)
 
 	var err error
-	t.taos, err = sql.Open("taosRestful", t.url)
+	t.rest, err = NewTdengineREST(t.url)
 	if err != nil {
 		logger.Sugar.Errorf("\t[component] %s init failed: %s", t.name, err)
 		return false
 	}
-	// 激活连接
-	if err = t.taos.Ping(); err != nil {
-		logger.Sugar.Fatalf("\t[component] %s connect failed: %s", t.name, err)
-		return false
+
+	// 已导入 taosRestful 驱动时同时提供 *sql.DB，否则只使用 REST 执行器
+	if slices.Contains(sql.Drivers(), "taosRestful") {
+		t.taos, err = sql.Open("taosRestful", t.url)
+		if err != nil {
+			logger.Sugar.Errorf("\t[component] %s init failed: %s", t.name, err)
+			return false
+		}
+		// 激活连接
+		if err = t.taos.Ping(); err != nil {
+			logger.Sugar.Fatalf("\t[component] %s connect failed: %s", t.name, err)
+			return false
+		}
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if _, err = t.rest.Query(ctx, "SELECT SERVER_VERSION()"); err != nil {
+			logger.Sugar.Errorf("\t[component] %s connect failed: %s", t.name, err)
+			return false
+		}
 	}
 	//将初始化设置为true
 	t.isInit = true
@@ -101,7 +123,28 @@ func (t *Tdengine) GetUrl() string {
 	return t.url
 }
 
-// 获取db
+// 获取db，未导入 taosRestful 驱动时为 nil，请使用 GetExecutor
 func (t *Tdengine) GetDb() *sql.DB {
 	return t.taos
 }
+
+// GetExecutor 获取 SQL 执行器，优先使用 *sql.DB
+func (t *Tdengine) GetExecutor() TdengineExecutor {
+	if t.taos != nil {
+		return NewTdengineSQLExecutor(t.taos)
+	}
+	return t.rest
+}
+
+// NewWriter 创建批量写入器，需通过 app.RegisterWorker 注册以随应用启停
+func (t *Tdengine) NewWriter(opts TdengineWriterOptions) *TdengineWriter {
+	return NewTdengineWriter(t.GetExecutor(), opts)
+}
+
+// 获取连接池统计信息
+func (t *Tdengine) Stats() sql.DBStats {
+	if t.taos == nil {
+		return sql.DBStats{}
+	}
+	return t.taos.Stats()
+}
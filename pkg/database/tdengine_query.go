@@ -0,0 +1,374 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tdengineTimeLayout 查询条件中的时间字面量格式
+const tdengineTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// tdengineIdent 允许的表名、列名
+var tdengineIdent = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+func checkTdengineIdent(name string) error {
+	if !tdengineIdent.MatchString(name) {
+		return fmt.Errorf("tdengine: invalid identifier %q", name)
+	}
+	return nil
+}
+
+// tdengineLiteral 把 Go 值格式化为 TDengine SQL 字面量
+func tdengineLiteral(v interface{}) (string, error) {
+	switch x := v.(type) {
+	case nil:
+		return "NULL", nil
+	case string:
+		return quoteTdengine(x), nil
+	case []byte:
+		return quoteTdengine(string(x)), nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case int:
+		return strconv.FormatInt(int64(x), 10), nil
+	case int8:
+		return strconv.FormatInt(int64(x), 10), nil
+	case int16:
+		return strconv.FormatInt(int64(x), 10), nil
+	case int32:
+		return strconv.FormatInt(int64(x), 10), nil
+	case int64:
+		return strconv.FormatInt(x, 10), nil
+	case uint:
+		return strconv.FormatUint(uint64(x), 10), nil
+	case uint8:
+		return strconv.FormatUint(uint64(x), 10), nil
+	case uint16:
+		return strconv.FormatUint(uint64(x), 10), nil
+	case uint32:
+		return strconv.FormatUint(uint64(x), 10), nil
+	case uint64:
+		return strconv.FormatUint(x, 10), nil
+	case float32:
+		return strconv.FormatFloat(float64(x), 'g', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64), nil
+	case json.Number:
+		return x.String(), nil
+	case time.Time:
+		return "'" + x.Format(tdengineTimeLayout) + "'", nil
+	}
+	return "", fmt.Errorf("tdengine: unsupported value type %T", v)
+}
+
+func quoteTdengine(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+	return "'" + s + "'"
+}
+
+// BindTdengine 把 ? 占位符替换为参数字面量（跳过引号内的内容）
+//
+// TDengine REST 接口不支持参数绑定，拼接用户输入时必须使用该函数而不是 fmt.Sprintf。
+func BindTdengine(query string, args ...interface{}) (string, error) {
+	var sb strings.Builder
+	var quote byte
+	n := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && i+1 < len(query) {
+				sb.WriteByte(c)
+				i++
+				c = query[i]
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?':
+			if n >= len(args) {
+				return "", errors.New("tdengine: not enough arguments for placeholders")
+			}
+			lit, err := tdengineLiteral(args[n])
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(lit)
+			n++
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	if n != len(args) {
+		return "", fmt.Errorf("tdengine: %d placeholders but %d arguments", n, len(args))
+	}
+	return sb.String(), nil
+}
+
+// TdengineWindow 时间窗口聚合查询
+//
+//	q := database.TdengineWindow{
+//		Table:       "meters",
+//		Select:      []string{"AVG(current) AS avg_current", "MAX(voltage) AS max_voltage"},
+//		Where:       []string{"location = ?"},
+//		Args:        []interface{}{"California"},
+//		Start:       time.Now().Add(-time.Hour),
+//		End:         time.Now(),
+//		Interval:    time.Minute,
+//		Fill:        "PREV",
+//		PartitionBy: []string{"tbname"},
+//	}
+//	rows, err := database.QueryTdengineWindow[MeterStat](ctx, executor, q)
+type TdengineWindow struct {
+	// Table 超级表或子表
+	Table string
+	// Select 聚合表达式；窗口起止时间通过 _wstart / _wend 获取，默认会加入 _wstart
+	Select []string
+	// TimeColumn 时间戳列，默认 ts
+	TimeColumn string
+	// Start / End 查询范围 [Start, End)
+	Start time.Time
+	End   time.Time
+	// Where 额外条件，使用 ? 占位
+	Where []string
+	Args  []interface{}
+	// PartitionBy 分组列，例如 tbname
+	PartitionBy []string
+	// Interval 窗口长度，Sliding 滑动步长（为 0 时与 Interval 相同）
+	Interval time.Duration
+	Sliding  time.Duration
+	// Fill 缺失窗口的填充方式：NONE / NULL / PREV / NEXT / LINEAR / VALUE,x
+	Fill string
+	// OrderBy 排序表达式，默认按 _wstart
+	OrderBy string
+	// Limit 最大返回行数
+	Limit int
+}
+
+var tdengineFill = regexp.MustCompile(`^(?i)(NONE|NULL|NULL_F|PREV|NEXT|LINEAR|(VALUE|VALUE_F)\s*,\s*[-+0-9.eE\s,]+)$`)
+
+// Build 生成 SQL
+func (q TdengineWindow) Build() (string, error) {
+	if err := checkTdengineIdent(q.Table); err != nil {
+		return "", err
+	}
+	if len(q.Select) == 0 {
+		return "", errors.New("tdengine: window query requires select expressions")
+	}
+	if q.Interval <= 0 {
+		return "", errors.New("tdengine: window query requires interval")
+	}
+	if q.Start.IsZero() || q.End.IsZero() || !q.End.After(q.Start) {
+		return "", errors.New("tdengine: window query requires start < end")
+	}
+
+	tsCol := q.TimeColumn
+	if tsCol == "" {
+		tsCol = "ts"
+	}
+	if err := checkTdengineIdent(tsCol); err != nil {
+		return "", err
+	}
+
+	columns := append([]string(nil), q.Select...)
+	if !containsFold(columns, "_wstart") {
+		columns = append([]string{"_wstart"}, columns...)
+	}
+	for _, col := range q.PartitionBy {
+		if err := checkTdengineIdent(col); err != nil {
+			return "", err
+		}
+		if !containsFold(columns, col) {
+			columns = append(columns, col)
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "SELECT %s FROM %s WHERE %s >= ? AND %s < ?", strings.Join(columns, ", "), q.Table, tsCol, tsCol)
+	for _, cond := range q.Where {
+		sb.WriteString(" AND (" + cond + ")")
+	}
+	if len(q.PartitionBy) > 0 {
+		sb.WriteString(" PARTITION BY " + strings.Join(q.PartitionBy, ", "))
+	}
+	sb.WriteString(" INTERVAL(" + tdengineDuration(q.Interval) + ")")
+	if q.Sliding > 0 {
+		sb.WriteString(" SLIDING(" + tdengineDuration(q.Sliding) + ")")
+	}
+	if q.Fill != "" {
+		if !tdengineFill.MatchString(q.Fill) {
+			return "", fmt.Errorf("tdengine: invalid fill %q", q.Fill)
+		}
+		sb.WriteString(" FILL(" + strings.ToUpper(q.Fill) + ")")
+	}
+	if q.OrderBy != "" {
+		sb.WriteString(" ORDER BY " + q.OrderBy)
+	} else if len(q.PartitionBy) == 0 {
+		sb.WriteString(" ORDER BY _wstart")
+	}
+	if q.Limit > 0 {
+		sb.WriteString(" LIMIT " + strconv.Itoa(q.Limit))
+	}
+
+	args := append([]interface{}{q.Start, q.End}, q.Args...)
+	return BindTdengine(sb.String(), args...)
+}
+
+// QueryTdengineWindow 执行窗口聚合查询并映射为 T
+func QueryTdengineWindow[T any](ctx context.Context, exec TdengineExecutor, q TdengineWindow) ([]T, error) {
+	query, err := q.Build()
+	if err != nil {
+		return nil, err
+	}
+	res, err := exec.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return ScanTdengineRows[T](res)
+}
+
+// ScanTdengineRows 按 db 标签（缺省为小写字段名）把结果映射为结构体切片
+func ScanTdengineRows[T any](res *TdengineResult) ([]T, error) {
+	var zero T
+	typ := reflect.TypeOf(zero)
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("tdengine: scan target must be a struct, got %s", typ)
+	}
+
+	// 列序号 -> 字段序号
+	fields := make([]int, len(res.Columns))
+	for i, col := range res.Columns {
+		fields[i] = -1
+		for j := 0; j < typ.NumField(); j++ {
+			f := typ.Field(j)
+			if !f.IsExported() {
+				continue
+			}
+			name := f.Tag.Get("db")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(f.Name)
+			}
+			if strings.EqualFold(name, col.Name) {
+				fields[i] = j
+				break
+			}
+		}
+	}
+
+	out := make([]T, len(res.Rows))
+	for r, row := range res.Rows {
+		v := reflect.ValueOf(&out[r]).Elem()
+		for i, val := range row {
+			if i >= len(fields) || fields[i] < 0 || val == nil {
+				continue
+			}
+			if err := assignTdengine(v.Field(fields[i]), val); err != nil {
+				return nil, fmt.Errorf("tdengine: column %s: %w", res.Columns[i].Name, err)
+			}
+		}
+	}
+	return out, nil
+}
+
+// assignTdengine 把结果值赋给字段，支持指针字段（用于接收 NULL）
+func assignTdengine(field reflect.Value, val interface{}) error {
+	if field.Kind() == reflect.Ptr {
+		ptr := reflect.New(field.Type().Elem())
+		if err := assignTdengine(ptr.Elem(), val); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+
+	if b, ok := val.([]byte); ok {
+		val = string(b)
+	}
+
+	if field.Type() == reflect.TypeOf(time.Time{}) {
+		switch x := val.(type) {
+		case time.Time:
+			field.Set(reflect.ValueOf(x))
+		case string:
+			ts, err := time.Parse(time.RFC3339Nano, x)
+			if err != nil {
+				return err
+			}
+			field.Set(reflect.ValueOf(ts))
+		default:
+			return fmt.Errorf("cannot assign %T to time.Time", val)
+		}
+		return nil
+	}
+
+	s := fmt.Sprint(val)
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+// tdengineDuration 把时长转换为 TDengine 时间单位写法
+func tdengineDuration(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	case d%time.Second == 0:
+		return fmt.Sprintf("%ds", d/time.Second)
+	case d%time.Millisecond == 0:
+		return fmt.Sprintf("%da", d/time.Millisecond)
+	}
+	return fmt.Sprintf("%du", d/time.Microsecond)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(strings.TrimSpace(item), s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,247 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// TdengineExecutor 执行 TDengine SQL
+//
+// TDengine 的 REST 接口不支持参数绑定，SQL 需预先拼接好字面量（参见 BindTdengine）。
+type TdengineExecutor interface {
+	// Exec 执行写入或 DDL，返回影响行数
+	Exec(ctx context.Context, query string) (int64, error)
+	// Query 执行查询
+	Query(ctx context.Context, query string) (*TdengineResult, error)
+}
+
+// TdengineColumn 结果列
+type TdengineColumn struct {
+	Name   string
+	Type   string
+	Length int
+}
+
+// TdengineResult 查询结果；数值为 json.Number，时间戳为 time.Time
+type TdengineResult struct {
+	Columns []TdengineColumn
+	Rows    [][]interface{}
+}
+
+// TdengineError TDengine 返回的错误
+type TdengineError struct {
+	Code int
+	Desc string
+}
+
+func (e *TdengineError) Error() string {
+	return fmt.Sprintf("tdengine: [0x%x] %s", e.Code, e.Desc)
+}
+
+// tdengineDSN 解析 taosRestful 驱动格式的连接串：user:password@http(host:port)/db?params
+var tdengineDSN = regexp.MustCompile(`^(?:([^:@]*)(?::([^@]*))?@)?(https?)\(([^)]+)\)/([^?]*)(?:\?.*)?$`)
+
+// TdengineREST 基于 TDengine REST 接口（taosAdapter）的执行器，无需 cgo 与驱动
+type TdengineREST struct {
+	endpoint string
+	username string
+	password string
+	client   *http.Client
+}
+
+// NewTdengineREST 根据 taosRestful 格式的连接串创建 REST 执行器，例如 root:taosdata@http(127.0.0.1:6041)/test
+func NewTdengineREST(dsn string) (*TdengineREST, error) {
+	m := tdengineDSN.FindStringSubmatch(dsn)
+	if m == nil {
+		return nil, fmt.Errorf("tdengine: invalid dsn, expected user:password@http(host:port)/db")
+	}
+
+	endpoint := fmt.Sprintf("%s://%s/rest/sql", m[3], m[4])
+	if m[5] != "" {
+		endpoint += "/" + m[5]
+	}
+
+	return &TdengineREST{
+		endpoint: endpoint,
+		username: m[1],
+		password: m[2],
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Endpoint 返回 REST 接口地址
+func (r *TdengineREST) Endpoint() string {
+	return r.endpoint
+}
+
+// Exec 执行写入或 DDL，返回影响行数
+func (r *TdengineREST) Exec(ctx context.Context, query string) (int64, error) {
+	res, err := r.do(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return res.affectedRows(), nil
+}
+
+// Query 执行查询
+func (r *TdengineREST) Query(ctx context.Context, query string) (*TdengineResult, error) {
+	res, err := r.do(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return res.result()
+}
+
+// restResponse REST 接口返回结构（TDengine 3.x）
+type restResponse struct {
+	Code       int             `json:"code"`
+	Desc       string          `json:"desc"`
+	ColumnMeta [][]interface{} `json:"column_meta"`
+	Data       [][]interface{} `json:"data"`
+	Rows       int64           `json:"rows"`
+}
+
+func (r *TdengineREST) do(ctx context.Context, query string) (*restResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, strings.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(r.username, r.password)
+	req.Header.Set("Content-Type", "text/plain")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var res restResponse
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&res); err != nil {
+		return nil, fmt.Errorf("tdengine: http %d: %s", resp.StatusCode, truncate(string(body), 200))
+	}
+	if res.Code != 0 {
+		return nil, &TdengineError{Code: res.Code, Desc: res.Desc}
+	}
+	return &res, nil
+}
+
+func (r *restResponse) affectedRows() int64 {
+	// 写入返回单列 affected_rows
+	if len(r.ColumnMeta) == 1 && len(r.ColumnMeta[0]) > 0 && r.ColumnMeta[0][0] == "affected_rows" &&
+		len(r.Data) == 1 && len(r.Data[0]) == 1 {
+		if n, ok := r.Data[0][0].(json.Number); ok {
+			if v, err := n.Int64(); err == nil {
+				return v
+			}
+		}
+	}
+	return r.Rows
+}
+
+func (r *restResponse) result() (*TdengineResult, error) {
+	res := &TdengineResult{Columns: make([]TdengineColumn, len(r.ColumnMeta))}
+	for i, meta := range r.ColumnMeta {
+		if len(meta) < 2 {
+			return nil, fmt.Errorf("tdengine: invalid column meta %v", meta)
+		}
+		col := TdengineColumn{}
+		col.Name, _ = meta[0].(string)
+		col.Type, _ = meta[1].(string)
+		if len(meta) > 2 {
+			if n, ok := meta[2].(json.Number); ok {
+				length, _ := n.Int64()
+				col.Length = int(length)
+			}
+		}
+		res.Columns[i] = col
+	}
+
+	res.Rows = make([][]interface{}, len(r.Data))
+	for i, row := range r.Data {
+		for j, v := range row {
+			if j >= len(res.Columns) || res.Columns[j].Type != "TIMESTAMP" {
+				continue
+			}
+			if s, ok := v.(string); ok {
+				ts, err := time.Parse(time.RFC3339Nano, s)
+				if err != nil {
+					return nil, fmt.Errorf("tdengine: parse timestamp %q: %w", s, err)
+				}
+				row[j] = ts
+			}
+		}
+		res.Rows[i] = row
+	}
+	return res, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// sqlTdengineExecutor 基于 database/sql 的执行器，用于已导入 taosRestful / taosSql 驱动的场景
+type sqlTdengineExecutor struct {
+	db *sql.DB
+}
+
+// NewTdengineSQLExecutor 使用 *sql.DB 创建执行器
+func NewTdengineSQLExecutor(db *sql.DB) TdengineExecutor {
+	return &sqlTdengineExecutor{db: db}
+}
+
+func (e *sqlTdengineExecutor) Exec(ctx context.Context, query string) (int64, error) {
+	res, err := e.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (e *sqlTdengineExecutor) Query(ctx context.Context, query string) (*TdengineResult, error) {
+	rows, err := e.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	res := &TdengineResult{Columns: make([]TdengineColumn, len(types))}
+	for i, t := range types {
+		length, _ := t.Length()
+		res.Columns[i] = TdengineColumn{Name: t.Name(), Type: t.DatabaseTypeName(), Length: int(length)}
+	}
+
+	for rows.Next() {
+		row := make([]interface{}, len(types))
+		ptrs := make([]interface{}, len(types))
+		for i := range row {
+			ptrs[i] = &row[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		res.Rows = append(res.Rows, row)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,335 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTdengine 模拟 taosAdapter 的 REST 接口
+type fakeTdengine struct {
+	mu      sync.Mutex
+	queries []string
+	paths   []string
+	// respond 根据 SQL 返回响应体，为空时按写入处理
+	respond func(query string) string
+	// block 不为 nil 时写入请求会等待其关闭
+	block chan struct{}
+}
+
+func newFakeTdengine(t *testing.T) (*fakeTdengine, *TdengineREST) {
+	f := &fakeTdengine{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "root" || pass != "taosdata" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"code":3,"desc":"Authentication failure"}`)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		query := string(body)
+
+		f.mu.Lock()
+		f.queries = append(f.queries, query)
+		f.paths = append(f.paths, r.URL.Path)
+		respond, block := f.respond, f.block
+		f.mu.Unlock()
+
+		if respond != nil {
+			fmt.Fprint(w, respond(query))
+			return
+		}
+		if block != nil && strings.HasPrefix(query, "INSERT") {
+			<-block
+		}
+		fmt.Fprint(w, `{"code":0,"column_meta":[["affected_rows","INT",4]],"data":[[1]],"rows":1}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	dsn := fmt.Sprintf("root:taosdata@http(%s)/power", strings.TrimPrefix(srv.URL, "http://"))
+	rest, err := NewTdengineREST(dsn)
+	require.NoError(t, err)
+	return f, rest
+}
+
+func (f *fakeTdengine) Queries() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.queries...)
+}
+
+func TestNewTdengineREST(t *testing.T) {
+	rest, err := NewTdengineREST("root:aaaa@http(127.0.0.1:6041)/test")
+	require.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:6041/rest/sql/test", rest.Endpoint())
+	assert.Equal(t, "root", rest.username)
+	assert.Equal(t, "aaaa", rest.password)
+
+	rest, err = NewTdengineREST("root:aaaa@https(td.example.com:443)/")
+	require.NoError(t, err)
+	assert.Equal(t, "https://td.example.com:443/rest/sql", rest.Endpoint())
+
+	_, err = NewTdengineREST("http://127.0.0.1:6041")
+	assert.Error(t, err)
+}
+
+func TestTdengineREST_QueryAndError(t *testing.T) {
+	f, rest := newFakeTdengine(t)
+	f.respond = func(query string) string {
+		if strings.Contains(query, "nope") {
+			return `{"code":9730,"desc":"Table does not exist"}`
+		}
+		return `{"code":0,"column_meta":[["ts","TIMESTAMP",8],["current","FLOAT",4],["location","VARCHAR",64]],` +
+			`"data":[["2024-01-02T03:04:05.000Z",10.5,"California"],["2024-01-02T03:04:06.000Z",null,"Nevada"]],"rows":2}`
+	}
+
+	res, err := rest.Query(context.Background(), "SELECT * FROM meters")
+	require.NoError(t, err)
+	require.Len(t, res.Rows, 2)
+	assert.Equal(t, "TIMESTAMP", res.Columns[0].Type)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), res.Rows[0][0].(time.Time).UTC())
+	assert.Equal(t, []string{"/rest/sql/power"}, f.paths)
+
+	type meter struct {
+		TS       time.Time `db:"ts"`
+		Current  *float64
+		Location string
+	}
+	rows, err := ScanTdengineRows[meter](res)
+	require.NoError(t, err)
+	require.NotNil(t, rows[0].Current)
+	assert.Equal(t, 10.5, *rows[0].Current)
+	assert.Nil(t, rows[1].Current)
+	assert.Equal(t, "Nevada", rows[1].Location)
+
+	_, err = rest.Exec(context.Background(), "SELECT * FROM nope")
+	var tdErr *TdengineError
+	require.True(t, errors.As(err, &tdErr))
+	assert.Equal(t, 9730, tdErr.Code)
+}
+
+func TestBindTdengine(t *testing.T) {
+	q, err := BindTdengine("SELECT * FROM meters WHERE location = ? AND note = '?' AND groupid = ?", "it's", 2)
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT * FROM meters WHERE location = 'it\'s' AND note = '?' AND groupid = 2`, q)
+
+	_, err = BindTdengine("SELECT ?", 1, 2)
+	assert.Error(t, err)
+}
+
+func TestTdengineWindow_Build(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	q := TdengineWindow{
+		Table:       "meters",
+		Select:      []string{"AVG(current) AS avg_current"},
+		Where:       []string{"location = ?"},
+		Args:        []interface{}{"California"},
+		Start:       start,
+		End:         start.Add(time.Hour),
+		Interval:    10 * time.Minute,
+		Sliding:     5 * time.Minute,
+		Fill:        "prev",
+		PartitionBy: []string{"tbname"},
+		Limit:       100,
+	}
+
+	query, err := q.Build()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT _wstart, AVG(current) AS avg_current, tbname FROM meters "+
+		"WHERE ts >= '2024-01-01T00:00:00.000Z' AND ts < '2024-01-01T01:00:00.000Z' AND (location = 'California') "+
+		"PARTITION BY tbname INTERVAL(10m) SLIDING(5m) FILL(PREV) LIMIT 100", query)
+
+	q.Fill = "VALUE, 0"
+	q.PartitionBy = nil
+	q.Sliding = 0
+	q.Limit = 0
+	query, err = q.Build()
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(query, "INTERVAL(10m) FILL(VALUE, 0) ORDER BY _wstart"), query)
+
+	q.Fill = "VALUE, 0); DROP DATABASE power"
+	_, err = q.Build()
+	assert.Error(t, err)
+
+	q.Fill = ""
+	q.Table = "meters; DROP DATABASE power"
+	_, err = q.Build()
+	assert.Error(t, err)
+}
+
+func TestQueryTdengineWindow(t *testing.T) {
+	f, rest := newFakeTdengine(t)
+	f.respond = func(query string) string {
+		return `{"code":0,"column_meta":[["_wstart","TIMESTAMP",8],["avg_current","DOUBLE",8]],` +
+			`"data":[["2024-01-01T00:00:00.000Z",10.25],["2024-01-01T00:10:00.000Z",11]],"rows":2}`
+	}
+
+	type stat struct {
+		Window     time.Time `db:"_wstart"`
+		AvgCurrent float64   `db:"avg_current"`
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows, err := QueryTdengineWindow[stat](context.Background(), rest, TdengineWindow{
+		Table:    "meters",
+		Select:   []string{"AVG(current) AS avg_current"},
+		Start:    start,
+		End:      start.Add(20 * time.Minute),
+		Interval: 10 * time.Minute,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, start.Add(10*time.Minute), rows[1].Window.UTC())
+	assert.Equal(t, 11.0, rows[1].AvgCurrent)
+}
+
+func meterPoint(table string, group int, ts time.Time, current float64) TdenginePoint {
+	return TdenginePoint{
+		SuperTable: "meters",
+		Table:      table,
+		Tags:       []interface{}{"California", group},
+		Columns:    []string{"ts", "current"},
+		Values:     []interface{}{ts, current},
+	}
+}
+
+func TestTdengineWriter_BatchesPerSubTable(t *testing.T) {
+	f, rest := newFakeTdengine(t)
+	w := NewTdengineWriter(rest, TdengineWriterOptions{BatchSize: 100, FlushInterval: time.Hour})
+
+	ts := time.UnixMilli(1700000000000)
+	ctx := context.Background()
+	require.NoError(t, w.Write(ctx,
+		meterPoint("d1001", 1, ts, 10.5),
+		meterPoint("d1002", 2, ts, 11),
+		meterPoint("d1001", 1, ts.Add(time.Second), 12),
+	))
+	assert.Equal(t, 3, w.Pending())
+	assert.Empty(t, f.Queries())
+
+	require.NoError(t, w.Flush(ctx))
+	assert.Equal(t, 0, w.Pending())
+
+	queries := f.Queries()
+	require.Len(t, queries, 1)
+	assert.Equal(t, "INSERT INTO "+
+		"d1001 USING meters TAGS ('California', 1) (ts, current) VALUES (1700000000000, 10.5) (1700000001000, 12) "+
+		"d1002 USING meters TAGS ('California', 2) (ts, current) VALUES (1700000000000, 11)", queries[0])
+}
+
+func TestTdengineWriter_FlushOnSizeAndInterval(t *testing.T) {
+	f, rest := newFakeTdengine(t)
+	w := NewTdengineWriter(rest, TdengineWriterOptions{BatchSize: 2, FlushInterval: 50 * time.Millisecond})
+	require.NoError(t, w.Start())
+
+	ctx := context.Background()
+	ts := time.UnixMilli(1700000000000)
+
+	// 达到 BatchSize 立即写入
+	require.NoError(t, w.Write(ctx, meterPoint("d1", 1, ts, 1), meterPoint("d1", 1, ts.Add(time.Millisecond), 2)))
+	require.Eventually(t, func() bool { return len(f.Queries()) == 1 }, time.Second, 5*time.Millisecond)
+
+	// 未满一批时按间隔写入
+	require.NoError(t, w.Write(ctx, meterPoint("d1", 1, ts.Add(2*time.Millisecond), 3)))
+	require.Eventually(t, func() bool { return len(f.Queries()) == 2 }, time.Second, 5*time.Millisecond)
+
+	// Stop 时写入剩余数据
+	require.NoError(t, w.Write(ctx, meterPoint("d1", 1, ts.Add(3*time.Millisecond), 4)))
+	require.NoError(t, w.Stop(ctx))
+	assert.Equal(t, 0, w.Pending())
+	assert.Contains(t, strings.Join(f.Queries(), "\n"), "(1700000000003, 4)")
+}
+
+func TestTdengineWriter_Backpressure(t *testing.T) {
+	f, rest := newFakeTdengine(t)
+	f.block = make(chan struct{})
+	w := NewTdengineWriter(rest, TdengineWriterOptions{BatchSize: 2, MaxPending: 2, FlushInterval: time.Hour})
+	require.NoError(t, w.Start())
+	defer w.Stop(context.Background())
+
+	ts := time.UnixMilli(1700000000000)
+	require.NoError(t, w.Write(context.Background(), meterPoint("d1", 1, ts, 1), meterPoint("d1", 1, ts, 2)))
+
+	// 缓冲区已满且写入被阻塞，Write 等待直到超时
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := w.Write(ctx, meterPoint("d1", 1, ts, 3))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(f.block)
+	require.NoError(t, w.Write(context.Background(), meterPoint("d1", 1, ts, 3)))
+}
+
+func TestTdengineWriter_SplitsLargeStatements(t *testing.T) {
+	f, rest := newFakeTdengine(t)
+	w := NewTdengineWriter(rest, TdengineWriterOptions{BatchSize: 1000, MaxSQLBytes: 200, FlushInterval: time.Hour})
+
+	ts := time.UnixMilli(1700000000000)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, w.Write(context.Background(), meterPoint("d1", 1, ts.Add(time.Duration(i)*time.Millisecond), float64(i))))
+	}
+	require.NoError(t, w.Flush(context.Background()))
+
+	queries := f.Queries()
+	assert.Greater(t, len(queries), 1)
+	total := 0
+	for _, q := range queries {
+		assert.LessOrEqual(t, len(q), 200)
+		assert.True(t, strings.HasPrefix(q, "INSERT INTO d1 USING meters TAGS ('California', 1) (ts, current) VALUES "), q)
+		total += strings.Count(q, "(17000000000")
+	}
+	assert.Equal(t, 10, total)
+}
+
+func TestTdengineWriter_OnError(t *testing.T) {
+	f, rest := newFakeTdengine(t)
+	f.respond = func(query string) string {
+		return `{"code":9731,"desc":"Invalid column name"}`
+	}
+
+	var failed []TdenginePoint
+	w := NewTdengineWriter(rest, TdengineWriterOptions{
+		FlushInterval: time.Hour,
+		OnError: func(points []TdenginePoint, err error) {
+			failed = append(failed, points...)
+		},
+	})
+
+	ts := time.UnixMilli(1700000000000)
+	require.NoError(t, w.Write(context.Background(), meterPoint("d1", 1, ts, 1)))
+	err := w.Flush(context.Background())
+
+	var tdErr *TdengineError
+	assert.True(t, errors.As(err, &tdErr))
+	assert.Len(t, failed, 1)
+	// SQL 错误不重试
+	assert.Len(t, f.Queries(), 1)
+	assert.Equal(t, 0, w.Pending())
+}
+
+func TestTdengineWriter_RejectsInvalidPoints(t *testing.T) {
+	_, rest := newFakeTdengine(t)
+	w := NewTdengineWriter(rest, TdengineWriterOptions{})
+
+	p := meterPoint("d1; DROP DATABASE power", 1, time.Now(), 1)
+	assert.Error(t, w.Write(context.Background(), p))
+
+	p = meterPoint("d1", 1, time.Now(), 1)
+	p.Tags = nil
+	assert.Error(t, w.Write(context.Background(), p))
+
+	p = meterPoint("d1", 1, time.Now(), 1)
+	p.Values = append(p.Values, struct{}{})
+	assert.Error(t, w.Write(context.Background(), p))
+	assert.Equal(t, 0, w.Pending())
+}
@@ -0,0 +1,410 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"project/pkg/logger"
+)
+
+// TdenginePoint 一条时序数据
+type TdenginePoint struct {
+	// SuperTable 超级表；不为空时子表不存在会按 Tags 自动创建
+	SuperTable string
+	// Table 子表（或普通表）名
+	Table string
+	// Tags 子表标签值，按超级表 TAG 定义顺序
+	Tags []interface{}
+	// Columns 列名，为空时按表结构写入全部列
+	Columns []string
+	// Values 列值，第一个值为时间戳（time.Time 或按精度换算后的整数）
+	Values []interface{}
+}
+
+// TdengineWriterOptions 批量写入配置
+type TdengineWriterOptions struct {
+	// BatchSize 缓冲点数达到该值时立即写入，默认 1000
+	BatchSize int
+	// FlushInterval 定时写入间隔，默认 1s
+	FlushInterval time.Duration
+	// MaxPending 最大缓冲点数，超过后 Write 阻塞直到写入完成（背压），默认 BatchSize*10
+	MaxPending int
+	// MaxSQLBytes 单条 SQL 的最大长度，超过时拆分为多条，默认 512KB（TDengine 默认上限 1MB）
+	MaxSQLBytes int
+	// Precision 数据库时间精度（time.Millisecond / Microsecond / Nanosecond），默认毫秒
+	Precision time.Duration
+	// Timeout 单次写入超时，默认 10s
+	Timeout time.Duration
+	// MaxRetries 写入失败的重试次数，默认 3
+	MaxRetries int
+	// OnError 重试后仍写入失败时回调，可用于落盘或告警；为空时只记录日志
+	OnError func(points []TdenginePoint, err error)
+}
+
+// TdengineWriter 时序数据批量写入器
+//
+// 数据按子表缓冲，达到 BatchSize 或每隔 FlushInterval 合并为多表 INSERT 写入：
+//
+//	INSERT INTO d1001 USING meters TAGS ('California', 2) VALUES (...) (...) d1002 USING meters TAGS (...) VALUES (...)
+//
+// 写入器实现 app.Worker，Stop 时会写入剩余数据。
+type TdengineWriter struct {
+	exec TdengineExecutor
+	opts TdengineWriterOptions
+
+	slots    chan struct{}
+	flushReq chan struct{}
+	flushMu  sync.Mutex
+
+	mu       sync.Mutex
+	batches  map[string]*tdengineBatch
+	order    []string
+	buffered int
+
+	runMu  sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type tdengineBatch struct {
+	head   string
+	points []TdenginePoint
+}
+
+// NewTdengineWriter 创建批量写入器，Start 之后开始定时写入
+func NewTdengineWriter(exec TdengineExecutor, opts TdengineWriterOptions) *TdengineWriter {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+	if opts.MaxPending < opts.BatchSize {
+		opts.MaxPending = opts.BatchSize * 10
+	}
+	if opts.MaxSQLBytes <= 0 {
+		opts.MaxSQLBytes = 512 * 1024
+	}
+	if opts.Precision <= 0 {
+		opts.Precision = time.Millisecond
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	} else if opts.MaxRetries == 0 {
+		opts.MaxRetries = 3
+	}
+
+	return &TdengineWriter{
+		exec:     exec,
+		opts:     opts,
+		slots:    make(chan struct{}, opts.MaxPending),
+		flushReq: make(chan struct{}, 1),
+		batches:  make(map[string]*tdengineBatch),
+	}
+}
+
+// Name 返回名称
+func (w *TdengineWriter) Name() string {
+	return "tdengine-writer"
+}
+
+// Write 缓冲数据；缓冲区已满时阻塞直到有空间或 ctx 结束
+//
+// ctx 结束时返回 ctx.Err()，此前的点已进入缓冲区。
+func (w *TdengineWriter) Write(ctx context.Context, points ...TdenginePoint) error {
+	for i := range points {
+		if err := validateTdenginePoint(&points[i]); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range points {
+		select {
+		case w.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		key, head, err := w.header(p)
+		if err != nil {
+			<-w.slots
+			return err
+		}
+
+		w.mu.Lock()
+		b, ok := w.batches[key]
+		if !ok {
+			b = &tdengineBatch{head: head}
+			w.batches[key] = b
+			w.order = append(w.order, key)
+		}
+		b.points = append(b.points, p)
+		w.buffered++
+		full := w.buffered >= w.opts.BatchSize
+		w.mu.Unlock()
+
+		if full {
+			select {
+			case w.flushReq <- struct{}{}:
+			default:
+			}
+		}
+	}
+	return nil
+}
+
+// Pending 返回尚未写入完成的点数（含正在写入的）
+func (w *TdengineWriter) Pending() int {
+	return len(w.slots)
+}
+
+// Flush 立即写入缓冲区中的全部数据，返回第一个写入错误
+func (w *TdengineWriter) Flush(ctx context.Context) error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	batches := make([]*tdengineBatch, 0, len(w.order))
+	for _, key := range w.order {
+		batches = append(batches, w.batches[key])
+	}
+	w.batches = make(map[string]*tdengineBatch)
+	w.order = nil
+	w.buffered = 0
+	w.mu.Unlock()
+
+	var firstErr error
+	for _, stmt := range w.statements(batches) {
+		err := w.execWithRetry(ctx, stmt.sql)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			if w.opts.OnError != nil {
+				w.opts.OnError(stmt.points, err)
+			} else {
+				logger.FromContext(ctx).Errorf("\t[tdengine] write %d points failed: %v", len(stmt.points), err)
+			}
+		}
+		// 无论成功与否都释放缓冲区空间，失败的数据已交给 OnError
+		for range stmt.points {
+			<-w.slots
+		}
+	}
+	return firstErr
+}
+
+// Start 启动定时写入协程（重复调用无效）
+func (w *TdengineWriter) Start() error {
+	w.runMu.Lock()
+	defer w.runMu.Unlock()
+
+	if w.done != nil {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.done = make(chan struct{})
+	go w.run(ctx, w.done)
+	return nil
+}
+
+// Stop 停止定时写入并写入剩余数据
+func (w *TdengineWriter) Stop(ctx context.Context) error {
+	w.runMu.Lock()
+	cancel, done := w.cancel, w.done
+	w.cancel, w.done = nil, nil
+	w.runMu.Unlock()
+
+	if done != nil {
+		cancel()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return w.Flush(ctx)
+}
+
+func (w *TdengineWriter) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(w.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.flushReq:
+		}
+		// 错误已在 Flush 中处理
+		_ = w.Flush(context.Background())
+	}
+}
+
+func (w *TdengineWriter) execWithRetry(ctx context.Context, query string) error {
+	var err error
+	for attempt := 0; attempt <= w.opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(attempt) * 100 * time.Millisecond):
+			}
+		}
+
+		execCtx, cancel := context.WithTimeout(ctx, w.opts.Timeout)
+		_, err = w.exec.Exec(execCtx, query)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		// SQL 本身有误时重试没有意义
+		var tdErr *TdengineError
+		if errors.As(err, &tdErr) {
+			return err
+		}
+	}
+	return err
+}
+
+type tdengineStatement struct {
+	sql    string
+	points []TdenginePoint
+}
+
+// statements 把各子表的数据合并为多表 INSERT，单条不超过 MaxSQLBytes
+func (w *TdengineWriter) statements(batches []*tdengineBatch) []tdengineStatement {
+	var (
+		stmts   []tdengineStatement
+		sb      strings.Builder
+		points  []TdenginePoint
+		curHead string
+	)
+	flush := func() {
+		if len(points) > 0 {
+			stmts = append(stmts, tdengineStatement{sql: sb.String(), points: points})
+		}
+		sb.Reset()
+		points = nil
+		curHead = ""
+	}
+
+	for _, b := range batches {
+		for _, p := range b.points {
+			row := w.row(p)
+			extra := len(row) + 1
+			if curHead != b.head {
+				extra += len(b.head) + len(" VALUES ") + 1
+			}
+			if sb.Len() > 0 && sb.Len()+extra > w.opts.MaxSQLBytes {
+				flush()
+			}
+
+			if sb.Len() == 0 {
+				sb.WriteString("INSERT INTO")
+			}
+			if curHead != b.head {
+				sb.WriteByte(' ')
+				sb.WriteString(b.head)
+				sb.WriteString(" VALUES")
+				curHead = b.head
+			}
+			sb.WriteByte(' ')
+			sb.WriteString(row)
+			points = append(points, p)
+		}
+	}
+	flush()
+	return stmts
+}
+
+// header 生成子表的写入头：tb USING stb TAGS (...) (cols)
+func (w *TdengineWriter) header(p TdenginePoint) (key, head string, err error) {
+	var sb strings.Builder
+	sb.WriteString(p.Table)
+	if p.SuperTable != "" {
+		sb.WriteString(" USING ")
+		sb.WriteString(p.SuperTable)
+		sb.WriteString(" TAGS (")
+		for i, tag := range p.Tags {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			lit, err := w.literal(tag)
+			if err != nil {
+				return "", "", err
+			}
+			sb.WriteString(lit)
+		}
+		sb.WriteByte(')')
+	}
+	if len(p.Columns) > 0 {
+		sb.WriteString(" (" + strings.Join(p.Columns, ", ") + ")")
+	}
+	return p.Table + "|" + strings.Join(p.Columns, ","), sb.String(), nil
+}
+
+func (w *TdengineWriter) row(p TdenginePoint) string {
+	values := make([]string, len(p.Values))
+	for i, v := range p.Values {
+		// 已在 validateTdenginePoint 中检查过类型
+		values[i], _ = w.literal(v)
+	}
+	return "(" + strings.Join(values, ", ") + ")"
+}
+
+// literal 时间按数据库精度写为整数，其余同 tdengineLiteral
+func (w *TdengineWriter) literal(v interface{}) (string, error) {
+	if t, ok := v.(time.Time); ok {
+		return fmt.Sprintf("%d", t.UnixNano()/int64(w.opts.Precision)), nil
+	}
+	return tdengineLiteral(v)
+}
+
+func validateTdenginePoint(p *TdenginePoint) error {
+	if err := checkTdengineIdent(p.Table); err != nil {
+		return err
+	}
+	if p.SuperTable != "" {
+		if err := checkTdengineIdent(p.SuperTable); err != nil {
+			return err
+		}
+		if len(p.Tags) == 0 {
+			return fmt.Errorf("tdengine: point for %s requires tags", p.Table)
+		}
+	}
+	for _, col := range p.Columns {
+		if err := checkTdengineIdent(col); err != nil {
+			return err
+		}
+	}
+	if len(p.Values) == 0 {
+		return fmt.Errorf("tdengine: point for %s has no values", p.Table)
+	}
+	if len(p.Columns) > 0 && len(p.Columns) != len(p.Values) {
+		return fmt.Errorf("tdengine: point for %s has %d columns but %d values", p.Table, len(p.Columns), len(p.Values))
+	}
+	for _, values := range [][]interface{}{p.Values, p.Tags} {
+		for _, v := range values {
+			if _, ok := v.(time.Time); ok {
+				continue
+			}
+			if _, err := tdengineLiteral(v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
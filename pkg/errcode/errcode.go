@@ -11,6 +11,7 @@ const (
 	RequestTimeout = 408
 	TooManyRequest = 429
 	ServerError    = 500
+	GatewayTimeout = 504
 
 	// 用户模块 (1000-1999)（示例）
 	UserNotFound      = 1001
@@ -40,6 +41,7 @@ var ErrorMessage = map[int]string{
 	ServerError:    "服务器错误",
 	RequestTimeout: "请求超时",
 	TooManyRequest: "请求过多",
+	GatewayTimeout: "请求处理超时",
 
 	// 用户模块（示例）
 	UserNotFound:      "用户不存在",
@@ -22,6 +22,11 @@ func TestGetMessage(t *testing.T) {
 			code: Unauthorized,
 			want: "未授权",
 		},
+		{
+			name: "系统级 - 请求处理超时",
+			code: GatewayTimeout,
+			want: "请求处理超时",
+		},
 		{
 			name: "用户模块 - 用户不存在",
 			code: UserNotFound,
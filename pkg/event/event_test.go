@@ -0,0 +1,240 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"project/pkg/logger"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = zap.NewNop()
+	logger.Sugar = logger.Logger.Sugar()
+	os.Exit(m.Run())
+}
+
+type orderCreated struct {
+	OrderID int64  `json:"order_id"`
+	User    string `json:"user"`
+}
+
+func newTestClient(t *testing.T) (*miniredis.Miniredis, redis.UniversalClient) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return mr, client
+}
+
+func TestSubscriber_ReceivesTypedMessages(t *testing.T) {
+	ctx := context.Background()
+	mr, client := newTestClient(t)
+
+	received := make(chan *Message[orderCreated], 1)
+	sub := NewSubscriber(client, func(ctx context.Context, msg *Message[orderCreated]) {
+		received <- msg
+	}, "orders")
+	require.NoError(t, sub.Start())
+
+	require.Eventually(t, func() bool {
+		return mr.PubSubNumSub("orders")["orders"] == 1
+	}, time.Second, 5*time.Millisecond)
+
+	n, err := Publish(ctx, client, "orders", orderCreated{OrderID: 1, User: "alice"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, "orders", msg.Channel)
+		assert.Equal(t, orderCreated{OrderID: 1, User: "alice"}, msg.Payload)
+	case <-time.After(time.Second):
+		t.Fatal("message not received")
+	}
+
+	stopCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	require.NoError(t, sub.Stop(stopCtx))
+	require.Eventually(t, func() bool {
+		return mr.PubSubNumSub("orders")["orders"] == 0
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestSubscriber_ResubscribesAfterDisconnect(t *testing.T) {
+	ctx := context.Background()
+	mr, client := newTestClient(t)
+
+	var count int32
+	sub := NewSubscriber(client, func(ctx context.Context, msg *Message[orderCreated]) {
+		atomic.AddInt32(&count, 1)
+	}, "orders")
+	require.NoError(t, sub.Start())
+	defer sub.Stop(ctx)
+
+	require.Eventually(t, func() bool {
+		return mr.PubSubNumSub("orders")["orders"] == 1
+	}, time.Second, 5*time.Millisecond)
+
+	// 模拟 Redis 重启，订阅者应自动重新订阅
+	mr.Close()
+	require.NoError(t, mr.Restart())
+
+	require.Eventually(t, func() bool {
+		Publish(ctx, client, "orders", orderCreated{OrderID: 2})
+		return atomic.LoadInt32(&count) > 0
+	}, 3*time.Second, 50*time.Millisecond)
+}
+
+func TestStreamConsumer_AcksHandledMessages(t *testing.T) {
+	ctx := context.Background()
+	_, client := newTestClient(t)
+
+	received := make(chan *StreamMessage[orderCreated], 10)
+	consumer := NewStreamConsumer(client, StreamOptions{
+		Stream:   "orders",
+		Group:    "billing",
+		Consumer: "node-1",
+		Block:    50 * time.Millisecond,
+	}, func(ctx context.Context, msg *StreamMessage[orderCreated]) error {
+		received <- msg
+		return nil
+	})
+	require.NoError(t, consumer.Start())
+
+	for i := int64(1); i <= 3; i++ {
+		_, err := AddStream(ctx, client, "orders", orderCreated{OrderID: i}, 0)
+		require.NoError(t, err)
+	}
+
+	for i := int64(1); i <= 3; i++ {
+		select {
+		case msg := <-received:
+			assert.Equal(t, i, msg.Payload.OrderID)
+			assert.Equal(t, int64(1), msg.Deliveries)
+		case <-time.After(time.Second):
+			t.Fatal("message not received")
+		}
+	}
+
+	stopCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	require.NoError(t, consumer.Stop(stopCtx))
+
+	pending, err := client.XPending(ctx, "orders", "billing").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), pending.Count)
+}
+
+func TestStreamConsumer_RetriesThenDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	_, client := newTestClient(t)
+
+	var attempts int32
+	consumer := NewStreamConsumer(client, StreamOptions{
+		Stream:        "orders",
+		Group:         "billing",
+		Consumer:      "node-1",
+		Block:         20 * time.Millisecond,
+		ClaimInterval: 20 * time.Millisecond,
+		MinIdle:       10 * time.Millisecond,
+		MaxRetries:    3,
+	}, func(ctx context.Context, msg *StreamMessage[orderCreated]) error {
+		atomic.AddInt32(&attempts, 1)
+		return errors.New("downstream unavailable")
+	})
+	require.NoError(t, consumer.Start())
+	defer consumer.Stop(ctx)
+
+	id, err := AddStream(ctx, client, "orders", orderCreated{OrderID: 9}, 0)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		n, _ := client.XLen(ctx, "orders:dead").Result()
+		return n == 1
+	}, 3*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+
+	dead, err := client.XRange(ctx, "orders:dead", "-", "+").Result()
+	require.NoError(t, err)
+	assert.Equal(t, id, dead[0].Values["source_id"])
+	assert.Equal(t, "max retries exceeded", dead[0].Values["reason"])
+	assert.JSONEq(t, `{"order_id":9,"user":""}`, dead[0].Values[payloadField].(string))
+
+	pending, err := client.XPending(ctx, "orders", "billing").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), pending.Count)
+}
+
+func TestStreamConsumer_PoisonMessage(t *testing.T) {
+	ctx := context.Background()
+	_, client := newTestClient(t)
+
+	consumer := NewStreamConsumer(client, StreamOptions{
+		Stream:   "orders",
+		Group:    "billing",
+		Consumer: "node-1",
+		StartID:  "0",
+		Block:    20 * time.Millisecond,
+	}, func(ctx context.Context, msg *StreamMessage[orderCreated]) error {
+		t.Error("handler should not be called")
+		return nil
+	})
+
+	require.NoError(t, client.XAdd(ctx, &redis.XAddArgs{
+		Stream: "orders",
+		Values: map[string]interface{}{payloadField: "not json"},
+	}).Err())
+	require.NoError(t, consumer.Start())
+	defer consumer.Stop(ctx)
+
+	require.Eventually(t, func() bool {
+		n, _ := client.XLen(ctx, "orders:dead").Result()
+		return n == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestStreamConsumer_ProcessesOwnPendingOnRestart(t *testing.T) {
+	ctx := context.Background()
+	_, client := newTestClient(t)
+
+	require.NoError(t, client.XGroupCreateMkStream(ctx, "orders", "billing", "$").Err())
+	_, err := AddStream(ctx, client, "orders", orderCreated{OrderID: 5}, 0)
+	require.NoError(t, err)
+
+	// 上次运行中已投递但未确认
+	_, err = client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group: "billing", Consumer: "node-1", Streams: []string{"orders", ">"}, Block: -1,
+	}).Result()
+	require.NoError(t, err)
+
+	received := make(chan *StreamMessage[orderCreated], 1)
+	consumer := NewStreamConsumer(client, StreamOptions{
+		Stream:   "orders",
+		Group:    "billing",
+		Consumer: "node-1",
+		Block:    20 * time.Millisecond,
+	}, func(ctx context.Context, msg *StreamMessage[orderCreated]) error {
+		received <- msg
+		return nil
+	})
+	require.NoError(t, consumer.Start())
+	defer consumer.Stop(ctx)
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, int64(5), msg.Payload.OrderID)
+		assert.GreaterOrEqual(t, msg.Deliveries, int64(1))
+	case <-time.After(time.Second):
+		t.Fatal("pending message not redelivered")
+	}
+}
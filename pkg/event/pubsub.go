@@ -0,0 +1,201 @@
+// Package event 基于 Redis 组件的事件收发：pub/sub 广播与 Streams 消费组。
+//
+// pub/sub 适用于允许丢失的实时通知（只有在线的订阅者能收到）：
+//
+//	event.Publish(ctx, client, "user.updated", UserUpdated{ID: 1})
+//
+//	sub := event.NewSubscriber(client, func(ctx context.Context, msg *event.Message[UserUpdated]) {
+//		...
+//	}, "user.updated")
+//	application.RegisterWorker(sub)
+//
+// Streams 消费组适用于需要至少一次投递的任务，参见 NewStreamConsumer。
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"project/pkg/logger"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Message pub/sub 消息
+type Message[T any] struct {
+	Channel string
+	Payload T
+}
+
+// Publish 以 JSON 编码发布消息，返回收到消息的订阅者数量
+func Publish[T any](ctx context.Context, client redis.UniversalClient, channel string, payload T) (int64, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("event: encode %s: %w", channel, err)
+	}
+	return client.Publish(ctx, channel, data).Result()
+}
+
+// Subscriber pub/sub 订阅者，连接断开后按退避间隔自动重新订阅
+type Subscriber[T any] struct {
+	client   redis.UniversalClient
+	channels []string
+	handler  func(ctx context.Context, msg *Message[T])
+
+	mu     sync.Mutex
+	pubsub *redis.PubSub
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewSubscriber 创建订阅者，Start 之后开始接收消息；handler 串行执行
+func NewSubscriber[T any](client redis.UniversalClient, handler func(ctx context.Context, msg *Message[T]), channels ...string) *Subscriber[T] {
+	return &Subscriber[T]{client: client, channels: channels, handler: handler}
+}
+
+// Name 返回名称
+func (s *Subscriber[T]) Name() string {
+	return fmt.Sprintf("subscriber:%v", s.channels)
+}
+
+// Start 启动订阅协程（重复调用无效）
+func (s *Subscriber[T]) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done != nil {
+		return nil
+	}
+	if len(s.channels) == 0 {
+		return fmt.Errorf("event: no channels to subscribe")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go s.run(ctx, s.done)
+	return nil
+}
+
+// Stop 取消订阅，等待正在执行的 handler 返回
+func (s *Subscriber[T]) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	done := s.done
+	if done == nil {
+		s.mu.Unlock()
+		return nil
+	}
+	// 持锁取消，保证 run 不会在此之后再登记新的连接
+	s.cancel()
+	pubsub := s.pubsub
+	s.cancel, s.done, s.pubsub = nil, nil, nil
+	s.mu.Unlock()
+
+	// Receive 不响应 ctx 取消，关闭连接使其立即返回
+	if pubsub != nil {
+		pubsub.Close()
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Subscriber[T]) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	backoff := newBackoff(100*time.Millisecond, 5*time.Second)
+	for {
+		pubsub := s.client.Subscribe(ctx, s.channels...)
+		s.mu.Lock()
+		if ctx.Err() != nil {
+			s.mu.Unlock()
+			pubsub.Close()
+			return
+		}
+		s.pubsub = pubsub
+		s.mu.Unlock()
+
+		err := s.receive(ctx, pubsub, backoff)
+		pubsub.Close()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		delay := backoff.next()
+		logger.Sugar.Warnf("\t[event] subscription %v interrupted: %v, resubscribing in %s", s.channels, err, delay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// receive 接收消息直到连接出错
+func (s *Subscriber[T]) receive(ctx context.Context, pubsub *redis.PubSub, backoff *backoff) error {
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if err != nil {
+			return err
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			if m.Kind == "subscribe" {
+				backoff.reset()
+				logger.Sugar.Infof("\t[event] subscribed channel: %s", m.Channel)
+			}
+
+		case *redis.Message:
+			var payload T
+			if err := json.Unmarshal([]byte(m.Payload), &payload); err != nil {
+				logger.Sugar.Errorf("\t[event] decode message from %s failed: %v", m.Channel, err)
+				continue
+			}
+			s.handle(ctx, &Message[T]{Channel: m.Channel, Payload: payload})
+		}
+	}
+}
+
+func (s *Subscriber[T]) handle(ctx context.Context, msg *Message[T]) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Sugar.Errorf("\t[event] handler panic on %s: %v", msg.Channel, r)
+		}
+	}()
+	s.handler(ctx, msg)
+}
+
+// backoff 指数退避
+type backoff struct {
+	min, max time.Duration
+	current  time.Duration
+}
+
+func newBackoff(min, max time.Duration) *backoff {
+	return &backoff{min: min, max: max}
+}
+
+func (b *backoff) next() time.Duration {
+	if b.current == 0 {
+		b.current = b.min
+	} else {
+		b.current *= 2
+		if b.current > b.max {
+			b.current = b.max
+		}
+	}
+	return b.current
+}
+
+func (b *backoff) reset() {
+	b.current = 0
+}
@@ -0,0 +1,422 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"project/pkg/logger"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// payloadField 消息体在 Stream 条目中的字段名
+const payloadField = "payload"
+
+// AddStream 以 JSON 编码向 Stream 追加消息；maxLen > 0 时按近似长度裁剪旧消息
+func AddStream[T any](ctx context.Context, client redis.UniversalClient, stream string, payload T, maxLen int64) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("event: encode %s: %w", stream, err)
+	}
+	return client.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: maxLen,
+		Approx: maxLen > 0,
+		Values: map[string]interface{}{payloadField: data},
+	}).Result()
+}
+
+// StreamMessage 消费组消息
+type StreamMessage[T any] struct {
+	ID      string
+	Stream  string
+	Payload T
+	// Deliveries 已投递次数（含本次），大于 1 表示是失败后的重试
+	Deliveries int64
+}
+
+// StreamOptions 消费组配置
+type StreamOptions struct {
+	// Stream 流名称
+	Stream string
+	// Group 消费组名称，不存在时自动创建
+	Group string
+	// Consumer 消费者名称，同一消费组内各副本需不同，通常使用主机名
+	Consumer string
+	// StartID 创建消费组时的起始位置，默认 "$"（只消费之后的新消息），"0" 表示从头消费
+	StartID string
+	// Count 每次读取的最大条数，默认 10
+	Count int64
+	// Block 阻塞读取的最长等待时间，默认 5s；同时也是 Stop 的最长等待时间
+	Block time.Duration
+	// Concurrency 同一批消息的并发处理数，默认 1
+	Concurrency int
+	// ClaimInterval 回收超时未确认消息的检查间隔，默认 30s
+	ClaimInterval time.Duration
+	// MinIdle 未确认超过该时长的消息会被回收重新处理，默认 1m
+	MinIdle time.Duration
+	// MaxRetries 最大投递次数，超过后转入死信流，默认 5
+	MaxRetries int64
+	// DeadLetterStream 死信流名称，默认 Stream + ":dead"
+	DeadLetterStream string
+	// DeadLetterIf 返回 true 的处理错误不再重试，直接转入死信流；为空时所有错误都重试
+	DeadLetterIf func(err error) bool
+}
+
+// StreamConsumer Redis Streams 消费组运行器
+//
+// handler 返回 nil 时确认消息；返回错误或 panic 时消息保持未确认状态，
+// 超过 MinIdle 后由 XAUTOCLAIM 回收重新投递，投递次数超过 MaxRetries 后转入死信流。
+// DeadLetterIf 判定为不可恢复的错误直接转入死信流。
+type StreamConsumer[T any] struct {
+	client  redis.UniversalClient
+	opts    StreamOptions
+	handler func(ctx context.Context, msg *StreamMessage[T]) error
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewStreamConsumer 创建消费组运行器，Start 之后开始消费
+func NewStreamConsumer[T any](client redis.UniversalClient, opts StreamOptions, handler func(ctx context.Context, msg *StreamMessage[T]) error) *StreamConsumer[T] {
+	if opts.StartID == "" {
+		opts.StartID = "$"
+	}
+	if opts.Count <= 0 {
+		opts.Count = 10
+	}
+	if opts.Block <= 0 {
+		opts.Block = 5 * time.Second
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if opts.ClaimInterval <= 0 {
+		opts.ClaimInterval = 30 * time.Second
+	}
+	if opts.MinIdle <= 0 {
+		opts.MinIdle = time.Minute
+	}
+	if opts.MaxRetries <= 0 {
+		opts.MaxRetries = 5
+	}
+	if opts.DeadLetterStream == "" {
+		opts.DeadLetterStream = opts.Stream + ":dead"
+	}
+	return &StreamConsumer[T]{client: client, opts: opts, handler: handler}
+}
+
+// Name 返回名称
+func (c *StreamConsumer[T]) Name() string {
+	return fmt.Sprintf("stream:%s/%s", c.opts.Stream, c.opts.Group)
+}
+
+// Start 创建消费组并启动消费协程（重复调用无效）
+func (c *StreamConsumer[T]) Start() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.done != nil {
+		return nil
+	}
+	if c.opts.Stream == "" || c.opts.Group == "" || c.opts.Consumer == "" {
+		return errors.New("event: stream, group and consumer are required")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := c.client.XGroupCreateMkStream(ctx, c.opts.Stream, c.opts.Group, c.opts.StartID).Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		cancel()
+		return fmt.Errorf("event: create group %s: %w", c.opts.Group, err)
+	}
+
+	c.cancel = cancel
+	c.done = make(chan struct{})
+	go c.run(ctx, c.done)
+
+	logger.Sugar.Infof("\t[event] stream consumer %s started, consumer: %s", c.Name(), c.opts.Consumer)
+	return nil
+}
+
+// Stop 停止读取新消息，等待正在处理的消息完成（最长等待 Block）
+func (c *StreamConsumer[T]) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	cancel, done := c.cancel, c.done
+	c.cancel, c.done = nil, nil
+	c.mu.Unlock()
+
+	if done == nil {
+		return nil
+	}
+
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *StreamConsumer[T]) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	// 先处理本消费者在上次退出前未确认的消息
+	c.readPending(ctx)
+
+	backoff := newBackoff(100*time.Millisecond, 5*time.Second)
+	lastClaim := time.Now()
+	for ctx.Err() == nil {
+		if time.Since(lastClaim) >= c.opts.ClaimInterval {
+			c.claim(ctx)
+			lastClaim = time.Now()
+		}
+
+		streams, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    c.opts.Group,
+			Consumer: c.opts.Consumer,
+			Streams:  []string{c.opts.Stream, ">"},
+			Count:    c.opts.Count,
+			Block:    c.opts.Block,
+		}).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) || ctx.Err() != nil {
+				continue
+			}
+			delay := backoff.next()
+			logger.Sugar.Errorf("\t[event] %s read failed: %v, retrying in %s", c.Name(), err, delay)
+			select {
+			case <-ctx.Done():
+			case <-time.After(delay):
+			}
+			continue
+		}
+		backoff.reset()
+
+		for _, s := range streams {
+			c.process(ctx, s.Messages, nil)
+		}
+	}
+}
+
+// readPending 重新处理已投递给本消费者但未确认的消息
+func (c *StreamConsumer[T]) readPending(ctx context.Context) {
+	start := "0"
+	for ctx.Err() == nil {
+		streams, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    c.opts.Group,
+			Consumer: c.opts.Consumer,
+			Streams:  []string{c.opts.Stream, start},
+			Count:    c.opts.Count,
+			Block:    -1, // 读取历史消息不阻塞
+		}).Result()
+		if err != nil {
+			if !errors.Is(err, redis.Nil) && ctx.Err() == nil {
+				logger.Sugar.Errorf("\t[event] %s read pending failed: %v", c.Name(), err)
+			}
+			return
+		}
+		if len(streams) == 0 || len(streams[0].Messages) == 0 {
+			return
+		}
+
+		messages := streams[0].Messages
+		c.process(ctx, messages, c.deliveries(ctx, messages))
+		start = messages[len(messages)-1].ID
+	}
+}
+
+// claim 回收其他消费者（可能已下线）超时未确认的消息
+func (c *StreamConsumer[T]) claim(ctx context.Context) {
+	start := "0-0"
+	for ctx.Err() == nil {
+		messages, next, err := c.autoClaim(ctx, start)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Sugar.Errorf("\t[event] %s claim failed: %v", c.Name(), err)
+			}
+			return
+		}
+
+		if len(messages) > 0 {
+			logger.Sugar.Infof("\t[event] %s claimed %d pending messages", c.Name(), len(messages))
+			c.process(ctx, messages, c.deliveries(ctx, messages))
+		}
+		if next == "0-0" || next == "" {
+			return
+		}
+		start = next
+	}
+}
+
+// autoClaim 执行 XAUTOCLAIM
+//
+// go-redis v8 的 XAutoClaim 只能解析 Redis 6.2 的两段式回复，Redis 7 增加了第三段（已删除的 ID），
+// 因此这里直接发送命令并自行解析。
+func (c *StreamConsumer[T]) autoClaim(ctx context.Context, start string) ([]redis.XMessage, string, error) {
+	reply, err := c.client.Do(ctx, "XAUTOCLAIM", c.opts.Stream, c.opts.Group, c.opts.Consumer,
+		c.opts.MinIdle.Milliseconds(), start, "COUNT", c.opts.Count).Slice()
+	if err != nil {
+		return nil, "", err
+	}
+	if len(reply) < 2 {
+		return nil, "", fmt.Errorf("unexpected XAUTOCLAIM reply length %d", len(reply))
+	}
+
+	next, _ := reply[0].(string)
+	entries, _ := reply[1].([]interface{})
+	messages := make([]redis.XMessage, 0, len(entries))
+	for _, e := range entries {
+		// Redis 6.2 对已删除的条目返回 nil
+		entry, ok := e.([]interface{})
+		if !ok || len(entry) < 2 {
+			continue
+		}
+		id, _ := entry[0].(string)
+		fields, _ := entry[1].([]interface{})
+
+		var values map[string]interface{}
+		if fields != nil {
+			values = make(map[string]interface{}, len(fields)/2)
+			for i := 0; i+1 < len(fields); i += 2 {
+				key, _ := fields[i].(string)
+				values[key] = fields[i+1]
+			}
+		}
+		messages = append(messages, redis.XMessage{ID: id, Values: values})
+	}
+	return messages, next, nil
+}
+
+// deliveries 查询消息的已投递次数
+func (c *StreamConsumer[T]) deliveries(ctx context.Context, messages []redis.XMessage) map[string]int64 {
+	counts := make(map[string]int64, len(messages))
+	if len(messages) == 0 {
+		return counts
+	}
+
+	pending, err := c.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream:   c.opts.Stream,
+		Group:    c.opts.Group,
+		Start:    messages[0].ID,
+		End:      messages[len(messages)-1].ID,
+		Count:    int64(len(messages)),
+		Consumer: c.opts.Consumer,
+	}).Result()
+	if err != nil {
+		logger.Sugar.Errorf("\t[event] %s query pending failed: %v", c.Name(), err)
+		return counts
+	}
+	for _, p := range pending {
+		counts[p.ID] = p.RetryCount
+	}
+	return counts
+}
+
+// process 按并发数处理一批消息，deliveries 为 nil 表示均为首次投递
+func (c *StreamConsumer[T]) process(ctx context.Context, messages []redis.XMessage, deliveries map[string]int64) {
+	sem := make(chan struct{}, c.opts.Concurrency)
+	var wg sync.WaitGroup
+
+	// 处理中的消息不随 Stop 取消；尚未开始的消息保持未确认，重启后重新处理
+	handlerCtx := context.WithoutCancel(ctx)
+	for _, m := range messages {
+		if ctx.Err() != nil {
+			break
+		}
+
+		count := int64(1)
+		if deliveries[m.ID] > 0 {
+			count = deliveries[m.ID]
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(m redis.XMessage, count int64) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			c.handle(handlerCtx, m, count)
+		}(m, count)
+	}
+	wg.Wait()
+}
+
+func (c *StreamConsumer[T]) handle(ctx context.Context, m redis.XMessage, deliveries int64) {
+	// 条目已被 XTRIM / XDEL 删除
+	if m.Values == nil {
+		c.ack(m.ID)
+		return
+	}
+
+	if deliveries > c.opts.MaxRetries {
+		c.deadLetter(m, deliveries, "max retries exceeded")
+		return
+	}
+
+	raw, _ := m.Values[payloadField].(string)
+	var payload T
+	if err := json.Unmarshal([]byte(raw), &payload); err != nil {
+		// 无法解码的消息重试也不会成功，直接转入死信流
+		c.deadLetter(m, deliveries, fmt.Sprintf("decode payload: %v", err))
+		return
+	}
+
+	msg := &StreamMessage[T]{ID: m.ID, Stream: c.opts.Stream, Payload: payload, Deliveries: deliveries}
+	if err := c.invoke(ctx, msg); err != nil {
+		if c.opts.DeadLetterIf != nil && c.opts.DeadLetterIf(err) {
+			c.deadLetter(m, deliveries, err.Error())
+			return
+		}
+		logger.Sugar.Warnf("\t[event] %s message %s failed (delivery %d/%d): %v", c.Name(), m.ID, deliveries, c.opts.MaxRetries, err)
+		return
+	}
+	c.ack(m.ID)
+}
+
+func (c *StreamConsumer[T]) invoke(ctx context.Context, msg *StreamMessage[T]) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return c.handler(ctx, msg)
+}
+
+// ack 确认消息；使用独立 ctx，保证 Stop 期间已处理完的消息仍能确认
+func (c *StreamConsumer[T]) ack(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.client.XAck(ctx, c.opts.Stream, c.opts.Group, id).Err(); err != nil {
+		logger.Sugar.Errorf("\t[event] %s ack %s failed: %v", c.Name(), id, err)
+	}
+}
+
+// deadLetter 把消息转入死信流并确认原消息
+func (c *StreamConsumer[T]) deadLetter(m redis.XMessage, deliveries int64, reason string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	values := make(map[string]interface{}, len(m.Values)+4)
+	for k, v := range m.Values {
+		values[k] = v
+	}
+	values["source_stream"] = c.opts.Stream
+	values["source_id"] = m.ID
+	values["deliveries"] = deliveries
+	values["reason"] = reason
+
+	if err := c.client.XAdd(ctx, &redis.XAddArgs{Stream: c.opts.DeadLetterStream, Values: values}).Err(); err != nil {
+		logger.Sugar.Errorf("\t[event] %s dead-letter %s failed: %v", c.Name(), m.ID, err)
+		return
+	}
+	logger.Sugar.Warnf("\t[event] %s message %s moved to %s: %s", c.Name(), m.ID, c.opts.DeadLetterStream, reason)
+	c.ack(m.ID)
+}
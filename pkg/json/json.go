@@ -3,6 +3,7 @@ package json
 import (
 	"encoding/json"
 	"project/pkg/logger"
+	"project/pkg/redact"
 )
 
 func JSONMarshal(data interface{}) []byte {
@@ -14,3 +15,15 @@ func JSONMarshal(data interface{}) []byte {
 
 	return bytes
 }
+
+// JSONMarshalRedacted 编码 data 的脱敏副本，用于写日志：
+// log:"redact"、log:"mask" 标记的字段及名称匹配敏感模式（password、token 等）的字段不会输出原文
+func JSONMarshalRedacted(data interface{}) []byte {
+	bytes, err := redact.JSON(data)
+	if err != nil {
+		logger.Sugar.Error(err)
+		return nil
+	}
+
+	return bytes
+}
@@ -0,0 +1,188 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"project/pkg/logger"
+)
+
+// Exclusive 在持有锁期间执行 fn，用于多副本部署下只需一个节点执行的定时任务：
+//
+//	err := lock.Exclusive(ctx, locker, "jobs:delete-expired-tokens", time.Minute, func(ctx context.Context) error {
+//		return repository.DeleteExpiredTokens(database.GetMysql().GetDb())
+//	})
+//	if errors.Is(err, lock.ErrNotObtained) {
+//		// 其他节点正在执行，本次跳过
+//	}
+//
+// 锁由看门狗自动续期；锁丢失时 fn 的 ctx 会被取消。
+func Exclusive(ctx context.Context, locker Locker, key string, ttl time.Duration, fn func(ctx context.Context) error) error {
+	l, err := locker.TryObtain(ctx, key, ttl, WithWatchdog())
+	if err != nil {
+		return err
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-l.Lost():
+			cancel()
+		case <-runCtx.Done():
+		}
+	}()
+
+	fnErr := fn(runCtx)
+
+	releaseCtx, releaseCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer releaseCancel()
+	if err := l.Release(releaseCtx); err != nil && !errors.Is(err, ErrNotHeld) {
+		logger.Sugar.Errorf("\t[lock] %s release failed: %v", key, err)
+	}
+
+	return fnErr
+}
+
+// ElectorOptions 主节点选举配置
+type ElectorOptions struct {
+	// TTL 主节点租约有效期，默认 15s；主节点异常退出后最多经过该时长完成切换
+	TTL time.Duration
+	// RetryInterval 非主节点的竞选间隔，默认 TTL/3
+	RetryInterval time.Duration
+	// OnElected 成为主节点时调用，ctx 在失去主节点身份时取消
+	OnElected func(ctx context.Context)
+	// OnRevoked 失去主节点身份时调用（OnElected 返回之后）
+	OnRevoked func()
+}
+
+// Elector 基于分布式锁的主节点选举
+type Elector struct {
+	locker Locker
+	key    string
+	opts   ElectorOptions
+
+	leader atomic.Bool
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewElector 创建选举器，同一 key 下同一时刻只有一个节点成为主节点
+func NewElector(locker Locker, key string, opts ElectorOptions) *Elector {
+	if opts.TTL <= 0 {
+		opts.TTL = 15 * time.Second
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = opts.TTL / 3
+	}
+	return &Elector{locker: locker, key: key, opts: opts}
+}
+
+// Name 返回选举键名
+func (e *Elector) Name() string {
+	return "elector:" + e.key
+}
+
+// IsLeader 当前节点是否为主节点
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Start 启动竞选协程（重复调用无效）
+func (e *Elector) Start() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.done != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+	e.done = make(chan struct{})
+	go e.run(ctx, e.done)
+
+	logger.Sugar.Infof("\t[lock] elector %s started", e.key)
+	return nil
+}
+
+// Stop 停止竞选；当前为主节点时会触发 OnRevoked 并释放锁
+func (e *Elector) Stop(ctx context.Context) error {
+	e.mu.Lock()
+	cancel, done := e.cancel, e.done
+	e.cancel, e.done = nil, nil
+	e.mu.Unlock()
+
+	if done == nil {
+		return nil
+	}
+
+	cancel()
+	select {
+	case <-done:
+		logger.Sugar.Infof("\t[lock] elector %s stopped", e.key)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *Elector) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	for {
+		l, err := e.locker.TryObtain(ctx, e.key, e.opts.TTL, WithWatchdog())
+		switch {
+		case err == nil:
+			e.lead(ctx, l)
+		case !errors.Is(err, ErrNotObtained):
+			logger.Sugar.Errorf("\t[lock] elector %s campaign failed: %v", e.key, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(e.opts.RetryInterval):
+		}
+	}
+}
+
+// lead 以主节点身份运行，直到锁丢失或选举器停止
+func (e *Elector) lead(ctx context.Context, l Lock) {
+	e.leader.Store(true)
+	logger.Sugar.Infof("\t[lock] elector %s: became leader", e.key)
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		if e.opts.OnElected != nil {
+			e.opts.OnElected(leaderCtx)
+		}
+	}()
+
+	select {
+	case <-l.Lost():
+	case <-ctx.Done():
+	}
+	cancel()
+	<-finished
+
+	e.leader.Store(false)
+
+	releaseCtx, releaseCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := l.Release(releaseCtx); err != nil && !errors.Is(err, ErrNotHeld) {
+		logger.Sugar.Errorf("\t[lock] elector %s release failed: %v", e.key, err)
+	}
+	releaseCancel()
+
+	logger.Sugar.Infof("\t[lock] elector %s: leadership revoked", e.key)
+	if e.opts.OnRevoked != nil {
+		e.opts.OnRevoked()
+	}
+}
@@ -0,0 +1,275 @@
+// Package lock 提供基于 Redis 组件的分布式锁与主节点选举。
+//
+// 锁以随机 token 标识持有者，只有持有者才能续期或释放；开启看门狗后会在锁过期前自动续期：
+//
+//	locker := lock.NewRedisLocker(database.GetRedis().GetClient())
+//	l, err := locker.Obtain(ctx, "jobs:cleanup", 30*time.Second, lock.WithWatchdog())
+//	if errors.Is(err, lock.ErrNotObtained) {
+//		return // 其他节点正在执行
+//	}
+//	defer l.Release(context.Background())
+//
+// 单元测试中可使用 NewMemoryLocker 替代 Redis。
+package lock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"project/pkg/logger"
+	"project/pkg/utils/idgen"
+)
+
+var (
+	// ErrNotObtained 在等待期内未能获取锁
+	ErrNotObtained = errors.New("lock: not obtained")
+	// ErrNotHeld 锁已过期或已被其他持有者获取
+	ErrNotHeld = errors.New("lock: not held")
+)
+
+// Locker 分布式锁
+type Locker interface {
+	// Obtain 获取锁，锁被占用时按重试间隔等待，直到获取成功或 ctx 结束
+	Obtain(ctx context.Context, key string, ttl time.Duration, opts ...Option) (Lock, error)
+	// TryObtain 只尝试一次，锁被占用时立即返回 ErrNotObtained
+	TryObtain(ctx context.Context, key string, ttl time.Duration, opts ...Option) (Lock, error)
+}
+
+// Lock 已获取的锁
+type Lock interface {
+	// Key 锁名
+	Key() string
+	// Token 持有者标识
+	Token() string
+	// TTL 剩余有效期，锁已丢失时返回 ErrNotHeld
+	TTL(ctx context.Context) (time.Duration, error)
+	// Refresh 以新的有效期续期
+	Refresh(ctx context.Context, ttl time.Duration) error
+	// Release 释放锁，同时停止看门狗
+	Release(ctx context.Context) error
+	// Lost 在看门狗续期失败（锁已丢失）时关闭
+	Lost() <-chan struct{}
+}
+
+// backend 锁的存储实现
+type backend interface {
+	acquire(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+	refresh(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+	release(ctx context.Context, key, token string) (bool, error)
+	pttl(ctx context.Context, key, token string) (time.Duration, bool, error)
+}
+
+// Option 获取锁的选项
+type Option func(*options)
+
+type options struct {
+	retryInterval time.Duration
+	watchdog      bool
+	token         string
+}
+
+// WithRetryInterval 设置锁被占用时的重试间隔，默认 100ms
+func WithRetryInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.retryInterval = d
+	}
+}
+
+// WithWatchdog 开启看门狗，每隔 ttl/3 自动续期直到 Release
+func WithWatchdog() Option {
+	return func(o *options) {
+		o.watchdog = true
+	}
+}
+
+// WithToken 指定持有者标识（默认随机生成）。锁已由相同 token 持有时获取成功并重置有效期，
+// 进程重启后使用持久化的 token 可以继续持有同一把锁
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// locker 通用加锁逻辑，存储细节由 backend 实现
+type locker struct {
+	backend backend
+	prefix  string
+}
+
+func (l *locker) Obtain(ctx context.Context, key string, ttl time.Duration, opts ...Option) (Lock, error) {
+	return l.obtain(ctx, key, ttl, true, opts)
+}
+
+func (l *locker) TryObtain(ctx context.Context, key string, ttl time.Duration, opts ...Option) (Lock, error) {
+	return l.obtain(ctx, key, ttl, false, opts)
+}
+
+func (l *locker) obtain(ctx context.Context, key string, ttl time.Duration, wait bool, opts []Option) (Lock, error) {
+	if ttl <= 0 {
+		return nil, errors.New("lock: ttl must be > 0")
+	}
+
+	o := options{retryInterval: 100 * time.Millisecond}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.token == "" {
+		token, err := idgen.GenerateUUID(22)
+		if err != nil {
+			return nil, err
+		}
+		o.token = token
+	}
+
+	fullKey := l.prefix + key
+	var timer *time.Timer
+	for {
+		ok, err := l.backend.acquire(ctx, fullKey, o.token, ttl)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return l.newLock(key, fullKey, o.token, ttl, o.watchdog), nil
+		}
+		if !wait {
+			return nil, ErrNotObtained
+		}
+
+		if timer == nil {
+			timer = time.NewTimer(o.retryInterval)
+			defer timer.Stop()
+		} else {
+			timer.Reset(o.retryInterval)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ErrNotObtained
+		case <-timer.C:
+		}
+	}
+}
+
+func (l *locker) newLock(key, fullKey, token string, ttl time.Duration, watchdog bool) *lock {
+	lk := &lock{
+		backend: l.backend,
+		key:     key,
+		fullKey: fullKey,
+		token:   token,
+		ttl:     ttl,
+		lost:    make(chan struct{}),
+		stop:    make(chan struct{}),
+	}
+	if watchdog {
+		go lk.watch()
+	}
+	return lk
+}
+
+type lock struct {
+	backend backend
+	key     string
+	fullKey string
+	token   string
+
+	mu  sync.Mutex
+	ttl time.Duration
+
+	lostOnce sync.Once
+	lost     chan struct{}
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+func (l *lock) Key() string {
+	return l.key
+}
+
+func (l *lock) Token() string {
+	return l.token
+}
+
+func (l *lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+func (l *lock) TTL(ctx context.Context) (time.Duration, error) {
+	d, held, err := l.backend.pttl(ctx, l.fullKey, l.token)
+	if err != nil {
+		return 0, err
+	}
+	if !held {
+		return 0, ErrNotHeld
+	}
+	return d, nil
+}
+
+func (l *lock) Refresh(ctx context.Context, ttl time.Duration) error {
+	ok, err := l.backend.refresh(ctx, l.fullKey, l.token, ttl)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotHeld
+	}
+
+	l.mu.Lock()
+	l.ttl = ttl
+	l.mu.Unlock()
+	return nil
+}
+
+func (l *lock) Release(ctx context.Context) error {
+	l.stopOnce.Do(func() { close(l.stop) })
+
+	ok, err := l.backend.release(ctx, l.fullKey, l.token)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotHeld
+	}
+	return nil
+}
+
+// watch 看门狗：按 ttl/3 续期；锁被他人持有，或连续失败超过 ttl 时视为锁丢失
+func (l *lock) watch() {
+	lastOK := time.Now()
+	for {
+		l.mu.Lock()
+		ttl := l.ttl
+		l.mu.Unlock()
+
+		select {
+		case <-l.stop:
+			return
+		case <-time.After(ttl / 3):
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), ttl/3)
+		err := l.Refresh(ctx, ttl)
+		cancel()
+
+		// 续期期间锁已被主动释放
+		select {
+		case <-l.stop:
+			return
+		default:
+		}
+
+		switch {
+		case err == nil:
+			lastOK = time.Now()
+		case errors.Is(err, ErrNotHeld) || time.Since(lastOK) >= ttl:
+			logger.Sugar.Warnf("\t[lock] %s lost: %v", l.key, err)
+			l.lostOnce.Do(func() { close(l.lost) })
+			return
+		default:
+			// 临时错误：锁仍在有效期内，下个周期重试
+			logger.Sugar.Errorf("\t[lock] %s refresh failed: %v", l.key, err)
+		}
+	}
+}
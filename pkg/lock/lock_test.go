@@ -0,0 +1,254 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"project/pkg/logger"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = zap.NewNop()
+	logger.Sugar = logger.Logger.Sugar()
+	os.Exit(m.Run())
+}
+
+func TestLock_ObtainRelease(t *testing.T) {
+	ctx := context.Background()
+	locker := NewMemoryLocker()
+
+	l, err := locker.TryObtain(ctx, "job", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "job", l.Key())
+	assert.NotEmpty(t, l.Token())
+
+	// 锁被占用
+	_, err = locker.TryObtain(ctx, "job", time.Second)
+	assert.ErrorIs(t, err, ErrNotObtained)
+
+	ttl, err := l.TTL(ctx)
+	require.NoError(t, err)
+	assert.True(t, ttl > 0 && ttl <= time.Second)
+
+	require.NoError(t, l.Release(ctx))
+	assert.ErrorIs(t, l.Release(ctx), ErrNotHeld)
+
+	_, err = locker.TryObtain(ctx, "job", time.Second)
+	assert.NoError(t, err)
+}
+
+func TestLock_OnlyOwnerCanRelease(t *testing.T) {
+	ctx := context.Background()
+	locker := NewMemoryLocker()
+
+	l, err := locker.TryObtain(ctx, "job", 50*time.Millisecond)
+	require.NoError(t, err)
+
+	// 过期后被其他持有者获取，原持有者不能续期或释放
+	time.Sleep(80 * time.Millisecond)
+	other, err := locker.TryObtain(ctx, "job", time.Second)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, l.Refresh(ctx, time.Second), ErrNotHeld)
+	assert.ErrorIs(t, l.Release(ctx), ErrNotHeld)
+	_, err = l.TTL(ctx)
+	assert.ErrorIs(t, err, ErrNotHeld)
+
+	assert.NoError(t, other.Release(ctx))
+}
+
+func TestLock_ObtainWaitsUntilReleased(t *testing.T) {
+	ctx := context.Background()
+	locker := NewMemoryLocker()
+
+	l, err := locker.TryObtain(ctx, "job", time.Second)
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		l.Release(ctx)
+	}()
+
+	waitCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	_, err = locker.Obtain(waitCtx, "job", time.Second, WithRetryInterval(10*time.Millisecond))
+	assert.NoError(t, err)
+}
+
+func TestLock_ObtainTimeout(t *testing.T) {
+	ctx := context.Background()
+	locker := NewMemoryLocker()
+
+	_, err := locker.TryObtain(ctx, "job", time.Second)
+	require.NoError(t, err)
+
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = locker.Obtain(waitCtx, "job", time.Second, WithRetryInterval(10*time.Millisecond))
+	assert.ErrorIs(t, err, ErrNotObtained)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestLock_Watchdog(t *testing.T) {
+	ctx := context.Background()
+	locker := NewMemoryLocker()
+
+	l, err := locker.TryObtain(ctx, "job", 60*time.Millisecond, WithWatchdog())
+	require.NoError(t, err)
+
+	// 超过多个 TTL 周期后仍然持有
+	time.Sleep(200 * time.Millisecond)
+	_, err = locker.TryObtain(ctx, "job", time.Second)
+	assert.ErrorIs(t, err, ErrNotObtained)
+
+	select {
+	case <-l.Lost():
+		t.Fatal("lock should not be lost")
+	default:
+	}
+
+	require.NoError(t, l.Release(ctx))
+}
+
+func TestLock_WatchdogDetectsLoss(t *testing.T) {
+	ctx := context.Background()
+	ml := NewMemoryLocker()
+
+	l, err := ml.TryObtain(ctx, "job", 60*time.Millisecond, WithWatchdog())
+	require.NoError(t, err)
+
+	// 模拟锁被外部删除后被他人获取
+	b := ml.(*locker).backend.(*memoryBackend)
+	b.mu.Lock()
+	b.entries["job"] = memoryEntry{token: "other", expireAt: time.Now().Add(time.Second)}
+	b.mu.Unlock()
+
+	select {
+	case <-l.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("expected lock to be lost")
+	}
+}
+
+func TestLock_ReacquireWithToken(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	lockers := map[string]Locker{
+		"memory": NewMemoryLocker(),
+		"redis":  NewRedisLocker(client),
+	}
+	for name, locker := range lockers {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			l, err := locker.TryObtain(ctx, "job", 100*time.Millisecond, WithToken("node-1"))
+			require.NoError(t, err)
+			assert.Equal(t, "node-1", l.Token())
+
+			// 重启后以相同 token 获取成功并重置有效期
+			again, err := locker.TryObtain(ctx, "job", time.Minute, WithToken("node-1"))
+			require.NoError(t, err)
+			ttl, err := again.TTL(ctx)
+			require.NoError(t, err)
+			assert.Greater(t, ttl, 30*time.Second)
+
+			// 其他 token 仍然无法获取
+			_, err = locker.TryObtain(ctx, "job", time.Second, WithToken("node-2"))
+			assert.ErrorIs(t, err, ErrNotObtained)
+			_, err = locker.TryObtain(ctx, "job", time.Second)
+			assert.ErrorIs(t, err, ErrNotObtained)
+
+			require.NoError(t, again.Release(ctx))
+			other, err := locker.TryObtain(ctx, "job", time.Second, WithToken("node-2"))
+			require.NoError(t, err)
+			require.NoError(t, other.Release(ctx))
+		})
+	}
+}
+
+func TestExclusive(t *testing.T) {
+	ctx := context.Background()
+	locker := NewMemoryLocker()
+
+	var runs int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	go Exclusive(ctx, locker, "cleanup", time.Second, func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	err := Exclusive(ctx, locker, "cleanup", time.Second, func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrNotObtained)
+	close(release)
+
+	wantErr := errors.New("boom")
+	require.Eventually(t, func() bool {
+		err = Exclusive(ctx, locker, "cleanup", time.Second, func(ctx context.Context) error {
+			atomic.AddInt32(&runs, 1)
+			return wantErr
+		})
+		return !errors.Is(err, ErrNotObtained)
+	}, time.Second, 10*time.Millisecond)
+	assert.ErrorIs(t, err, wantErr)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+}
+
+func TestElector_Failover(t *testing.T) {
+	locker := NewMemoryLocker()
+
+	var elected, revoked [2]int32
+	electors := make([]*Elector, 2)
+	for i := range electors {
+		i := i
+		electors[i] = NewElector(locker, "leader", ElectorOptions{
+			TTL:           90 * time.Millisecond,
+			RetryInterval: 10 * time.Millisecond,
+			OnElected: func(ctx context.Context) {
+				atomic.AddInt32(&elected[i], 1)
+				<-ctx.Done()
+			},
+			OnRevoked: func() { atomic.AddInt32(&revoked[i], 1) },
+		})
+	}
+
+	require.NoError(t, electors[0].Start())
+	require.Eventually(t, electors[0].IsLeader, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, electors[1].Start())
+	time.Sleep(100 * time.Millisecond)
+	assert.False(t, electors[1].IsLeader(), "only one leader at a time")
+
+	// 主节点停止后由另一个节点接管
+	require.NoError(t, electors[0].Stop(context.Background()))
+	assert.False(t, electors[0].IsLeader())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&revoked[0]))
+
+	require.Eventually(t, electors[1].IsLeader, time.Second, 5*time.Millisecond)
+	require.NoError(t, electors[1].Stop(context.Background()))
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&elected[0]))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&elected[1]))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&revoked[1]))
+}
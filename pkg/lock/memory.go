@@ -0,0 +1,79 @@
+package lock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// NewMemoryLocker 创建进程内锁，行为与 Redis 实现一致，用于单元测试和单机部署
+func NewMemoryLocker() Locker {
+	return &locker{backend: &memoryBackend{entries: make(map[string]memoryEntry)}}
+}
+
+type memoryEntry struct {
+	token    string
+	expireAt time.Time
+}
+
+type memoryBackend struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+// get 返回未过期的锁记录，顺带清理过期记录
+func (b *memoryBackend) get(key string) (memoryEntry, bool) {
+	e, ok := b.entries[key]
+	if ok && !time.Now().Before(e.expireAt) {
+		delete(b.entries, key)
+		return memoryEntry{}, false
+	}
+	return e, ok
+}
+
+func (b *memoryBackend) acquire(_ context.Context, key, token string, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if e, ok := b.get(key); ok && e.token != token {
+		return false, nil
+	}
+	b.entries[key] = memoryEntry{token: token, expireAt: time.Now().Add(ttl)}
+	return true, nil
+}
+
+func (b *memoryBackend) refresh(_ context.Context, key, token string, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e, ok := b.get(key)
+	if !ok || e.token != token {
+		return false, nil
+	}
+	e.expireAt = time.Now().Add(ttl)
+	b.entries[key] = e
+	return true, nil
+}
+
+func (b *memoryBackend) release(_ context.Context, key, token string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e, ok := b.get(key)
+	if !ok || e.token != token {
+		return false, nil
+	}
+	delete(b.entries, key)
+	return true, nil
+}
+
+func (b *memoryBackend) pttl(_ context.Context, key, token string) (time.Duration, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e, ok := b.get(key)
+	if !ok || e.token != token {
+		return 0, false, nil
+	}
+	return time.Until(e.expireAt), true, nil
+}
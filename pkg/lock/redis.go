@@ -0,0 +1,96 @@
+package lock
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// DefaultKeyPrefix Redis 中锁的默认键前缀
+const DefaultKeyPrefix = "lock:"
+
+var (
+	// 锁空闲时获取；已由同一 token 持有时视为重入，重置有效期
+	acquireScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return 1
+end
+return 0`)
+
+	// 仅当值等于 token 时续期
+	refreshScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+	// 仅当值等于 token 时删除
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+	// 仅当值等于 token 时返回剩余毫秒数，否则返回 -3
+	pttlScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PTTL", KEYS[1])
+end
+return -3`)
+)
+
+// NewRedisLocker 基于 Redis 客户端创建分布式锁
+//
+//	locker := lock.NewRedisLocker(database.GetRedis().GetClient())
+func NewRedisLocker(client redis.UniversalClient) Locker {
+	return NewRedisLockerWithPrefix(client, DefaultKeyPrefix)
+}
+
+// NewRedisLockerWithPrefix 使用自定义键前缀创建分布式锁
+func NewRedisLockerWithPrefix(client redis.UniversalClient, prefix string) Locker {
+	return &locker{backend: &redisBackend{client: client}, prefix: prefix}
+}
+
+type redisBackend struct {
+	client redis.UniversalClient
+}
+
+func (b *redisBackend) acquire(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	n, err := acquireScript.Run(ctx, b.client, []string{key}, token, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (b *redisBackend) refresh(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	n, err := refreshScript.Run(ctx, b.client, []string{key}, token, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (b *redisBackend) release(ctx context.Context, key, token string) (bool, error) {
+	n, err := releaseScript.Run(ctx, b.client, []string{key}, token).Int64()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (b *redisBackend) pttl(ctx context.Context, key, token string) (time.Duration, bool, error) {
+	n, err := pttlScript.Run(ctx, b.client, []string{key}, token).Int64()
+	if err != nil {
+		return 0, false, err
+	}
+	if n < 0 {
+		// -3: 不是持有者；-2: 键不存在
+		return 0, false, nil
+	}
+	return time.Duration(n) * time.Millisecond, true, nil
+}
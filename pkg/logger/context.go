@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 上下文 logger 的字段名
+const (
+	FieldRequestID = "request_id"
+	FieldClientIP  = "client_ip"
+	FieldMethod    = "method"
+	FieldRoute     = "route"
+	FieldUserID    = "user_id"
+	FieldTraceID   = "trace_id"
+	FieldSpanID    = "span_id"
+)
+
+type ctxKey struct{}
+
+// ctxLogger 同时保存 Sugar，避免每次 FromContext 都创建
+type ctxLogger struct {
+	logger *zap.Logger
+	sugar  *zap.SugaredLogger
+}
+
+// NewContext 返回携带子 logger 的 ctx，fields 追加在 ctx 中已有的字段之后
+//
+//	ctx = logger.NewContext(ctx, zap.String(logger.FieldRequestID, id))
+//	logger.FromContext(ctx).Infof("order %d created", id) // 自动带上 request_id
+func NewContext(ctx context.Context, fields ...zap.Field) context.Context {
+	l := loggerFrom(ctx).With(fields...)
+	return context.WithValue(ctx, ctxKey{}, &ctxLogger{logger: l, sugar: l.Sugar()})
+}
+
+// NamedContext 返回携带命名子 logger 的 ctx，名称可通过 SetLoggerLevel 单独调整级别
+func NamedContext(ctx context.Context, name string) context.Context {
+	l := loggerFrom(ctx).Named(name)
+	return context.WithValue(ctx, ctxKey{}, &ctxLogger{logger: l, sugar: l.Sugar()})
+}
+
+// FromContext 返回 ctx 中的 logger，未携带时返回全局 Sugar
+func FromContext(ctx context.Context) *zap.SugaredLogger {
+	if ctx != nil {
+		if l, ok := ctx.Value(ctxKey{}).(*ctxLogger); ok {
+			return l.sugar
+		}
+	}
+	if Sugar == nil {
+		return zap.NewNop().Sugar()
+	}
+	return Sugar
+}
+
+// FromGin 返回当前请求的 logger，字段由中间件写入
+func FromGin(c *gin.Context) *zap.SugaredLogger {
+	if c == nil || c.Request == nil {
+		return FromContext(context.Background())
+	}
+	return FromContext(c.Request.Context())
+}
+
+// WithGin 为当前请求的 logger 追加字段，之后的 FromGin 与 FromContext(c.Request.Context()) 都会带上
+func WithGin(c *gin.Context, fields ...zap.Field) {
+	if c == nil || c.Request == nil {
+		return
+	}
+	c.Request = c.Request.WithContext(NewContext(c.Request.Context(), fields...))
+}
+
+// TraceFields 从 W3C traceparent（00-<trace-id>-<span-id>-<flags>）中提取 trace_id 与 span_id 字段，格式不合法时返回 nil
+func TraceFields(traceparent string) []zap.Field {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 || !isHex(parts[1]) || !isHex(parts[2]) {
+		return nil
+	}
+	return []zap.Field{zap.String(FieldTraceID, parts[1]), zap.String(FieldSpanID, parts[2])}
+}
+
+func loggerFrom(ctx context.Context) *zap.Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(ctxKey{}).(*ctxLogger); ok {
+			return l.logger
+		}
+	}
+	if Logger == nil {
+		return zap.NewNop()
+	}
+	return Logger
+}
+
+func isHex(s string) bool {
+	for _, r := range s {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+package logger
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// observeGlobal 将全局 logger 替换为内存 observer，测试结束后恢复
+func observeGlobal(t *testing.T) *observer.ObservedLogs {
+	t.Helper()
+	core, logs := observer.New(zap.DebugLevel)
+	oldLogger, oldSugar := Logger, Sugar
+	Logger = zap.New(core)
+	Sugar = Logger.Sugar()
+	t.Cleanup(func() { Logger, Sugar = oldLogger, oldSugar })
+	return logs
+}
+
+func TestNewContext(t *testing.T) {
+	logs := observeGlobal(t)
+
+	ctx := NewContext(context.Background(), zap.String(FieldRequestID, "req-1"))
+	ctx = NewContext(ctx, zap.String(FieldUserID, "42"))
+	FromContext(ctx).Infow("hello", "k", "v")
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("entries = %d, want 1", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	for k, want := range map[string]string{FieldRequestID: "req-1", FieldUserID: "42", "k": "v"} {
+		if fields[k] != want {
+			t.Errorf("field %s = %v, want %s", k, fields[k], want)
+		}
+	}
+}
+
+func TestFromContext_Fallback(t *testing.T) {
+	logs := observeGlobal(t)
+
+	var nilCtx context.Context
+	FromContext(nilCtx).Info("nil ctx")
+	FromContext(context.Background()).Info("background")
+	if logs.Len() != 2 {
+		t.Errorf("entries = %d, want 2", logs.Len())
+	}
+
+	Logger, Sugar = nil, nil
+	FromContext(context.Background()).Info("nop") // 未初始化时不 panic
+	NewContext(context.Background(), zap.String("k", "v"))
+}
+
+func TestFromGinAndWithGin(t *testing.T) {
+	logs := observeGlobal(t)
+	gin.SetMode(gin.TestMode)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/users/1", nil)
+	WithGin(c, zap.String(FieldRequestID, "req-2"))
+	WithGin(c, zap.String(FieldUserID, "7"))
+	FromGin(c).Info("from gin")
+	FromContext(c.Request.Context()).Info("from request ctx")
+
+	for _, e := range logs.All() {
+		fields := e.ContextMap()
+		if fields[FieldRequestID] != "req-2" || fields[FieldUserID] != "7" {
+			t.Errorf("%q fields = %v", e.Message, fields)
+		}
+	}
+	if logs.Len() != 2 {
+		t.Errorf("entries = %d, want 2", logs.Len())
+	}
+
+	// 没有 Request 时不 panic
+	empty, _ := gin.CreateTestContext(httptest.NewRecorder())
+	WithGin(empty, zap.String("k", "v"))
+	FromGin(empty).Info("empty")
+	FromGin(nil).Info("nil")
+}
+
+func TestTraceFields(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		wantTrace   string
+		wantSpan    string
+	}{
+		{"valid", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"},
+		{"empty", "", "", ""},
+		{"wrong parts", "00-4bf92f3577b34da6a3ce929d0e0e4736-01", "", ""},
+		{"short trace id", "00-4bf92f35-00f067aa0ba902b7-01", "", ""},
+		{"uppercase", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := TraceFields(tt.traceparent)
+			if tt.wantTrace == "" {
+				if fields != nil {
+					t.Errorf("TraceFields(%q) = %v, want nil", tt.traceparent, fields)
+				}
+				return
+			}
+			if len(fields) != 2 || fields[0].String != tt.wantTrace || fields[1].String != tt.wantSpan {
+				t.Errorf("TraceFields(%q) = %v", tt.traceparent, fields)
+			}
+		})
+	}
+}
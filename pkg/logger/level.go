@@ -0,0 +1,296 @@
+package logger
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LevelState 某个 logger 的运行时级别
+type LevelState struct {
+	Level string `json:"level"`
+	// RevertAt 自动恢复的时间，为空表示不自动恢复
+	RevertAt *time.Time `json:"revert_at,omitempty"`
+}
+
+// LevelSnapshot 运行时级别快照
+type LevelSnapshot struct {
+	// Default 配置文件中的级别，SIGUSR1 与自动恢复都回到该级别
+	Default string     `json:"default"`
+	Global  LevelState `json:"global"`
+	// Loggers 按名称覆盖的级别，名称匹配 Named 创建的 logger 及其子 logger（如 gorm 匹配 gorm.migrate）
+	Loggers map[string]LevelState `json:"loggers"`
+}
+
+// levelRegistry 保存全局级别与按名称覆盖的级别
+type levelRegistry struct {
+	global zap.AtomicLevel
+	// floor 全局与所有覆盖级别中的最低值，用于快速判断 Enabled
+	floor zap.AtomicLevel
+
+	mu        sync.RWMutex
+	base      zapcore.Level
+	overrides map[string]zapcore.Level
+	timers    map[string]*time.Timer
+	revertAt  map[string]time.Time
+}
+
+func newLevelRegistry(base zapcore.Level) *levelRegistry {
+	return &levelRegistry{
+		global:    zap.NewAtomicLevelAt(base),
+		floor:     zap.NewAtomicLevelAt(base),
+		base:      base,
+		overrides: make(map[string]zapcore.Level),
+		timers:    make(map[string]*time.Timer),
+		revertAt:  make(map[string]time.Time),
+	}
+}
+
+// levelFor 返回名称为 name 的 logger 的生效级别：先精确匹配，再逐级匹配父名称，最后使用全局级别
+func (r *levelRegistry) levelFor(name string) zapcore.Level {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for len(r.overrides) > 0 && name != "" {
+		if l, ok := r.overrides[name]; ok {
+			return l
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return r.global.Level()
+}
+
+// set 设置级别，name 为空表示全局；revertAfter > 0 时到期自动恢复
+func (r *levelRegistry) set(name string, level zapcore.Level, revertAfter time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopTimerLocked(name)
+	if name == "" {
+		r.global.SetLevel(level)
+	} else {
+		r.overrides[name] = level
+	}
+	if revertAfter > 0 {
+		r.revertAt[name] = time.Now().Add(revertAfter)
+		r.timers[name] = time.AfterFunc(revertAfter, func() { r.revert(name, level) })
+	}
+	r.updateFloorLocked()
+}
+
+// reset 恢复全局级别为配置值，或删除名称的覆盖级别
+func (r *levelRegistry) reset(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resetLocked(name)
+}
+
+// revert 定时器到期时恢复，级别已被再次修改时不处理
+func (r *levelRegistry) revert(name string, level zapcore.Level) {
+	r.mu.Lock()
+	if _, ok := r.timers[name]; !ok {
+		r.mu.Unlock()
+		return
+	}
+	if current, ok := r.levelLocked(name); !ok || current != level {
+		r.mu.Unlock()
+		return
+	}
+	r.resetLocked(name)
+	r.mu.Unlock()
+
+	// 写日志时会读取级别，不能持有锁
+	Named("logger").Infof("\t[logger] %s level reverted, now %s", levelName(name), r.levelFor(name))
+}
+
+func (r *levelRegistry) resetLocked(name string) {
+	r.stopTimerLocked(name)
+	if name == "" {
+		r.global.SetLevel(r.base)
+	} else {
+		delete(r.overrides, name)
+	}
+	r.updateFloorLocked()
+}
+
+func (r *levelRegistry) levelLocked(name string) (zapcore.Level, bool) {
+	if name == "" {
+		return r.global.Level(), true
+	}
+	l, ok := r.overrides[name]
+	return l, ok
+}
+
+func (r *levelRegistry) stopTimerLocked(name string) {
+	if t, ok := r.timers[name]; ok {
+		t.Stop()
+		delete(r.timers, name)
+		delete(r.revertAt, name)
+	}
+}
+
+func (r *levelRegistry) updateFloorLocked() {
+	floor := r.global.Level()
+	for _, l := range r.overrides {
+		if l < floor {
+			floor = l
+		}
+	}
+	r.floor.SetLevel(floor)
+}
+
+// toggleDebug 全局级别不是 debug 时切换到 debug，否则恢复配置值
+func (r *levelRegistry) toggleDebug() zapcore.Level {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.global.Level() == zap.DebugLevel {
+		r.resetLocked("")
+	} else {
+		r.stopTimerLocked("")
+		r.global.SetLevel(zap.DebugLevel)
+		r.updateFloorLocked()
+	}
+	return r.global.Level()
+}
+
+func (r *levelRegistry) snapshot() LevelSnapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s := LevelSnapshot{
+		Default: r.base.String(),
+		Global:  r.stateLocked("", r.global.Level()),
+		Loggers: make(map[string]LevelState, len(r.overrides)),
+	}
+	for name, l := range r.overrides {
+		s.Loggers[name] = r.stateLocked(name, l)
+	}
+	return s
+}
+
+func (r *levelRegistry) stateLocked(name string, l zapcore.Level) LevelState {
+	s := LevelState{Level: l.String()}
+	if t, ok := r.revertAt[name]; ok {
+		s.RevertAt = &t
+	}
+	return s
+}
+
+// stop 停止所有自动恢复定时器
+func (r *levelRegistry) stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name := range r.timers {
+		r.stopTimerLocked(name)
+	}
+}
+
+func levelName(name string) string {
+	if name == "" {
+		return "global"
+	}
+	return name
+}
+
+// levelCore 按 logger 名称应用运行时级别
+type levelCore struct {
+	zapcore.Core
+	reg *levelRegistry
+}
+
+func (c *levelCore) Enabled(l zapcore.Level) bool {
+	return c.reg.floor.Enabled(l) && c.Core.Enabled(l)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), reg: c.reg}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level < c.reg.levelFor(ent.LoggerName) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// currentLevels 返回全局 Logger 的级别注册表，未初始化时返回 nil
+func currentLevels() *levelRegistry {
+	mu.Lock()
+	defer mu.Unlock()
+	return levels
+}
+
+// GetLevel 返回全局级别
+func GetLevel() zapcore.Level {
+	if r := currentLevels(); r != nil {
+		return r.global.Level()
+	}
+	return zap.InfoLevel
+}
+
+// SetLevel 修改全局级别，revertAfter > 0 时到期自动恢复为配置值；
+// 只影响未单独配置 StdoutLevel / FileLevel 的输出
+func SetLevel(level zapcore.Level, revertAfter time.Duration) {
+	if r := currentLevels(); r != nil {
+		r.set("", level, revertAfter)
+	}
+}
+
+// SetLoggerLevel 修改名称为 name（及其子 logger）的级别，revertAfter > 0 时到期自动删除：
+//
+//	logger.SetLoggerLevel("gorm", zap.DebugLevel, 10*time.Minute) // 10 分钟内输出所有 SQL
+func SetLoggerLevel(name string, level zapcore.Level, revertAfter time.Duration) {
+	if r := currentLevels(); r != nil {
+		r.set(name, level, revertAfter)
+	}
+}
+
+// ResetLevel 恢复全局级别为配置值
+func ResetLevel() {
+	if r := currentLevels(); r != nil {
+		r.reset("")
+	}
+}
+
+// ResetLoggerLevel 删除名称的覆盖级别，之后使用全局级别
+func ResetLoggerLevel(name string) {
+	if r := currentLevels(); r != nil {
+		r.reset(name)
+	}
+}
+
+// LoggerLevel 返回名称为 name 的 logger 的生效级别
+func LoggerLevel(name string) zapcore.Level {
+	if r := currentLevels(); r != nil {
+		return r.levelFor(name)
+	}
+	return zap.InfoLevel
+}
+
+// ToggleDebug 在 debug 与配置级别之间切换全局级别，返回切换后的级别
+func ToggleDebug() zapcore.Level {
+	if r := currentLevels(); r != nil {
+		return r.toggleDebug()
+	}
+	return zap.InfoLevel
+}
+
+// GetLevels 返回运行时级别快照
+func GetLevels() LevelSnapshot {
+	if r := currentLevels(); r != nil {
+		return r.snapshot()
+	}
+	return LevelSnapshot{Loggers: map[string]LevelState{}}
+}
+
+// Named 返回全局 Sugar 的命名子 logger，名称可通过 SetLoggerLevel 单独调整级别
+func Named(name string) *zap.SugaredLogger {
+	if Sugar == nil {
+		return zap.NewNop().Sugar()
+	}
+	return Sugar.Named(name)
+}
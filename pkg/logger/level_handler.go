@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// LevelRequest PUT 请求体
+type LevelRequest struct {
+	// Logger logger 名称，为空表示全局
+	Logger string `json:"logger"`
+	// Level 新级别；为空或 reset 时恢复全局级别为配置值，或删除名称的覆盖级别
+	Level string `json:"level"`
+	// Duration 自动恢复时间，如 10m，为空表示不自动恢复
+	Duration string `json:"duration"`
+}
+
+// LevelHandler 查看与修改运行时日志级别：
+//
+//	GET  /debug/log/level                返回 LevelSnapshot
+//	GET  /debug/log/level?logger=gorm    返回名称为 gorm 的 logger 的生效级别
+//	PUT  /debug/log/level                {"logger": "gorm", "level": "debug", "duration": "10m"}
+//
+// 本身不做认证，只能挂载在需要认证的管理路由上，不能暴露到公网
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if name := r.URL.Query().Get("logger"); name != "" {
+				writeLevelJSON(w, http.StatusOK, map[string]string{"logger": name, "level": LoggerLevel(name).String()})
+				return
+			}
+			writeLevelJSON(w, http.StatusOK, GetLevels())
+		case http.MethodPut:
+			var req LevelRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeLevelError(w, fmt.Errorf("invalid request body: %w", err))
+				return
+			}
+			if err := applyLevelRequest(req); err != nil {
+				writeLevelError(w, err)
+				return
+			}
+			writeLevelJSON(w, http.StatusOK, GetLevels())
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			writeLevelJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		}
+	})
+}
+
+func applyLevelRequest(req LevelRequest) error {
+	name := strings.TrimSpace(req.Logger)
+	if req.Level == "" || strings.EqualFold(req.Level, "reset") {
+		if name == "" {
+			ResetLevel()
+		} else {
+			ResetLoggerLevel(name)
+		}
+		Named("logger").Infof("\t[logger] %s level reset", levelName(name))
+		return nil
+	}
+
+	level, err := ParseLevel(req.Level)
+	if err != nil {
+		return err
+	}
+	var revertAfter time.Duration
+	if req.Duration != "" {
+		if revertAfter, err = time.ParseDuration(req.Duration); err != nil || revertAfter <= 0 {
+			return fmt.Errorf("invalid duration: %s", req.Duration)
+		}
+	}
+	if name == "" {
+		SetLevel(level, revertAfter)
+	} else {
+		SetLoggerLevel(name, level, revertAfter)
+	}
+	Named("logger").Infof("\t[logger] %s level set to %s (revert after %v)", levelName(name), level, revertAfter)
+	return nil
+}
+
+func writeLevelError(w http.ResponseWriter, err error) {
+	writeLevelJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+}
+
+func writeLevelJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
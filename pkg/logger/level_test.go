@@ -0,0 +1,214 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// observeLevels 使用内存 observer 创建带运行时级别的全局 logger，测试结束后恢复
+func observeLevels(t *testing.T, base zapcore.Level) *observer.ObservedLogs {
+	t.Helper()
+	core, logs := observer.New(zap.DebugLevel)
+	reg := newLevelRegistry(base)
+
+	mu.Lock()
+	oldLogger, oldSugar, oldLevels := Logger, Sugar, levels
+	Logger = zap.New(&levelCore{Core: core, reg: reg})
+	Sugar = Logger.Sugar()
+	levels = reg
+	mu.Unlock()
+
+	t.Cleanup(func() {
+		reg.stop()
+		mu.Lock()
+		Logger, Sugar, levels = oldLogger, oldSugar, oldLevels
+		mu.Unlock()
+	})
+	return logs
+}
+
+func messages(logs *observer.ObservedLogs) string {
+	var msgs []string
+	for _, e := range logs.TakeAll() {
+		msgs = append(msgs, e.Message)
+	}
+	return strings.Join(msgs, ",")
+}
+
+func TestSetLevel(t *testing.T) {
+	logs := observeLevels(t, zap.InfoLevel)
+
+	Sugar.Debug("hidden")
+	SetLevel(zap.DebugLevel, 0)
+	Sugar.Debug("shown")
+	if got := messages(logs); got != "shown" {
+		t.Errorf("messages = %q", got)
+	}
+
+	ResetLevel()
+	if GetLevel() != zap.InfoLevel {
+		t.Errorf("level after reset = %s", GetLevel())
+	}
+}
+
+func TestSetLoggerLevel(t *testing.T) {
+	logs := observeLevels(t, zap.WarnLevel)
+
+	SetLoggerLevel("gorm", zap.DebugLevel, 0)
+	Named("gorm").Debug("gorm debug")
+	Named("gorm").Named("migrate").Debug("child debug")
+	Named("rabbitmq").Info("rabbitmq info")
+	Sugar.Info("root info")
+	if got := messages(logs); got != "gorm debug,child debug" {
+		t.Errorf("messages = %q", got)
+	}
+
+	if l := LoggerLevel("gorm.migrate"); l != zap.DebugLevel {
+		t.Errorf("LoggerLevel(gorm.migrate) = %s", l)
+	}
+	if l := LoggerLevel("gormx"); l != zap.WarnLevel {
+		t.Errorf("LoggerLevel(gormx) = %s", l)
+	}
+
+	// 覆盖级别也可以高于全局级别
+	SetLoggerLevel("rabbitmq", zap.ErrorLevel, 0)
+	Named("rabbitmq").Warn("rabbitmq warn")
+	Sugar.Warn("root warn")
+	if got := messages(logs); got != "root warn" {
+		t.Errorf("messages = %q", got)
+	}
+
+	ResetLoggerLevel("gorm")
+	Named("gorm").Debug("gorm debug")
+	if got := messages(logs); got != "" {
+		t.Errorf("messages after reset = %q", got)
+	}
+}
+
+func TestSetLevel_AutoRevert(t *testing.T) {
+	observeLevels(t, zap.InfoLevel)
+
+	SetLevel(zap.DebugLevel, 20*time.Millisecond)
+	SetLoggerLevel("gorm", zap.DebugLevel, 20*time.Millisecond)
+	s := GetLevels()
+	if s.Global.Level != "debug" || s.Global.RevertAt == nil || s.Loggers["gorm"].RevertAt == nil {
+		t.Fatalf("snapshot = %+v", s)
+	}
+
+	// 再次修改会取消之前的定时器
+	SetLoggerLevel("gorm", zap.ErrorLevel, 0)
+
+	deadline := time.Now().Add(time.Second)
+	for GetLevel() != zap.InfoLevel && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if GetLevel() != zap.InfoLevel {
+		t.Errorf("global level not reverted: %s", GetLevel())
+	}
+	time.Sleep(30 * time.Millisecond)
+	if l := LoggerLevel("gorm"); l != zap.ErrorLevel {
+		t.Errorf("gorm level = %s, want error", l)
+	}
+}
+
+func TestToggleDebug(t *testing.T) {
+	observeLevels(t, zap.WarnLevel)
+
+	if l := ToggleDebug(); l != zap.DebugLevel {
+		t.Errorf("first toggle = %s", l)
+	}
+	if l := ToggleDebug(); l != zap.WarnLevel {
+		t.Errorf("second toggle = %s", l)
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	observeLevels(t, zap.InfoLevel)
+	h := LevelHandler()
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, target, bytes.NewBufferString(body)))
+		return w
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"global", `{"level":"debug"}`, http.StatusOK},
+		{"named with duration", `{"logger":"gorm","level":"WARN","duration":"10m"}`, http.StatusOK},
+		{"invalid level", `{"level":"verbose"}`, http.StatusBadRequest},
+		{"invalid duration", `{"level":"debug","duration":"soon"}`, http.StatusBadRequest},
+		{"negative duration", `{"level":"debug","duration":"-1m"}`, http.StatusBadRequest},
+		{"invalid body", `{`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := do(http.MethodPut, "/debug/log/level", tt.body); w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+
+	var s LevelSnapshot
+	w := do(http.MethodGet, "/debug/log/level", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &s); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if s.Default != "info" || s.Global.Level != "debug" || s.Loggers["gorm"].Level != "warn" || s.Loggers["gorm"].RevertAt == nil {
+		t.Errorf("snapshot = %s", w.Body)
+	}
+
+	w = do(http.MethodGet, "/debug/log/level?logger=gorm.migrate", "")
+	if !strings.Contains(w.Body.String(), `"level":"warn"`) {
+		t.Errorf("named level = %s", w.Body)
+	}
+
+	do(http.MethodPut, "/debug/log/level", `{"logger":"gorm","level":"reset"}`)
+	do(http.MethodPut, "/debug/log/level", `{"level":""}`)
+	if s := GetLevels(); s.Global.Level != "info" || len(s.Loggers) != 0 {
+		t.Errorf("after reset = %+v", s)
+	}
+
+	if w := do(http.MethodPost, "/debug/log/level", ""); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d", w.Code)
+	}
+}
+
+func TestNew_RuntimeLevelOnlyAffectsDefaultOutputs(t *testing.T) {
+	dir := t.TempDir()
+	l, reg, closeFn, err := build(Options{Dir: dir, Level: "info", StdoutLevel: LevelOff, ErrorFile: true})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	defer closeFn()
+	defer reg.stop()
+
+	l.Debug("debug before")
+	reg.set("", zap.ErrorLevel, 0)
+	l.Warn("warn hidden")
+	l.Error("error shown")
+	reg.set("", zap.DebugLevel, 0)
+	l.Debug("debug after")
+
+	all := readLogFile(t, dir, ".log")
+	if strings.Contains(all, "debug before") || strings.Contains(all, "warn hidden") ||
+		!strings.Contains(all, "error shown") || !strings.Contains(all, "debug after") {
+		t.Errorf("unexpected log file content: %q", all)
+	}
+	// 错误日志文件固定为 error 级别
+	if errs := readLogFile(t, dir, ".error.log"); strings.Contains(errs, "debug after") || !strings.Contains(errs, "error shown") {
+		t.Errorf("unexpected error file content: %q", errs)
+	}
+}
@@ -0,0 +1,258 @@
+package logger
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var logfmtPool = buffer.NewPool()
+
+// logfmtEncoder 输出 key=value 形式的日志行，嵌套对象与数组按 JSON 编码为字符串值
+type logfmtEncoder struct {
+	cfg *zapcore.EncoderConfig
+	buf *buffer.Buffer
+	// ns OpenNamespace 打开的命名空间，作为后续字段名的前缀
+	ns string
+}
+
+// NewLogfmtEncoder 创建 logfmt 编码器
+func NewLogfmtEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &logfmtEncoder{cfg: &cfg, buf: logfmtPool.Get()}
+}
+
+func (e *logfmtEncoder) Clone() zapcore.Encoder {
+	c := &logfmtEncoder{cfg: e.cfg, buf: logfmtPool.Get(), ns: e.ns}
+	_, _ = c.buf.Write(e.buf.Bytes())
+	return c
+}
+
+func (e *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := &logfmtEncoder{cfg: e.cfg, buf: logfmtPool.Get()}
+	cfg := e.cfg
+
+	if cfg.TimeKey != "" && cfg.EncodeTime != nil {
+		final.addString(cfg.TimeKey, primitive(func(a zapcore.PrimitiveArrayEncoder) { cfg.EncodeTime(ent.Time, a) }))
+	}
+	if cfg.LevelKey != "" && cfg.EncodeLevel != nil {
+		final.addString(cfg.LevelKey, primitive(func(a zapcore.PrimitiveArrayEncoder) { cfg.EncodeLevel(ent.Level, a) }))
+	}
+	if cfg.NameKey != "" && ent.LoggerName != "" {
+		final.addString(cfg.NameKey, ent.LoggerName)
+	}
+	if cfg.CallerKey != "" && ent.Caller.Defined && cfg.EncodeCaller != nil {
+		final.addString(cfg.CallerKey, primitive(func(a zapcore.PrimitiveArrayEncoder) { cfg.EncodeCaller(ent.Caller, a) }))
+	}
+	if cfg.MessageKey != "" {
+		final.addString(cfg.MessageKey, ent.Message)
+	}
+
+	if e.buf.Len() > 0 {
+		if final.buf.Len() > 0 {
+			final.buf.AppendByte(' ')
+		}
+		_, _ = final.buf.Write(e.buf.Bytes())
+	}
+	final.ns = e.ns
+	for _, f := range fields {
+		f.AddTo(final)
+	}
+	final.ns = ""
+
+	if cfg.StacktraceKey != "" && ent.Stack != "" {
+		final.addString(cfg.StacktraceKey, ent.Stack)
+	}
+	if cfg.LineEnding != "" {
+		final.buf.AppendString(cfg.LineEnding)
+	} else {
+		final.buf.AppendString(zapcore.DefaultLineEnding)
+	}
+	return final.buf, nil
+}
+
+// key 写入分隔符与字段名
+func (e *logfmtEncoder) key(k string) {
+	if e.buf.Len() > 0 {
+		e.buf.AppendByte(' ')
+	}
+	e.buf.AppendString(e.ns)
+	e.buf.AppendString(k)
+	e.buf.AppendByte('=')
+}
+
+func (e *logfmtEncoder) addString(k, v string) {
+	e.key(k)
+	if needsQuote(v) {
+		e.buf.AppendString(strconv.Quote(v))
+	} else {
+		e.buf.AppendString(v)
+	}
+}
+
+func (e *logfmtEncoder) addJSON(k string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	e.addString(k, string(data))
+	return nil
+}
+
+func (e *logfmtEncoder) AddArray(k string, v zapcore.ArrayMarshaler) error {
+	m := zapcore.NewMapObjectEncoder()
+	if err := m.AddArray(k, v); err != nil {
+		return err
+	}
+	return e.addJSON(k, m.Fields[k])
+}
+
+func (e *logfmtEncoder) AddObject(k string, v zapcore.ObjectMarshaler) error {
+	m := zapcore.NewMapObjectEncoder()
+	if err := v.MarshalLogObject(m); err != nil {
+		return err
+	}
+	return e.addJSON(k, m.Fields)
+}
+
+func (e *logfmtEncoder) AddReflected(k string, v interface{}) error {
+	return e.addJSON(k, v)
+}
+
+func (e *logfmtEncoder) OpenNamespace(k string) {
+	e.ns += k + "."
+}
+
+func (e *logfmtEncoder) AddBinary(k string, v []byte) {
+	e.addString(k, base64.StdEncoding.EncodeToString(v))
+}
+
+func (e *logfmtEncoder) AddByteString(k string, v []byte) { e.addString(k, string(v)) }
+func (e *logfmtEncoder) AddString(k, v string)            { e.addString(k, v) }
+
+func (e *logfmtEncoder) AddBool(k string, v bool) {
+	e.key(k)
+	e.buf.AppendBool(v)
+}
+
+func (e *logfmtEncoder) AddComplex128(k string, v complex128) {
+	e.key(k)
+	e.buf.AppendString(strconv.FormatComplex(v, 'g', -1, 128))
+}
+
+func (e *logfmtEncoder) AddComplex64(k string, v complex64) {
+	e.key(k)
+	e.buf.AppendString(strconv.FormatComplex(complex128(v), 'g', -1, 64))
+}
+
+func (e *logfmtEncoder) AddDuration(k string, v time.Duration) {
+	if e.cfg.EncodeDuration == nil {
+		e.addString(k, v.String())
+		return
+	}
+	e.addString(k, primitive(func(a zapcore.PrimitiveArrayEncoder) { e.cfg.EncodeDuration(v, a) }))
+}
+
+func (e *logfmtEncoder) AddTime(k string, v time.Time) {
+	if e.cfg.EncodeTime == nil {
+		e.addString(k, v.Format(time.RFC3339Nano))
+		return
+	}
+	e.addString(k, primitive(func(a zapcore.PrimitiveArrayEncoder) { e.cfg.EncodeTime(v, a) }))
+}
+
+func (e *logfmtEncoder) AddFloat64(k string, v float64) {
+	e.key(k)
+	e.appendFloat(v, 64)
+}
+
+func (e *logfmtEncoder) AddFloat32(k string, v float32) {
+	e.key(k)
+	e.appendFloat(float64(v), 32)
+}
+
+func (e *logfmtEncoder) appendFloat(v float64, bits int) {
+	switch {
+	case math.IsNaN(v):
+		e.buf.AppendString("NaN")
+	case math.IsInf(v, 1):
+		e.buf.AppendString("+Inf")
+	case math.IsInf(v, -1):
+		e.buf.AppendString("-Inf")
+	default:
+		e.buf.AppendFloat(v, bits)
+	}
+}
+
+func (e *logfmtEncoder) AddInt64(k string, v int64) {
+	e.key(k)
+	e.buf.AppendInt(v)
+}
+
+func (e *logfmtEncoder) AddUint64(k string, v uint64) {
+	e.key(k)
+	e.buf.AppendUint(v)
+}
+
+func (e *logfmtEncoder) AddInt(k string, v int)         { e.AddInt64(k, int64(v)) }
+func (e *logfmtEncoder) AddInt32(k string, v int32)     { e.AddInt64(k, int64(v)) }
+func (e *logfmtEncoder) AddInt16(k string, v int16)     { e.AddInt64(k, int64(v)) }
+func (e *logfmtEncoder) AddInt8(k string, v int8)       { e.AddInt64(k, int64(v)) }
+func (e *logfmtEncoder) AddUint(k string, v uint)       { e.AddUint64(k, uint64(v)) }
+func (e *logfmtEncoder) AddUint32(k string, v uint32)   { e.AddUint64(k, uint64(v)) }
+func (e *logfmtEncoder) AddUint16(k string, v uint16)   { e.AddUint64(k, uint64(v)) }
+func (e *logfmtEncoder) AddUint8(k string, v uint8)     { e.AddUint64(k, uint64(v)) }
+func (e *logfmtEncoder) AddUintptr(k string, v uintptr) { e.AddUint64(k, uint64(v)) }
+
+// needsQuote 空值、包含空白、引号、等号或控制字符的值需要加引号
+func needsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r == '"' || r == '=' || r == '\\' || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// primitive 收集 EncodeTime/EncodeLevel 等回调输出的值，多个值以逗号连接
+func primitive(fn func(zapcore.PrimitiveArrayEncoder)) string {
+	var a primitiveArray
+	fn(&a)
+	return strings.Join(a, ",")
+}
+
+type primitiveArray []string
+
+func (a *primitiveArray) AppendBool(v bool)         { *a = append(*a, strconv.FormatBool(v)) }
+func (a *primitiveArray) AppendByteString(v []byte) { *a = append(*a, string(v)) }
+func (a *primitiveArray) AppendComplex128(v complex128) {
+	*a = append(*a, strconv.FormatComplex(v, 'g', -1, 128))
+}
+func (a *primitiveArray) AppendComplex64(v complex64) { a.AppendComplex128(complex128(v)) }
+func (a *primitiveArray) AppendFloat64(v float64) {
+	*a = append(*a, strconv.FormatFloat(v, 'g', -1, 64))
+}
+func (a *primitiveArray) AppendFloat32(v float32) {
+	*a = append(*a, strconv.FormatFloat(float64(v), 'g', -1, 32))
+}
+func (a *primitiveArray) AppendInt(v int)         { a.AppendInt64(int64(v)) }
+func (a *primitiveArray) AppendInt64(v int64)     { *a = append(*a, strconv.FormatInt(v, 10)) }
+func (a *primitiveArray) AppendInt32(v int32)     { a.AppendInt64(int64(v)) }
+func (a *primitiveArray) AppendInt16(v int16)     { a.AppendInt64(int64(v)) }
+func (a *primitiveArray) AppendInt8(v int8)       { a.AppendInt64(int64(v)) }
+func (a *primitiveArray) AppendString(v string)   { *a = append(*a, v) }
+func (a *primitiveArray) AppendUint(v uint)       { a.AppendUint64(uint64(v)) }
+func (a *primitiveArray) AppendUint64(v uint64)   { *a = append(*a, strconv.FormatUint(v, 10)) }
+func (a *primitiveArray) AppendUint32(v uint32)   { a.AppendUint64(uint64(v)) }
+func (a *primitiveArray) AppendUint16(v uint16)   { a.AppendUint64(uint64(v)) }
+func (a *primitiveArray) AppendUint8(v uint8)     { a.AppendUint64(uint64(v)) }
+func (a *primitiveArray) AppendUintptr(v uintptr) { a.AppendUint64(uint64(v)) }
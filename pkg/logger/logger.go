@@ -1,61 +1,308 @@
 package logger
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 var Logger *zap.Logger
 var Sugar *zap.SugaredLogger
 
-func InitLogger(logDir string, logLevel string) {
+var (
+	mu sync.Mutex
+	// closeFiles 关闭全局 Logger 使用的日志文件
+	closeFiles func() error
+	// levels 全局 Logger 的运行时级别
+	levels *levelRegistry
+)
+
+// 日志输出格式
+const (
+	FormatConsole = "console"
+	FormatJSON    = "json"
+	FormatLogfmt  = "logfmt"
+)
+
+// LevelOff 关闭某个输出（仅用于 Options.StdoutLevel / Options.FileLevel）
+const LevelOff = "off"
+
+// Levels 支持的日志级别，配置校验与 InitLogger 共用
+var Levels = []string{"debug", "info", "warn", "error", "fatal"}
+
+// Formats 支持的输出格式
+var Formats = []string{FormatConsole, FormatJSON, FormatLogfmt}
+
+// Options 日志配置
+type Options struct {
+	// Dir 日志目录，为空时只输出到 stdout
+	Dir string
+	// Level 默认级别
+	Level string
+	// Format 输出格式：console、json、logfmt，默认 console
+	Format string
+	// StdoutLevel stdout 的级别，为空时同 Level 并随 SetLevel 调整，off 关闭
+	StdoutLevel string
+	// FileLevel 日志文件的级别，为空时同 Level 并随 SetLevel 调整，off 关闭
+	FileLevel string
+	// ErrorFile 另外将 error 及以上级别写入单独的文件，文件名在 Pattern 的扩展名前加 .error
+	ErrorFile bool
+	// Pattern 文件名模式，{date} 替换为日期，默认 {date}.log
+	Pattern string
+	// MaxSize 单个文件最大大小（MB），默认 100
+	MaxSize int
+	// MaxBackups 保留旧文件数量，0 表示不限制
+	MaxBackups int
+	// MaxAge 保留天数，0 表示不限制
+	MaxAge int
+	// MaxTotalSize 日志文件总大小上限（MB），0 表示不限制
+	MaxTotalSize int
+	// Compress 压缩旧文件
+	Compress bool
+	// Sinks 额外的输出：syslog、单独的文件、HTTP 推送
+	Sinks []SinkOptions
+}
 
-	w := zapcore.AddSync(&lumberjack.Logger{
-		Filename:   logDir + time.Now().Format("2006-01-02") + ".log",
-		MaxSize:    500,
-		MaxBackups: 3,
-		MaxAge:     28,
-	})
+func (o *Options) setDefaults() {
+	if o.Format == "" {
+		o.Format = FormatConsole
+	}
+	if o.Pattern == "" {
+		o.Pattern = DefaultPattern
+	}
+	if o.MaxSize <= 0 {
+		o.MaxSize = 100
+	}
+}
 
-	// 判断配置文件配置的日志等级
-	var zapLevel zapcore.Level
+// rotateOptions 返回 pattern 对应的滚动文件配置
+func (o *Options) rotateOptions(pattern string) RotateOptions {
+	return RotateOptions{
+		Dir:          o.Dir,
+		Pattern:      pattern,
+		MaxSize:      o.MaxSize,
+		MaxBackups:   o.MaxBackups,
+		MaxAge:       o.MaxAge,
+		MaxTotalSize: o.MaxTotalSize,
+		Compress:     o.Compress,
+	}
+}
+
+// ErrorPattern 返回错误日志文件的文件名模式，如 {date}.log → {date}.error.log
+func ErrorPattern(pattern string) string {
+	if pattern == "" {
+		pattern = DefaultPattern
+	}
+	var ext string
+	if i := strings.Index(pattern, datePlaceholder); i >= 0 {
+		ext = filepath.Ext(pattern[i+len(datePlaceholder):])
+	}
+	return strings.TrimSuffix(pattern, ext) + ".error" + ext
+}
 
-	switch logLevel {
+// ParseLevel 解析日志级别（不区分大小写）
+func ParseLevel(level string) (zapcore.Level, error) {
+	switch strings.ToLower(level) {
 	case "debug":
-		zapLevel = zap.DebugLevel
+		return zap.DebugLevel, nil
 	case "info":
-		zapLevel = zap.InfoLevel
+		return zap.InfoLevel, nil
 	case "warn":
-		zapLevel = zap.WarnLevel
+		return zap.WarnLevel, nil
 	case "error":
-		zapLevel = zap.ErrorLevel
-	default:
-		panic("level: debug, info, warn, error")
+		return zap.ErrorLevel, nil
+	case "fatal":
+		return zap.FatalLevel, nil
+	}
+	return zap.InfoLevel, fmt.Errorf("invalid log level: %s (must be %s)", level, strings.Join(Levels, "/"))
+}
+
+// ParseFormat 校验输出格式（不区分大小写），空字符串视为 console
+func ParseFormat(format string) (string, error) {
+	f := strings.ToLower(format)
+	switch f {
+	case "":
+		return FormatConsole, nil
+	case FormatConsole, FormatJSON, FormatLogfmt:
+		return f, nil
+	}
+	return "", fmt.Errorf("invalid log format: %s (must be %s)", format, strings.Join(Formats, "/"))
+}
+
+// InitLogger 按级别初始化日志，输出到 stdout 与 logDir 下的日期文件；级别无效时 panic
+func InitLogger(logDir string, logLevel string) {
+	err := Init(Options{Dir: logDir, Level: logLevel, MaxSize: 500, MaxBackups: 3, MaxAge: 28})
+	if err != nil {
+		panic(err)
 	}
+}
 
-	core := zapcore.NewCore(
-		zapcore.NewConsoleEncoder(NewEncoderConfig()),
-		zapcore.NewMultiWriteSyncer(zapcore.AddSync(os.Stdout),
-			w),
-		zapLevel,
-	)
+// Init 按配置初始化全局 Logger 与 Sugar；重复调用时替换全局 Logger 并关闭之前的日志文件
+func Init(opts Options) error {
+	l, reg, closeFn, err := build(opts)
+	if err != nil {
+		return err
+	}
 
-	Logger = zap.New(core, zap.AddCaller())
+	mu.Lock()
+	prev, prevClose, prevLevels := Logger, closeFiles, levels
+	Logger = l
 	Sugar = Logger.Sugar()
+	closeFiles = closeFn
+	levels = reg
+	mu.Unlock()
+
+	if prevLevels != nil {
+		prevLevels.stop()
+	}
+
+	if prev != nil {
+		_ = prev.Sync()
+	}
+	if prevClose != nil {
+		return prevClose()
+	}
+	return nil
+}
+
+// Close 刷新并关闭全局 Logger 的日志文件，应用退出前调用；之后写入文件的日志被丢弃
+func Close() error {
+	mu.Lock()
+	closeFn := closeFiles
+	closeFiles = nil
+	mu.Unlock()
 
-	if logDir == "" {
-		// 输出到 stdout
-		logger, _ := zap.NewProduction()
-		Sugar = logger.Sugar()
-		return
+	if Logger != nil {
+		_ = Logger.Sync()
+	}
+	if closeFn == nil {
+		return nil
+	}
+	return closeFn()
+}
+
+// New 按配置创建 Logger，不修改全局变量；返回的 close 用于关闭日志文件
+func New(opts Options) (*zap.Logger, func() error, error) {
+	l, _, closeFn, err := build(opts)
+	return l, closeFn, err
+}
+
+// build 创建 Logger 与其运行时级别：未单独配置级别的输出经 levelCore 按运行时级别过滤，
+// 单独配置了 StdoutLevel / FileLevel 的输出使用固定级别
+func build(opts Options) (*zap.Logger, *levelRegistry, func() error, error) {
+	opts.setDefaults()
+
+	base, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	format, err := ParseFormat(opts.Format)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	encoder := NewEncoder(format)
+	reg := newLevelRegistry(base)
+
+	// dynamic 随运行时级别调整的输出，static 固定级别的输出
+	var dynamic, static []zapcore.Core
+	addCore := func(ws zapcore.WriteSyncer, level string) error {
+		if level == "" {
+			dynamic = append(dynamic, zapcore.NewCore(encoder, ws, zap.DebugLevel))
+			return nil
+		}
+		l, err := ParseLevel(level)
+		if err != nil {
+			return err
+		}
+		static = append(static, zapcore.NewCore(encoder, ws, l))
+		return nil
+	}
+
+	if opts.StdoutLevel != LevelOff {
+		if err := addCore(zapcore.Lock(os.Stdout), opts.StdoutLevel); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	var closers []func() error
+	closeFn := func() error {
+		var errs []error
+		for _, c := range closers {
+			errs = append(errs, c())
+		}
+		return errors.Join(errs...)
+	}
+
+	if opts.Dir != "" {
+		if opts.FileLevel != LevelOff {
+			if opts.FileLevel != "" {
+				if _, err := ParseLevel(opts.FileLevel); err != nil {
+					return nil, nil, nil, err
+				}
+			}
+			w, err := NewRotatingWriter(opts.rotateOptions(opts.Pattern))
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			closers = append(closers, w.Close)
+			_ = addCore(w, opts.FileLevel)
+		}
+		if opts.ErrorFile {
+			w, err := NewRotatingWriter(opts.rotateOptions(ErrorPattern(opts.Pattern)))
+			if err != nil {
+				_ = closeFn()
+				return nil, nil, nil, err
+			}
+			closers = append(closers, w.Close)
+			static = append(static, zapcore.NewCore(encoder, w, zap.ErrorLevel))
+		}
+	}
+
+	for _, s := range opts.Sinks {
+		if s.Level == LevelOff {
+			continue
+		}
+		var enab zapcore.LevelEnabler = zap.DebugLevel
+		if s.Level != "" {
+			enab, _ = ParseLevel(s.Level)
+		}
+		core, closeSink, err := newSink(&opts, s, enab)
+		if err != nil {
+			_ = closeFn()
+			return nil, nil, nil, fmt.Errorf("log sink %s: %w", s.Type, err)
+		}
+		closers = append(closers, closeSink)
+		if s.Level == "" {
+			dynamic = append(dynamic, core)
+		} else {
+			static = append(static, core)
+		}
 	}
 
-	setupLogRotation(logDir, logLevel)
+	cores := static
+	if len(dynamic) > 0 {
+		cores = append(cores, &levelCore{Core: zapcore.NewTee(dynamic...), reg: reg})
+	}
+	return zap.New(zapcore.NewTee(cores...), zap.AddCaller()), reg, closeFn, nil
+}
+
+// NewEncoder 创建指定格式的编码器，未知格式使用 console；敏感字段经 NewRedactEncoder 脱敏
+func NewEncoder(format string) zapcore.Encoder {
+	switch format {
+	case FormatJSON:
+		return NewRedactEncoder(zapcore.NewJSONEncoder(NewStructuredEncoderConfig()))
+	case FormatLogfmt:
+		return NewRedactEncoder(NewLogfmtEncoder(NewStructuredEncoderConfig()))
+	default:
+		return NewRedactEncoder(zapcore.NewConsoleEncoder(NewEncoderConfig()))
+	}
 }
 
 func NewEncoderConfig() zapcore.EncoderConfig {
@@ -75,22 +322,23 @@ func NewEncoderConfig() zapcore.EncoderConfig {
 	}
 }
 
-func TimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
-	enc.AppendString(t.Format("2006-01-02 15:04:05.000"))
-}
-
-var cronInstance *cron.Cron
-
-func setupLogRotation(logDir string, logLevel string) {
-
-	if cronInstance != nil {
-		cronInstance.Stop() // 避免重复启动
+// NewStructuredEncoderConfig json 与 logfmt 格式的编码配置，字段名便于日志平台解析
+func NewStructuredEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		TimeKey:        "time",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.RFC3339NanoTimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
 	}
+}
 
-	cronInstance = cron.New()
-	// 每天凌晨1点分割日志
-	cronInstance.AddFunc("0 0 1 * * *", func() {
-		InitLogger(logDir, logLevel)
-	})
-	cronInstance.Start()
+func TimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendString(t.Format("2006-01-02 15:04:05.000"))
 }
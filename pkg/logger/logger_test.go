@@ -1,8 +1,11 @@
 package logger
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -57,11 +60,8 @@ func TestInitLogger(t *testing.T) {
 				defer os.RemoveAll(tt.logDir)
 			}
 
-			// 停止之前的 cron 实例
-			if cronInstance != nil {
-				cronInstance.Stop()
-				cronInstance = nil
-			}
+			// 关闭之前的日志文件
+			defer Close()
 
 			InitLogger(tt.logDir, tt.logLevel)
 
@@ -92,11 +92,6 @@ func TestInitLoggerPanic(t *testing.T) {
 		if r := recover(); r == nil {
 			t.Error("Expected panic for invalid log level")
 		}
-		// 清理
-		if cronInstance != nil {
-			cronInstance.Stop()
-			cronInstance = nil
-		}
 	}()
 
 	InitLogger("testlogs/", "invalid")
@@ -189,10 +184,7 @@ func TestLogLevels(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if cronInstance != nil {
-				cronInstance.Stop()
-				cronInstance = nil
-			}
+			defer Close()
 
 			InitLogger(testDir, tt.logLevel)
 			tt.logFunc(tt.message)
@@ -206,59 +198,23 @@ func TestLogLevels(t *testing.T) {
 	}
 }
 
-func TestSetupLogRotation(t *testing.T) {
-	testDir := "testlogs/"
-	os.MkdirAll(testDir, 0755)
-	defer os.RemoveAll(testDir)
-
-	// 停止之前的 cron
-	if cronInstance != nil {
-		cronInstance.Stop()
-		cronInstance = nil
-	}
-
-	setupLogRotation(testDir, "info")
-
-	if cronInstance == nil {
-		t.Error("cronInstance should not be nil after setupLogRotation")
-	}
-
-	// 验证 cron 任务数量
-	entries := cronInstance.Entries()
-	if len(entries) != 1 {
-		t.Errorf("Expected 1 cron entry, got %d", len(entries))
-	}
-
-	// 清理
-	cronInstance.Stop()
-	cronInstance = nil
-}
-
 func TestMultipleInitLoggerCalls(t *testing.T) {
 	testDir := "testlogs/"
 	os.MkdirAll(testDir, 0755)
 	defer os.RemoveAll(testDir)
+	defer Close()
 
-	// 多次调用 InitLogger
+	// 多次调用 InitLogger，之前的日志文件被关闭，日志继续写入同一个文件
 	for i := 0; i < 3; i++ {
 		InitLogger(testDir, "info")
 		Logger.Info("test message", zap.Int("iteration", i))
 	}
 
-	// 验证只有一个 cron 实例在运行
-	if cronInstance == nil {
-		t.Error("cronInstance should exist")
-	}
-
-	entries := cronInstance.Entries()
-	if len(entries) != 1 {
-		t.Errorf("Expected 1 cron entry after multiple inits, got %d", len(entries))
-	}
-
-	// 清理
-	if cronInstance != nil {
-		cronInstance.Stop()
-		cronInstance = nil
+	content := readLogFile(t, testDir, ".log")
+	for i := 0; i < 3; i++ {
+		if !strings.Contains(content, fmt.Sprintf(`{"iteration": %d}`, i)) {
+			t.Errorf("Log file should contain iteration %d: %q", i, content)
+		}
 	}
 }
 
@@ -267,10 +223,7 @@ func TestSugarLogger(t *testing.T) {
 	os.MkdirAll(testDir, 0755)
 	defer os.RemoveAll(testDir)
 
-	if cronInstance != nil {
-		cronInstance.Stop()
-		cronInstance = nil
-	}
+	defer Close()
 
 	InitLogger(testDir, "info")
 
@@ -289,18 +242,160 @@ func TestSugarLogger(t *testing.T) {
 	}
 }
 
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		level   string
+		want    zapcore.Level
+		wantErr bool
+	}{
+		{"debug", zap.DebugLevel, false},
+		{"INFO", zap.InfoLevel, false},
+		{"warn", zap.WarnLevel, false},
+		{"error", zap.ErrorLevel, false},
+		{"fatal", zap.FatalLevel, false},
+		{"off", zap.InfoLevel, true},
+		{"", zap.InfoLevel, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			got, err := ParseLevel(tt.level)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevel(%q) error = %v, wantErr %v", tt.level, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLevel(%q) = %v, want %v", tt.level, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		check  func(t *testing.T, line string)
+	}{
+		{
+			format: FormatJSON,
+			check: func(t *testing.T, line string) {
+				var m map[string]interface{}
+				if err := json.Unmarshal([]byte(line), &m); err != nil {
+					t.Fatalf("invalid json %q: %v", line, err)
+				}
+				if m["level"] != "info" || m["msg"] != "order created" || m["order_id"] != float64(42) {
+					t.Errorf("unexpected json fields: %v", m)
+				}
+			},
+		},
+		{
+			format: FormatLogfmt,
+			check: func(t *testing.T, line string) {
+				for _, want := range []string{"level=info", `msg="order created"`, "order_id=42"} {
+					if !strings.Contains(line, want) {
+						t.Errorf("logfmt line %q should contain %s", line, want)
+					}
+				}
+			},
+		},
+		{
+			format: FormatConsole,
+			check: func(t *testing.T, line string) {
+				if !strings.Contains(line, "INFO") || !strings.Contains(line, `{"order_id": 42}`) {
+					t.Errorf("unexpected console line %q", line)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			dir := t.TempDir()
+			l, closeFn, err := New(Options{Dir: dir, Level: "info", Format: tt.format, StdoutLevel: LevelOff})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			defer closeFn()
+			l.Info("order created", zap.Int("order_id", 42))
+			tt.check(t, strings.TrimSpace(readLogFile(t, dir, ".log")))
+		})
+	}
+
+	if _, _, err := New(Options{Level: "info", Format: "xml"}); err == nil {
+		t.Error("expected error for invalid format")
+	}
+	if _, _, err := New(Options{Level: "info", FileLevel: "verbose"}); err != nil {
+		t.Errorf("file level should be ignored without Dir: %v", err)
+	}
+	if _, _, err := New(Options{Level: "info", StdoutLevel: "verbose"}); err == nil {
+		t.Error("expected error for invalid stdout level")
+	}
+}
+
+func TestNewSeparateLevelsAndErrorFile(t *testing.T) {
+	dir := t.TempDir()
+	l, closeFn, err := New(Options{Dir: dir, Level: "debug", FileLevel: "warn", StdoutLevel: LevelOff, ErrorFile: true})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer closeFn()
+	l.Info("info message")
+	l.Warn("warn message")
+	l.Error("error message")
+
+	all := readLogFile(t, dir, ".log")
+	if strings.Contains(all, "info message") || !strings.Contains(all, "warn message") || !strings.Contains(all, "error message") {
+		t.Errorf("unexpected log file content: %q", all)
+	}
+	errs := readLogFile(t, dir, ".error.log")
+	if strings.Contains(errs, "warn message") || !strings.Contains(errs, "error message") {
+		t.Errorf("unexpected error file content: %q", errs)
+	}
+}
+
+func TestLogfmtEncoder(t *testing.T) {
+	cfg := NewStructuredEncoderConfig()
+	cfg.TimeKey = ""
+	enc := NewLogfmtEncoder(cfg)
+	enc.AddString("service", "order api")
+
+	clone := enc.Clone()
+	clone.OpenNamespace("req")
+	buf, err := clone.EncodeEntry(zapcore.Entry{Level: zap.WarnLevel, Message: "slow"}, []zapcore.Field{
+		zap.Duration("latency", 1500*time.Millisecond),
+		zap.Strings("tags", []string{"a", "b"}),
+		zap.String("empty", ""),
+		zap.Bool("ok", false),
+	})
+	if err != nil {
+		t.Fatalf("EncodeEntry: %v", err)
+	}
+
+	want := `level=warn msg=slow service="order api" req.latency=1.5s req.tags="[\"a\",\"b\"]" req.empty="" req.ok=false` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
+
+// readLogFile 读取 dir 下以 suffix 结尾的当天日志文件
+func readLogFile(t *testing.T, dir, suffix string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, time.Now().Format("2006-01-02")+suffix))
+	if err != nil {
+		t.Fatalf("read log file: %v", err)
+	}
+	return string(data)
+}
+
 // 基准测试
 func BenchmarkInitLogger(b *testing.B) {
 	testDir := "benchlogs/"
 	os.MkdirAll(testDir, 0755)
 	defer os.RemoveAll(testDir)
 
+	defer Close()
+
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		if cronInstance != nil {
-			cronInstance.Stop()
-			cronInstance = nil
-		}
 		InitLogger(testDir, "info")
 	}
 }
@@ -310,10 +405,7 @@ func BenchmarkLoggerInfo(b *testing.B) {
 	os.MkdirAll(testDir, 0755)
 	defer os.RemoveAll(testDir)
 
-	if cronInstance != nil {
-		cronInstance.Stop()
-		cronInstance = nil
-	}
+	defer Close()
 	InitLogger(testDir, "info")
 
 	b.ResetTimer()
@@ -327,10 +419,7 @@ func BenchmarkSugarInfo(b *testing.B) {
 	os.MkdirAll(testDir, 0755)
 	defer os.RemoveAll(testDir)
 
-	if cronInstance != nil {
-		cronInstance.Stop()
-		cronInstance = nil
-	}
+	defer Close()
 	InitLogger(testDir, "info")
 
 	b.ResetTimer()
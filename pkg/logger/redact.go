@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"project/pkg/redact"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// redactEncoder 在编码前脱敏字段：名称匹配 redact 敏感模式的字段替换为 [REDACTED]，
+// zap.Any / Sugar 的 w 系列方法传入的结构体按 log tag 脱敏
+type redactEncoder struct {
+	zapcore.Encoder
+}
+
+// NewRedactEncoder 包装编码器，对字段脱敏
+func NewRedactEncoder(enc zapcore.Encoder) zapcore.Encoder {
+	return &redactEncoder{Encoder: enc}
+}
+
+func (e *redactEncoder) Clone() zapcore.Encoder {
+	return &redactEncoder{Encoder: e.Encoder.Clone()}
+}
+
+func (e *redactEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	var redacted []zapcore.Field
+	for i, f := range fields {
+		if f, ok := redactField(f); ok {
+			// 复制后修改，不影响调用方的切片
+			if redacted == nil {
+				redacted = append([]zapcore.Field(nil), fields...)
+			}
+			redacted[i] = f
+		}
+	}
+	if redacted != nil {
+		fields = redacted
+	}
+	return e.Encoder.EncodeEntry(ent, fields)
+}
+
+// With 添加的字段通过以下方法写入
+func (e *redactEncoder) AddString(key, value string) {
+	e.Encoder.AddString(key, redact.String(key, value))
+}
+
+func (e *redactEncoder) AddByteString(key string, value []byte) {
+	if len(value) > 0 && redact.IsSensitiveKey(key) {
+		e.Encoder.AddString(key, redact.Placeholder)
+		return
+	}
+	e.Encoder.AddByteString(key, value)
+}
+
+func (e *redactEncoder) AddReflected(key string, value interface{}) error {
+	if value != nil && redact.IsSensitiveKey(key) {
+		e.Encoder.AddString(key, redact.Placeholder)
+		return nil
+	}
+	return e.Encoder.AddReflected(key, redact.Value(value))
+}
+
+// redactField 返回脱敏后的字段，无需脱敏时 ok 为 false
+func redactField(f zapcore.Field) (zapcore.Field, bool) {
+	switch f.Type {
+	case zapcore.StringType, zapcore.ByteStringType, zapcore.StringerType, zapcore.BinaryType:
+		if redact.IsSensitiveKey(f.Key) {
+			return zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: redact.Placeholder}, true
+		}
+	case zapcore.ReflectType:
+		if f.Interface == nil {
+			return f, false
+		}
+		if redact.IsSensitiveKey(f.Key) {
+			return zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: redact.Placeholder}, true
+		}
+		return zapcore.Field{Key: f.Key, Type: zapcore.ReflectType, Interface: redact.Value(f.Interface)}, true
+	}
+	return f, false
+}
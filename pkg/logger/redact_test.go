@@ -0,0 +1,48 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type redactTestRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password" log:"redact"`
+	Phone    string `json:"phone" log:"mask"`
+}
+
+func TestRedactEncoder(t *testing.T) {
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			l := zap.New(zapcore.NewCore(NewEncoder(format), zapcore.AddSync(&buf), zap.DebugLevel))
+
+			req := redactTestRequest{Username: "alice", Password: "p@ssw0rd", Phone: "13812345678"}
+			l.With(zap.String("authorization", "Bearer abc"), zap.String("user", "alice")).Sugar().Infow("login",
+				"req", req,
+				"access_token", "eyJhbGciOi",
+				"refresh_token", []byte("r-token"),
+				"secret", req,
+			)
+
+			out := buf.String()
+			for _, leaked := range []string{"p@ssw0rd", "13812345678", "Bearer abc", "eyJhbGciOi", "r-token"} {
+				if strings.Contains(out, leaked) {
+					t.Errorf("%s leaked in %q", leaked, out)
+				}
+			}
+			for _, want := range []string{"alice", "13*******78", "[REDACTED]"} {
+				if !strings.Contains(out, want) {
+					t.Errorf("%s missing in %q", want, out)
+				}
+			}
+			if req.Password != "p@ssw0rd" {
+				t.Error("original value modified")
+			}
+		})
+	}
+}
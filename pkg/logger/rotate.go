@@ -0,0 +1,382 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultPattern 默认文件名模式
+	DefaultPattern = "{date}.log"
+
+	datePlaceholder = "{date}"
+	dateLayout      = "2006-01-02"
+	megabyte        = 1024 * 1024
+)
+
+// RotateOptions 滚动文件配置
+type RotateOptions struct {
+	// Dir 日志目录，不存在时自动创建
+	Dir string
+	// Pattern 文件名模式，{date} 替换为日期（2006-01-02），默认 {date}.log；
+	// 同一天内按大小切分出的旧文件在扩展名前追加序号，如 2024-01-15.1.log
+	Pattern string
+	// MaxSize 单个文件最大大小（MB），0 表示只按日期切分
+	MaxSize int
+	// MaxBackups 保留的旧文件数量，0 表示不限制
+	MaxBackups int
+	// MaxAge 旧文件保留天数，0 表示不限制
+	MaxAge int
+	// MaxTotalSize 所有文件（含当前文件）的总大小上限（MB），超出时从最旧的文件开始删除，0 表示不限制
+	MaxTotalSize int
+	// Compress gzip 压缩旧文件
+	Compress bool
+}
+
+// RotatingWriter 按日期与大小滚动的日志文件
+//
+// 写入时日期变化或超过 MaxSize 则关闭当前文件并切换到新文件，切换在写锁内完成，并发写入不会交错或丢失；
+// 旧文件的压缩与清理在后台协程中进行，不阻塞写入。
+type RotatingWriter struct {
+	opts              RotateOptions
+	prefix, stem, ext string
+	now               func() time.Time
+
+	mu     sync.Mutex
+	file   *os.File
+	date   string
+	size   int64
+	closed bool
+
+	millCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewRotatingWriter 创建滚动文件并打开当前日期的文件
+func NewRotatingWriter(opts RotateOptions) (*RotatingWriter, error) {
+	return newRotatingWriter(opts, time.Now)
+}
+
+func newRotatingWriter(opts RotateOptions, now func() time.Time) (*RotatingWriter, error) {
+	if opts.Pattern == "" {
+		opts.Pattern = DefaultPattern
+	}
+	i := strings.Index(opts.Pattern, datePlaceholder)
+	if i < 0 {
+		return nil, fmt.Errorf("log file pattern %q must contain %s", opts.Pattern, datePlaceholder)
+	}
+	if strings.ContainsAny(opts.Pattern, `/\`) {
+		return nil, fmt.Errorf("log file pattern %q must not contain path separators", opts.Pattern)
+	}
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, err
+	}
+
+	suffix := opts.Pattern[i+len(datePlaceholder):]
+	w := &RotatingWriter{
+		opts:   opts,
+		prefix: opts.Pattern[:i],
+		ext:    filepath.Ext(suffix),
+		now:    now,
+		millCh: make(chan struct{}, 1),
+	}
+	w.stem = strings.TrimSuffix(suffix, w.ext)
+
+	if err := w.openLocked(w.now()); err != nil {
+		return nil, err
+	}
+
+	w.wg.Add(1)
+	go w.millLoop()
+	// 启动时清理上次运行遗留的旧文件
+	w.millCh <- struct{}{}
+	return w, nil
+}
+
+// Write 写入当前文件，必要时先滚动
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	now := w.now()
+	switch {
+	case w.file == nil:
+		// 上次滚动时打开新文件失败
+		if err := w.openLocked(now); err != nil {
+			return 0, err
+		}
+	case now.Format(dateLayout) != w.date:
+		if err := w.rotateLocked(now, false); err != nil {
+			return 0, err
+		}
+	case w.opts.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > int64(w.opts.MaxSize)*megabyte:
+		if err := w.rotateLocked(now, true); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Sync 将当前文件刷入磁盘
+func (w *RotatingWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+// Close 关闭当前文件并等待后台压缩与清理完成，之后的写入返回 os.ErrClosed
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	err := w.closeFileLocked()
+	close(w.millCh)
+	w.mu.Unlock()
+
+	w.wg.Wait()
+	return err
+}
+
+// Filename 返回当前写入的文件路径
+func (w *RotatingWriter) Filename() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return filepath.Join(w.opts.Dir, w.name(w.date, 0))
+}
+
+// name 返回日期 date 的第 index 个文件名，0 为当前文件
+func (w *RotatingWriter) name(date string, index int) string {
+	if index == 0 {
+		return w.prefix + date + w.stem + w.ext
+	}
+	return w.prefix + date + w.stem + "." + strconv.Itoa(index) + w.ext
+}
+
+// parse 解析由当前模式生成的文件名，返回日期与序号
+func (w *RotatingWriter) parse(name string) (date string, index int, ok bool) {
+	name = strings.TrimSuffix(name, ".gz")
+	if !strings.HasPrefix(name, w.prefix) || !strings.HasSuffix(name, w.ext) || len(name) < len(w.prefix)+len(w.ext)+len(dateLayout) {
+		return "", 0, false
+	}
+	middle := name[len(w.prefix) : len(name)-len(w.ext)]
+	date, rest := middle[:len(dateLayout)], middle[len(dateLayout):]
+	if _, err := time.Parse(dateLayout, date); err != nil {
+		return "", 0, false
+	}
+	if rest == w.stem {
+		return date, 0, true
+	}
+	if !strings.HasPrefix(rest, w.stem+".") {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(rest[len(w.stem)+1:])
+	return date, n, err == nil && n > 0
+}
+
+func (w *RotatingWriter) openLocked(now time.Time) error {
+	date := now.Format(dateLayout)
+	f, err := os.OpenFile(filepath.Join(w.opts.Dir, w.name(date, 0)), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	w.file, w.date, w.size = f, date, info.Size()
+	return nil
+}
+
+func (w *RotatingWriter) closeFileLocked() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Sync()
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	w.file = nil
+	return err
+}
+
+// rotateLocked 关闭当前文件，按大小滚动时将其重命名为下一个序号，然后打开新文件
+func (w *RotatingWriter) rotateLocked(now time.Time, bySize bool) error {
+	if err := w.closeFileLocked(); err != nil {
+		return err
+	}
+	if bySize {
+		current := filepath.Join(w.opts.Dir, w.name(w.date, 0))
+		for i := 1; ; i++ {
+			backup := filepath.Join(w.opts.Dir, w.name(w.date, i))
+			if exists(backup) || exists(backup+".gz") {
+				continue
+			}
+			if err := os.Rename(current, backup); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	if err := w.openLocked(now); err != nil {
+		return err
+	}
+
+	select {
+	case w.millCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (w *RotatingWriter) millLoop() {
+	defer w.wg.Done()
+	for range w.millCh {
+		if err := w.mill(); err != nil {
+			fmt.Fprintf(os.Stderr, "logger: clean up %s: %v\n", w.opts.Dir, err)
+		}
+	}
+}
+
+type logFile struct {
+	path string
+	date string
+	info os.FileInfo
+}
+
+// mill 压缩旧文件并按数量、天数和总大小删除最旧的文件
+func (w *RotatingWriter) mill() error {
+	w.mu.Lock()
+	currentDate := w.date
+	w.mu.Unlock()
+
+	entries, err := os.ReadDir(w.opts.Dir)
+	if err != nil {
+		return err
+	}
+	var currentSize int64
+	var backups []logFile
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		date, index, ok := w.parse(e.Name())
+		if !ok {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		// 读取日期后可能已切换到更新日期的文件，同样视为当前文件
+		if index == 0 && date >= currentDate && !strings.HasSuffix(e.Name(), ".gz") {
+			currentSize += info.Size()
+			continue
+		}
+		backups = append(backups, logFile{path: filepath.Join(w.opts.Dir, e.Name()), date: date, info: info})
+	}
+	// 最新的在前：先按文件名中的日期，同一天内按修改时间
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].date != backups[j].date {
+			return backups[i].date > backups[j].date
+		}
+		return backups[i].info.ModTime().After(backups[j].info.ModTime())
+	})
+
+	var errs []error
+	var keep []logFile
+	cutoff := w.now().Add(-time.Duration(w.opts.MaxAge) * 24 * time.Hour)
+	total := currentSize
+	for i, f := range backups {
+		switch {
+		case w.opts.MaxBackups > 0 && i >= w.opts.MaxBackups,
+			w.opts.MaxAge > 0 && f.info.ModTime().Before(cutoff):
+			errs = append(errs, os.Remove(f.path))
+		default:
+			keep = append(keep, f)
+			total += f.info.Size()
+		}
+	}
+
+	if w.opts.MaxTotalSize > 0 {
+		limit := int64(w.opts.MaxTotalSize) * megabyte
+		for len(keep) > 0 && total > limit {
+			oldest := keep[len(keep)-1]
+			keep = keep[:len(keep)-1]
+			total -= oldest.info.Size()
+			errs = append(errs, os.Remove(oldest.path))
+		}
+	}
+
+	if w.opts.Compress {
+		for _, f := range keep {
+			if !strings.HasSuffix(f.path, ".gz") {
+				errs = append(errs, compressFile(f.path, f.info))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// compressFile 将 src 压缩为 src.gz 并删除 src，保留修改时间以便按时间清理
+func compressFile(src string, info os.FileInfo) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	dst := src + ".gz"
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = out.Close()
+			_ = os.Remove(dst)
+		}
+	}()
+
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	_ = in.Close()
+	if err != nil {
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	if err = os.Chtimes(dst, info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
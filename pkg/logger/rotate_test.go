@@ -0,0 +1,266 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock 可手动推进的时钟
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) add(d time.Duration) {
+	c.mu.Lock()
+	c.t = c.t.Add(d)
+	c.mu.Unlock()
+}
+
+func newTestWriter(t *testing.T, opts RotateOptions, clock *fakeClock) *RotatingWriter {
+	t.Helper()
+	w, err := newRotatingWriter(opts, clock.now)
+	if err != nil {
+		t.Fatalf("newRotatingWriter: %v", err)
+	}
+	t.Cleanup(func() { _ = w.Close() })
+	return w
+}
+
+// listDir 返回目录下的文件名（已排序）
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func assertFiles(t *testing.T, dir string, want ...string) {
+	t.Helper()
+	sort.Strings(want)
+	if got := listDir(t, dir); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("files = %v, want %v", got, want)
+	}
+}
+
+func writeString(t *testing.T, w io.Writer, s string) {
+	t.Helper()
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func TestRotatingWriter_RotatesByDate(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Date(2024, 1, 15, 23, 59, 0, 0, time.Local)}
+	w := newTestWriter(t, RotateOptions{Dir: dir, Pattern: "app-{date}.log"}, clock)
+
+	writeString(t, w, "day1\n")
+	clock.add(2 * time.Minute)
+	writeString(t, w, "day2\n")
+
+	if got := w.Filename(); got != filepath.Join(dir, "app-2024-01-16.log") {
+		t.Errorf("Filename() = %s", got)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	assertFiles(t, dir, "app-2024-01-15.log", "app-2024-01-16.log")
+
+	data, _ := os.ReadFile(filepath.Join(dir, "app-2024-01-15.log"))
+	if string(data) != "day1\n" {
+		t.Errorf("2024-01-15 content = %q", data)
+	}
+	if _, err := w.Write([]byte("late\n")); err != os.ErrClosed {
+		t.Errorf("write after close error = %v, want os.ErrClosed", err)
+	}
+}
+
+func TestRotatingWriter_RotatesBySizeAndCompresses(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Date(2024, 1, 15, 10, 0, 0, 0, time.Local)}
+	w := newTestWriter(t, RotateOptions{Dir: dir, MaxSize: 1, Compress: true}, clock)
+
+	chunk := bytes.Repeat([]byte("x"), 600*1024)
+	for _, c := range []byte("abc") {
+		chunk[0] = c
+		writeString(t, w, string(chunk))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	assertFiles(t, dir, "2024-01-15.log", "2024-01-15.1.log.gz", "2024-01-15.2.log.gz")
+
+	// 序号越大越新
+	for i, want := range []byte("ab") {
+		f, err := os.Open(filepath.Join(dir, fmt.Sprintf("2024-01-15.%d.log.gz", i+1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(gz)
+		_ = f.Close()
+		if err != nil || len(data) != len(chunk) || data[0] != want {
+			t.Errorf("segment %d: len=%d first=%q err=%v", i+1, len(data), data[:1], err)
+		}
+	}
+}
+
+func TestRotatingWriter_Cleanup(t *testing.T) {
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		name  string
+		opts  RotateOptions
+		files map[string]int // 已存在的文件及大小（KB）
+		want  []string
+	}{
+		{
+			name:  "max backups",
+			opts:  RotateOptions{MaxBackups: 2},
+			files: map[string]int{"2024-01-11.log": 1, "2024-01-12.log": 1, "2024-01-13.1.log": 1, "2024-01-13.log": 1},
+			want:  []string{"2024-01-13.1.log", "2024-01-13.log", "2024-01-15.log"},
+		},
+		{
+			name:  "max age",
+			opts:  RotateOptions{MaxAge: 7},
+			files: map[string]int{"2020-01-01.log": 1, "2024-01-14.log": 1},
+			want:  []string{"2024-01-14.log", "2024-01-15.log"},
+		},
+		{
+			name:  "max total size",
+			opts:  RotateOptions{MaxTotalSize: 1},
+			files: map[string]int{"2024-01-12.log": 600, "2024-01-13.log.gz": 300, "2024-01-14.log": 300},
+			want:  []string{"2024-01-13.log.gz", "2024-01-14.log", "2024-01-15.log"},
+		},
+		{
+			name:  "ignores other files",
+			opts:  RotateOptions{MaxBackups: 1},
+			files: map[string]int{"2024-01-13.error.log": 1, "2024-01-14.error.log": 1, "other.log": 1, "2024-13-01.log": 1},
+			want:  []string{"2024-01-13.error.log", "2024-01-14.error.log", "2024-01-15.log", "2024-13-01.log", "other.log"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, kb := range tt.files {
+				path := filepath.Join(dir, name)
+				if err := os.WriteFile(path, bytes.Repeat([]byte("x"), kb*1024), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if strings.HasPrefix(name, "2020") {
+					_ = os.Chtimes(path, old, old)
+				}
+			}
+
+			tt.opts.Dir = dir
+			clock := &fakeClock{t: time.Date(2024, 1, 15, 10, 0, 0, 0, time.Local)}
+			w := newTestWriter(t, tt.opts, clock)
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+			assertFiles(t, dir, tt.want...)
+		})
+	}
+}
+
+func TestRotatingWriter_ConcurrentWrites(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Date(2024, 1, 15, 10, 0, 0, 0, time.Local)}
+	w := newTestWriter(t, RotateOptions{Dir: dir, Compress: true}, clock)
+
+	const writers, lines = 8, 200
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < lines; j++ {
+				if id == 0 && j%50 == 0 {
+					clock.add(24 * time.Hour)
+				}
+				_, _ = fmt.Fprintf(w, "writer=%d line=%d\n", id, j)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// 每行完整地写入某一个文件，不丢失、不交错
+	var total int
+	for _, name := range listDir(t, dir) {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r io.Reader = f
+		if strings.HasSuffix(name, ".gz") {
+			if r, err = gzip.NewReader(f); err != nil {
+				t.Fatal(err)
+			}
+		}
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			var id, line int
+			if _, err := fmt.Sscanf(scanner.Text(), "writer=%d line=%d", &id, &line); err != nil {
+				t.Errorf("%s: corrupted line %q", name, scanner.Text())
+			}
+			total++
+		}
+		_ = f.Close()
+	}
+	if total != writers*lines {
+		t.Errorf("total lines = %d, want %d", total, writers*lines)
+	}
+}
+
+func TestNewRotatingWriter_InvalidPattern(t *testing.T) {
+	for _, pattern := range []string{"app.log", "logs/{date}.log"} {
+		if _, err := NewRotatingWriter(RotateOptions{Dir: t.TempDir(), Pattern: pattern}); err == nil {
+			t.Errorf("pattern %q should be rejected", pattern)
+		}
+	}
+}
+
+func TestErrorPattern(t *testing.T) {
+	tests := map[string]string{
+		"":                "{date}.error.log",
+		"{date}.log":      "{date}.error.log",
+		"app-{date}.log":  "app-{date}.error.log",
+		"app.v1-{date}":   "app.v1-{date}.error",
+		"{date}.json.log": "{date}.json.error.log",
+	}
+	for pattern, want := range tests {
+		if got := ErrorPattern(pattern); got != want {
+			t.Errorf("ErrorPattern(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
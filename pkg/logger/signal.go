@@ -0,0 +1,33 @@
+//go:build !windows
+
+package logger
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// WatchSignal 收到 SIGUSR1 时在 debug 与配置级别之间切换全局级别，返回的 stop 停止监听：
+//
+//	kill -USR1 <pid>
+func WatchSignal() (stop func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ch:
+				level := ToggleDebug()
+				Named("logger").Warnf("\t[logger] received SIGUSR1, global level is now %s", level)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
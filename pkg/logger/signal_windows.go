@@ -0,0 +1,6 @@
+package logger
+
+// WatchSignal Windows 不支持 SIGUSR1，不做处理
+func WatchSignal() (stop func()) {
+	return func() {}
+}
@@ -0,0 +1,218 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// 输出类型
+const (
+	SinkFile   = "file"
+	SinkSyslog = "syslog"
+	SinkHTTP   = "http"
+)
+
+// HTTP 输出的协议
+const (
+	ProtocolLoki          = "loki"
+	ProtocolElasticsearch = "elasticsearch"
+)
+
+// HTTP 输出缓冲区满时的丢弃策略
+const (
+	DropNewest = "newest"
+	DropOldest = "oldest"
+)
+
+// SinkTypes 支持的输出类型
+var SinkTypes = []string{SinkFile, SinkSyslog, SinkHTTP}
+
+// SyslogFacilities 支持的 syslog facility
+var SyslogFacilities = []string{"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7"}
+
+// SinkOptions 额外的日志输出
+type SinkOptions struct {
+	// Type 输出类型：file、syslog、http
+	Type string
+	// Level 级别，为空时同 Options.Level 并随 SetLevel 调整
+	Level string
+	// Format 输出格式，为空时同 Options.Format
+	Format string
+
+	// Pattern file：文件名模式，写入 Options.Dir，滚动与清理配置同主日志文件
+	Pattern string
+
+	// Network syslog：网络类型（udp、tcp），为空时写本机 syslog
+	Network string
+	// Address syslog：地址，Network 为空时忽略
+	Address string
+	// Tag syslog：标识，默认为程序名
+	Tag string
+	// Facility syslog：默认 user
+	Facility string
+
+	// URL http：推送地址，如 http://loki:3100/loki/api/v1/push 或 http://es:9200/_bulk
+	URL string
+	// Protocol http：loki 或 elasticsearch
+	Protocol string
+	// Labels http：Loki 的 stream 标签
+	Labels map[string]string
+	// Index http：Elasticsearch 的索引名
+	Index string
+	// Headers http：附加的请求头，如 Authorization
+	Headers map[string]string
+	// BatchSize http：单次推送的最大条数，默认 100
+	BatchSize int
+	// FlushInterval http：推送间隔，默认 1s
+	FlushInterval time.Duration
+	// BufferSize http：缓冲的最大条数，默认 10000
+	BufferSize int
+	// DropPolicy http：缓冲区满时丢弃最新（newest，默认）或最旧（oldest）的日志
+	DropPolicy string
+	// MaxRetries http：推送失败（网络错误、429、5xx）的重试次数，默认 3，小于 0 不重试
+	MaxRetries int
+	// Timeout http：单次请求超时，默认 5s
+	Timeout time.Duration
+}
+
+// Validate 校验输出配置
+func (s *SinkOptions) Validate() error {
+	if s.Level != "" && s.Level != LevelOff {
+		if _, err := ParseLevel(s.Level); err != nil {
+			return err
+		}
+	}
+	if _, err := ParseFormat(s.Format); err != nil {
+		return err
+	}
+	switch s.Type {
+	case SinkFile:
+		if s.Pattern == "" {
+			return fmt.Errorf("file sink pattern is required")
+		}
+		if !strings.Contains(s.Pattern, datePlaceholder) || strings.ContainsAny(s.Pattern, `/\`) {
+			return fmt.Errorf("file sink pattern %q must contain %s and no path separators", s.Pattern, datePlaceholder)
+		}
+	case SinkSyslog:
+		if s.Network != "" && s.Address == "" {
+			return fmt.Errorf("syslog sink address is required when network is set")
+		}
+		if s.Facility != "" && !contains(SyslogFacilities, strings.ToLower(s.Facility)) {
+			return fmt.Errorf("invalid syslog facility: %s", s.Facility)
+		}
+	case SinkHTTP:
+		if s.URL == "" {
+			return fmt.Errorf("http sink url is required")
+		}
+		if s.Protocol != ProtocolLoki && s.Protocol != ProtocolElasticsearch {
+			return fmt.Errorf("invalid http sink protocol: %s (must be %s/%s)", s.Protocol, ProtocolLoki, ProtocolElasticsearch)
+		}
+		if s.DropPolicy != "" && s.DropPolicy != DropNewest && s.DropPolicy != DropOldest {
+			return fmt.Errorf("invalid http sink drop policy: %s (must be %s/%s)", s.DropPolicy, DropNewest, DropOldest)
+		}
+	default:
+		return fmt.Errorf("invalid log sink type: %s (must be %s)", s.Type, strings.Join(SinkTypes, "/"))
+	}
+	return nil
+}
+
+// entryWriter 按日志条目写入的输出，需要级别或时间的输出（syslog、http）实现该接口
+type entryWriter interface {
+	// WriteEntry 写入编码后的一条日志，调用返回后 line 会被复用
+	WriteEntry(ent zapcore.Entry, line []byte) error
+	Sync() error
+	Close() error
+}
+
+// entryCore 将编码后的日志连同条目信息交给 entryWriter
+type entryCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	out entryWriter
+}
+
+func newEntryCore(enc zapcore.Encoder, out entryWriter, enab zapcore.LevelEnabler) zapcore.Core {
+	return &entryCore{LevelEnabler: enab, enc: enc, out: out}
+}
+
+func (c *entryCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &entryCore{LevelEnabler: c.LevelEnabler, enc: enc, out: c.out}
+}
+
+func (c *entryCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *entryCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	err = c.out.WriteEntry(ent, buf.Bytes())
+	buf.Free()
+	if err != nil {
+		return err
+	}
+	if ent.Level > zapcore.ErrorLevel {
+		// 与 ioCore 一致，panic/fatal 前刷新
+		_ = c.out.Sync()
+	}
+	return nil
+}
+
+func (c *entryCore) Sync() error {
+	return c.out.Sync()
+}
+
+// newSink 创建额外输出，返回的 close 用于关闭输出
+func newSink(opts *Options, s SinkOptions, enab zapcore.LevelEnabler) (zapcore.Core, func() error, error) {
+	if err := s.Validate(); err != nil {
+		return nil, nil, err
+	}
+	format := opts.Format
+	if s.Format != "" {
+		format, _ = ParseFormat(s.Format)
+	}
+	enc := NewEncoder(format)
+
+	switch s.Type {
+	case SinkFile:
+		if opts.Dir == "" {
+			return nil, nil, fmt.Errorf("file sink requires log dir")
+		}
+		w, err := NewRotatingWriter(opts.rotateOptions(s.Pattern))
+		if err != nil {
+			return nil, nil, err
+		}
+		return zapcore.NewCore(enc, w, enab), w.Close, nil
+	case SinkSyslog:
+		w, err := newSyslogWriter(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		return newEntryCore(enc, w, enab), w.Close, nil
+	default:
+		w := NewHTTPWriter(s)
+		return newEntryCore(enc, w, enab), w.Close, nil
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
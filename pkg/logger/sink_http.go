@@ -0,0 +1,324 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// httpEntry 缓冲中的一条日志
+type httpEntry struct {
+	time  time.Time
+	level zapcore.Level
+	line  string
+}
+
+// HTTPWriter 异步批量推送日志到 Loki 或 Elasticsearch bulk 接口
+//
+// 写入只放入有界缓冲区，不阻塞业务；缓冲区满时按 DropPolicy 丢弃。后台协程按 BatchSize 或 FlushInterval 推送，
+// 网络错误、429 与 5xx 按指数退避重试 MaxRetries 次，仍失败则丢弃该批次并输出到 stderr。
+type HTTPWriter struct {
+	opts   SinkOptions
+	client *http.Client
+
+	entries chan httpEntry
+	syncCh  chan chan struct{}
+	done    chan struct{}
+	wg      sync.WaitGroup
+
+	closeOnce sync.Once
+	closed    atomic.Bool
+	dropped   atomic.Int64
+	// retryBackoff 首次重试的等待时间，测试中缩短
+	retryBackoff time.Duration
+}
+
+// NewHTTPWriter 创建 HTTP 输出并启动后台推送
+func NewHTTPWriter(s SinkOptions) *HTTPWriter {
+	if s.BatchSize <= 0 {
+		s.BatchSize = 100
+	}
+	if s.FlushInterval <= 0 {
+		s.FlushInterval = time.Second
+	}
+	if s.BufferSize <= 0 {
+		s.BufferSize = 10000
+	}
+	if s.DropPolicy == "" {
+		s.DropPolicy = DropNewest
+	}
+	if s.MaxRetries < 0 {
+		s.MaxRetries = 0
+	} else if s.MaxRetries == 0 {
+		s.MaxRetries = 3
+	}
+	if s.Timeout <= 0 {
+		s.Timeout = 5 * time.Second
+	}
+
+	w := &HTTPWriter{
+		opts:         s,
+		client:       &http.Client{Timeout: s.Timeout},
+		entries:      make(chan httpEntry, s.BufferSize),
+		syncCh:       make(chan chan struct{}),
+		done:         make(chan struct{}),
+		retryBackoff: 500 * time.Millisecond,
+	}
+	w.wg.Add(1)
+	go w.loop()
+	return w
+}
+
+// WriteEntry 放入缓冲区，缓冲区满时按 DropPolicy 丢弃
+func (w *HTTPWriter) WriteEntry(ent zapcore.Entry, line []byte) error {
+	if w.closed.Load() {
+		return os.ErrClosed
+	}
+	e := httpEntry{time: ent.Time, level: ent.Level, line: string(bytes.TrimRight(line, "\n"))}
+	select {
+	case w.entries <- e:
+		return nil
+	default:
+	}
+
+	if w.opts.DropPolicy == DropOldest {
+		select {
+		case <-w.entries:
+			w.dropped.Add(1)
+		default:
+		}
+		select {
+		case w.entries <- e:
+			return nil
+		default:
+		}
+	}
+	w.dropped.Add(1)
+	return nil
+}
+
+// Dropped 返回因缓冲区满或推送失败丢弃的日志条数
+func (w *HTTPWriter) Dropped() int64 {
+	return w.dropped.Load()
+}
+
+// Sync 推送缓冲区中的全部日志后返回
+func (w *HTTPWriter) Sync() error {
+	if w.closed.Load() {
+		return nil
+	}
+	ack := make(chan struct{})
+	select {
+	case w.syncCh <- ack:
+		<-ack
+	case <-w.done:
+	}
+	return nil
+}
+
+// Close 推送缓冲区中的全部日志并停止后台协程，之后的写入返回 os.ErrClosed
+func (w *HTTPWriter) Close() error {
+	w.closeOnce.Do(func() {
+		w.closed.Store(true)
+		close(w.done)
+		w.wg.Wait()
+	})
+	return nil
+}
+
+func (w *HTTPWriter) loop() {
+	defer w.wg.Done()
+	ticker := time.NewTicker(w.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]httpEntry, 0, w.opts.BatchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			w.send(batch)
+			batch = batch[:0]
+		}
+	}
+	// drain 取出缓冲区中已有的日志并全部推送
+	drain := func() {
+		for {
+			select {
+			case e := <-w.entries:
+				batch = append(batch, e)
+				if len(batch) >= w.opts.BatchSize {
+					flush()
+				}
+			default:
+				flush()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case e := <-w.entries:
+			batch = append(batch, e)
+			if len(batch) >= w.opts.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case ack := <-w.syncCh:
+			drain()
+			close(ack)
+		case <-w.done:
+			drain()
+			return
+		}
+	}
+}
+
+// send 推送一批日志，可重试的错误按指数退避重试
+func (w *HTTPWriter) send(batch []httpEntry) {
+	body, contentType, err := w.encode(batch)
+	if err != nil {
+		w.fail(len(batch), err)
+		return
+	}
+
+	backoff := w.retryBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(body, contentType)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= w.opts.MaxRetries {
+			w.fail(len(batch), err)
+			return
+		}
+		select {
+		case <-time.After(backoff):
+		case <-w.done:
+			// 关闭时不再等待退避，直接做最后一次尝试
+		}
+		backoff *= 2
+	}
+}
+
+func (w *HTTPWriter) fail(n int, err error) {
+	w.dropped.Add(int64(n))
+	fmt.Fprintf(os.Stderr, "logger: push %d entries to %s failed: %v\n", n, w.opts.URL, err)
+}
+
+// post 发送请求，返回的 retry 表示错误是否可重试
+func (w *HTTPWriter) post(body []byte, contentType string) (retry bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), w.opts.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range w.opts.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if w.opts.Protocol == ProtocolElasticsearch {
+			return false, bulkError(respBody)
+		}
+		return false, nil
+	}
+	err = fmt.Errorf("status %d: %s", resp.StatusCode, bytes.TrimSpace(respBody))
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+func (w *HTTPWriter) encode(batch []httpEntry) ([]byte, string, error) {
+	if w.opts.Protocol == ProtocolElasticsearch {
+		return w.encodeBulk(batch)
+	}
+	return w.encodeLoki(batch)
+}
+
+// encodeLoki 编码为 Loki push API 格式，同一批日志按级别分为不同的 stream
+func (w *HTTPWriter) encodeLoki(batch []httpEntry) ([]byte, string, error) {
+	type stream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	var streams []*stream
+	byLevel := make(map[zapcore.Level]*stream)
+	for _, e := range batch {
+		s, ok := byLevel[e.level]
+		if !ok {
+			labels := make(map[string]string, len(w.opts.Labels)+1)
+			for k, v := range w.opts.Labels {
+				labels[k] = v
+			}
+			labels["level"] = e.level.String()
+			s = &stream{Stream: labels}
+			byLevel[e.level] = s
+			streams = append(streams, s)
+		}
+		s.Values = append(s.Values, [2]string{strconv.FormatInt(e.time.UnixNano(), 10), e.line})
+	}
+	body, err := json.Marshal(map[string]interface{}{"streams": streams})
+	return body, "application/json", err
+}
+
+// encodeBulk 编码为 Elasticsearch bulk API 格式；JSON 格式的日志直接作为文档，其他格式包装为 message 字段
+func (w *HTTPWriter) encodeBulk(batch []httpEntry) ([]byte, string, error) {
+	index := w.opts.Index
+	if index == "" {
+		index = "logs"
+	}
+	action, err := json.Marshal(map[string]map[string]string{"index": {"_index": index}})
+	if err != nil {
+		return nil, "", err
+	}
+
+	var buf bytes.Buffer
+	for _, e := range batch {
+		buf.Write(action)
+		buf.WriteByte('\n')
+		if json.Valid([]byte(e.line)) && len(e.line) > 0 && e.line[0] == '{' {
+			buf.WriteString(e.line)
+		} else {
+			doc, err := json.Marshal(map[string]string{
+				"@timestamp": e.time.Format(time.RFC3339Nano),
+				"level":      e.level.String(),
+				"message":    e.line,
+			})
+			if err != nil {
+				return nil, "", err
+			}
+			buf.Write(doc)
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), "application/x-ndjson", nil
+}
+
+// bulkError bulk 接口返回 200 时仍可能有部分文档失败
+func bulkError(body []byte) error {
+	var resp struct {
+		Errors bool `json:"errors"`
+	}
+	// 响应体只读取了前 1KB，无法完整解析时按字段匹配
+	if json.Unmarshal(body, &resp) == nil && resp.Errors || bytes.Contains(body, []byte(`"errors":true`)) {
+		return fmt.Errorf("bulk request has failed items")
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+//go:build !windows
+
+package logger
+
+import (
+	"log/syslog"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+var syslogFacilities = map[string]syslog.Priority{
+	"kern": syslog.LOG_KERN, "user": syslog.LOG_USER, "mail": syslog.LOG_MAIL, "daemon": syslog.LOG_DAEMON,
+	"auth": syslog.LOG_AUTH, "syslog": syslog.LOG_SYSLOG, "lpr": syslog.LOG_LPR, "news": syslog.LOG_NEWS,
+	"uucp": syslog.LOG_UUCP, "cron": syslog.LOG_CRON, "authpriv": syslog.LOG_AUTHPRIV, "ftp": syslog.LOG_FTP,
+	"local0": syslog.LOG_LOCAL0, "local1": syslog.LOG_LOCAL1, "local2": syslog.LOG_LOCAL2, "local3": syslog.LOG_LOCAL3,
+	"local4": syslog.LOG_LOCAL4, "local5": syslog.LOG_LOCAL5, "local6": syslog.LOG_LOCAL6, "local7": syslog.LOG_LOCAL7,
+}
+
+// syslogWriter 按日志级别映射 syslog severity 写入
+type syslogWriter struct {
+	w *syslog.Writer
+}
+
+func newSyslogWriter(s SinkOptions) (entryWriter, error) {
+	facility := syslog.LOG_USER
+	if s.Facility != "" {
+		facility = syslogFacilities[strings.ToLower(s.Facility)]
+	}
+	w, err := syslog.Dial(s.Network, s.Address, facility|syslog.LOG_INFO, s.Tag)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogWriter{w: w}, nil
+}
+
+func (w *syslogWriter) WriteEntry(ent zapcore.Entry, line []byte) error {
+	msg := strings.TrimSuffix(string(line), "\n")
+	switch ent.Level {
+	case zapcore.DebugLevel:
+		return w.w.Debug(msg)
+	case zapcore.InfoLevel:
+		return w.w.Info(msg)
+	case zapcore.WarnLevel:
+		return w.w.Warning(msg)
+	case zapcore.ErrorLevel:
+		return w.w.Err(msg)
+	default:
+		return w.w.Crit(msg)
+	}
+}
+
+func (w *syslogWriter) Sync() error {
+	return nil
+}
+
+func (w *syslogWriter) Close() error {
+	return w.w.Close()
+}
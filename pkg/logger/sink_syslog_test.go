@@ -0,0 +1,51 @@
+//go:build !windows
+
+package logger
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestSyslogSink(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("listen udp: %v", err)
+	}
+	defer conn.Close()
+
+	l, closeFn, err := New(Options{Level: "info", StdoutLevel: LevelOff, Sinks: []SinkOptions{
+		{Type: SinkSyslog, Network: "udp", Address: conn.LocalAddr().String(), Tag: "demo", Facility: "local0"},
+	}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer closeFn()
+
+	tests := []struct {
+		log      func(string, ...zap.Field)
+		msg      string
+		priority string // facility local0(16)*8 + severity
+	}{
+		{l.Info, "info message", "<134>"},
+		{l.Warn, "warn message", "<132>"},
+		{l.Error, "error message", "<131>"},
+	}
+	buf := make([]byte, 4096)
+	for _, tt := range tests {
+		tt.log(tt.msg)
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		packet := string(buf[:n])
+		if !strings.HasPrefix(packet, tt.priority) || !strings.Contains(packet, "demo") || !strings.Contains(packet, tt.msg) {
+			t.Errorf("packet = %q, want priority %s", packet, tt.priority)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+package logger
+
+import "errors"
+
+func newSyslogWriter(SinkOptions) (entryWriter, error) {
+	return nil, errors.New("syslog sink is not supported on windows")
+}
@@ -0,0 +1,267 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// logServer 记录收到的推送请求体，handler 为空时返回 204
+type logServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	bodies  []string
+	handler func(w http.ResponseWriter, r *http.Request) bool
+}
+
+func newLogServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request) bool) *logServer {
+	t.Helper()
+	s := &logServer{handler: handler}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.handler != nil && !s.handler(w, r) {
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.bodies = append(s.bodies, string(body))
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *logServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.bodies...)
+}
+
+func newHTTPTestLogger(t *testing.T, s SinkOptions) (*zap.Logger, *HTTPWriter) {
+	t.Helper()
+	w := NewHTTPWriter(s)
+	w.retryBackoff = time.Millisecond
+	t.Cleanup(func() { _ = w.Close() })
+	enc := NewEncoder(FormatJSON)
+	return zap.New(newEntryCore(enc, w, zap.DebugLevel)), w
+}
+
+func TestHTTPWriter_Loki(t *testing.T) {
+	server := newLogServer(t, nil)
+	l, w := newHTTPTestLogger(t, SinkOptions{
+		URL: server.URL, Protocol: ProtocolLoki, Labels: map[string]string{"app": "demo"},
+		BatchSize: 2, FlushInterval: time.Hour,
+	})
+
+	l.Info("first")
+	l.Error("second")
+	l.Info("third")
+	if err := w.Sync(); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	bodies := server.received()
+	if len(bodies) != 2 {
+		t.Fatalf("requests = %d, want 2 (batch of 2 + sync)", len(bodies))
+	}
+	var push struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal([]byte(bodies[0]), &push); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(push.Streams) != 2 {
+		t.Fatalf("streams = %+v", push.Streams)
+	}
+	s := push.Streams[0]
+	if s.Stream["app"] != "demo" || s.Stream["level"] != "info" || len(s.Values) != 1 || !strings.Contains(s.Values[0][1], `"msg":"first"`) {
+		t.Errorf("stream = %+v", s)
+	}
+	if push.Streams[1].Stream["level"] != "error" {
+		t.Errorf("second stream = %+v", push.Streams[1])
+	}
+	if !strings.Contains(bodies[1], "third") {
+		t.Errorf("sync body = %s", bodies[1])
+	}
+}
+
+func TestHTTPWriter_ElasticsearchBulk(t *testing.T) {
+	var contentType atomic.Value
+	server := newLogServer(t, func(w http.ResponseWriter, r *http.Request) bool {
+		contentType.Store(r.Header.Get("Content-Type"))
+		return true
+	})
+	w := NewHTTPWriter(SinkOptions{URL: server.URL, Protocol: ProtocolElasticsearch, Index: "app-logs"})
+	enc := NewEncoder(FormatConsole)
+	l := zap.New(newEntryCore(enc, w, zap.DebugLevel))
+
+	l.Info("console line")
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	bodies := server.received()
+	if len(bodies) != 1 {
+		t.Fatalf("requests = %d", len(bodies))
+	}
+	if contentType.Load() != "application/x-ndjson" {
+		t.Errorf("content type = %v", contentType.Load())
+	}
+	scanner := bufio.NewScanner(strings.NewReader(bodies[0]))
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) != 2 || lines[0] != `{"index":{"_index":"app-logs"}}` {
+		t.Fatalf("bulk body = %q", bodies[0])
+	}
+	var doc map[string]string
+	if err := json.Unmarshal([]byte(lines[1]), &doc); err != nil {
+		t.Fatalf("decode doc: %v", err)
+	}
+	if doc["level"] != "info" || !strings.Contains(doc["message"], "console line") || doc["@timestamp"] == "" {
+		t.Errorf("doc = %v", doc)
+	}
+
+	if err := w.WriteEntry(zapcore.Entry{}, []byte("late")); err == nil {
+		t.Error("write after close should fail")
+	}
+}
+
+func TestHTTPWriter_Retry(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		maxRetries   int
+		wantRequests int32
+		wantDropped  int64
+	}{
+		{"retry 5xx then succeed", []int{500, 503}, 3, 3, 0},
+		{"retry 429", []int{429}, 3, 2, 0},
+		{"no retry on 4xx", []int{400}, 3, 1, 1},
+		{"give up after max retries", []int{500, 500, 500}, 2, 3, 1},
+		{"retries disabled", []int{500}, -1, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := newLogServer(t, func(w http.ResponseWriter, r *http.Request) bool {
+				n := int(requests.Add(1))
+				if n <= len(tt.statuses) {
+					w.WriteHeader(tt.statuses[n-1])
+					return false
+				}
+				return true
+			})
+			l, w := newHTTPTestLogger(t, SinkOptions{URL: server.URL, Protocol: ProtocolLoki, MaxRetries: tt.maxRetries})
+
+			l.Info("message")
+			_ = w.Sync()
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+			if got := w.Dropped(); got != tt.wantDropped {
+				t.Errorf("dropped = %d, want %d", got, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestHTTPWriter_DropPolicy(t *testing.T) {
+	tests := []struct {
+		policy string
+		want   []string
+	}{
+		{DropNewest, []string{"m1", "m2", "m3"}},
+		{DropOldest, []string{"m1", "m3", "m4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			arrived := make(chan struct{}, 10)
+			release := make(chan struct{})
+			server := newLogServer(t, func(w http.ResponseWriter, r *http.Request) bool {
+				arrived <- struct{}{}
+				<-release
+				return true
+			})
+			l, w := newHTTPTestLogger(t, SinkOptions{
+				URL: server.URL, Protocol: ProtocolLoki, BatchSize: 1, BufferSize: 2, DropPolicy: tt.policy,
+			})
+
+			// m1 被后台协程取出并阻塞在推送中，m2、m3 填满缓冲区，m4 触发丢弃
+			l.Info("m1")
+			<-arrived
+			for _, msg := range []string{"m2", "m3", "m4"} {
+				l.Info(msg)
+			}
+			close(release)
+			_ = w.Sync()
+
+			var got []string
+			for _, body := range server.received() {
+				for _, msg := range []string{"m1", "m2", "m3", "m4"} {
+					if strings.Contains(body, `\"msg\":\"`+msg+`\"`) {
+						got = append(got, msg)
+					}
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("received = %v, want %v", got, tt.want)
+			}
+			if w.Dropped() != 1 {
+				t.Errorf("dropped = %d, want 1", w.Dropped())
+			}
+		})
+	}
+}
+
+func TestNew_Sinks(t *testing.T) {
+	dir := t.TempDir()
+	server := newLogServer(t, nil)
+	l, closeFn, err := New(Options{
+		Dir: dir, Level: "info", StdoutLevel: LevelOff, FileLevel: LevelOff,
+		Sinks: []SinkOptions{
+			{Type: SinkFile, Level: "error", Pattern: "{date}.errors.log", Format: FormatJSON},
+			{Type: SinkHTTP, URL: server.URL, Protocol: ProtocolLoki, FlushInterval: time.Hour},
+			{Type: SinkSyslog, Level: LevelOff},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	l.Debug("debug message")
+	l.Info("info message")
+	l.Error("error message")
+	if err := closeFn(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	errs := readLogFile(t, dir, ".errors.log")
+	if strings.Contains(errs, "info message") || !strings.Contains(errs, `"msg":"error message"`) {
+		t.Errorf("unexpected error file content: %q", errs)
+	}
+	bodies := strings.Join(server.received(), "")
+	if strings.Contains(bodies, "debug message") || !strings.Contains(bodies, "info message") || !strings.Contains(bodies, "error message") {
+		t.Errorf("unexpected http sink content: %q", bodies)
+	}
+
+	if _, _, err := New(Options{Level: "info", Sinks: []SinkOptions{{Type: SinkFile, Pattern: "{date}.x.log"}}}); err == nil {
+		t.Error("file sink without dir should fail")
+	}
+	if _, _, err := New(Options{Level: "info", Sinks: []SinkOptions{{Type: "kafka"}}}); err == nil {
+		t.Error("unknown sink type should fail")
+	}
+}
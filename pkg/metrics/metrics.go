@@ -0,0 +1,252 @@
+// Package metrics 提供轻量的指标注册表，并以 Prometheus 文本格式导出。
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 指标类型
+const (
+	TypeGauge   = "gauge"
+	TypeCounter = "counter"
+)
+
+var defaultRegistry = NewRegistry()
+
+// Default 返回全局默认注册表。
+func Default() *Registry {
+	return defaultRegistry
+}
+
+// Registry 指标注册表。
+type Registry struct {
+	mu       sync.RWMutex
+	families map[string]*family
+}
+
+// NewRegistry 创建空的注册表。
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+type family struct {
+	name       string
+	help       string
+	typ        string
+	labelNames []string
+
+	mu     sync.RWMutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+
+	mu    sync.Mutex
+	value float64
+}
+
+// Vec 是一组同名、不同标签值的指标。
+type Vec struct {
+	f *family
+}
+
+// Metric 是具体标签值下的单个指标。
+type Metric struct {
+	s *series
+}
+
+// NewGaugeVec 在默认注册表中注册 gauge。
+func NewGaugeVec(name, help string, labelNames ...string) *Vec {
+	return defaultRegistry.GaugeVec(name, help, labelNames...)
+}
+
+// NewCounterVec 在默认注册表中注册 counter。
+func NewCounterVec(name, help string, labelNames ...string) *Vec {
+	return defaultRegistry.CounterVec(name, help, labelNames...)
+}
+
+// GaugeVec 注册（或获取已注册的）gauge。
+func (r *Registry) GaugeVec(name, help string, labelNames ...string) *Vec {
+	return r.register(name, help, TypeGauge, labelNames)
+}
+
+// CounterVec 注册（或获取已注册的）counter。
+func (r *Registry) CounterVec(name, help string, labelNames ...string) *Vec {
+	return r.register(name, help, TypeCounter, labelNames)
+}
+
+func (r *Registry) register(name, help, typ string, labelNames []string) *Vec {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if f, ok := r.families[name]; ok {
+		if f.typ != typ || len(f.labelNames) != len(labelNames) {
+			panic(fmt.Sprintf("metrics: %s already registered with different type or labels", name))
+		}
+		return &Vec{f: f}
+	}
+
+	f := &family{
+		name:       name,
+		help:       help,
+		typ:        typ,
+		labelNames: append([]string(nil), labelNames...),
+		series:     make(map[string]*series),
+	}
+	r.families[name] = f
+	return &Vec{f: f}
+}
+
+// WithLabelValues 按标签值获取指标，标签值数量必须与注册时一致。
+func (v *Vec) WithLabelValues(values ...string) Metric {
+	if len(values) != len(v.f.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.f.name, len(v.f.labelNames), len(values)))
+	}
+
+	key := strings.Join(values, "\xff")
+
+	v.f.mu.RLock()
+	s, ok := v.f.series[key]
+	v.f.mu.RUnlock()
+	if ok {
+		return Metric{s: s}
+	}
+
+	v.f.mu.Lock()
+	defer v.f.mu.Unlock()
+	if s, ok = v.f.series[key]; !ok {
+		s = &series{labelValues: append([]string(nil), values...)}
+		v.f.series[key] = s
+	}
+	return Metric{s: s}
+}
+
+// Delete 删除指定标签值的指标。
+func (v *Vec) Delete(values ...string) {
+	v.f.mu.Lock()
+	delete(v.f.series, strings.Join(values, "\xff"))
+	v.f.mu.Unlock()
+}
+
+// Set 设置当前值（gauge）。
+func (m Metric) Set(value float64) {
+	m.s.mu.Lock()
+	m.s.value = value
+	m.s.mu.Unlock()
+}
+
+// Add 增加指定值。
+func (m Metric) Add(delta float64) {
+	m.s.mu.Lock()
+	m.s.value += delta
+	m.s.mu.Unlock()
+}
+
+// Inc 加一。
+func (m Metric) Inc() {
+	m.Add(1)
+}
+
+// Value 返回当前值。
+func (m Metric) Value() float64 {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+	return m.s.value
+}
+
+// WriteText 以 Prometheus 文本格式写出所有指标。
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	r.mu.RUnlock()
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		r.mu.RLock()
+		f := r.families[name]
+		r.mu.RUnlock()
+
+		f.mu.RLock()
+		keys := make([]string, 0, len(f.series))
+		for k := range f.series {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		if f.help != "" {
+			fmt.Fprintf(&sb, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+		}
+		fmt.Fprintf(&sb, "# TYPE %s %s\n", f.name, f.typ)
+		for _, k := range keys {
+			s := f.series[k]
+			sb.WriteString(f.name)
+			if len(f.labelNames) > 0 {
+				sb.WriteByte('{')
+				for i, ln := range f.labelNames {
+					if i > 0 {
+						sb.WriteByte(',')
+					}
+					fmt.Fprintf(&sb, "%s=\"%s\"", ln, escapeLabelValue(s.labelValues[i]))
+				}
+				sb.WriteByte('}')
+			}
+			s.mu.Lock()
+			sb.WriteByte(' ')
+			sb.WriteString(formatValue(s.value))
+			s.mu.Unlock()
+			sb.WriteByte('\n')
+		}
+		f.mu.RUnlock()
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Handler 返回导出默认注册表的 HTTP 处理器。
+func Handler() http.Handler {
+	return defaultRegistry.Handler()
+}
+
+// Handler 返回导出当前注册表的 HTTP 处理器。
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := r.WriteText(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+// escapeLabelValue 按文本格式转义标签值，只转义反斜杠、双引号与换行
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_WriteText(t *testing.T) {
+	r := NewRegistry()
+	open := r.GaugeVec("db_open", "Open connections.", "component")
+	waits := r.CounterVec("db_waits_total", "Waits.", "component")
+
+	open.WithLabelValues("mysql").Set(3)
+	open.WithLabelValues("gorm").Set(1)
+	waits.WithLabelValues("mysql").Inc()
+	waits.WithLabelValues("mysql").Add(2)
+
+	var sb strings.Builder
+	require.NoError(t, r.WriteText(&sb))
+
+	out := sb.String()
+	assert.Contains(t, out, "# HELP db_open Open connections.\n# TYPE db_open gauge\n")
+	assert.Contains(t, out, `db_open{component="gorm"} 1`)
+	assert.Contains(t, out, `db_open{component="mysql"} 3`)
+	assert.Contains(t, out, "# TYPE db_waits_total counter\n")
+	assert.Contains(t, out, `db_waits_total{component="mysql"} 3`)
+}
+
+func TestRegistry_WriteTextEscapesLabelValues(t *testing.T) {
+	r := NewRegistry()
+	c := r.CounterVec("requests_total", "Requests.", "path")
+	c.WithLabelValues("a\\b\"c\nd").Inc()
+	c.WithLabelValues("用户\t列表").Inc()
+
+	var sb strings.Builder
+	require.NoError(t, r.WriteText(&sb))
+
+	out := sb.String()
+	assert.Contains(t, out, `requests_total{path="a\\b\"c\nd"} 1`)
+	// 非 ASCII 与制表符原样输出，不使用 Go 字符串转义
+	assert.Contains(t, out, "requests_total{path=\"用户\t列表\"} 1")
+}
+
+func TestRegistry_ReRegister(t *testing.T) {
+	r := NewRegistry()
+	a := r.GaugeVec("same", "", "l")
+	b := r.GaugeVec("same", "", "l")
+
+	a.WithLabelValues("x").Set(5)
+	assert.Equal(t, float64(5), b.WithLabelValues("x").Value())
+
+	assert.Panics(t, func() { r.CounterVec("same", "", "l") })
+	assert.Panics(t, func() { a.WithLabelValues("x", "y") })
+}
+
+func TestVec_Delete(t *testing.T) {
+	r := NewRegistry()
+	g := r.GaugeVec("g", "", "l")
+	g.WithLabelValues("x").Set(1)
+	g.Delete("x")
+
+	var sb strings.Builder
+	require.NoError(t, r.WriteText(&sb))
+	assert.NotContains(t, sb.String(), `g{l="x"}`)
+}
@@ -0,0 +1,118 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"project/pkg/database"
+	"project/pkg/queue"
+
+	"gorm.io/gorm"
+)
+
+// Inbox 消费端收件箱，按消息 ID 去重实现幂等处理
+//
+// 消息 ID 与业务数据在同一个事务中写入：事务提交即视为处理完成，
+// 回滚时 ID 一并撤销，消息重投后可再次处理。
+type Inbox struct {
+	db       *sql.DB
+	dialect  database.Dialect
+	table    string
+	consumer string
+}
+
+// NewInbox 创建收件箱，consumer 区分同一消息的不同消费方（如服务名），table 为空时使用 DefaultInboxTable
+func NewInbox(db *sql.DB, d database.Dialect, table, consumer string) *Inbox {
+	if table == "" {
+		table = DefaultInboxTable
+	}
+	return &Inbox{db: db, dialect: d, table: table, consumer: consumer}
+}
+
+// Process 在事务中处理消息，已处理过的消息直接返回 nil：
+//
+//	sub, _ := broker.Subscribe("orders", func(ctx context.Context, msg *queue.Message) error {
+//		return inbox.Process(ctx, msg, func(tx *sql.Tx) error {
+//			_, err := tx.ExecContext(ctx, "UPDATE stock SET ...")
+//			return err
+//		})
+//	})
+func (i *Inbox) Process(ctx context.Context, msg *queue.Message, fn func(tx *sql.Tx) error) error {
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	first, err := i.record(ctx, tx, msg)
+	if err != nil || !first {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ProcessGorm 在 GORM 事务中处理消息，已处理过的消息直接返回 nil
+func (i *Inbox) ProcessGorm(ctx context.Context, db *gorm.DB, msg *queue.Message, fn func(tx *gorm.DB) error) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		first, err := i.record(ctx, tx.Statement.ConnPool, msg)
+		if err != nil || !first {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// Processed 消息是否已处理
+func (i *Inbox) Processed(ctx context.Context, id string) (bool, error) {
+	query, args := database.Select(i.dialect, i.table, "message_id").
+		Where("consumer = ?", i.consumer).
+		Where("message_id = ?", id).
+		Build()
+	var got string
+	err := i.db.QueryRowContext(ctx, query, args...).Scan(&got)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Purge 删除早于 before 的处理记录，返回删除数量；应保证 before 之前的消息不会再被重投
+func (i *Inbox) Purge(ctx context.Context, before time.Time) (int64, error) {
+	query, args := database.Delete(i.dialect, i.table).
+		Where("consumer = ?", i.consumer).
+		Where("processed_at < ?", before.UTC()).
+		Build()
+	res, err := i.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("inbox: purge: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// record 写入处理记录，返回 false 表示已处理过
+//
+// 并发重复投递时后到的事务会在唯一键上等待先到的事务结束，不会重复处理。
+func (i *Inbox) record(ctx context.Context, tx Execer, msg *queue.Message) (bool, error) {
+	if msg.ID == "" {
+		return false, queue.Permanent(errors.New("inbox: message without id"))
+	}
+	query, args := database.Insert(i.dialect, i.table, "consumer", "message_id", "processed_at").
+		Values(i.consumer, msg.ID, now()).
+		Ignore().
+		Build()
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("inbox: insert: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
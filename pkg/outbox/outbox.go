@@ -0,0 +1,173 @@
+// Package outbox 提供事务性发件箱与收件箱，保证数据库写入与消息发布的一致性。
+//
+// 业务数据与待发布的消息在同一个事务中写入，提交后由 Relay 异步投递到消息队列：
+//
+//	mysql := database.GetMysql()
+//	store := outbox.NewStore(mysql.Dialect(), "")
+//	tx, _ := mysql.GetDb().BeginTx(ctx, nil)
+//	// ... 写业务数据
+//	msg, _ := queue.NewMessage("order.created", order)
+//	err := store.Add(ctx, tx, "orders", order.No, msg)
+//	err = tx.Commit()
+//
+// Relay 至少投递一次（崩溃重启后可能重复），消费端通过 Inbox 按消息 ID 去重。
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"project/pkg/database"
+	"project/pkg/queue"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultOutboxTable 默认发件箱表名
+	DefaultOutboxTable = "outbox_messages"
+	// DefaultInboxTable 默认收件箱表名
+	DefaultInboxTable = "inbox_messages"
+)
+
+// Execer 可执行语句的连接或事务，*sql.DB、*sql.Tx 以及 gorm 的 ConnPool 均满足
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Migration 返回创建发件箱与收件箱表的迁移，version 由调用方按自己的迁移序号指定，表名为空时使用默认值
+func Migration(version int64, outboxTable, inboxTable string) database.Migration {
+	if outboxTable == "" {
+		outboxTable = DefaultOutboxTable
+	}
+	if inboxTable == "" {
+		inboxTable = DefaultInboxTable
+	}
+	return database.Migration{
+		Version: version,
+		Name:    fmt.Sprintf("create %s and %s", outboxTable, inboxTable),
+		Up: func(d database.Dialect) []string {
+			q := d.Quote
+			return []string{
+				fmt.Sprintf(`CREATE TABLE %s (
+	%s %s,
+	%s VARCHAR(64) NOT NULL,
+	%s VARCHAR(255) NOT NULL,
+	%s VARCHAR(255) NOT NULL,
+	%s VARCHAR(255) NOT NULL,
+	%s %s,
+	%s %s,
+	%s %s NOT NULL,
+	%s INT NOT NULL DEFAULT 0,
+	%s %s,
+	%s %s NULL,
+	%s %s NULL
+)`,
+					q(outboxTable),
+					q("id"), d.AutoIncrementPK(),
+					q("message_id"),
+					q("topic"),
+					q("aggregate_key"),
+					q("type"),
+					q("headers"), d.TextType(),
+					q("payload"), d.BlobType(),
+					q("created_at"), d.TimestampType(),
+					q("attempts"),
+					q("last_error"), d.TextType(),
+					q("next_attempt_at"), d.TimestampType(),
+					q("published_at"), d.TimestampType(),
+				),
+				fmt.Sprintf("CREATE INDEX %s ON %s (%s, %s)",
+					q("idx_"+outboxTable+"_pending"), q(outboxTable), q("published_at"), q("id")),
+				fmt.Sprintf(`CREATE TABLE %s (
+	%s VARCHAR(255) NOT NULL,
+	%s VARCHAR(64) NOT NULL,
+	%s %s NOT NULL,
+	PRIMARY KEY (%s, %s)
+)`,
+					q(inboxTable),
+					q("consumer"),
+					q("message_id"),
+					q("processed_at"), d.TimestampType(),
+					q("consumer"), q("message_id"),
+				),
+			}
+		},
+	}
+}
+
+// Store 向发件箱写入消息
+type Store struct {
+	dialect database.Dialect
+	table   string
+}
+
+// NewStore 创建发件箱写入器，table 为空时使用 DefaultOutboxTable
+func NewStore(d database.Dialect, table string) *Store {
+	if table == "" {
+		table = DefaultOutboxTable
+	}
+	return &Store{dialect: d, table: table}
+}
+
+// Add 在事务 tx 中写入待发布的消息
+//
+// key 为聚合键（如订单号），同一聚合键的消息按写入顺序投递；为空表示不要求顺序。
+func (s *Store) Add(ctx context.Context, tx Execer, topic, key string, msgs ...*queue.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	b := database.Insert(s.dialect, s.table,
+		"message_id", "topic", "aggregate_key", "type", "headers", "payload", "created_at")
+	for _, m := range msgs {
+		// 补全 ID 与时间戳，调用方持有的消息同步更新
+		if m.ID == "" {
+			m.ID = uuid.NewString()
+		}
+		if m.Timestamp.IsZero() {
+			m.Timestamp = time.Now()
+		}
+
+		var headers interface{}
+		if len(m.Headers) > 0 {
+			data, err := json.Marshal(m.Headers)
+			if err != nil {
+				return fmt.Errorf("outbox: encode headers: %w", err)
+			}
+			headers = string(data)
+		}
+		b.Values(m.ID, topic, key, m.Type, headers, m.Payload, m.Timestamp.UTC())
+	}
+
+	query, args := b.Build()
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("outbox: insert: %w", err)
+	}
+	return nil
+}
+
+// AddGorm 在 GORM 事务中写入待发布的消息：
+//
+//	err := db.Transaction(func(tx *gorm.DB) error {
+//		if err := tx.Create(&order).Error; err != nil {
+//			return err
+//		}
+//		return store.AddGorm(tx, "orders", order.No, msg)
+//	})
+func (s *Store) AddGorm(tx *gorm.DB, topic, key string, msgs ...*queue.Message) error {
+	ctx := tx.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return s.Add(ctx, tx.Statement.ConnPool, topic, key, msgs...)
+}
+
+// now 统一以 UTC 存储时间
+func now() time.Time {
+	return time.Now().UTC()
+}
@@ -0,0 +1,362 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"project/pkg/config"
+	"project/pkg/database"
+	"project/pkg/lock"
+	"project/pkg/logger"
+	"project/pkg/queue"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = zap.NewNop()
+	logger.Sugar = logger.Logger.Sugar()
+	os.Exit(m.Run())
+}
+
+// newTestDB 创建内存库并建表
+func newTestDB(t *testing.T) *database.Sqlite {
+	t.Helper()
+
+	s := database.NewSqlite(&config.Sqlite{Path: ":memory:"})
+	require.True(t, s.InitComponent())
+	t.Cleanup(func() { s.Close() })
+
+	err := database.Migrate(context.Background(), s.GetSqlDb(), s.Dialect(), []database.Migration{Migration(1, "", "")})
+	require.NoError(t, err)
+	return s
+}
+
+// recordingPublisher 记录发布的消息，fail 返回非 nil 时发布失败
+type recordingPublisher struct {
+	mu        sync.Mutex
+	published []*queue.Message
+	topics    []string
+	fail      func(msg *queue.Message) error
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, topic string, msgs ...*queue.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, m := range msgs {
+		if p.fail != nil {
+			if err := p.fail(m); err != nil {
+				return err
+			}
+		}
+		p.published = append(p.published, m)
+		p.topics = append(p.topics, topic)
+	}
+	return nil
+}
+
+func (p *recordingPublisher) types() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var types []string
+	for _, m := range p.published {
+		types = append(types, m.Type)
+	}
+	return types
+}
+
+func count(t *testing.T, db *sql.DB, where string) int {
+	var n int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM outbox_messages WHERE "+where).Scan(&n))
+	return n
+}
+
+func addInTx(t *testing.T, s *database.Sqlite, store *Store, key string, types ...string) {
+	t.Helper()
+	tx, err := s.GetSqlDb().Begin()
+	require.NoError(t, err)
+	for _, typ := range types {
+		msg, err := queue.NewMessage(typ, map[string]string{"key": key})
+		require.NoError(t, err)
+		require.NoError(t, store.Add(context.Background(), tx, "orders", key, msg))
+	}
+	require.NoError(t, tx.Commit())
+}
+
+func TestStore_AddWithinTransaction(t *testing.T) {
+	s := newTestDB(t)
+	db := s.GetSqlDb()
+	store := NewStore(s.Dialect(), "")
+
+	msg, err := queue.NewMessage("order.created", map[string]int{"id": 1})
+	require.NoError(t, err)
+	msg.SetHeader("trace-id", "abc")
+
+	// 回滚时消息一并撤销
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	require.NoError(t, store.Add(context.Background(), tx, "orders", "1", msg))
+	require.NoError(t, tx.Rollback())
+	assert.Equal(t, 0, count(t, db, "1 = 1"))
+
+	tx, err = db.Begin()
+	require.NoError(t, err)
+	require.NoError(t, store.Add(context.Background(), tx, "orders", "1", msg))
+	require.NoError(t, tx.Commit())
+
+	pub := &recordingPublisher{}
+	relay := NewRelay(db, s.Dialect(), pub, RelayOptions{})
+	n, err := relay.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	require.Len(t, pub.published, 1)
+	got := pub.published[0]
+	assert.Equal(t, "orders", pub.topics[0])
+	assert.Equal(t, msg.ID, got.ID)
+	assert.Equal(t, "order.created", got.Type)
+	assert.Equal(t, "abc", got.Header("trace-id"))
+	assert.JSONEq(t, `{"id":1}`, string(got.Payload))
+	assert.WithinDuration(t, msg.Timestamp, got.Timestamp, time.Second)
+
+	// 已投递的消息不再读取
+	n, err = relay.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, 1, count(t, db, "published_at IS NOT NULL"))
+}
+
+func TestStore_AddGorm(t *testing.T) {
+	s := newTestDB(t)
+	store := NewStore(s.Dialect(), "")
+
+	type Order struct {
+		ID int64 `gorm:"primaryKey"`
+		No string
+	}
+	require.NoError(t, s.GetDb().AutoMigrate(&Order{}))
+
+	boom := errors.New("boom")
+	err := s.GetDb().Transaction(func(tx *gorm.DB) error {
+		require.NoError(t, tx.Create(&Order{No: "A1"}).Error)
+		msg, _ := queue.NewMessage("order.created", nil)
+		require.NoError(t, store.AddGorm(tx, "orders", "A1", msg))
+		return boom
+	})
+	assert.ErrorIs(t, err, boom)
+	assert.Equal(t, 0, count(t, s.GetSqlDb(), "1 = 1"))
+
+	err = s.GetDb().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&Order{No: "A2"}).Error; err != nil {
+			return err
+		}
+		msg, _ := queue.NewMessage("order.created", nil)
+		return store.AddGorm(tx, "orders", "A2", msg)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count(t, s.GetSqlDb(), "aggregate_key = 'A2'"))
+}
+
+func TestRelay_PreservesOrderPerAggregate(t *testing.T) {
+	s := newTestDB(t)
+	store := NewStore(s.Dialect(), "")
+	addInTx(t, s, store, "a", "a1", "a2")
+	addInTx(t, s, store, "b", "b1")
+	addInTx(t, s, store, "a", "a3")
+
+	failing := true
+	pub := &recordingPublisher{fail: func(msg *queue.Message) error {
+		if failing && msg.Type == "a1" {
+			return errors.New("broker unavailable")
+		}
+		return nil
+	}}
+	relay := NewRelay(s.GetSqlDb(), s.Dialect(), pub, RelayOptions{Concurrency: 2, RetryDelay: 10 * time.Millisecond})
+
+	// a1 失败时 a 的后续消息暂停，b 不受影响
+	n, err := relay.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"b1"}, pub.types())
+	assert.Equal(t, 1, count(t, s.GetSqlDb(), "attempts = 1 AND last_error = 'broker unavailable'"))
+
+	// 退避期间 a 的消息都不读取
+	failing = false
+	n, err = relay.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	time.Sleep(20 * time.Millisecond)
+	n, err = relay.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, []string{"b1", "a1", "a2", "a3"}, pub.types())
+}
+
+func TestRelay_FailingMessagesDoNotStarve(t *testing.T) {
+	s := newTestDB(t)
+	db := s.GetSqlDb()
+	store := NewStore(s.Dialect(), "")
+	addInTx(t, s, store, "", "bad", "bad")
+	addInTx(t, s, store, "k", "bad", "k2")
+	addInTx(t, s, store, "", "ok1", "ok2")
+
+	pub := &recordingPublisher{fail: func(msg *queue.Message) error {
+		if msg.Type == "bad" {
+			return errors.New("rejected")
+		}
+		return nil
+	}}
+	relay := NewRelay(db, s.Dialect(), pub, RelayOptions{BatchSize: 2, RetryDelay: time.Minute, MaxAttempts: 3})
+
+	// 失败的消息进入退避，后续批次继续读取之后的消息
+	for i := 0; i < 3; i++ {
+		_, err := relay.Flush(context.Background())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"ok1", "ok2"}, pub.types())
+	assert.Equal(t, 3, count(t, db, "attempts = 1 AND next_attempt_at IS NOT NULL"))
+
+	// 达到 MaxAttempts 后不再投递，同一聚合键的后续消息保持暂停
+	_, err := db.Exec("UPDATE outbox_messages SET next_attempt_at = NULL")
+	require.NoError(t, err)
+	relay = NewRelay(db, s.Dialect(), pub, RelayOptions{BatchSize: 2, RetryDelay: time.Millisecond, MaxAttempts: 3})
+	for i := 0; i < 10; i++ {
+		time.Sleep(5 * time.Millisecond)
+		_, err := relay.Flush(context.Background())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3, count(t, db, "attempts = 3 AND last_error = 'rejected'"))
+	assert.Equal(t, 1, count(t, db, "aggregate_key = 'k' AND attempts = 0 AND published_at IS NULL"))
+	assert.Equal(t, []string{"ok1", "ok2"}, pub.types())
+
+	n, err := relay.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestRelay_RetryDelay(t *testing.T) {
+	relay := NewRelay(nil, nil, nil, RelayOptions{RetryDelay: time.Second, MaxRetryDelay: 5 * time.Second})
+	assert.Equal(t, time.Second, relay.retryDelay(1))
+	assert.Equal(t, 2*time.Second, relay.retryDelay(2))
+	assert.Equal(t, 4*time.Second, relay.retryDelay(3))
+	assert.Equal(t, 5*time.Second, relay.retryDelay(4))
+	assert.Equal(t, 5*time.Second, relay.retryDelay(100))
+}
+
+func TestRelay_Cleanup(t *testing.T) {
+	s := newTestDB(t)
+	store := NewStore(s.Dialect(), "")
+	addInTx(t, s, store, "", "a", "b")
+
+	relay := NewRelay(s.GetSqlDb(), s.Dialect(), &recordingPublisher{}, RelayOptions{Retention: time.Millisecond})
+	_, err := relay.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, count(t, s.GetSqlDb(), "published_at IS NOT NULL"))
+
+	time.Sleep(10 * time.Millisecond)
+	n, err := relay.Cleanup(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	// 不保留时投递后立即删除
+	addInTx(t, s, store, "", "c")
+	relay = NewRelay(s.GetSqlDb(), s.Dialect(), &recordingPublisher{}, RelayOptions{Retention: -1})
+	_, err = relay.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, count(t, s.GetSqlDb(), "1 = 1"))
+}
+
+func TestRelay_LeaderOnly(t *testing.T) {
+	s := newTestDB(t)
+	store := NewStore(s.Dialect(), "")
+	locker := lock.NewMemoryLocker()
+	pub := &recordingPublisher{}
+
+	var relays []*Relay
+	for i := 0; i < 2; i++ {
+		relay := NewRelay(s.GetSqlDb(), s.Dialect(), pub, RelayOptions{
+			PollInterval: 10 * time.Millisecond,
+			Locker:       locker,
+		})
+		require.NoError(t, relay.Start())
+		t.Cleanup(func() { _ = relay.Stop(context.Background()) })
+		relays = append(relays, relay)
+	}
+
+	for i := 0; i < 20; i++ {
+		addInTx(t, s, store, "k", "m")
+		relays[i%2].Trigger()
+	}
+
+	require.Eventually(t, func() bool { return count(t, s.GetSqlDb(), "published_at IS NULL") == 0 }, 2*time.Second, 10*time.Millisecond)
+	assert.Len(t, pub.types(), 20)
+}
+
+func TestRelay_WithMemoryBroker(t *testing.T) {
+	s := newTestDB(t)
+	store := NewStore(s.Dialect(), "")
+	broker := queue.NewMemoryBroker(queue.MemoryBrokerOptions{})
+
+	relay := NewRelay(s.GetSqlDb(), s.Dialect(), broker, RelayOptions{PollInterval: 10 * time.Millisecond})
+	require.NoError(t, relay.Start())
+	t.Cleanup(func() { _ = relay.Stop(context.Background()) })
+
+	addInTx(t, s, store, "1", "order.created", "order.paid")
+	relay.Trigger()
+
+	require.Eventually(t, func() bool { return broker.Pending("orders") == 2 }, time.Second, 10*time.Millisecond)
+}
+
+func TestInbox_Process(t *testing.T) {
+	s := newTestDB(t)
+	inbox := NewInbox(s.GetSqlDb(), s.Dialect(), "", "billing")
+	msg, err := queue.NewMessage("order.created", nil)
+	require.NoError(t, err)
+
+	// 处理失败时记录随事务回滚，消息可再次处理
+	boom := errors.New("boom")
+	err = inbox.Process(context.Background(), msg, func(tx *sql.Tx) error { return boom })
+	assert.ErrorIs(t, err, boom)
+	processed, err := inbox.Processed(context.Background(), msg.ID)
+	require.NoError(t, err)
+	assert.False(t, processed)
+
+	calls := 0
+	for i := 0; i < 3; i++ {
+		err := inbox.Process(context.Background(), msg, func(tx *sql.Tx) error {
+			calls++
+			return nil
+		})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, calls)
+	processed, err = inbox.Processed(context.Background(), msg.ID)
+	require.NoError(t, err)
+	assert.True(t, processed)
+
+	// 不同的消费方各自处理一次
+	other := NewInbox(s.GetSqlDb(), s.Dialect(), "", "shipping")
+	err = other.ProcessGorm(context.Background(), s.GetDb(), msg, func(tx *gorm.DB) error {
+		calls++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	n, err := inbox.Purge(context.Background(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	// 没有 ID 的消息无法去重
+	err = inbox.Process(context.Background(), &queue.Message{}, func(tx *sql.Tx) error { return nil })
+	assert.Error(t, err)
+}
@@ -0,0 +1,388 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"project/pkg/database"
+	"project/pkg/lock"
+	"project/pkg/logger"
+	"project/pkg/queue"
+)
+
+// RelayOptions 投递器配置
+type RelayOptions struct {
+	// Table 发件箱表名，默认 DefaultOutboxTable
+	Table string
+	// BatchSize 每次读取的消息数，默认 100
+	BatchSize int
+	// PollInterval 轮询间隔，默认 1s；写入后调用 Trigger 可立即投递
+	PollInterval time.Duration
+	// Concurrency 不同聚合键的消息并发投递数，同一聚合键始终串行，默认 1
+	Concurrency int
+	// RetryDelay 发布失败后首次重试的延迟，之后每次翻倍，默认 1s
+	RetryDelay time.Duration
+	// MaxRetryDelay 重试延迟上限，默认 10m
+	MaxRetryDelay time.Duration
+	// MaxAttempts 最大发布次数，达到后消息不再投递（保留在表中，attempts 与 last_error 记录原因），0 表示一直重试
+	MaxAttempts int
+	// Retention 已投递消息的保留时长，默认 24h；小于 0 表示投递后立即删除
+	Retention time.Duration
+	// CleanupInterval 清理已投递消息的间隔，默认 10m
+	CleanupInterval time.Duration
+	// Locker 多副本部署时用于选举，只有主节点投递；为空时直接运行（仅适用于单副本）
+	Locker lock.Locker
+	// LockKey 选举键，默认 outbox:<table>
+	LockKey string
+}
+
+// Relay 轮询发件箱并发布到消息队列，实现 app.Worker
+//
+// 消息发布成功后才标记为已投递，因此进程崩溃可能导致重复投递（至少一次）。
+// 同一聚合键的消息按写入顺序投递，其中一条失败时按退避延迟重试，期间同一聚合键的后续消息暂停，
+// 其他消息不受影响；达到 MaxAttempts 的消息不再投递，同一聚合键的后续消息随之停止，需人工处理。
+type Relay struct {
+	db      *sql.DB
+	dialect database.Dialect
+	pub     queue.Publisher
+	opts    RelayOptions
+	wake    chan struct{}
+
+	mu      sync.Mutex
+	elector *lock.Elector
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewRelay 创建投递器：
+//
+//	broker, _ := queue.Open("default")
+//	relay := outbox.NewRelay(mysql.GetDb(), mysql.Dialect(), broker, outbox.RelayOptions{
+//		Locker: lock.NewRedisLocker(database.GetRedis().GetClient()),
+//	})
+//	app.RegisterWorker(relay)
+func NewRelay(db *sql.DB, d database.Dialect, pub queue.Publisher, opts RelayOptions) *Relay {
+	if opts.Table == "" {
+		opts.Table = DefaultOutboxTable
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = time.Second
+	}
+	if opts.MaxRetryDelay <= 0 {
+		opts.MaxRetryDelay = 10 * time.Minute
+	}
+	if opts.Retention == 0 {
+		opts.Retention = 24 * time.Hour
+	}
+	if opts.CleanupInterval <= 0 {
+		opts.CleanupInterval = 10 * time.Minute
+	}
+	if opts.LockKey == "" {
+		opts.LockKey = "outbox:" + opts.Table
+	}
+	return &Relay{
+		db:      db,
+		dialect: d,
+		pub:     pub,
+		opts:    opts,
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Name 返回投递器名称
+func (r *Relay) Name() string {
+	return "outbox-relay:" + r.opts.Table
+}
+
+// Start 启动投递（重复调用无效）
+func (r *Relay) Start() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.elector != nil || r.done != nil {
+		return nil
+	}
+
+	if r.opts.Locker != nil {
+		r.elector = lock.NewElector(r.opts.Locker, r.opts.LockKey, lock.ElectorOptions{OnElected: r.run})
+		return r.elector.Start()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+	go func(done chan struct{}) {
+		defer close(done)
+		r.run(ctx)
+	}(r.done)
+	return nil
+}
+
+// Stop 停止投递，等待正在进行的批次完成
+func (r *Relay) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	elector, cancel, done := r.elector, r.cancel, r.done
+	r.elector, r.cancel, r.done = nil, nil, nil
+	r.mu.Unlock()
+
+	if elector != nil {
+		return elector.Stop(ctx)
+	}
+	if done == nil {
+		return nil
+	}
+
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Trigger 唤醒投递器立即轮询，通常在写入发件箱的事务提交后调用
+func (r *Relay) Trigger() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run 轮询直到 ctx 结束（选举模式下为失去主节点身份）
+func (r *Relay) run(ctx context.Context) {
+	logger.Sugar.Infof("\t[outbox] %s started", r.Name())
+	defer logger.Sugar.Infof("\t[outbox] %s stopped", r.Name())
+
+	poll := time.NewTicker(r.opts.PollInterval)
+	defer poll.Stop()
+	cleanup := time.NewTicker(r.opts.CleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		// 积压时连续读取，直到一批未满或没有可投递的消息
+		for {
+			n, err := r.Flush(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Sugar.Errorf("\t[outbox] %s flush failed: %v", r.Name(), err)
+				}
+				break
+			}
+			if n < r.opts.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+		case <-r.wake:
+		case <-cleanup.C:
+			if _, err := r.Cleanup(ctx); err != nil && ctx.Err() == nil {
+				logger.Sugar.Errorf("\t[outbox] %s cleanup failed: %v", r.Name(), err)
+			}
+		}
+	}
+}
+
+// record 发件箱中的一条消息
+type record struct {
+	id       int64
+	topic    string
+	key      string
+	attempts int
+	msg      *queue.Message
+}
+
+// Flush 读取一批待投递的消息并发布，返回成功投递的数量
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	records, err := r.pending(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// 按聚合键分组并保持组内顺序，没有聚合键的消息各自成组
+	var groups [][]record
+	index := make(map[string]int)
+	for _, rec := range records {
+		if rec.key == "" {
+			groups = append(groups, []record{rec})
+			continue
+		}
+		i, ok := index[rec.key]
+		if !ok {
+			i = len(groups)
+			index[rec.key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], rec)
+	}
+
+	var (
+		mu        sync.Mutex
+		published int
+		wg        sync.WaitGroup
+		sem       = make(chan struct{}, r.opts.Concurrency)
+	)
+	for _, group := range groups {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(group []record) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			n := r.publishGroup(ctx, group)
+			mu.Lock()
+			published += n
+			mu.Unlock()
+		}(group)
+	}
+	wg.Wait()
+
+	return published, ctx.Err()
+}
+
+// publishGroup 按顺序发布同一聚合键的消息，遇到失败即停止
+func (r *Relay) publishGroup(ctx context.Context, group []record) int {
+	for i, rec := range group {
+		if err := r.pub.Publish(ctx, rec.topic, rec.msg); err != nil {
+			if ctx.Err() == nil {
+				logger.Sugar.Warnf("\t[outbox] %s publish %s to %s failed: %v", r.Name(), rec.msg.ID, rec.topic, err)
+				r.markFailed(rec, err)
+			}
+			return i
+		}
+		if err := r.markPublished(rec.id); err != nil {
+			// 消息已发布但未能标记，下次轮询会重复投递，由消费端去重
+			logger.Sugar.Errorf("\t[outbox] %s mark %s published failed: %v", r.Name(), rec.msg.ID, err)
+			return i
+		}
+	}
+	return len(group)
+}
+
+// pending 按写入顺序读取到期的未投递消息，跳过等待重试或已放弃的消息及其所在聚合键的后续消息
+func (r *Relay) pending(ctx context.Context) ([]record, error) {
+	q := r.dialect.Quote
+	ts := now()
+
+	// blocked 为等待重试或已放弃的消息，prev 为子查询中同一聚合键的之前的消息
+	prev := q("prev") + "."
+	blocked, blockedArgs := prev+q("next_attempt_at")+" > ?", []interface{}{ts}
+	b := database.Select(r.dialect, r.opts.Table,
+		"id", "message_id", "topic", "aggregate_key", "type", "headers", "payload", "created_at", "attempts").
+		Where("published_at IS NULL").
+		Where(fmt.Sprintf("(%s IS NULL OR %s <= ?)", q("next_attempt_at"), q("next_attempt_at")), ts)
+	if r.opts.MaxAttempts > 0 {
+		b.Where(q("attempts")+" < ?", r.opts.MaxAttempts)
+		blocked = fmt.Sprintf("(%s OR %s >= ?)", blocked, prev+q("attempts"))
+		blockedArgs = append(blockedArgs, r.opts.MaxAttempts)
+	}
+	b.Where(fmt.Sprintf(`NOT EXISTS (SELECT 1 FROM %[1]s %[2]s WHERE %[3]s%[4]s = %[1]s.%[4]s AND %[3]s%[4]s <> '' AND %[3]s%[5]s IS NULL AND %[3]s%[6]s < %[1]s.%[6]s AND %[7]s)`,
+		q(r.opts.Table), q("prev"), prev, q("aggregate_key"), q("published_at"), q("id"), blocked), blockedArgs...)
+	query, args := b.OrderBy("id").Limit(r.opts.BatchSize).Build()
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("outbox: query: %w", err)
+	}
+	defer rows.Close()
+
+	var records []record
+	for rows.Next() {
+		var (
+			rec     record
+			msg     queue.Message
+			headers sql.NullString
+		)
+		if err := rows.Scan(&rec.id, &msg.ID, &rec.topic, &rec.key, &msg.Type, &headers, &msg.Payload, &msg.Timestamp, &rec.attempts); err != nil {
+			return nil, fmt.Errorf("outbox: scan: %w", err)
+		}
+		if headers.Valid && headers.String != "" {
+			if err := json.Unmarshal([]byte(headers.String), &msg.Headers); err != nil {
+				return nil, fmt.Errorf("outbox: decode headers of %s: %w", msg.ID, err)
+			}
+		}
+		rec.msg = &msg
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
+
+// markPublished 标记为已投递；不保留时直接删除。与发布请求无关，不受 ctx 取消影响
+func (r *Relay) markPublished(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var query string
+	var args []interface{}
+	if r.opts.Retention < 0 {
+		query, args = database.Delete(r.dialect, r.opts.Table).Where("id = ?", id).Build()
+	} else {
+		query, args = database.Update(r.dialect, r.opts.Table).
+			Set("published_at", now()).
+			Where("id = ?", id).
+			Build()
+	}
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// markFailed 记录失败次数与原因，并按退避延迟设置下次投递时间
+func (r *Relay) markFailed(rec record, cause error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	attempts := rec.attempts + 1
+	query, args := database.Update(r.dialect, r.opts.Table).
+		Set("attempts", attempts).
+		Set("last_error", cause.Error()).
+		Set("next_attempt_at", now().Add(r.retryDelay(attempts))).
+		Where("id = ?", rec.id).
+		Build()
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		logger.Sugar.Errorf("\t[outbox] %s record failure of %d failed: %v", r.Name(), rec.id, err)
+		return
+	}
+	if r.opts.MaxAttempts > 0 && attempts >= r.opts.MaxAttempts {
+		logger.Sugar.Errorf("\t[outbox] %s message %s gave up after %d attempt(s): %v", r.Name(), rec.msg.ID, attempts, cause)
+	}
+}
+
+// retryDelay 第 attempts 次失败后的重试延迟
+func (r *Relay) retryDelay(attempts int) time.Duration {
+	d := r.opts.RetryDelay
+	for i := 1; i < attempts && d < r.opts.MaxRetryDelay; i++ {
+		d *= 2
+	}
+	return min(d, r.opts.MaxRetryDelay)
+}
+
+// Cleanup 删除超过保留时长的已投递消息，返回删除数量
+func (r *Relay) Cleanup(ctx context.Context) (int64, error) {
+	if r.opts.Retention < 0 {
+		return 0, nil
+	}
+	query, args := database.Delete(r.dialect, r.opts.Table).
+		Where("published_at IS NOT NULL").
+		Where("published_at < ?", now().Add(-r.opts.Retention)).
+		Build()
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("outbox: cleanup: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,446 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"project/pkg/logger"
+
+	"github.com/streadway/amqp"
+)
+
+const (
+	// HeaderRetryCount 消息已重试的次数
+	HeaderRetryCount = "x-retry-count"
+	// HeaderError 最后一次处理失败的原因（死信消息）
+	HeaderError = "x-error"
+	// HeaderOriginalQueue 死信消息的原始队列
+	HeaderOriginalQueue = "x-original-queue"
+)
+
+// Handler 消息处理函数，返回 nil 时确认消息，返回错误时延迟重试，超过最大次数后进入死信队列
+type Handler func(ctx context.Context, d *amqp.Delivery) error
+
+// permanentError 不再重试的错误
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 包装不可恢复的错误（如消息格式错误），消息直接进入死信队列而不重试
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// isPermanent 判断是否为 Permanent 包装的错误
+func isPermanent(err error) bool {
+	var perm *permanentError
+	return errors.As(err, &perm)
+}
+
+// ConsumerOptions 消费者配置
+type ConsumerOptions struct {
+	// Queue 消费的队列，需已存在
+	Queue string
+	// Tag 消费者标识，默认按队列名与进程号生成
+	Tag string
+	// Concurrency 并发处理数，默认 1
+	Concurrency int
+	// Prefetch 未确认消息上限（basic.qos），默认 Concurrency*2
+	Prefetch int
+	// MaxAttempts 最大处理次数（含首次），超过后进入死信队列，默认 5；为 1 时不重试
+	MaxAttempts int
+	// RetryDelay 首次重试延迟，之后每次翻倍，默认 1s
+	RetryDelay time.Duration
+	// MaxRetryDelay 重试延迟上限，默认 10m
+	MaxRetryDelay time.Duration
+	// DeadLetterQueue 死信队列，默认 Queue + ".dead"
+	DeadLetterQueue string
+	// HandlerTimeout 单条消息处理超时，0 表示不限制
+	HandlerTimeout time.Duration
+}
+
+var consumerSeq int64
+
+// Consumer 队列消费者，实现 app.Worker
+//
+// 失败的消息按重试次数发布到 TTL 重试队列 <queue>.retry.<delay>，过期后经默认交换机回到原队列：
+//
+//	orders -> handler error -> orders.retry.1s (x-message-ttl=1s, x-dead-letter-routing-key=orders) -> orders
+//
+// 超过 MaxAttempts 或返回 Permanent 错误的消息发布到死信队列后确认。
+// Stop 时取消订阅，等待处理中的消息完成，已预取但未开始处理的消息退回队列。
+type Consumer struct {
+	mq      *RabbitMQ
+	opts    ConsumerOptions
+	handler Handler
+
+	mu         sync.Mutex
+	cancel     context.CancelFunc
+	stopHandle context.CancelFunc
+	done       chan struct{}
+}
+
+// NewConsumer 创建消费者，Start 之后开始消费
+func NewConsumer(mq *RabbitMQ, opts ConsumerOptions, handler Handler) *Consumer {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if opts.Prefetch <= 0 {
+		opts.Prefetch = opts.Concurrency * 2
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = time.Second
+	}
+	if opts.MaxRetryDelay < opts.RetryDelay {
+		opts.MaxRetryDelay = 10 * time.Minute
+		if opts.MaxRetryDelay < opts.RetryDelay {
+			opts.MaxRetryDelay = opts.RetryDelay
+		}
+	}
+	if opts.DeadLetterQueue == "" {
+		opts.DeadLetterQueue = opts.Queue + ".dead"
+	}
+	if opts.Tag == "" {
+		opts.Tag = fmt.Sprintf("%s-%d-%d", opts.Queue, os.Getpid(), atomic.AddInt64(&consumerSeq, 1))
+	}
+	return &Consumer{mq: mq, opts: opts, handler: handler}
+}
+
+// Consume 创建指定队列的消费者，需通过 app.RegisterWorker 注册或手动 Start
+func (r *RabbitMQ) Consume(opts ConsumerOptions, handler Handler) *Consumer {
+	return NewConsumer(r, opts, handler)
+}
+
+// Name 返回名称
+func (c *Consumer) Name() string {
+	return "rabbitmq-consumer:" + c.opts.Queue
+}
+
+// Start 声明重试队列与死信队列并开始消费（重复调用无效）
+func (c *Consumer) Start() error {
+	if c.opts.Queue == "" {
+		return errors.New("rabbitmq: consumer queue is required")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.done != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handleCtx, stopHandle := context.WithCancel(context.Background())
+
+	startCtx, startCancel := context.WithTimeout(ctx, c.mq.publishTimeout)
+	ch, deliveries, err := c.subscribe(startCtx)
+	startCancel()
+	if err != nil {
+		cancel()
+		stopHandle()
+		return err
+	}
+
+	c.cancel, c.stopHandle = cancel, stopHandle
+	c.done = make(chan struct{})
+	go c.run(ctx, handleCtx, ch, deliveries, c.done)
+	return nil
+}
+
+// Stop 停止接收新消息并等待处理中的消息完成；ctx 结束时取消处理中的 handler 并返回 ctx.Err()
+func (c *Consumer) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	cancel, stopHandle, done := c.cancel, c.stopHandle, c.done
+	c.cancel, c.stopHandle, c.done = nil, nil, nil
+	c.mu.Unlock()
+
+	if done == nil {
+		return nil
+	}
+	cancel()
+	defer stopHandle()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// subscribe 打开 channel、声明辅助队列并订阅
+func (c *Consumer) subscribe(ctx context.Context) (amqpChannel, <-chan amqp.Delivery, error) {
+	conn, err := c.mq.waitConnection(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	ch, err := c.mq.openChannel(conn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deliveries, err := c.setup(ch)
+	if err != nil {
+		_ = ch.Close()
+		return nil, nil, err
+	}
+	return ch, deliveries, nil
+}
+
+func (c *Consumer) setup(ch amqpChannel) (<-chan amqp.Delivery, error) {
+	if err := ch.Qos(c.opts.Prefetch, 0, false); err != nil {
+		return nil, fmt.Errorf("rabbitmq: qos: %w", err)
+	}
+
+	if c.opts.MaxAttempts > 1 {
+		for _, delay := range c.retryDelays() {
+			_, err := ch.QueueDeclare(c.retryQueue(delay), true, false, false, false, amqp.Table{
+				"x-message-ttl":             int64(delay / time.Millisecond),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": c.opts.Queue,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("rabbitmq: declare retry queue: %w", err)
+			}
+		}
+	}
+	if _, err := ch.QueueDeclare(c.opts.DeadLetterQueue, true, false, false, false, nil); err != nil {
+		return nil, fmt.Errorf("rabbitmq: declare dead letter queue: %w", err)
+	}
+
+	deliveries, err := ch.Consume(c.opts.Queue, c.opts.Tag, false, false, false, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("rabbitmq: consume %s: %w", c.opts.Queue, err)
+	}
+	return deliveries, nil
+}
+
+// run 消费直到 Stop；channel 或连接断开后按退避重新订阅
+func (c *Consumer) run(ctx, handleCtx context.Context, ch amqpChannel, deliveries <-chan amqp.Delivery, done chan struct{}) {
+	defer close(done)
+
+	interval := c.mq.reconnectInterval
+	for {
+		c.consume(ctx, handleCtx, ch, deliveries)
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Sugar.Warnf("\t[rabbitmq] consumer %s channel closed, resubscribing", c.opts.Queue)
+
+		for {
+			var err error
+			ch, deliveries, err = c.subscribe(ctx)
+			if err == nil {
+				interval = c.mq.reconnectInterval
+				break
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Sugar.Errorf("\t[rabbitmq] consumer %s subscribe failed: %v", c.opts.Queue, err)
+
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return
+			}
+			interval *= 2
+			if interval > c.mq.maxReconnectInterval {
+				interval = c.mq.maxReconnectInterval
+			}
+		}
+	}
+}
+
+// consume 并发处理消息，直到 deliveries 关闭或 ctx 结束
+func (c *Consumer) consume(ctx, handleCtx context.Context, ch amqpChannel, deliveries <-chan amqp.Delivery) {
+	var wg sync.WaitGroup
+	for i := 0; i < c.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case d, ok := <-deliveries:
+					if !ok {
+						return
+					}
+					if ctx.Err() != nil {
+						// 已停止：预取的消息退回队列
+						_ = d.Nack(false, true)
+						continue
+					}
+					c.handle(handleCtx, d)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		// 取消订阅后关闭 channel，未确认的预取消息由 broker 重新投递
+		_ = ch.Cancel(c.opts.Tag, false)
+		_ = ch.Close()
+	}
+}
+
+func (c *Consumer) handle(ctx context.Context, d amqp.Delivery) {
+	ctx = deliveryContext(ctx, c.opts.Queue, &d)
+	log := logger.FromContext(ctx)
+
+	attempt := RetryCount(&d) + 1
+	err := c.invoke(ctx, &d)
+	if err == nil {
+		if ackErr := d.Ack(false); ackErr != nil {
+			log.Warnf("\t[rabbitmq] consumer %s ack failed: %v", c.opts.Queue, ackErr)
+		}
+		return
+	}
+
+	var route error
+	if isPermanent(err) || attempt >= c.opts.MaxAttempts {
+		log.Errorf("\t[rabbitmq] consumer %s message %s dead-lettered after %d attempt(s): %v",
+			c.opts.Queue, d.MessageId, attempt, err)
+		route = c.forward(ctx, &d, "", c.opts.DeadLetterQueue, attempt-1, err)
+	} else {
+		delay := c.retryDelay(attempt)
+		log.Warnf("\t[rabbitmq] consumer %s message %s failed (attempt %d), retry in %s: %v",
+			c.opts.Queue, d.MessageId, attempt, delay, err)
+		route = c.forward(ctx, &d, "", c.retryQueue(delay), attempt, err)
+	}
+
+	if route != nil {
+		// 无法转发时退回原队列，由 broker 重新投递
+		log.Errorf("\t[rabbitmq] consumer %s requeue message %s: %v", c.opts.Queue, d.MessageId, route)
+		_ = d.Nack(false, true)
+		return
+	}
+	_ = d.Ack(false)
+}
+
+// invoke 调用 handler，恢复 panic
+func (c *Consumer) invoke(ctx context.Context, d *amqp.Delivery) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.FromContext(ctx).Errorf("\t[rabbitmq] consumer %s handler panic: %v\n%s", c.opts.Queue, r, debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	if c.opts.HandlerTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.HandlerTimeout)
+		defer cancel()
+	}
+	return c.handler(ctx, d)
+}
+
+// forward 把消息连同重试次数与失败原因发布到重试队列或死信队列
+func (c *Consumer) forward(ctx context.Context, d *amqp.Delivery, exchange, key string, retries int, cause error) error {
+	msg := deliveryPublishing(d)
+	msg.Headers[HeaderRetryCount] = int32(retries)
+	msg.Headers[HeaderError] = cause.Error()
+	msg.Headers[HeaderOriginalQueue] = c.opts.Queue
+
+	// handler 已超时或被取消时仍需完成转发
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.mq.publishTimeout)
+	defer cancel()
+	return c.mq.Publish(ctx, exchange, key, msg, WithMandatory())
+}
+
+// deliveryPublishing 复制收到的消息用于重新发布
+func deliveryPublishing(d *amqp.Delivery) amqp.Publishing {
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	return amqp.Publishing{
+		Headers:         headers,
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		DeliveryMode:    d.DeliveryMode,
+		Priority:        d.Priority,
+		CorrelationId:   d.CorrelationId,
+		ReplyTo:         d.ReplyTo,
+		MessageId:       d.MessageId,
+		Timestamp:       d.Timestamp,
+		Type:            d.Type,
+		UserId:          d.UserId,
+		AppId:           d.AppId,
+		Body:            d.Body,
+	}
+}
+
+// retryDelay 第 attempt 次失败后的重试延迟
+func (c *Consumer) retryDelay(attempt int) time.Duration {
+	delay := c.opts.RetryDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= c.opts.MaxRetryDelay {
+			return c.opts.MaxRetryDelay
+		}
+	}
+	return delay
+}
+
+// retryDelays 全部不重复的重试延迟
+func (c *Consumer) retryDelays() []time.Duration {
+	var delays []time.Duration
+	for attempt := 1; attempt < c.opts.MaxAttempts; attempt++ {
+		delay := c.retryDelay(attempt)
+		if len(delays) > 0 && delays[len(delays)-1] == delay {
+			break
+		}
+		delays = append(delays, delay)
+	}
+	return delays
+}
+
+func (c *Consumer) retryQueue(delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%s", c.opts.Queue, delay)
+}
+
+// RetryCount 返回消息已重试的次数，首次投递为 0
+func RetryCount(d *amqp.Delivery) int {
+	return headerInt(d.Headers[HeaderRetryCount])
+}
+
+// headerInt 读取整数头，兼容不同的整数宽度
+func headerInt(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int8:
+		return int(n)
+	case int16:
+		return int(n)
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case uint8:
+		return int(n)
+	case uint16:
+		return int(n)
+	case uint32:
+		return int(n)
+	}
+	return 0
+}
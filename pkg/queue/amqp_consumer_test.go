@@ -0,0 +1,295 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"project/pkg/config"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startConsumer(t *testing.T, mq *RabbitMQ, opts ConsumerOptions, handler Handler) *Consumer {
+	if opts.RetryDelay == 0 {
+		opts.RetryDelay = 10 * time.Millisecond
+	}
+	c := mq.Consume(opts, handler)
+	require.NoError(t, c.Start())
+	t.Cleanup(func() { _ = c.Stop(context.Background()) })
+	return c
+}
+
+func publishTo(t *testing.T, mq *RabbitMQ, queue, body string) {
+	err := mq.Publish(context.Background(), "", queue, amqp.Publishing{MessageId: body, Body: []byte(body)})
+	require.NoError(t, err)
+}
+
+func TestConsumer_AcksOnSuccess(t *testing.T) {
+	broker := newFakeBroker()
+	broker.declare("orders", nil)
+	mq := newTestRabbitMQ(t, broker, config.RabbitMQ{})
+
+	received := make(chan string, 10)
+	startConsumer(t, mq, ConsumerOptions{Queue: "orders", MaxAttempts: 3}, func(ctx context.Context, d *amqp.Delivery) error {
+		received <- string(d.Body)
+		return nil
+	})
+
+	assert.True(t, broker.hasQueue("orders.retry.10ms"))
+	assert.True(t, broker.hasQueue("orders.retry.20ms"))
+	assert.True(t, broker.hasQueue("orders.dead"))
+
+	publishTo(t, mq, "orders", "o-1")
+	publishTo(t, mq, "orders", "o-2")
+	assert.Equal(t, "o-1", <-received)
+	assert.Equal(t, "o-2", <-received)
+	assert.Empty(t, broker.ready("orders"))
+	assert.Empty(t, broker.ready("orders.dead"))
+}
+
+func TestConsumer_RetriesWithBackoff(t *testing.T) {
+	broker := newFakeBroker()
+	broker.declare("orders", nil)
+	mq := newTestRabbitMQ(t, broker, config.RabbitMQ{})
+
+	var (
+		mu       sync.Mutex
+		attempts []int
+		times    []time.Time
+	)
+	done := make(chan struct{})
+	startConsumer(t, mq, ConsumerOptions{Queue: "orders", MaxAttempts: 5, RetryDelay: 20 * time.Millisecond}, func(ctx context.Context, d *amqp.Delivery) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, RetryCount(d))
+		times = append(times, time.Now())
+		if len(attempts) < 3 {
+			return errors.New("temporary failure")
+		}
+		close(done)
+		return nil
+	})
+
+	publishTo(t, mq, "orders", "o-1")
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("message was not retried")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []int{0, 1, 2}, attempts)
+	// 第二次重试的延迟是第一次的两倍
+	assert.GreaterOrEqual(t, times[1].Sub(times[0]), 20*time.Millisecond)
+	assert.GreaterOrEqual(t, times[2].Sub(times[1]), 40*time.Millisecond)
+	assert.Empty(t, broker.ready("orders.dead"))
+}
+
+func TestConsumer_DeadLettersAfterMaxAttempts(t *testing.T) {
+	broker := newFakeBroker()
+	broker.declare("orders", nil)
+	mq := newTestRabbitMQ(t, broker, config.RabbitMQ{})
+
+	var calls int32
+	startConsumer(t, mq, ConsumerOptions{Queue: "orders", MaxAttempts: 3}, func(ctx context.Context, d *amqp.Delivery) error {
+		atomic.AddInt32(&calls, 1)
+		return errors.New("boom")
+	})
+
+	publishTo(t, mq, "orders", "o-1")
+	require.Eventually(t, func() bool { return len(broker.ready("orders.dead")) == 1 }, 2*time.Second, 5*time.Millisecond)
+
+	dead := broker.ready("orders.dead")[0]
+	assert.Equal(t, "o-1", string(dead.Body))
+	assert.Equal(t, "o-1", dead.MessageId)
+	assert.Equal(t, int32(2), dead.Headers[HeaderRetryCount])
+	assert.Equal(t, "boom", dead.Headers[HeaderError])
+	assert.Equal(t, "orders", dead.Headers[HeaderOriginalQueue])
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestConsumer_PermanentErrorSkipsRetry(t *testing.T) {
+	broker := newFakeBroker()
+	broker.declare("orders", nil)
+	mq := newTestRabbitMQ(t, broker, config.RabbitMQ{})
+
+	var calls int32
+	startConsumer(t, mq, ConsumerOptions{Queue: "orders"}, func(ctx context.Context, d *amqp.Delivery) error {
+		atomic.AddInt32(&calls, 1)
+		return Permanent(errors.New("invalid payload"))
+	})
+
+	publishTo(t, mq, "orders", "bad")
+	require.Eventually(t, func() bool { return len(broker.ready("orders.dead")) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(0), broker.ready("orders.dead")[0].Headers[HeaderRetryCount])
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Nil(t, Permanent(nil))
+}
+
+func TestConsumer_RecoversPanic(t *testing.T) {
+	broker := newFakeBroker()
+	broker.declare("orders", nil)
+	mq := newTestRabbitMQ(t, broker, config.RabbitMQ{})
+
+	received := make(chan string, 10)
+	startConsumer(t, mq, ConsumerOptions{Queue: "orders", MaxAttempts: 1}, func(ctx context.Context, d *amqp.Delivery) error {
+		if string(d.Body) == "panic" {
+			panic("nil map")
+		}
+		received <- string(d.Body)
+		return nil
+	})
+
+	publishTo(t, mq, "orders", "panic")
+	publishTo(t, mq, "orders", "ok")
+	assert.Equal(t, "ok", <-received)
+
+	require.Eventually(t, func() bool { return len(broker.ready("orders.dead")) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Contains(t, broker.ready("orders.dead")[0].Headers[HeaderError], "panic: nil map")
+}
+
+func TestConsumer_StopWaitsForInFlight(t *testing.T) {
+	broker := newFakeBroker()
+	broker.declare("orders", nil)
+	mq := newTestRabbitMQ(t, broker, config.RabbitMQ{})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var finished int32
+	c := startConsumer(t, mq, ConsumerOptions{Queue: "orders"}, func(ctx context.Context, d *amqp.Delivery) error {
+		close(started)
+		<-release
+		atomic.StoreInt32(&finished, 1)
+		return nil
+	})
+
+	publishTo(t, mq, "orders", "o-1")
+	<-started
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- c.Stop(context.Background()) }()
+
+	select {
+	case <-stopped:
+		t.Fatal("Stop returned before in-flight message finished")
+	case <-time.After(30 * time.Millisecond):
+	}
+	close(release)
+	require.NoError(t, <-stopped)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&finished))
+
+	// 停止后不再消费新消息
+	publishTo(t, mq, "orders", "o-2")
+	assert.Len(t, broker.ready("orders"), 1)
+}
+
+func TestConsumer_StopTimeoutCancelsHandler(t *testing.T) {
+	broker := newFakeBroker()
+	broker.declare("orders", nil)
+	mq := newTestRabbitMQ(t, broker, config.RabbitMQ{})
+
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	c := startConsumer(t, mq, ConsumerOptions{Queue: "orders"}, func(ctx context.Context, d *amqp.Delivery) error {
+		close(started)
+		<-ctx.Done()
+		close(canceled)
+		return ctx.Err()
+	})
+
+	publishTo(t, mq, "orders", "o-1")
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, c.Stop(ctx), context.DeadlineExceeded)
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("handler context was not canceled")
+	}
+}
+
+func TestConsumer_ResubscribesAfterReconnect(t *testing.T) {
+	broker := newFakeBroker()
+	broker.declare("orders", nil)
+	mq := newTestRabbitMQ(t, broker, config.RabbitMQ{})
+
+	received := make(chan string, 10)
+	startConsumer(t, mq, ConsumerOptions{Queue: "orders"}, func(ctx context.Context, d *amqp.Delivery) error {
+		received <- string(d.Body)
+		return nil
+	})
+
+	broker.drop()
+	publishTo(t, mq, "orders", "after-reconnect")
+
+	select {
+	case body := <-received:
+		assert.Equal(t, "after-reconnect", body)
+	case <-time.After(2 * time.Second):
+		t.Fatal("consumer did not resubscribe")
+	}
+}
+
+func TestConsumer_Concurrency(t *testing.T) {
+	broker := newFakeBroker()
+	broker.declare("orders", nil)
+	mq := newTestRabbitMQ(t, broker, config.RabbitMQ{})
+
+	var running, peak int32
+	var wg sync.WaitGroup
+	wg.Add(6)
+	startConsumer(t, mq, ConsumerOptions{Queue: "orders", Concurrency: 3}, func(ctx context.Context, d *amqp.Delivery) error {
+		defer wg.Done()
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return nil
+	})
+
+	for i := 0; i < 6; i++ {
+		publishTo(t, mq, "orders", "o")
+	}
+	wg.Wait()
+	assert.Equal(t, int32(3), atomic.LoadInt32(&peak))
+}
+
+func TestConsumer_StartFailsForMissingQueue(t *testing.T) {
+	broker := newFakeBroker()
+	mq := newTestRabbitMQ(t, broker, config.RabbitMQ{})
+
+	c := mq.Consume(ConsumerOptions{Queue: "missing"}, func(ctx context.Context, d *amqp.Delivery) error { return nil })
+	err := c.Start()
+	var amqpErr *amqp.Error
+	require.ErrorAs(t, err, &amqpErr)
+	assert.Equal(t, amqp.NotFound, amqpErr.Code)
+
+	assert.Error(t, mq.Consume(ConsumerOptions{}, nil).Start())
+}
+
+func TestConsumer_RetryDelays(t *testing.T) {
+	c := NewConsumer(nil, ConsumerOptions{
+		Queue:         "orders",
+		MaxAttempts:   8,
+		RetryDelay:    time.Second,
+		MaxRetryDelay: 5 * time.Second,
+	}, nil)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}, c.retryDelays())
+	assert.Equal(t, "orders.retry.4s", c.retryQueue(c.retryDelay(3)))
+	assert.Equal(t, 5*time.Second, c.retryDelay(7))
+}
@@ -0,0 +1,318 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+var (
+	// ErrNacked broker 拒绝了消息（nack），通常是队列溢出或内部错误，可以重试
+	ErrNacked = errors.New("rabbitmq: message nacked by broker")
+	// ErrBufferFull 断线期间等待重连的发布数已达上限
+	ErrBufferFull = errors.New("rabbitmq: publish buffer full")
+
+	// errRepublish channel 或连接在确认前断开，需要在新 channel 上重发
+	errRepublish = errors.New("rabbitmq: channel closed before confirm")
+)
+
+// ReturnedError mandatory 消息无法路由到任何队列，被 broker 退回
+type ReturnedError struct {
+	Return amqp.Return
+}
+
+func (e *ReturnedError) Error() string {
+	return fmt.Sprintf("rabbitmq: message returned: %d %s (exchange=%q key=%q)",
+		e.Return.ReplyCode, e.Return.ReplyText, e.Return.Exchange, e.Return.RoutingKey)
+}
+
+// AMQPPublisherOptions 发布者配置
+type AMQPPublisherOptions struct {
+	// PoolSize 最多同时打开的 confirm channel 数，即最大并发发布数，默认 8
+	PoolSize int
+	// Buffer 断线期间允许等待重连的发布数，超过后立即返回 ErrBufferFull，默认 1000
+	Buffer int
+	// Timeout ctx 未设置截止时间时的发布超时（含等待重连与确认），默认 5s
+	Timeout time.Duration
+}
+
+// PublishOption 单次发布选项
+type PublishOption func(*publishOptions)
+
+type publishOptions struct {
+	mandatory bool
+}
+
+// WithMandatory 消息无法路由时由 broker 退回，Publish 返回 *ReturnedError
+func WithMandatory() PublishOption {
+	return func(o *publishOptions) {
+		o.mandatory = true
+	}
+}
+
+// AMQPPublisher 可靠发布者
+//
+// 每个 channel 处于 confirm 模式且同一时间只发布一条消息，Publish 等待 broker 确认后返回。
+// 连接断开时发布会等待重连（受 Buffer 限制），channel 在确认前关闭时会在新 channel 上重发，
+// 因此投递语义为至少一次，消费方需要幂等。
+type AMQPPublisher struct {
+	mq      *RabbitMQ
+	opts    AMQPPublisherOptions
+	idle    chan *confirmChannel
+	open    chan struct{}
+	waiting chan struct{}
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+type confirmChannel struct {
+	ch       amqpChannel
+	confirms chan amqp.Confirmation
+	returns  chan amqp.Return
+	closed   chan *amqp.Error
+}
+
+// closeReason broker 因通道级异常（如交换机不存在）关闭 channel 时返回该异常，
+// 连接断开等其他情况返回 errRepublish
+func (c *confirmChannel) closeReason() error {
+	select {
+	case err, ok := <-c.closed:
+		if ok && err != nil && err.Server && err.Recover {
+			return err
+		}
+	default:
+	}
+	return errRepublish
+}
+
+func (c *confirmChannel) alive() bool {
+	select {
+	case <-c.closed:
+		return false
+	default:
+		return true
+	}
+}
+
+// NewAMQPPublisher 创建发布者，channel 按需打开
+func NewAMQPPublisher(mq *RabbitMQ, opts AMQPPublisherOptions) *AMQPPublisher {
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 8
+	}
+	if opts.Buffer <= 0 {
+		opts.Buffer = 1000
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	return &AMQPPublisher{
+		mq:      mq,
+		opts:    opts,
+		idle:    make(chan *confirmChannel, opts.PoolSize),
+		open:    make(chan struct{}, opts.PoolSize),
+		waiting: make(chan struct{}, opts.Buffer),
+		closed:  make(chan struct{}),
+	}
+}
+
+// Publish 发布消息并等待 broker 确认
+//
+// 返回 nil 表示 broker 已持久化（对持久化队列）或已接收消息；ErrNacked 表示被拒绝；
+// 使用 WithMandatory 且无法路由时返回 *ReturnedError；超时返回 ctx.Err()，此时消息可能已经发出。
+func (p *AMQPPublisher) Publish(ctx context.Context, exchange, key string, msg amqp.Publishing, opts ...PublishOption) error {
+	var o publishOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	msg.Headers = withOutgoingMetadata(ctx, msg.Headers)
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.opts.Timeout)
+		defer cancel()
+	}
+
+	for {
+		cc, err := p.acquire(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = p.publish(ctx, cc, exchange, key, o.mandatory, msg)
+		if err != errRepublish {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// Close 关闭空闲 channel，之后的 Publish 返回 ErrClosed
+func (p *AMQPPublisher) Close() {
+	p.closeOnce.Do(func() {
+		close(p.closed)
+	})
+	for {
+		select {
+		case cc := <-p.idle:
+			p.discard(cc)
+		default:
+			return
+		}
+	}
+}
+
+func (p *AMQPPublisher) publish(ctx context.Context, cc *confirmChannel, exchange, key string, mandatory bool, msg amqp.Publishing) error {
+	if err := cc.ch.Publish(exchange, key, mandatory, false, msg); err != nil {
+		p.discard(cc)
+		if errors.Is(err, amqp.ErrClosed) {
+			return errRepublish
+		}
+		return err
+	}
+
+	select {
+	case c, ok := <-cc.confirms:
+		if !ok {
+			p.discard(cc)
+			return cc.closeReason()
+		}
+		// 同一 channel 上 basic.return 先于 basic.ack 到达
+		var returned *amqp.Return
+		select {
+		case r := <-cc.returns:
+			returned = &r
+		default:
+		}
+		p.release(cc)
+
+		if !c.Ack {
+			return ErrNacked
+		}
+		if returned != nil {
+			return &ReturnedError{Return: *returned}
+		}
+		return nil
+	case <-ctx.Done():
+		// 迟到的确认会与下一条消息混淆，丢弃该 channel
+		p.discard(cc)
+		return ctx.Err()
+	}
+}
+
+// acquire 取空闲 channel，没有时在池容量内新建，否则等待归还
+func (p *AMQPPublisher) acquire(ctx context.Context) (*confirmChannel, error) {
+	for {
+		select {
+		case <-p.closed:
+			return nil, ErrClosed
+		default:
+		}
+
+		select {
+		case cc := <-p.idle:
+			if cc.alive() {
+				return cc, nil
+			}
+			p.discard(cc)
+			continue
+		default:
+		}
+
+		select {
+		case cc := <-p.idle:
+			if cc.alive() {
+				return cc, nil
+			}
+			p.discard(cc)
+		case p.open <- struct{}{}:
+			cc, err := p.newChannel(ctx)
+			if err != nil {
+				<-p.open
+				return nil, err
+			}
+			return cc, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-p.closed:
+			return nil, ErrClosed
+		}
+	}
+}
+
+// newChannel 打开 confirm channel；断线时占用一个缓冲名额等待重连
+func (p *AMQPPublisher) newChannel(ctx context.Context) (*confirmChannel, error) {
+	for {
+		if !p.mq.Connected() {
+			select {
+			case p.waiting <- struct{}{}:
+			default:
+				return nil, ErrBufferFull
+			}
+		}
+		conn, err := p.mq.waitConnection(ctx)
+		select {
+		case <-p.waiting:
+		default:
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		ch, err := p.mq.openChannel(conn)
+		if err == nil {
+			var cc *confirmChannel
+			cc, err = setupConfirm(ch)
+			if err == nil {
+				return cc, nil
+			}
+			_ = ch.Close()
+		}
+		if !errors.Is(err, amqp.ErrClosed) {
+			return nil, err
+		}
+		// 连接恰好断开，等待重连后重试
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func setupConfirm(ch amqpChannel) (*confirmChannel, error) {
+	if err := ch.Confirm(false); err != nil {
+		return nil, err
+	}
+	return &confirmChannel{
+		ch:       ch,
+		confirms: ch.NotifyPublish(make(chan amqp.Confirmation, 1)),
+		returns:  ch.NotifyReturn(make(chan amqp.Return, 1)),
+		closed:   ch.NotifyClose(make(chan *amqp.Error, 1)),
+	}, nil
+}
+
+func (p *AMQPPublisher) release(cc *confirmChannel) {
+	select {
+	case <-p.closed:
+		p.discard(cc)
+		return
+	default:
+	}
+	select {
+	case p.idle <- cc:
+	default:
+		p.discard(cc)
+	}
+}
+
+func (p *AMQPPublisher) discard(cc *confirmChannel) {
+	_ = cc.ch.Close()
+	<-p.open
+}
@@ -0,0 +1,85 @@
+package queue
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"project/pkg/config"
+	"project/pkg/database"
+)
+
+var (
+	brokersMu sync.Mutex
+	brokers   = make(map[string]Broker)
+)
+
+// Open 返回配置中 brokers.<name> 对应的驱动（同名复用同一实例）
+//
+//	b, err := queue.Open("orders")
+//	err = b.Publish(ctx, "order.created", msg)
+//	sub, err := b.Subscribe("order.created", handler)
+//	app.RegisterWorker(sub)
+func Open(name string) (Broker, error) {
+	name = strings.ToLower(name)
+
+	brokersMu.Lock()
+	defer brokersMu.Unlock()
+	if b, ok := brokers[name]; ok {
+		return b, nil
+	}
+
+	if !config.IsInitialized() || config.Get().Brokers[name] == nil {
+		return nil, fmt.Errorf("queue: broker %q is not configured", name)
+	}
+	b, err := newBroker(config.Get().Brokers[name])
+	if err != nil {
+		return nil, fmt.Errorf("queue: broker %q: %w", name, err)
+	}
+	brokers[name] = b
+	return b, nil
+}
+
+// Register 以指定名称注册驱动，覆盖配置；测试中可用于替换为 MemoryBroker
+func Register(name string, b Broker) {
+	brokersMu.Lock()
+	defer brokersMu.Unlock()
+	brokers[strings.ToLower(name)] = b
+}
+
+func newBroker(c *config.Broker) (Broker, error) {
+	switch c.Driver {
+	case "rabbitmq":
+		mq := GetRabbitMQ()
+		if mq == nil || !mq.IsInitialize() {
+			return nil, fmt.Errorf("rabbitmq component is not initialized")
+		}
+		return NewRabbitBroker(mq, RabbitBrokerOptions{
+			Exchange:    c.Exchange,
+			Concurrency: c.Concurrency,
+			MaxAttempts: c.MaxAttempts,
+			RetryDelay:  c.RetryDelay,
+		}), nil
+	case "redis":
+		r := database.GetRedis()
+		if r == nil || !r.IsInitialize() {
+			return nil, fmt.Errorf("redis component is not initialized")
+		}
+		return NewRedisBroker(r.GetClient(), RedisBrokerOptions{
+			Prefix:      c.Prefix,
+			Group:       c.Group,
+			MaxLen:      c.MaxLen,
+			Concurrency: c.Concurrency,
+			MaxAttempts: c.MaxAttempts,
+			RetryDelay:  c.RetryDelay,
+		}), nil
+	case "memory":
+		return NewMemoryBroker(MemoryBrokerOptions{
+			Buffer:      c.Buffer,
+			Concurrency: c.Concurrency,
+			MaxAttempts: c.MaxAttempts,
+			RetryDelay:  c.RetryDelay,
+		}), nil
+	}
+	return nil, fmt.Errorf("unsupported driver %q", c.Driver)
+}
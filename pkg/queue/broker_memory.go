@@ -0,0 +1,236 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"project/pkg/logger"
+)
+
+// MemoryBrokerOptions 内存驱动配置
+type MemoryBrokerOptions struct {
+	// Buffer 每个主题的缓冲消息数，缓冲区满时 Publish 阻塞，默认 1024
+	Buffer int
+	// Concurrency 每个订阅的并发处理数，默认 1
+	Concurrency int
+	// MaxAttempts 最大处理次数（含首次），超过后进入死信列表，默认 5
+	MaxAttempts int
+	// RetryDelay 失败后等待多久再重试，0 表示立即重试；等待期间占用一个并发处理槽
+	RetryDelay time.Duration
+}
+
+// MemoryBroker 进程内驱动，用于测试与本地开发
+//
+// 失败的消息等待 RetryDelay 后重试，超过 MaxAttempts 后保存在死信列表中（可通过 DeadLetters 查看）。
+// 进程退出时未处理的消息会丢失。
+type MemoryBroker struct {
+	opts MemoryBrokerOptions
+
+	mu     sync.Mutex
+	topics map[string]chan *Message
+	dead   map[string][]*Message
+	closed bool
+}
+
+// NewMemoryBroker 创建内存驱动
+func NewMemoryBroker(opts MemoryBrokerOptions) *MemoryBroker {
+	if opts.Buffer <= 0 {
+		opts.Buffer = 1024
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	return &MemoryBroker{
+		opts:   opts,
+		topics: make(map[string]chan *Message),
+		dead:   make(map[string][]*Message),
+	}
+}
+
+func (b *MemoryBroker) topic(name string) (chan *Message, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+	ch, ok := b.topics[name]
+	if !ok {
+		ch = make(chan *Message, b.opts.Buffer)
+		b.topics[name] = ch
+	}
+	return ch, nil
+}
+
+// Publish 把消息放入主题缓冲区
+func (b *MemoryBroker) Publish(ctx context.Context, topic string, msgs ...*Message) error {
+	ch, err := b.topic(topic)
+	if err != nil {
+		return err
+	}
+	for _, m := range msgs {
+		m.prepare(ctx)
+		select {
+		case ch <- m.clone():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Subscribe 创建订阅
+func (b *MemoryBroker) Subscribe(topic string, handler MessageHandler) (Subscription, error) {
+	ch, err := b.topic(topic)
+	if err != nil {
+		return nil, err
+	}
+	return &memorySubscription{broker: b, topic: topic, ch: ch, handler: handler}, nil
+}
+
+// Pending 返回主题中尚未被消费的消息数
+func (b *MemoryBroker) Pending(topic string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.topics[topic])
+}
+
+// DeadLetters 返回主题中超过最大处理次数的消息
+func (b *MemoryBroker) DeadLetters(topic string) []*Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*Message(nil), b.dead[topic]...)
+}
+
+// Close 之后的 Publish 与 Subscribe 返回 ErrClosed，已创建的订阅需单独停止
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return nil
+}
+
+type memorySubscription struct {
+	broker  *MemoryBroker
+	topic   string
+	ch      chan *Message
+	handler MessageHandler
+
+	mu         sync.Mutex
+	cancel     context.CancelFunc
+	stopHandle context.CancelFunc
+	done       chan struct{}
+}
+
+func (s *memorySubscription) Name() string {
+	return "memory-subscription:" + s.topic
+}
+
+func (s *memorySubscription) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handleCtx, stopHandle := context.WithCancel(context.Background())
+	s.cancel, s.stopHandle = cancel, stopHandle
+	s.done = make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.broker.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case m := <-s.ch:
+					s.handle(handleCtx, m)
+				}
+			}
+		}()
+	}
+	go func(done chan struct{}) {
+		wg.Wait()
+		close(done)
+	}(s.done)
+	return nil
+}
+
+func (s *memorySubscription) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	cancel, stopHandle, done := s.cancel, s.stopHandle, s.done
+	s.cancel, s.stopHandle, s.done = nil, nil, nil
+	s.mu.Unlock()
+
+	if done == nil {
+		return nil
+	}
+	cancel()
+	defer stopHandle()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *memorySubscription) handle(ctx context.Context, m *Message) {
+	ctx = messageContext(ctx, s.topic, m)
+	log := logger.FromContext(ctx)
+
+	var err error
+	for attempt := 1; attempt <= s.broker.opts.MaxAttempts; attempt++ {
+		msg := m.clone()
+		msg.Attempt = attempt
+		if err = invokeHandler(ctx, s.handler, msg); err == nil {
+			return
+		}
+		if isPermanent(err) || ctx.Err() != nil {
+			break
+		}
+		log.Warnf("\t[queue] %s message %s failed (attempt %d): %v", s.Name(), m.ID, attempt, err)
+		if attempt < s.broker.opts.MaxAttempts && !sleepContext(ctx, s.broker.opts.RetryDelay) {
+			break
+		}
+	}
+
+	log.Errorf("\t[queue] %s message %s dead-lettered: %v", s.Name(), m.ID, err)
+	s.broker.mu.Lock()
+	s.broker.dead[s.topic] = append(s.broker.dead[s.topic], m)
+	s.broker.mu.Unlock()
+}
+
+// sleepContext 等待 d，ctx 结束时提前返回 false
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// invokeHandler 调用 handler，恢复 panic
+func invokeHandler(ctx context.Context, handler MessageHandler, msg *Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handler(ctx, msg)
+}
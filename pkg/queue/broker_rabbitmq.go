@@ -0,0 +1,128 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"project/pkg/config"
+
+	"github.com/streadway/amqp"
+)
+
+// RabbitBrokerOptions RabbitMQ 驱动配置
+type RabbitBrokerOptions struct {
+	// Exchange 发布使用的交换机，路由键为主题；为空时经默认交换机直接投递到与主题同名的队列
+	Exchange string
+	// Concurrency 每个订阅的并发处理数，默认 1
+	Concurrency int
+	// MaxAttempts 最大处理次数（含首次），默认 5
+	MaxAttempts int
+	// RetryDelay 首次重试延迟，之后每次翻倍，默认 1s
+	RetryDelay time.Duration
+}
+
+// RabbitBroker 基于 RabbitMQ 组件的驱动
+//
+// 每个主题对应一个同名的持久化队列，订阅时声明（配置了 Exchange 时同时以主题为路由键绑定），
+// 重试与死信沿用 Consumer 的 TTL 重试队列机制。
+type RabbitBroker struct {
+	mq       *RabbitMQ
+	opts     RabbitBrokerOptions
+	declared sync.Map
+}
+
+// NewRabbitBroker 创建 RabbitMQ 驱动
+func NewRabbitBroker(mq *RabbitMQ, opts RabbitBrokerOptions) *RabbitBroker {
+	return &RabbitBroker{mq: mq, opts: opts}
+}
+
+// Publish 逐条发布并等待 broker 确认；没有队列接收时返回 *ReturnedError
+func (b *RabbitBroker) Publish(ctx context.Context, topic string, msgs ...*Message) error {
+	if b.opts.Exchange == "" {
+		// 先于订阅发布的消息也需要有队列接收
+		if err := b.declare(ctx, topic); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range msgs {
+		m.prepare(ctx)
+		if err := b.mq.Publish(ctx, b.opts.Exchange, topic, toPublishing(m), WithMandatory()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subscribe 声明主题队列并创建消费者
+func (b *RabbitBroker) Subscribe(topic string, handler MessageHandler) (Subscription, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.mq.publishTimeout)
+	defer cancel()
+	if err := b.declare(ctx, topic); err != nil {
+		return nil, err
+	}
+
+	return b.mq.Consume(ConsumerOptions{
+		Queue:       topic,
+		Concurrency: b.opts.Concurrency,
+		MaxAttempts: b.opts.MaxAttempts,
+		RetryDelay:  b.opts.RetryDelay,
+	}, func(ctx context.Context, d *amqp.Delivery) error {
+		return handler(ctx, fromDelivery(d))
+	}), nil
+}
+
+// Close 无需释放资源，连接由 RabbitMQ 组件管理
+func (b *RabbitBroker) Close() error {
+	return nil
+}
+
+// declare 声明主题队列及绑定（每个主题只声明一次）
+func (b *RabbitBroker) declare(ctx context.Context, topic string) error {
+	if _, ok := b.declared.Load(topic); ok {
+		return nil
+	}
+
+	t := &config.Topology{Queues: []config.Queue{{Name: topic, Durable: true}}}
+	if b.opts.Exchange != "" {
+		t.Bindings = []config.Binding{{Exchange: b.opts.Exchange, Queue: topic, RoutingKey: topic}}
+	}
+	if err := b.mq.DeclareTopology(ctx, t); err != nil {
+		return err
+	}
+	b.declared.Store(topic, struct{}{})
+	return nil
+}
+
+// toPublishing 把消息信封转换为持久化的 AMQP 消息
+func toPublishing(m *Message) amqp.Publishing {
+	headers := make(amqp.Table, len(m.Headers))
+	for k, v := range m.Headers {
+		headers[k] = v
+	}
+	return amqp.Publishing{
+		MessageId:    m.ID,
+		Type:         m.Type,
+		Timestamp:    m.Timestamp,
+		Headers:      headers,
+		DeliveryMode: amqp.Persistent,
+		Body:         m.Payload,
+	}
+}
+
+// fromDelivery 把 AMQP 消息还原为消息信封
+func fromDelivery(d *amqp.Delivery) *Message {
+	msg := &Message{
+		ID:        d.MessageId,
+		Type:      d.Type,
+		Timestamp: d.Timestamp,
+		Payload:   d.Body,
+		Attempt:   RetryCount(d) + 1,
+	}
+	for k, v := range d.Headers {
+		msg.SetHeader(k, fmt.Sprint(v))
+	}
+	return msg
+}
@@ -0,0 +1,92 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"project/pkg/event"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisBrokerOptions Redis Streams 驱动配置
+type RedisBrokerOptions struct {
+	// Prefix Stream 键前缀
+	Prefix string
+	// Group 消费组，默认 default
+	Group string
+	// Consumer 消费者名称，默认 主机名-进程号
+	Consumer string
+	// MaxLen Stream 近似最大长度，0 表示不裁剪
+	MaxLen int64
+	// Concurrency 每个订阅的并发处理数，默认 1
+	Concurrency int
+	// MaxAttempts 最大投递次数，超过后转入死信流 <stream>:dead，默认 5
+	MaxAttempts int
+	// RetryDelay 未确认消息空闲多久后被回收重新投递，默认 1m
+	RetryDelay time.Duration
+	// Block 阻塞读取的最长等待时间，同时也是 Stop 的最长等待时间，默认 5s
+	Block time.Duration
+}
+
+// RedisBroker 基于 Redis Streams 消费组的驱动
+//
+// 消费组首次创建时从 Stream 开头消费，因此先于订阅发布的消息不会丢失（受 MaxLen 裁剪影响）。
+// 失败的消息保持未确认，空闲超过 RetryDelay 后重新投递；Permanent 错误不重试，直接转入死信流。
+type RedisBroker struct {
+	client redis.UniversalClient
+	opts   RedisBrokerOptions
+}
+
+// NewRedisBroker 创建 Redis Streams 驱动
+func NewRedisBroker(client redis.UniversalClient, opts RedisBrokerOptions) *RedisBroker {
+	if opts.Group == "" {
+		opts.Group = "default"
+	}
+	if opts.Consumer == "" {
+		host, _ := os.Hostname()
+		opts.Consumer = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = time.Minute
+	}
+	return &RedisBroker{client: client, opts: opts}
+}
+
+// Publish 以 JSON 编码追加到 Stream
+func (b *RedisBroker) Publish(ctx context.Context, topic string, msgs ...*Message) error {
+	for _, m := range msgs {
+		m.prepare(ctx)
+		if _, err := event.AddStream(ctx, b.client, b.opts.Prefix+topic, m, b.opts.MaxLen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subscribe 创建消费组运行器
+func (b *RedisBroker) Subscribe(topic string, handler MessageHandler) (Subscription, error) {
+	return event.NewStreamConsumer[Message](b.client, event.StreamOptions{
+		Stream:        b.opts.Prefix + topic,
+		Group:         b.opts.Group,
+		Consumer:      b.opts.Consumer,
+		StartID:       "0",
+		Block:         b.opts.Block,
+		Concurrency:   b.opts.Concurrency,
+		MaxRetries:    int64(b.opts.MaxAttempts),
+		MinIdle:       b.opts.RetryDelay,
+		ClaimInterval: b.opts.RetryDelay / 2,
+		DeadLetterIf:  isPermanent,
+	}, func(ctx context.Context, sm *event.StreamMessage[Message]) error {
+		msg := sm.Payload
+		msg.Attempt = int(sm.Deliveries)
+		return handler(messageContext(ctx, topic, &msg), &msg)
+	}), nil
+}
+
+// Close 无需释放资源，客户端由 Redis 组件管理
+func (b *RedisBroker) Close() error {
+	return nil
+}
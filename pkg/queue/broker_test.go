@@ -0,0 +1,379 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"project/pkg/config"
+	"project/pkg/requestid"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type order struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+}
+
+func TestMessage_EncodeDecode(t *testing.T) {
+	m, err := NewMessage("order.created", order{ID: 1, Status: "new"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, m.ID)
+	assert.False(t, m.Timestamp.IsZero())
+	assert.JSONEq(t, `{"id":1,"status":"new"}`, string(m.Payload))
+
+	var o order
+	require.NoError(t, m.Decode(&o))
+	assert.Equal(t, order{ID: 1, Status: "new"}, o)
+
+	raw, err := NewMessage("raw", []byte("not json"))
+	require.NoError(t, err)
+	assert.Equal(t, "not json", string(raw.Payload))
+
+	// 无法解码的消息重试也不会成功
+	err = raw.Decode(&o)
+	var perm *permanentError
+	assert.ErrorAs(t, err, &perm)
+
+	_, err = NewMessage("bad", make(chan int))
+	assert.Error(t, err)
+}
+
+// subscribe 创建并启动订阅，测试结束时停止
+func subscribe(t *testing.T, b Broker, topic string, handler MessageHandler) Subscription {
+	sub, err := b.Subscribe(topic, handler)
+	require.NoError(t, err)
+	require.NoError(t, sub.Start())
+	t.Cleanup(func() { _ = sub.Stop(context.Background()) })
+	return sub
+}
+
+// testBrokers 各驱动使用相同的用例验证 Broker 语义
+func testBrokers(t *testing.T) map[string]func(t *testing.T) Broker {
+	return map[string]func(t *testing.T) Broker{
+		"memory": func(t *testing.T) Broker {
+			return NewMemoryBroker(MemoryBrokerOptions{MaxAttempts: 3})
+		},
+		"redis": func(t *testing.T) Broker {
+			mr := miniredis.RunT(t)
+			client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			t.Cleanup(func() { _ = client.Close() })
+			return NewRedisBroker(client, RedisBrokerOptions{
+				Prefix:      "test:",
+				MaxAttempts: 3,
+				RetryDelay:  50 * time.Millisecond,
+				Block:       20 * time.Millisecond,
+			})
+		},
+		"rabbitmq": func(t *testing.T) Broker {
+			mq := newTestRabbitMQ(t, newFakeBroker(), config.RabbitMQ{})
+			return NewRabbitBroker(mq, RabbitBrokerOptions{MaxAttempts: 3, RetryDelay: 10 * time.Millisecond})
+		},
+	}
+}
+
+func TestBroker_RoundTrip(t *testing.T) {
+	for name, open := range testBrokers(t) {
+		t.Run(name, func(t *testing.T) {
+			b := open(t)
+			sent, err := NewMessage("order.created", order{ID: 7})
+			require.NoError(t, err)
+			sent.SetHeader("trace-id", "abc")
+
+			// 先于订阅发布的消息不丢失
+			require.NoError(t, b.Publish(context.Background(), "orders", sent))
+
+			received := make(chan *Message, 1)
+			subscribe(t, b, "orders", func(ctx context.Context, msg *Message) error {
+				received <- msg
+				return nil
+			})
+
+			select {
+			case msg := <-received:
+				assert.Equal(t, sent.ID, msg.ID)
+				assert.Equal(t, "order.created", msg.Type)
+				assert.Equal(t, "abc", msg.Header("trace-id"))
+				assert.WithinDuration(t, sent.Timestamp, msg.Timestamp, time.Second)
+				assert.Equal(t, 1, msg.Attempt)
+				var o order
+				require.NoError(t, msg.Decode(&o))
+				assert.Equal(t, 7, o.ID)
+			case <-time.After(2 * time.Second):
+				t.Fatal("message not delivered")
+			}
+		})
+	}
+}
+
+func TestBroker_PropagatesRequestID(t *testing.T) {
+	for name, open := range testBrokers(t) {
+		t.Run(name, func(t *testing.T) {
+			b := open(t)
+			type seen struct{ header, fromCtx, httpID string }
+			received := make(chan seen, 2)
+			subscribe(t, b, "orders", func(ctx context.Context, msg *Message) error {
+				received <- seen{msg.Header(HeaderRequestID), RequestIDFromContext(ctx), requestid.FromContext(ctx)}
+				return nil
+			})
+
+			// HTTP 请求的请求 ID 写入消息头
+			ctx := requestid.NewContext(context.Background(), "http-req")
+			m1, err := NewMessage("order.created", order{ID: 1})
+			require.NoError(t, err)
+			require.NoError(t, b.Publish(ctx, "orders", m1))
+
+			// 已设置的头不覆盖
+			m2, err := NewMessage("order.created", order{ID: 2})
+			require.NoError(t, err)
+			m2.SetHeader(HeaderRequestID, "explicit")
+			require.NoError(t, b.Publish(ctx, "orders", m2))
+
+			want := map[string]bool{"http-req": true, "explicit": true}
+			for i := 0; i < 2; i++ {
+				select {
+				case s := <-received:
+					assert.True(t, want[s.header], "unexpected request id %q", s.header)
+					delete(want, s.header)
+					assert.Equal(t, s.header, s.fromCtx)
+					assert.Equal(t, s.header, s.httpID)
+				case <-time.After(2 * time.Second):
+					t.Fatal("message not delivered")
+				}
+			}
+		})
+	}
+}
+
+func TestBroker_RetriesFailedMessages(t *testing.T) {
+	for name, open := range testBrokers(t) {
+		t.Run(name, func(t *testing.T) {
+			b := open(t)
+			attempts := make(chan int, 10)
+			subscribe(t, b, "orders", func(ctx context.Context, msg *Message) error {
+				attempts <- msg.Attempt
+				if msg.Attempt < 2 {
+					return errors.New("temporary failure")
+				}
+				return nil
+			})
+
+			msg, err := NewMessage("order.created", order{ID: 1})
+			require.NoError(t, err)
+			require.NoError(t, b.Publish(context.Background(), "orders", msg))
+
+			for _, want := range []int{1, 2} {
+				select {
+				case got := <-attempts:
+					assert.Equal(t, want, got)
+				case <-time.After(2 * time.Second):
+					t.Fatalf("attempt %d not delivered", want)
+				}
+			}
+			select {
+			case got := <-attempts:
+				t.Fatalf("unexpected attempt %d after success", got)
+			case <-time.After(150 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestMemoryBroker_DeadLetters(t *testing.T) {
+	b := NewMemoryBroker(MemoryBrokerOptions{MaxAttempts: 3})
+
+	var calls int32
+	subscribe(t, b, "orders", func(ctx context.Context, msg *Message) error {
+		atomic.AddInt32(&calls, 1)
+		if msg.Type == "poison" {
+			return Permanent(errors.New("bad payload"))
+		}
+		if msg.Type == "panic" {
+			panic("boom")
+		}
+		return errors.New("always fails")
+	})
+
+	for _, typ := range []string{"retry", "poison", "panic"} {
+		msg, err := NewMessage(typ, nil)
+		require.NoError(t, err)
+		require.NoError(t, b.Publish(context.Background(), "orders", msg))
+	}
+
+	require.Eventually(t, func() bool { return len(b.DeadLetters("orders")) == 3 }, time.Second, 5*time.Millisecond)
+	// retry 与 panic 各处理 3 次，poison 只处理 1 次
+	assert.Equal(t, int32(7), atomic.LoadInt32(&calls))
+	assert.Equal(t, 0, b.Pending("orders"))
+}
+
+func TestMemoryBroker_RetryDelay(t *testing.T) {
+	b := NewMemoryBroker(MemoryBrokerOptions{MaxAttempts: 3, RetryDelay: 30 * time.Millisecond})
+
+	attempts := make(chan time.Time, 3)
+	subscribe(t, b, "orders", func(ctx context.Context, msg *Message) error {
+		attempts <- time.Now()
+		return errors.New("always fails")
+	})
+
+	msg, err := NewMessage("order.created", nil)
+	require.NoError(t, err)
+	require.NoError(t, b.Publish(context.Background(), "orders", msg))
+
+	require.Eventually(t, func() bool { return len(b.DeadLetters("orders")) == 1 }, time.Second, 5*time.Millisecond)
+	require.Len(t, attempts, 3)
+	prev := <-attempts
+	for i := 0; i < 2; i++ {
+		next := <-attempts
+		assert.GreaterOrEqual(t, next.Sub(prev), 30*time.Millisecond)
+		prev = next
+	}
+}
+
+func TestRedisBroker_DeadLettersPermanentErrors(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	b := NewRedisBroker(client, RedisBrokerOptions{
+		Prefix:      "test:",
+		MaxAttempts: 3,
+		RetryDelay:  50 * time.Millisecond,
+		Block:       20 * time.Millisecond,
+	})
+
+	var calls int32
+	subscribe(t, b, "orders", func(ctx context.Context, msg *Message) error {
+		atomic.AddInt32(&calls, 1)
+		var o order
+		return msg.Decode(&o)
+	})
+
+	raw, err := NewMessage("order.created", []byte("not json"))
+	require.NoError(t, err)
+	require.NoError(t, b.Publish(context.Background(), "orders", raw))
+
+	require.Eventually(t, func() bool {
+		n, err := client.XLen(context.Background(), "test:orders:dead").Result()
+		return err == nil && n == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// 解码失败不重试，消息已确认
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	pending, err := client.XPending(context.Background(), "test:orders", "default").Result()
+	require.NoError(t, err)
+	assert.Zero(t, pending.Count)
+
+	dead, err := client.XRange(context.Background(), "test:orders:dead", "-", "+").Result()
+	require.NoError(t, err)
+	assert.Equal(t, "1", dead[0].Values["deliveries"])
+}
+
+func TestMemoryBroker_StopWaitsForInFlight(t *testing.T) {
+	b := NewMemoryBroker(MemoryBrokerOptions{Concurrency: 2})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var done int32
+	sub, err := b.Subscribe("orders", func(ctx context.Context, msg *Message) error {
+		close(started)
+		<-release
+		atomic.StoreInt32(&done, 1)
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, sub.Start())
+
+	msg, err := NewMessage("order.created", nil)
+	require.NoError(t, err)
+	require.NoError(t, b.Publish(context.Background(), "orders", msg))
+	<-started
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, sub.Stop(context.Background()))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&done))
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	// 停止后发布的消息保留在缓冲区
+	require.NoError(t, b.Publish(context.Background(), "orders", msg))
+	assert.Equal(t, 1, b.Pending("orders"))
+}
+
+func TestMemoryBroker_Close(t *testing.T) {
+	b := NewMemoryBroker(MemoryBrokerOptions{Buffer: 1})
+
+	msg, err := NewMessage("order.created", nil)
+	require.NoError(t, err)
+	require.NoError(t, b.Publish(context.Background(), "orders", msg))
+
+	// 缓冲区已满时 Publish 阻塞到 ctx 结束
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, b.Publish(ctx, "orders", msg), context.DeadlineExceeded)
+
+	require.NoError(t, b.Close())
+	assert.ErrorIs(t, b.Publish(context.Background(), "orders", msg), ErrClosed)
+	_, err = b.Subscribe("orders", func(context.Context, *Message) error { return nil })
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestRabbitBroker_Exchange(t *testing.T) {
+	broker := newFakeBroker()
+	broker.exchanges["events"] = fakeExchange{kind: "topic", durable: true}
+	mq := newTestRabbitMQ(t, broker, config.RabbitMQ{})
+	b := NewRabbitBroker(mq, RabbitBrokerOptions{Exchange: "events"})
+
+	subscribe(t, b, "order.created", func(context.Context, *Message) error { return nil })
+	assert.True(t, broker.hasQueue("order.created"))
+	assert.Contains(t, broker.bindings, fakeBinding{exchange: "events", queue: "order.created", key: "order.created"})
+
+	msg, err := NewMessage("order.created", order{ID: 1})
+	require.NoError(t, err)
+	msg.SetHeader("tenant", "t1")
+	require.NoError(t, b.Publish(context.Background(), "order.created", msg))
+
+	msgs := broker.messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "events", msgs[0].Exchange)
+	assert.Equal(t, "order.created", msgs[0].Key)
+	assert.Equal(t, msg.ID, msgs[0].Msg.MessageId)
+	assert.Equal(t, "t1", msgs[0].Msg.Headers["tenant"])
+}
+
+func TestOpen(t *testing.T) {
+	_, err := Open("unknown")
+	assert.Error(t, err)
+
+	mem := NewMemoryBroker(MemoryBrokerOptions{})
+	Register("Orders", mem)
+	t.Cleanup(func() {
+		brokersMu.Lock()
+		delete(brokers, "orders")
+		brokersMu.Unlock()
+	})
+
+	b, err := Open("orders")
+	require.NoError(t, err)
+	assert.Same(t, mem, b)
+}
+
+func TestNewBroker(t *testing.T) {
+	b, err := newBroker(&config.Broker{Driver: "memory", Buffer: 8})
+	require.NoError(t, err)
+	assert.IsType(t, &MemoryBroker{}, b)
+
+	_, err = newBroker(&config.Broker{Driver: "kafka"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,602 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// fakeBroker 内存中的 AMQP broker，模拟确认、退回、通道异常、断线与队列投递
+//
+// 约定：发往交换机 "missing" 的消息触发 404 通道异常；默认交换机按路由键投递到同名队列，
+// 其他交换机不投递，mandatory 且路由键为 "unroutable" 或目标队列不存在的消息被退回。
+// 队列设置了 x-message-ttl 与 x-dead-letter-routing-key 时，消息过期后转投到死信队列。
+type fakeBroker struct {
+	mu        sync.Mutex
+	dials     int
+	failDials int
+	nack      bool
+	hold      bool
+	conns     []*fakeConn
+	channels  int
+	published []fakePublishing
+	queues    map[string]*fakeQueue
+	exchanges map[string]fakeExchange
+	bindings  []fakeBinding
+}
+
+type fakeExchange struct {
+	kind    string
+	durable bool
+	args    amqp.Table
+}
+
+type fakeBinding struct {
+	exchange, queue, key string
+}
+
+type fakeQueue struct {
+	name      string
+	durable   bool
+	args      amqp.Table
+	ready     []amqp.Publishing
+	consumers []*fakeConsumer
+	next      int
+}
+
+type fakeConsumer struct {
+	ch         *fakeChannel
+	tag        string
+	deliveries chan amqp.Delivery
+}
+
+type fakePublishing struct {
+	Exchange string
+	Key      string
+	Msg      amqp.Publishing
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{queues: make(map[string]*fakeQueue), exchanges: make(map[string]fakeExchange)}
+}
+
+// declare 声明队列（测试中用于预先创建业务队列）
+func (b *fakeBroker) declare(name string, args amqp.Table) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.queues[name]; !ok {
+		b.queues[name] = &fakeQueue{name: name, args: args}
+	}
+}
+
+// ready 返回队列中等待投递的消息
+func (b *fakeBroker) ready(name string) []amqp.Publishing {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q, ok := b.queues[name]
+	if !ok {
+		return nil
+	}
+	return append([]amqp.Publishing(nil), q.ready...)
+}
+
+// hasQueue 队列是否已声明
+func (b *fakeBroker) hasQueue(name string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.queues[name]
+	return ok
+}
+
+// enqueue 投递到队列，需持有 b.mu
+func (b *fakeBroker) enqueue(q *fakeQueue, msg amqp.Publishing, redelivered bool) {
+	if ttl, ok := q.args["x-message-ttl"].(int64); ok {
+		target, _ := q.args["x-dead-letter-routing-key"].(string)
+		time.AfterFunc(time.Duration(ttl)*time.Millisecond, func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if dst, ok := b.queues[target]; ok {
+				b.enqueue(dst, msg, false)
+			}
+		})
+		return
+	}
+	q.ready = append(q.ready, msg)
+	b.dispatch(q, redelivered)
+}
+
+// dispatch 把就绪消息轮询投递给消费者，需持有 b.mu
+func (b *fakeBroker) dispatch(q *fakeQueue, redelivered bool) {
+	for len(q.ready) > 0 && len(q.consumers) > 0 {
+		q.next = (q.next + 1) % len(q.consumers)
+		c := q.consumers[q.next]
+		msg := q.ready[0]
+		q.ready = q.ready[1:]
+
+		c.ch.tag++
+		c.ch.unacked[c.ch.tag] = fakeUnacked{queue: q, msg: msg}
+		c.deliveries <- amqp.Delivery{
+			Acknowledger:    c.ch,
+			Headers:         msg.Headers,
+			ContentType:     msg.ContentType,
+			ContentEncoding: msg.ContentEncoding,
+			DeliveryMode:    msg.DeliveryMode,
+			Priority:        msg.Priority,
+			CorrelationId:   msg.CorrelationId,
+			ReplyTo:         msg.ReplyTo,
+			Expiration:      msg.Expiration,
+			MessageId:       msg.MessageId,
+			Timestamp:       msg.Timestamp,
+			Type:            msg.Type,
+			UserId:          msg.UserId,
+			AppId:           msg.AppId,
+			ConsumerTag:     c.tag,
+			DeliveryTag:     c.ch.tag,
+			Redelivered:     redelivered,
+			RoutingKey:      q.name,
+			Body:            msg.Body,
+		}
+	}
+}
+
+func (b *fakeBroker) dial(string) (amqpConnection, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dials++
+	if b.failDials > 0 {
+		b.failDials--
+		return nil, errors.New("dial tcp: connection refused")
+	}
+	c := &fakeConn{broker: b}
+	b.conns = append(b.conns, c)
+	return c, nil
+}
+
+func (b *fakeBroker) set(fn func(b *fakeBroker)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	fn(b)
+}
+
+func (b *fakeBroker) dialCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dials
+}
+
+func (b *fakeBroker) channelCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.channels
+}
+
+func (b *fakeBroker) messages() []fakePublishing {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]fakePublishing(nil), b.published...)
+}
+
+// drop 模拟 broker 强制断开当前连接
+func (b *fakeBroker) drop() {
+	b.mu.Lock()
+	conns := append([]*fakeConn(nil), b.conns...)
+	b.mu.Unlock()
+	for _, c := range conns {
+		c.shutdown(&amqp.Error{Code: amqp.ConnectionForced, Reason: "CONNECTION_FORCED", Server: true})
+	}
+}
+
+type fakeConn struct {
+	broker   *fakeBroker
+	mu       sync.Mutex
+	closed   bool
+	notify   []chan *amqp.Error
+	channels []*fakeChannel
+}
+
+func (c *fakeConn) Channel() (amqpChannel, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, amqp.ErrClosed
+	}
+	ch := &fakeChannel{conn: c, unacked: make(map[uint64]fakeUnacked)}
+	c.channels = append(c.channels, ch)
+	c.broker.set(func(b *fakeBroker) { b.channels++ })
+	return ch, nil
+}
+
+func (c *fakeConn) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		close(receiver)
+		return receiver
+	}
+	c.notify = append(c.notify, receiver)
+	return receiver
+}
+
+func (c *fakeConn) IsClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+func (c *fakeConn) Close() error {
+	if c.IsClosed() {
+		return amqp.ErrClosed
+	}
+	c.shutdown(nil)
+	return nil
+}
+
+func (c *fakeConn) shutdown(err *amqp.Error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	notify, channels := c.notify, c.channels
+	c.notify, c.channels = nil, nil
+	c.mu.Unlock()
+
+	for _, ch := range channels {
+		ch.shutdown(err)
+	}
+	for _, n := range notify {
+		if err != nil {
+			n <- err
+		}
+		close(n)
+	}
+}
+
+type fakeChannel struct {
+	conn     *fakeConn
+	mu       sync.Mutex
+	closed   bool
+	confirm  bool
+	seq      uint64
+	confirms []chan amqp.Confirmation
+	returns  []chan amqp.Return
+	closes   []chan *amqp.Error
+
+	// 以下字段由 broker.mu 保护
+	tag       uint64
+	unacked   map[uint64]fakeUnacked
+	consumers []*fakeConsumer
+}
+
+type fakeUnacked struct {
+	queue *fakeQueue
+	msg   amqp.Publishing
+}
+
+func (ch *fakeChannel) Confirm(bool) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.closed {
+		return amqp.ErrClosed
+	}
+	ch.confirm = true
+	return nil
+}
+
+func (ch *fakeChannel) NotifyPublish(c chan amqp.Confirmation) chan amqp.Confirmation {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.closed {
+		close(c)
+		return c
+	}
+	ch.confirms = append(ch.confirms, c)
+	return c
+}
+
+func (ch *fakeChannel) NotifyReturn(c chan amqp.Return) chan amqp.Return {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.closed {
+		close(c)
+		return c
+	}
+	ch.returns = append(ch.returns, c)
+	return c
+}
+
+func (ch *fakeChannel) NotifyClose(c chan *amqp.Error) chan *amqp.Error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.closed {
+		close(c)
+		return c
+	}
+	ch.closes = append(ch.closes, c)
+	return c
+}
+
+func (ch *fakeChannel) Publish(exchange, key string, mandatory, _ bool, msg amqp.Publishing) error {
+	// 持锁发送通知，避免与 shutdown 关闭通知 channel 并发
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.closed {
+		return amqp.ErrClosed
+	}
+	ch.seq++
+
+	b := ch.conn.broker
+	b.mu.Lock()
+	nack, hold := b.nack, b.hold
+	routed := key != "unroutable"
+	if exchange != "missing" {
+		b.published = append(b.published, fakePublishing{Exchange: exchange, Key: key, Msg: msg})
+	}
+	if exchange == "" {
+		q, ok := b.queues[key]
+		routed = ok
+		if ok {
+			b.enqueue(q, msg, false)
+		}
+	}
+	b.mu.Unlock()
+
+	if exchange == "missing" {
+		go ch.shutdown(&amqp.Error{Code: amqp.NotFound, Reason: "NOT_FOUND - no exchange 'missing'", Server: true, Recover: true})
+		return nil
+	}
+	if mandatory && !routed {
+		for _, c := range ch.returns {
+			c <- amqp.Return{ReplyCode: amqp.NoRoute, ReplyText: "NO_ROUTE", Exchange: exchange, RoutingKey: key}
+		}
+	}
+	if ch.confirm && !hold {
+		for _, c := range ch.confirms {
+			c <- amqp.Confirmation{DeliveryTag: ch.seq, Ack: !nack}
+		}
+	}
+	return nil
+}
+
+func (ch *fakeChannel) Qos(int, int, bool) error {
+	return nil
+}
+
+// fail 模拟通道异常：返回错误并关闭 channel
+func (ch *fakeChannel) fail(code int, reason string) error {
+	err := &amqp.Error{Code: code, Reason: reason, Server: true, Recover: true}
+	go ch.shutdown(err)
+	return err
+}
+
+func (ch *fakeChannel) isClosed() bool {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return ch.closed
+}
+
+func (ch *fakeChannel) ExchangeDeclare(name, kind string, durable, _, _, _ bool, args amqp.Table) error {
+	if ch.isClosed() {
+		return amqp.ErrClosed
+	}
+	b := ch.conn.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if e, ok := b.exchanges[name]; ok {
+		if e.kind != kind || e.durable != durable || !sameTable(e.args, args) {
+			return ch.fail(amqp.PreconditionFailed, "PRECONDITION_FAILED - inequivalent arg for exchange '"+name+"'")
+		}
+		return nil
+	}
+	b.exchanges[name] = fakeExchange{kind: kind, durable: durable, args: args}
+	return nil
+}
+
+func (ch *fakeChannel) ExchangeDeclarePassive(name, _ string, _, _, _, _ bool, _ amqp.Table) error {
+	if ch.isClosed() {
+		return amqp.ErrClosed
+	}
+	b := ch.conn.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.exchanges[name]; !ok {
+		return ch.fail(amqp.NotFound, "NOT_FOUND - no exchange '"+name+"'")
+	}
+	return nil
+}
+
+func (ch *fakeChannel) QueueDeclare(name string, durable, _, _, _ bool, args amqp.Table) (amqp.Queue, error) {
+	if ch.isClosed() {
+		return amqp.Queue{}, amqp.ErrClosed
+	}
+
+	b := ch.conn.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q, ok := b.queues[name]
+	if !ok {
+		q = &fakeQueue{name: name, durable: durable, args: args}
+		b.queues[name] = q
+	} else if q.durable != durable || !sameTable(q.args, args) {
+		return amqp.Queue{}, ch.fail(amqp.PreconditionFailed, "PRECONDITION_FAILED - inequivalent arg for queue '"+name+"'")
+	}
+	return amqp.Queue{Name: name, Messages: len(q.ready), Consumers: len(q.consumers)}, nil
+}
+
+func (ch *fakeChannel) QueueDeclarePassive(name string, _, _, _, _ bool, _ amqp.Table) (amqp.Queue, error) {
+	if ch.isClosed() {
+		return amqp.Queue{}, amqp.ErrClosed
+	}
+	b := ch.conn.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q, ok := b.queues[name]
+	if !ok {
+		return amqp.Queue{}, ch.fail(amqp.NotFound, "NOT_FOUND - no queue '"+name+"'")
+	}
+	return amqp.Queue{Name: name, Messages: len(q.ready), Consumers: len(q.consumers)}, nil
+}
+
+func (ch *fakeChannel) QueueBind(name, key, exchange string, _ bool, _ amqp.Table) error {
+	if ch.isClosed() {
+		return amqp.ErrClosed
+	}
+	b := ch.conn.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.queues[name]; !ok {
+		return ch.fail(amqp.NotFound, "NOT_FOUND - no queue '"+name+"'")
+	}
+	if _, ok := b.exchanges[exchange]; !ok {
+		return ch.fail(amqp.NotFound, "NOT_FOUND - no exchange '"+exchange+"'")
+	}
+	binding := fakeBinding{exchange: exchange, queue: name, key: key}
+	for _, existing := range b.bindings {
+		if existing == binding {
+			return nil
+		}
+	}
+	b.bindings = append(b.bindings, binding)
+	return nil
+}
+
+func sameTable(a, b amqp.Table) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if fmt.Sprint(v) != fmt.Sprint(b[k]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (ch *fakeChannel) Consume(queue, consumer string, _, _, _, _ bool, _ amqp.Table) (<-chan amqp.Delivery, error) {
+	if ch.isClosed() {
+		return nil, amqp.ErrClosed
+	}
+
+	b := ch.conn.broker
+	b.mu.Lock()
+	q, ok := b.queues[queue]
+	if !ok {
+		b.mu.Unlock()
+		return nil, ch.fail(amqp.NotFound, "NOT_FOUND - no queue '"+queue+"'")
+	}
+	c := &fakeConsumer{ch: ch, tag: consumer, deliveries: make(chan amqp.Delivery, 1000)}
+	q.consumers = append(q.consumers, c)
+	ch.consumers = append(ch.consumers, c)
+	b.dispatch(q, false)
+	b.mu.Unlock()
+	return c.deliveries, nil
+}
+
+func (ch *fakeChannel) Cancel(consumer string, _ bool) error {
+	b := ch.conn.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ch.removeConsumers(func(c *fakeConsumer) bool { return c.tag == consumer })
+	return nil
+}
+
+// removeConsumers 移除匹配的消费者并关闭其投递 channel，需持有 broker.mu
+func (ch *fakeChannel) removeConsumers(match func(c *fakeConsumer) bool) {
+	kept := ch.consumers[:0]
+	for _, c := range ch.consumers {
+		if !match(c) {
+			kept = append(kept, c)
+			continue
+		}
+		close(c.deliveries)
+		for _, q := range ch.conn.broker.queues {
+			for i, qc := range q.consumers {
+				if qc == c {
+					q.consumers = append(q.consumers[:i], q.consumers[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+	ch.consumers = kept
+}
+
+func (ch *fakeChannel) Ack(tag uint64, _ bool) error {
+	b := ch.conn.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := ch.unacked[tag]; !ok {
+		return amqp.ErrClosed
+	}
+	delete(ch.unacked, tag)
+	return nil
+}
+
+func (ch *fakeChannel) Nack(tag uint64, _ bool, requeue bool) error {
+	b := ch.conn.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	u, ok := ch.unacked[tag]
+	if !ok {
+		return amqp.ErrClosed
+	}
+	delete(ch.unacked, tag)
+	if requeue {
+		b.enqueue(u.queue, u.msg, true)
+	}
+	return nil
+}
+
+func (ch *fakeChannel) Reject(tag uint64, requeue bool) error {
+	return ch.Nack(tag, false, requeue)
+}
+
+func (ch *fakeChannel) Close() error {
+	ch.mu.Lock()
+	closed := ch.closed
+	ch.mu.Unlock()
+	if closed {
+		return amqp.ErrClosed
+	}
+	ch.shutdown(nil)
+	return nil
+}
+
+func (ch *fakeChannel) shutdown(err *amqp.Error) {
+	ch.mu.Lock()
+	if ch.closed {
+		ch.mu.Unlock()
+		return
+	}
+	ch.closed = true
+	closes, confirms, returns := ch.closes, ch.confirms, ch.returns
+	ch.closes, ch.confirms, ch.returns = nil, nil, nil
+	ch.mu.Unlock()
+
+	ch.conn.broker.set(func(b *fakeBroker) {
+		b.channels--
+		ch.removeConsumers(func(*fakeConsumer) bool { return true })
+		// 未确认的消息重新入队
+		for tag, u := range ch.unacked {
+			delete(ch.unacked, tag)
+			b.enqueue(u.queue, u.msg, true)
+		}
+	})
+
+	for _, c := range closes {
+		if err != nil {
+			c <- err
+		}
+		close(c)
+	}
+	for _, c := range confirms {
+		close(c)
+	}
+	for _, c := range returns {
+		close(c)
+	}
+}
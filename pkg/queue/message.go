@@ -0,0 +1,131 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message 与驱动无关的消息信封
+type Message struct {
+	// ID 消息唯一标识，发布时为空则自动生成，可用于消费端幂等
+	ID string `json:"id"`
+	// Type 消息类型，例如 order.created
+	Type string `json:"type"`
+	// Headers 自定义头，例如 trace 信息
+	Headers map[string]string `json:"headers,omitempty"`
+	// Timestamp 产生时间，发布时为空则取当前时间
+	Timestamp time.Time `json:"timestamp"`
+	// Payload 消息体，通常为 JSON
+	Payload []byte `json:"payload"`
+	// Attempt 第几次处理（从 1 开始），仅在消费时有效
+	Attempt int `json:"-"`
+}
+
+// NewMessage 创建消息，payload 为 []byte 时原样使用，否则按 JSON 编码
+func NewMessage(typ string, payload interface{}) (*Message, error) {
+	var data []byte
+	switch p := payload.(type) {
+	case []byte:
+		data = p
+	case json.RawMessage:
+		data = p
+	default:
+		var err error
+		data, err = json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("queue: encode %s: %w", typ, err)
+		}
+	}
+	return &Message{
+		ID:        uuid.NewString(),
+		Type:      typ,
+		Timestamp: time.Now(),
+		Payload:   data,
+	}, nil
+}
+
+// Decode 按 JSON 解码消息体
+func (m *Message) Decode(v interface{}) error {
+	if err := json.Unmarshal(m.Payload, v); err != nil {
+		return Permanent(fmt.Errorf("queue: decode %s: %w", m.Type, err))
+	}
+	return nil
+}
+
+// Header 返回自定义头
+func (m *Message) Header(key string) string {
+	return m.Headers[key]
+}
+
+// SetHeader 设置自定义头
+func (m *Message) SetHeader(key, value string) {
+	if m.Headers == nil {
+		m.Headers = make(map[string]string)
+	}
+	m.Headers[key] = value
+}
+
+// prepare 补全 ID 与时间戳，并写入 ctx 中需要传播的元数据（已设置的头不覆盖）
+func (m *Message) prepare(ctx context.Context) {
+	if m.ID == "" {
+		m.ID = uuid.NewString()
+	}
+	if m.Timestamp.IsZero() {
+		m.Timestamp = time.Now()
+	}
+	for k, v := range outgoingMetadata(ctx) {
+		if m.Header(k) == "" {
+			m.SetHeader(k, v)
+		}
+	}
+}
+
+// clone 深拷贝，避免发布方与消费方共享 map 与切片
+func (m *Message) clone() *Message {
+	cp := *m
+	if m.Headers != nil {
+		cp.Headers = make(map[string]string, len(m.Headers))
+		for k, v := range m.Headers {
+			cp.Headers[k] = v
+		}
+	}
+	cp.Payload = append([]byte(nil), m.Payload...)
+	return &cp
+}
+
+// MessageHandler 消息处理函数，返回 nil 表示处理成功；返回错误时按驱动的策略重试，
+// 使用 Permanent 包装的错误不再重试
+type MessageHandler func(ctx context.Context, msg *Message) error
+
+// Publisher 发布消息
+type Publisher interface {
+	// Publish 发布到主题，返回 nil 表示 broker 已接收
+	Publish(ctx context.Context, topic string, msgs ...*Message) error
+}
+
+// Subscriber 订阅消息
+//
+// 同一主题的多个订阅（包括多个副本）竞争消费，每条消息只由其中一个处理。
+type Subscriber interface {
+	// Subscribe 创建订阅，需调用 Start 或通过 app.RegisterWorker 注册后才开始消费
+	Subscribe(topic string, handler MessageHandler) (Subscription, error)
+}
+
+// Subscription 订阅，实现 app.Worker
+type Subscription interface {
+	Name() string
+	Start() error
+	Stop(ctx context.Context) error
+}
+
+// Broker 消息队列驱动
+type Broker interface {
+	Publisher
+	Subscriber
+	// Close 释放驱动自身的资源，不关闭底层组件
+	Close() error
+}
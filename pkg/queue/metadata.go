@@ -0,0 +1,126 @@
+package queue
+
+import (
+	"context"
+
+	"project/pkg/logger"
+	"project/pkg/requestid"
+
+	"github.com/streadway/amqp"
+	"go.uber.org/zap"
+)
+
+// 跨服务传播的元数据头
+const (
+	// HeaderRequestID 请求 ID
+	HeaderRequestID = "x-request-id"
+	// HeaderTraceParent W3C Trace Context 的 traceparent
+	HeaderTraceParent = "traceparent"
+	// HeaderTraceState W3C Trace Context 的 tracestate
+	HeaderTraceState = "tracestate"
+)
+
+// PropagatedHeaders 随消息传播的元数据头
+var PropagatedHeaders = []string{HeaderRequestID, HeaderTraceParent, HeaderTraceState}
+
+type metadataKey struct{}
+
+// WithMetadata 返回携带元数据的 ctx，与 ctx 中已有的元数据合并（同名覆盖）
+func WithMetadata(ctx context.Context, md map[string]string) context.Context {
+	merged := make(map[string]string, len(md))
+	for k, v := range MetadataFromContext(ctx) {
+		merged[k] = v
+	}
+	for k, v := range md {
+		if v != "" {
+			merged[k] = v
+		}
+	}
+	return context.WithValue(ctx, metadataKey{}, merged)
+}
+
+// MetadataFromContext 返回 ctx 中的元数据，不存在时返回 nil；返回值不应被修改
+func MetadataFromContext(ctx context.Context) map[string]string {
+	md, _ := ctx.Value(metadataKey{}).(map[string]string)
+	return md
+}
+
+// RequestIDFromContext 返回 ctx 中的请求 ID，元数据中没有时使用 HTTP 请求的请求 ID
+func RequestIDFromContext(ctx context.Context) string {
+	if id := MetadataFromContext(ctx)[HeaderRequestID]; id != "" {
+		return id
+	}
+	return requestid.FromContext(ctx)
+}
+
+// outgoingMetadata 返回发布消息时需要写入消息头的元数据：ctx 中的元数据，以及 HTTP 请求的请求 ID
+func outgoingMetadata(ctx context.Context) map[string]string {
+	md := MetadataFromContext(ctx)
+	id := requestid.FromContext(ctx)
+	if id == "" || md[HeaderRequestID] != "" {
+		return md
+	}
+	out := make(map[string]string, len(md)+1)
+	for k, v := range md {
+		out[k] = v
+	}
+	out[HeaderRequestID] = id
+	return out
+}
+
+// withOutgoingMetadata 返回写入元数据后的 AMQP 头副本，已设置的头不覆盖；没有元数据时原样返回
+func withOutgoingMetadata(ctx context.Context, headers amqp.Table) amqp.Table {
+	md := outgoingMetadata(ctx)
+	if len(md) == 0 {
+		return headers
+	}
+	out := make(amqp.Table, len(headers)+len(md))
+	for k, v := range headers {
+		out[k] = v
+	}
+	for k, v := range md {
+		if _, ok := out[k]; !ok {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// metadataFromTable 从 AMQP 头中提取需要传播的元数据
+func metadataFromTable(headers map[string]interface{}) map[string]string {
+	md := make(map[string]string)
+	for _, k := range PropagatedHeaders {
+		if v, ok := headers[k].(string); ok && v != "" {
+			md[k] = v
+		}
+	}
+	return md
+}
+
+// deliveryContext 将消息头中的元数据写入 ctx，并创建携带队列、消息 ID、请求 ID 与 trace 字段的 logger
+func deliveryContext(ctx context.Context, queue string, d *amqp.Delivery) context.Context {
+	return withMessageLogger(WithMetadata(ctx, metadataFromTable(d.Headers)), "rabbitmq", queue, d.MessageId)
+}
+
+// messageContext 同 deliveryContext，用于 Message 形式的消息
+func messageContext(ctx context.Context, topic string, m *Message) context.Context {
+	md := make(map[string]string)
+	for _, k := range PropagatedHeaders {
+		if v := m.Header(k); v != "" {
+			md[k] = v
+		}
+	}
+	return withMessageLogger(WithMetadata(ctx, md), "queue", topic, m.ID)
+}
+
+func withMessageLogger(ctx context.Context, name, queue, messageID string) context.Context {
+	md := MetadataFromContext(ctx)
+	fields := []zap.Field{zap.String("queue", queue), zap.String("message_id", messageID)}
+	if id := md[HeaderRequestID]; id != "" {
+		fields = append(fields, zap.String(logger.FieldRequestID, id))
+		// 处理消息时发出的 HTTP 请求同样携带该 ID
+		ctx = requestid.NewContext(ctx, id)
+	}
+	fields = append(fields, logger.TraceFields(md[HeaderTraceParent])...)
+	return logger.NewContext(logger.NamedContext(ctx, name), fields...)
+}
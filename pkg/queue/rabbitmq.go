@@ -1,12 +1,21 @@
 package queue
 
 import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"project/pkg/config"
 	"project/pkg/logger"
 
 	"github.com/streadway/amqp"
 )
 
+// ErrClosed 组件已关闭
+var ErrClosed = errors.New("rabbitmq: closed")
+
 var rabbitmqEntity *RabbitMQ
 
 type RabbitMQ struct {
@@ -16,16 +25,68 @@ type RabbitMQ struct {
 	url string
 	//初始化标识
 	isInit bool
-	//conn
-	rmq *amqp.Connection
+	//当前打开的 channel 数
+	channels int64
+
+	//断线重连间隔
+	reconnectInterval    time.Duration
+	maxReconnectInterval time.Duration
+	//发布配置
+	poolSize       int
+	publishBuffer  int
+	publishTimeout time.Duration
+	//启动时声明的拓扑
+	topology *config.Topology
+
+	dial dialFunc
+
+	mu sync.RWMutex
+	//conn，断线期间为 nil
+	conn amqpConnection
+	//连接可用时关闭，断线时替换为新的 channel
+	ready chan struct{}
+	//Close 时关闭
+	closed    chan struct{}
+	publisher *AMQPPublisher
 }
 
 func newRabbitMQ() *RabbitMQ {
-	return &RabbitMQ{
-		name:   "rabbitmq",
-		isInit: false,
-		url:    config.Get().RabbitMQ.URL,
+	return NewRabbitMQ(config.Get().RabbitMQ)
+}
+
+// NewRabbitMQ 根据配置创建组件，未设置的项使用默认值
+func NewRabbitMQ(cfg *config.RabbitMQ) *RabbitMQ {
+	r := &RabbitMQ{
+		name:                 "rabbitmq",
+		isInit:               false,
+		url:                  cfg.URL,
+		reconnectInterval:    cfg.ReconnectInterval,
+		maxReconnectInterval: cfg.MaxReconnectInterval,
+		poolSize:             cfg.ChannelPoolSize,
+		publishBuffer:        cfg.PublishBuffer,
+		publishTimeout:       cfg.PublishTimeout,
+		topology:             cfg.Topology,
+		dial:                 dialAMQP,
+	}
+	if r.reconnectInterval <= 0 {
+		r.reconnectInterval = time.Second
+	}
+	if r.maxReconnectInterval < r.reconnectInterval {
+		r.maxReconnectInterval = 30 * time.Second
+		if r.maxReconnectInterval < r.reconnectInterval {
+			r.maxReconnectInterval = r.reconnectInterval
+		}
+	}
+	if r.poolSize <= 0 {
+		r.poolSize = 8
 	}
+	if r.publishBuffer <= 0 {
+		r.publishBuffer = 1000
+	}
+	if r.publishTimeout <= 0 {
+		r.publishTimeout = 5 * time.Second
+	}
+	return r
 }
 
 func GetRabbitMQ() *RabbitMQ {
@@ -59,13 +120,20 @@ func (r *RabbitMQ) InitComponent() bool {
 
 	logger.Sugar.Infof("\t[component] %s is initiating...", r.name)
 
-	var err error
-	r.rmq, err = amqp.Dial(r.url)
-	if err != nil {
+	r.mu.Lock()
+	r.ready = make(chan struct{})
+	r.closed = make(chan struct{})
+	r.mu.Unlock()
+
+	if err := r.connect(); err != nil {
 		logger.Sugar.Errorf("\t[component] %s init failed: %s", r.name, err)
 		return false
 	}
-	// defer conn.Close()
+	if err := r.applyTopology(true); err != nil {
+		logger.Sugar.Errorf("\t[component] %s declare topology failed: %s", r.name, err)
+		_ = r.Close()
+		return false
+	}
 	//将初始化设置为true
 	r.isInit = true
 
@@ -74,18 +142,34 @@ func (r *RabbitMQ) InitComponent() bool {
 	return true
 }
 
-// Close 关闭 RabbitMQ 连接
+// Close 关闭发布者与 RabbitMQ 连接，停止断线重连
 func (r *RabbitMQ) Close() error {
-	if r.rmq == nil {
+	r.mu.Lock()
+	if r.closed == nil {
+		r.mu.Unlock()
 		return nil
 	}
-	err := r.rmq.Close()
-	if err != nil {
+	select {
+	case <-r.closed:
+	default:
+		close(r.closed)
+	}
+	conn, publisher := r.conn, r.publisher
+	r.conn, r.publisher = nil, nil // 避免重复关闭
+	r.mu.Unlock()
+
+	if publisher != nil {
+		publisher.Close()
+	}
+	r.isInit = false
+	if conn == nil {
+		return nil
+	}
+	err := conn.Close()
+	if err != nil && !errors.Is(err, amqp.ErrClosed) {
 		logger.Sugar.Errorf("\t[component] %s close failed: %v", r.name, err)
 		return err
 	}
-	r.isInit = false
-	r.rmq = nil // 避免重复关闭
 	logger.Sugar.Infof("\t[component] %s closed successfully", r.name)
 	return nil
 }
@@ -98,7 +182,197 @@ func (r *RabbitMQ) GetUrl() string {
 	return r.url
 }
 
-// 获取db
+// 获取db，断线重连期间返回 nil
 func (r *RabbitMQ) GetQueue() *amqp.Connection {
-	return r.rmq
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if c, ok := r.conn.(amqpConn); ok {
+		return c.Connection
+	}
+	return nil
+}
+
+// Connected 当前连接是否可用
+func (r *RabbitMQ) Connected() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.conn != nil && !r.conn.IsClosed()
+}
+
+// Channel 打开一个新的 channel，channel 关闭时自动从计数中移除
+//
+// 断线重连后已打开的 channel 不会恢复，长期使用 channel 的调用方应监听 NotifyClose 后重新获取。
+func (r *RabbitMQ) Channel() (*amqp.Channel, error) {
+	r.mu.RLock()
+	conn := r.conn
+	r.mu.RUnlock()
+	if conn == nil {
+		return nil, errors.New("rabbitmq is not connected")
+	}
+
+	ch, err := r.openChannel(conn)
+	if err != nil {
+		return nil, err
+	}
+	raw, ok := ch.(*amqp.Channel)
+	if !ok {
+		_ = ch.Close()
+		return nil, errors.New("rabbitmq: not an amqp channel")
+	}
+	return raw, nil
+}
+
+// ChannelCount 返回当前打开且尚未关闭的 channel 数（含发布者 channel 池）
+func (r *RabbitMQ) ChannelCount() int {
+	return int(atomic.LoadInt64(&r.channels))
+}
+
+// Publisher 返回按配置创建的默认发布者
+func (r *RabbitMQ) Publisher() *AMQPPublisher {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.publisher == nil {
+		r.publisher = NewAMQPPublisher(r, AMQPPublisherOptions{
+			PoolSize: r.poolSize,
+			Buffer:   r.publishBuffer,
+			Timeout:  r.publishTimeout,
+		})
+	}
+	return r.publisher
+}
+
+// Publish 使用默认发布者发布消息并等待 broker 确认，参见 AMQPPublisher.Publish
+func (r *RabbitMQ) Publish(ctx context.Context, exchange, key string, msg amqp.Publishing, opts ...PublishOption) error {
+	return r.Publisher().Publish(ctx, exchange, key, msg, opts...)
+}
+
+// openChannel 在指定连接上打开 channel 并计数
+func (r *RabbitMQ) openChannel(conn amqpConnection) (amqpChannel, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	atomic.AddInt64(&r.channels, 1)
+	closed := ch.NotifyClose(make(chan *amqp.Error, 1))
+	go func() {
+		<-closed
+		atomic.AddInt64(&r.channels, -1)
+	}()
+
+	return ch, nil
+}
+
+// connect 建立连接并监听断线
+func (r *RabbitMQ) connect() error {
+	conn, err := r.dial(r.url)
+	if err != nil {
+		return err
+	}
+	notify := conn.NotifyClose(make(chan *amqp.Error, 1))
+
+	r.mu.Lock()
+	select {
+	case <-r.closed:
+		// 重连期间组件已关闭
+		r.mu.Unlock()
+		_ = conn.Close()
+		return ErrClosed
+	default:
+	}
+	r.conn = conn
+	close(r.ready)
+	closed := r.closed
+	r.mu.Unlock()
+
+	go r.watch(conn, notify, closed)
+	return nil
+}
+
+// watch 连接被动断开后按指数退避重连
+func (r *RabbitMQ) watch(conn amqpConnection, notify chan *amqp.Error, closed chan struct{}) {
+	var reason *amqp.Error
+	select {
+	case reason = <-notify:
+	case <-closed:
+		return
+	}
+
+	r.mu.Lock()
+	select {
+	case <-closed:
+		r.mu.Unlock()
+		return
+	default:
+	}
+	if r.conn == conn {
+		r.conn = nil
+		r.ready = make(chan struct{})
+	}
+	r.mu.Unlock()
+
+	logger.Named("rabbitmq").Warnf("\t[rabbitmq] connection lost: %v, reconnecting", reason)
+
+	interval := r.reconnectInterval
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-time.After(interval):
+		case <-closed:
+			return
+		}
+
+		err := r.connect()
+		if err == nil {
+			logger.Named("rabbitmq").Infof("\t[rabbitmq] reconnected after %d attempt(s)", attempt)
+			// 非持久化的实体在 broker 重启后丢失，重连后重新声明
+			if err := r.applyTopology(false); err != nil {
+				logger.Named("rabbitmq").Errorf("\t[rabbitmq] redeclare topology failed: %v", err)
+			}
+			return
+		}
+		if errors.Is(err, ErrClosed) {
+			return
+		}
+		logger.Named("rabbitmq").Warnf("\t[rabbitmq] reconnect attempt %d failed: %v", attempt, err)
+
+		interval *= 2
+		if interval > r.maxReconnectInterval {
+			interval = r.maxReconnectInterval
+		}
+	}
+}
+
+// waitConnection 等待连接可用，直到 ctx 结束或组件关闭
+func (r *RabbitMQ) waitConnection(ctx context.Context) (amqpConnection, error) {
+	for {
+		r.mu.RLock()
+		conn, ready, closed := r.conn, r.ready, r.closed
+		r.mu.RUnlock()
+
+		if closed == nil {
+			return nil, errors.New("rabbitmq is not initialized")
+		}
+		select {
+		case <-closed:
+			return nil, ErrClosed
+		default:
+		}
+		if conn != nil && !conn.IsClosed() {
+			return conn, nil
+		}
+
+		wait, retry := ready, (<-chan time.Time)(nil)
+		if conn != nil {
+			// 连接已断开但 watch 尚未处理，稍后重试
+			wait, retry = nil, time.After(10*time.Millisecond)
+		}
+		select {
+		case <-wait:
+		case <-retry:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-closed:
+			return nil, ErrClosed
+		}
+	}
 }
@@ -0,0 +1,245 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"project/pkg/errcode"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+const (
+	// HeaderRPCDeadline RPC 请求的截止时间（Unix 毫秒），服务端据此设置 handler 的 ctx
+	HeaderRPCDeadline = "x-rpc-deadline"
+	// HeaderRPCErrorCode RPC 失败响应的错误码，此时消息体为错误信息
+	HeaderRPCErrorCode = "x-rpc-error-code"
+)
+
+// ErrReplyLost 等待响应期间应答 channel 断开（请求可能已被处理）
+var ErrReplyLost = errors.New("rabbitmq: rpc reply channel closed")
+
+var rpcClientSeq int64
+
+// RPCClientOptions RPC 客户端配置
+type RPCClientOptions struct {
+	// Timeout ctx 未设置截止时间时的默认超时，默认 30s
+	Timeout time.Duration
+}
+
+// RPCClient 基于 reply-to 队列与 correlation ID 的 RPC 客户端
+//
+// 每个客户端使用一个独占的应答队列，连接断开后在下次调用时重新声明；多个 goroutine 可并发调用。
+type RPCClient struct {
+	mq      *RabbitMQ
+	opts    RPCClientOptions
+	replyTo string
+
+	mu      sync.Mutex
+	ch      amqpChannel
+	pending map[string]*rpcCall
+	closed  bool
+}
+
+// rpcCall 等待响应的调用，ch 为发送请求时的应答 channel
+type rpcCall struct {
+	ch      amqpChannel
+	replies chan rpcReply
+}
+
+type rpcReply struct {
+	d   *amqp.Delivery
+	err error
+}
+
+// NewRPCClient 创建 RPC 客户端
+func NewRPCClient(mq *RabbitMQ, opts RPCClientOptions) *RPCClient {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	host, _ := os.Hostname()
+	return &RPCClient{
+		mq:      mq,
+		opts:    opts,
+		replyTo: fmt.Sprintf("rpc.reply.%s-%d-%d", host, os.Getpid(), atomic.AddInt64(&rpcClientSeq, 1)),
+		pending: make(map[string]*rpcCall),
+	}
+}
+
+// Call 向 queue 发送请求并等待响应，req 与 resp 按 JSON 编解码（resp 为 nil 时忽略响应体）
+//
+//	var user User
+//	err := client.Call(ctx, "user-service", "user.get", GetUserRequest{ID: 1}, &user)
+//	var e *errcode.Error
+//	if errors.As(err, &e) && e.Code == errcode.UserNotFound { ... }
+//
+// 服务端返回的错误为 *errcode.Error；没有服务端监听该队列时返回 *ReturnedError；
+// ctx 中的请求 ID 与 trace 元数据随请求传播。
+func (c *RPCClient) Call(ctx context.Context, queue, method string, req, resp interface{}) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.Timeout)
+		defer cancel()
+	}
+	deadline, _ := ctx.Deadline()
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("rabbitmq: rpc encode %s: %w", method, err)
+	}
+
+	id := uuid.NewString()
+	replies, err := c.register(ctx, id)
+	if err != nil {
+		return err
+	}
+	defer c.unregister(id)
+
+	headers := amqp.Table{HeaderRPCDeadline: deadline.UnixMilli()}
+	for k, v := range MetadataFromContext(ctx) {
+		headers[k] = v
+	}
+	expiration := time.Until(deadline).Milliseconds()
+	if expiration < 1 {
+		expiration = 1
+	}
+
+	err = c.mq.Publish(ctx, "", queue, amqp.Publishing{
+		CorrelationId: id,
+		MessageId:     id,
+		ReplyTo:       c.replyTo,
+		Type:          method,
+		Headers:       headers,
+		ContentType:   "application/json",
+		Timestamp:     time.Now(),
+		// 超时未被处理的请求由 broker 丢弃
+		Expiration: fmt.Sprint(expiration),
+		Body:       body,
+	}, WithMandatory())
+	if err != nil {
+		return err
+	}
+
+	select {
+	case r := <-replies:
+		if r.err != nil {
+			return r.err
+		}
+		return decodeReply(r.d, method, resp)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close 删除应答队列，等待中的调用返回 ErrReplyLost
+func (c *RPCClient) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	ch := c.ch
+	c.ch = nil
+	c.mu.Unlock()
+
+	if ch != nil {
+		return ch.Close()
+	}
+	return nil
+}
+
+// register 登记等待中的调用，必要时声明应答队列
+func (c *RPCClient) register(ctx context.Context, id string) (chan rpcReply, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, ErrClosed
+	}
+
+	if c.ch == nil {
+		ch, err := c.listen(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.ch = ch
+	}
+
+	call := &rpcCall{ch: c.ch, replies: make(chan rpcReply, 1)}
+	c.pending[id] = call
+	return call.replies, nil
+}
+
+func (c *RPCClient) unregister(id string) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+// listen 声明独占的应答队列并开始接收响应
+func (c *RPCClient) listen(ctx context.Context) (amqpChannel, error) {
+	conn, err := c.mq.waitConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ch, err := c.mq.openChannel(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := ch.QueueDeclare(c.replyTo, false, true, true, false, nil); err != nil {
+		_ = ch.Close()
+		return nil, fmt.Errorf("rabbitmq: declare reply queue: %w", err)
+	}
+	deliveries, err := ch.Consume(c.replyTo, "", true, true, false, false, nil)
+	if err != nil {
+		_ = ch.Close()
+		return nil, fmt.Errorf("rabbitmq: consume reply queue: %w", err)
+	}
+
+	go c.dispatch(ch, deliveries)
+	return ch, nil
+}
+
+// dispatch 按 correlation ID 分发响应；channel 关闭后通知所有等待中的调用
+func (c *RPCClient) dispatch(ch amqpChannel, deliveries <-chan amqp.Delivery) {
+	for d := range deliveries {
+		d := d
+		c.mu.Lock()
+		call, ok := c.pending[d.CorrelationId]
+		delete(c.pending, d.CorrelationId)
+		c.mu.Unlock()
+		if ok {
+			call.replies <- rpcReply{d: &d}
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ch == ch {
+		c.ch = nil
+	}
+	for id, call := range c.pending {
+		if call.ch == ch {
+			call.replies <- rpcReply{err: ErrReplyLost}
+			delete(c.pending, id)
+		}
+	}
+}
+
+// decodeReply 解析响应：失败响应转换为 *errcode.Error
+func decodeReply(d *amqp.Delivery, method string, resp interface{}) error {
+	if _, failed := d.Headers[HeaderRPCErrorCode]; failed {
+		return &errcode.Error{Code: headerInt(d.Headers[HeaderRPCErrorCode]), Message: string(d.Body)}
+	}
+	if resp == nil || len(d.Body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(d.Body, resp); err != nil {
+		return fmt.Errorf("rabbitmq: rpc decode %s: %w", method, err)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"project/pkg/config"
+	"project/pkg/errcode"
+	"project/pkg/logger"
+
+	"github.com/streadway/amqp"
+)
+
+// RPCServerOptions RPC 服务端配置
+type RPCServerOptions struct {
+	// Queue 请求队列，必填
+	Queue string
+	// Concurrency 同时处理的请求数上限，默认 10
+	Concurrency int
+	// HandlerTimeout 请求未携带截止时间时 handler 的超时，默认 30s
+	HandlerTimeout time.Duration
+}
+
+// rpcHandler 解码请求、调用业务处理并返回响应
+type rpcHandler func(ctx context.Context, body []byte) (interface{}, error)
+
+// RPCServer RPC 服务端，实现 app.Worker
+//
+// 请求按 Type 字段分发到 HandleRPC 注册的处理函数，handler 返回的 *errcode.Error 原样返回给客户端；
+// 已超过客户端截止时间的请求直接丢弃。
+type RPCServer struct {
+	mq       *RabbitMQ
+	opts     RPCServerOptions
+	consumer *Consumer
+
+	mu       sync.RWMutex
+	handlers map[string]rpcHandler
+}
+
+// NewRPCServer 创建 RPC 服务端：
+//
+//	server := queue.NewRPCServer(queue.GetRabbitMQ(), queue.RPCServerOptions{Queue: "user-service"})
+//	queue.HandleRPC(server, "user.get", func(ctx context.Context, req *GetUserRequest) (*User, error) {
+//		return service.GetUser(ctx, req.ID)
+//	})
+//	app.RegisterWorker(server)
+func NewRPCServer(mq *RabbitMQ, opts RPCServerOptions) *RPCServer {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 10
+	}
+	if opts.HandlerTimeout <= 0 {
+		opts.HandlerTimeout = 30 * time.Second
+	}
+	s := &RPCServer{mq: mq, opts: opts, handlers: make(map[string]rpcHandler)}
+	// 失败的请求已应答给客户端，不重试
+	s.consumer = mq.Consume(ConsumerOptions{
+		Queue:       opts.Queue,
+		Concurrency: opts.Concurrency,
+		Prefetch:    opts.Concurrency,
+		MaxAttempts: 1,
+	}, s.serve)
+	return s
+}
+
+// HandleRPC 注册类型化的处理函数，请求与响应按 JSON 编解码；重复注册同名方法时覆盖
+func HandleRPC[Req, Resp any](s *RPCServer, method string, fn func(ctx context.Context, req *Req) (*Resp, error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[method] = func(ctx context.Context, body []byte) (interface{}, error) {
+		req := new(Req)
+		if len(body) > 0 {
+			if err := json.Unmarshal(body, req); err != nil {
+				return nil, errcode.New(errcode.BadRequest, fmt.Sprintf("decode %s request: %v", method, err))
+			}
+		}
+		resp, err := fn(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+}
+
+// Name 返回名称
+func (s *RPCServer) Name() string {
+	return "rabbitmq-rpc-server:" + s.opts.Queue
+}
+
+// Start 声明请求队列并开始处理
+func (s *RPCServer) Start() error {
+	if s.opts.Queue == "" {
+		return errors.New("rabbitmq: rpc server queue is required")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.mq.publishTimeout)
+	defer cancel()
+	err := s.mq.DeclareTopology(ctx, &config.Topology{Queues: []config.Queue{{Name: s.opts.Queue, Durable: true}}})
+	if err != nil {
+		return err
+	}
+	return s.consumer.Start()
+}
+
+// Stop 停止接收请求，等待处理中的请求完成
+func (s *RPCServer) Stop(ctx context.Context) error {
+	return s.consumer.Stop(ctx)
+}
+
+// serve 处理一个请求并应答
+func (s *RPCServer) serve(ctx context.Context, d *amqp.Delivery) error {
+	if d.ReplyTo == "" {
+		return Permanent(fmt.Errorf("rpc request %s without reply-to", d.CorrelationId))
+	}
+
+	// 元数据与请求 ID 已由 Consumer 写入 ctx
+	log := logger.FromContext(ctx)
+	if ms := headerInt(d.Headers[HeaderRPCDeadline]); ms > 0 {
+		deadline := time.UnixMilli(int64(ms))
+		if time.Now().After(deadline) {
+			log.Warnf("\t[rabbitmq] %s drop expired request %s (%s)", s.Name(), d.CorrelationId, d.Type)
+			return nil
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	} else {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.opts.HandlerTimeout)
+		defer cancel()
+	}
+
+	resp, err := s.invoke(ctx, d)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		// 客户端已超时返回，不再应答
+		log.Warnf("\t[rabbitmq] %s %s exceeded deadline", s.Name(), d.Type)
+		return nil
+	}
+
+	reply := amqp.Publishing{
+		CorrelationId: d.CorrelationId,
+		ContentType:   "application/json",
+		Timestamp:     time.Now(),
+		Headers:       amqp.Table{},
+	}
+	for k, v := range MetadataFromContext(ctx) {
+		reply.Headers[k] = v
+	}
+	if err != nil {
+		var e *errcode.Error
+		if !errors.As(err, &e) {
+			e = errcode.New(errcode.ServerError, err.Error())
+		}
+		log.Warnf("\t[rabbitmq] %s %s failed: %v", s.Name(), d.Type, err)
+		reply.ContentType = "text/plain"
+		reply.Headers[HeaderRPCErrorCode] = int32(e.Code)
+		reply.Body = []byte(e.Message)
+	} else if reply.Body, err = json.Marshal(resp); err != nil {
+		reply.ContentType = "text/plain"
+		reply.Headers[HeaderRPCErrorCode] = int32(errcode.ServerError)
+		reply.Body = []byte(fmt.Sprintf("encode %s response: %v", d.Type, err))
+	}
+
+	// 应答不受 handler ctx 取消影响，应答队列不存在时 broker 直接丢弃
+	pubCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.mq.publishTimeout)
+	defer cancel()
+	return s.mq.Publish(pubCtx, "", d.ReplyTo, reply)
+}
+
+// invoke 查找并调用处理函数，恢复 panic
+func (s *RPCServer) invoke(ctx context.Context, d *amqp.Delivery) (resp interface{}, err error) {
+	s.mu.RLock()
+	h, ok := s.handlers[d.Type]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, errcode.New(errcode.NotFound, fmt.Sprintf("unknown rpc method %q", d.Type))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.FromContext(ctx).Errorf("\t[rabbitmq] %s %s panic: %v\n%s", s.Name(), d.Type, r, debug.Stack())
+			err = errcode.New(errcode.ServerError)
+		}
+	}()
+	return h(ctx, d.Body)
+}
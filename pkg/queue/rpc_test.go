@@ -0,0 +1,179 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"project/pkg/config"
+	"project/pkg/errcode"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type getUserRequest struct {
+	ID int `json:"id"`
+}
+
+type getUserResponse struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func startRPCServer(t *testing.T, mq *RabbitMQ, opts RPCServerOptions, register func(s *RPCServer)) *RPCServer {
+	s := NewRPCServer(mq, opts)
+	register(s)
+	require.NoError(t, s.Start())
+	t.Cleanup(func() { _ = s.Stop(context.Background()) })
+	return s
+}
+
+func newTestRPCClient(t *testing.T, mq *RabbitMQ) *RPCClient {
+	c := NewRPCClient(mq, RPCClientOptions{Timeout: 2 * time.Second})
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestRPC_RoundTrip(t *testing.T) {
+	mq := newTestRabbitMQ(t, newFakeBroker(), config.RabbitMQ{})
+	startRPCServer(t, mq, RPCServerOptions{Queue: "user-service"}, func(s *RPCServer) {
+		HandleRPC(s, "user.get", func(ctx context.Context, req *getUserRequest) (*getUserResponse, error) {
+			if req.ID != 1 {
+				return nil, errcode.New(errcode.UserNotFound)
+			}
+			return &getUserResponse{ID: 1, Name: "alice"}, nil
+		})
+		HandleRPC(s, "user.fail", func(ctx context.Context, req *getUserRequest) (*getUserResponse, error) {
+			return nil, errors.New("db down")
+		})
+		HandleRPC(s, "user.panic", func(ctx context.Context, req *getUserRequest) (*getUserResponse, error) {
+			panic("boom")
+		})
+	})
+	client := newTestRPCClient(t, mq)
+	ctx := context.Background()
+
+	var resp getUserResponse
+	require.NoError(t, client.Call(ctx, "user-service", "user.get", getUserRequest{ID: 1}, &resp))
+	assert.Equal(t, getUserResponse{ID: 1, Name: "alice"}, resp)
+
+	tests := []struct {
+		method string
+		req    interface{}
+		code   int
+	}{
+		{"user.get", getUserRequest{ID: 2}, errcode.UserNotFound},
+		{"user.get", "not an object", errcode.BadRequest},
+		{"user.fail", getUserRequest{}, errcode.ServerError},
+		{"user.panic", getUserRequest{}, errcode.ServerError},
+		{"user.delete", getUserRequest{}, errcode.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			err := client.Call(ctx, "user-service", tt.method, tt.req, &resp)
+			var e *errcode.Error
+			require.True(t, errors.As(err, &e), "got %v", err)
+			assert.Equal(t, tt.code, e.Code)
+		})
+	}
+}
+
+func TestRPC_PropagatesMetadataAndDeadline(t *testing.T) {
+	mq := newTestRabbitMQ(t, newFakeBroker(), config.RabbitMQ{})
+	type seen struct {
+		requestID string
+		deadline  time.Time
+	}
+	got := make(chan seen, 1)
+	startRPCServer(t, mq, RPCServerOptions{Queue: "user-service"}, func(s *RPCServer) {
+		HandleRPC(s, "user.get", func(ctx context.Context, req *getUserRequest) (*getUserResponse, error) {
+			deadline, _ := ctx.Deadline()
+			got <- seen{requestID: RequestIDFromContext(ctx), deadline: deadline}
+			return &getUserResponse{ID: req.ID}, nil
+		})
+	})
+	client := newTestRPCClient(t, mq)
+
+	deadline := time.Now().Add(time.Second)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	ctx = WithMetadata(ctx, map[string]string{HeaderRequestID: "req-1"})
+	require.NoError(t, client.Call(ctx, "user-service", "user.get", getUserRequest{ID: 1}, nil))
+
+	s := <-got
+	assert.Equal(t, "req-1", s.requestID)
+	assert.Equal(t, deadline.UnixMilli(), s.deadline.UnixMilli())
+}
+
+func TestRPC_Timeout(t *testing.T) {
+	mq := newTestRabbitMQ(t, newFakeBroker(), config.RabbitMQ{})
+	cancelled := make(chan struct{})
+	startRPCServer(t, mq, RPCServerOptions{Queue: "user-service"}, func(s *RPCServer) {
+		HandleRPC(s, "user.slow", func(ctx context.Context, req *getUserRequest) (*getUserResponse, error) {
+			<-ctx.Done()
+			close(cancelled)
+			return nil, ctx.Err()
+		})
+	})
+	client := newTestRPCClient(t, mq)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := client.Call(ctx, "user-service", "user.slow", getUserRequest{}, nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// 服务端 handler 的 ctx 随客户端截止时间取消
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("handler ctx not cancelled at client deadline")
+	}
+}
+
+func TestRPC_NoServer(t *testing.T) {
+	mq := newTestRabbitMQ(t, newFakeBroker(), config.RabbitMQ{})
+	client := newTestRPCClient(t, mq)
+
+	err := client.Call(context.Background(), "nobody", "user.get", getUserRequest{}, nil)
+	var returned *ReturnedError
+	assert.True(t, errors.As(err, &returned), "got %v", err)
+}
+
+func TestRPC_Concurrency(t *testing.T) {
+	mq := newTestRabbitMQ(t, newFakeBroker(), config.RabbitMQ{})
+	var inFlight, maxInFlight int32
+	startRPCServer(t, mq, RPCServerOptions{Queue: "user-service", Concurrency: 2}, func(s *RPCServer) {
+		HandleRPC(s, "user.get", func(ctx context.Context, req *getUserRequest) (*getUserResponse, error) {
+			n := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+			for {
+				m := atomic.LoadInt32(&maxInFlight)
+				if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			return &getUserResponse{ID: req.ID}, nil
+		})
+	})
+	client := newTestRPCClient(t, mq)
+
+	errs := make(chan error, 6)
+	for i := 0; i < 6; i++ {
+		go func(id int) {
+			var resp getUserResponse
+			err := client.Call(context.Background(), "user-service", "user.get", getUserRequest{ID: id}, &resp)
+			if err == nil && resp.ID != id {
+				err = errors.New("mismatched reply")
+			}
+			errs <- err
+		}(i)
+	}
+	for i := 0; i < 6; i++ {
+		require.NoError(t, <-errs)
+	}
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxInFlight), int32(2))
+}
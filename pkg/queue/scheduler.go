@@ -0,0 +1,212 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"project/pkg/logger"
+)
+
+// ScheduledMessage 待发布的延迟消息
+type ScheduledMessage struct {
+	// Topic 到期后发布的主题
+	Topic string `json:"topic"`
+	// At 计划发布时间（毫秒精度）
+	At time.Time `json:"at"`
+	// Message 消息信封
+	Message *Message `json:"message"`
+}
+
+// Scheduler 延迟发布，例如 30 分钟后取消未支付的订单：
+//
+//	msg, _ := queue.NewMessage("order.cancel", order.No)
+//	msg.ID = "order.cancel:" + order.No // 固定 ID 便于支付成功后取消
+//	err := scheduler.Schedule(ctx, "orders", time.Now().Add(30*time.Minute), msg)
+//	// 支付成功
+//	_, err = scheduler.Cancel(ctx, "order.cancel:"+order.No)
+//
+// 消息至少发布一次，消费端应按消息 ID 去重。
+type Scheduler interface {
+	// Schedule 在 at 时刻把消息发布到主题，at 已过时立即发布；
+	// 消息 ID 为空时自动生成，相同 ID 的消息重新计划时覆盖之前的计划
+	Schedule(ctx context.Context, topic string, at time.Time, msg *Message) error
+	// Cancel 取消尚未发布的消息，返回 false 表示消息不存在或已开始发布
+	Cancel(ctx context.Context, id string) (bool, error)
+	// Pending 按计划时间顺序列出待发布的消息，topic 为空时返回全部
+	Pending(ctx context.Context, topic string) ([]*ScheduledMessage, error)
+}
+
+// scheduleStore 延迟消息的存储
+//
+// 消息先处于待发布状态，到期后被认领进入发布中状态并获得租约，发布成功后删除；
+// 持有租约的进程崩溃时，租约到期后消息可再次被认领。只有待发布的消息可以取消。
+type scheduleStore interface {
+	// add 写入或覆盖待发布消息
+	add(ctx context.Context, sm *ScheduledMessage) error
+	// cancel 删除待发布消息
+	cancel(ctx context.Context, id string) (bool, error)
+	// due 认领到期的消息以及租约已过期的发布中消息
+	due(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*ScheduledMessage, error)
+	// claim 认领指定消息：待发布消息的计划时间须与 at 一致，发布中的消息直接续租；不存在时返回 nil
+	claim(ctx context.Context, id string, at time.Time, lease time.Duration) (*ScheduledMessage, error)
+	// complete 删除已发布的消息
+	complete(ctx context.Context, id string) error
+	// list 列出待发布与发布中的消息
+	list(ctx context.Context, topic string) ([]*ScheduledMessage, error)
+}
+
+// SchedulerOptions 轮询调度器配置
+type SchedulerOptions struct {
+	// Prefix Redis 键前缀，默认 queue:schedule:（仅 Redis 使用）
+	Prefix string
+	// PollInterval 检查到期消息的间隔，默认 1s
+	PollInterval time.Duration
+	// BatchSize 每次认领的消息数，默认 100
+	BatchSize int
+	// Lease 发布中消息的租约，超时未完成时重新发布，默认 30s
+	Lease time.Duration
+}
+
+// PollingScheduler 定时轮询存储并发布到期消息的调度器，实现 app.Worker
+//
+// 需调用 Start 或通过 app.RegisterWorker 注册后才会发布；多副本同时运行时每条消息只会被一个副本认领。
+type PollingScheduler struct {
+	name  string
+	store scheduleStore
+	pub   Publisher
+	opts  SchedulerOptions
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newPollingScheduler(name string, store scheduleStore, pub Publisher, opts SchedulerOptions) *PollingScheduler {
+	return &PollingScheduler{name: name, store: store, pub: pub, opts: opts}
+}
+
+func (o *SchedulerOptions) setDefaults() {
+	if o.Prefix == "" {
+		o.Prefix = "queue:schedule:"
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = time.Second
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 100
+	}
+	if o.Lease <= 0 {
+		o.Lease = 30 * time.Second
+	}
+}
+
+// Schedule 写入存储，到期后由轮询发布
+func (s *PollingScheduler) Schedule(ctx context.Context, topic string, at time.Time, msg *Message) error {
+	msg.prepare(ctx)
+	if !at.After(time.Now()) {
+		// 已计划的相同 ID 不再发布
+		if _, err := s.store.cancel(ctx, msg.ID); err != nil {
+			return err
+		}
+		return s.pub.Publish(ctx, topic, msg)
+	}
+	return s.store.add(ctx, &ScheduledMessage{Topic: topic, At: at.Truncate(time.Millisecond), Message: msg.clone()})
+}
+
+// Cancel 取消尚未发布的消息
+func (s *PollingScheduler) Cancel(ctx context.Context, id string) (bool, error) {
+	return s.store.cancel(ctx, id)
+}
+
+// Pending 列出待发布的消息
+func (s *PollingScheduler) Pending(ctx context.Context, topic string) ([]*ScheduledMessage, error) {
+	return s.store.list(ctx, topic)
+}
+
+// Name 返回名称
+func (s *PollingScheduler) Name() string {
+	return s.name
+}
+
+// Start 启动轮询（重复调用无效）
+func (s *PollingScheduler) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go s.run(ctx, s.done)
+	return nil
+}
+
+// Stop 停止轮询并等待正在发布的批次完成
+func (s *PollingScheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel, s.done = nil, nil
+	s.mu.Unlock()
+
+	if done == nil {
+		return nil
+	}
+	cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *PollingScheduler) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(s.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		// 积压时连续认领，直到一批未满
+		for {
+			n, err := s.Flush(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Sugar.Errorf("\t[queue] %s flush failed: %v", s.name, err)
+				}
+				break
+			}
+			if n < s.opts.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush 认领一批到期消息并发布，返回认领的数量；发布失败的消息在租约到期后重试
+func (s *PollingScheduler) Flush(ctx context.Context) (int, error) {
+	due, err := s.store.due(ctx, time.Now(), s.opts.BatchSize, s.opts.Lease)
+	if err != nil {
+		return 0, err
+	}
+	for _, sm := range due {
+		if err := s.pub.Publish(ctx, sm.Topic, sm.Message); err != nil {
+			logger.Sugar.Warnf("\t[queue] %s publish %s to %s failed, retry in %s: %v",
+				s.name, sm.Message.ID, sm.Topic, s.opts.Lease, err)
+			continue
+		}
+		if err := s.store.complete(ctx, sm.Message.ID); err != nil {
+			logger.Sugar.Errorf("\t[queue] %s complete %s failed: %v", s.name, sm.Message.ID, err)
+		}
+	}
+	return len(due), nil
+}
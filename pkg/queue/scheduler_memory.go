@@ -0,0 +1,101 @@
+package queue
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// NewMemoryScheduler 创建进程内调度器，用于测试与本地开发；进程退出时未发布的消息会丢失
+func NewMemoryScheduler(pub Publisher, opts SchedulerOptions) *PollingScheduler {
+	opts.setDefaults()
+	return newPollingScheduler("memory-scheduler", newMemoryScheduleStore(), pub, opts)
+}
+
+type scheduleEntry struct {
+	sm *ScheduledMessage
+	// leaseUntil 非零表示发布中
+	leaseUntil time.Time
+}
+
+type memoryScheduleStore struct {
+	mu      sync.Mutex
+	entries map[string]*scheduleEntry
+}
+
+func newMemoryScheduleStore() *memoryScheduleStore {
+	return &memoryScheduleStore{entries: make(map[string]*scheduleEntry)}
+}
+
+func (s *memoryScheduleStore) add(_ context.Context, sm *ScheduledMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[sm.Message.ID] = &scheduleEntry{sm: sm}
+	return nil
+}
+
+func (s *memoryScheduleStore) cancel(_ context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[id]
+	if !ok || !e.leaseUntil.IsZero() {
+		return false, nil
+	}
+	delete(s.entries, id)
+	return true, nil
+}
+
+func (s *memoryScheduleStore) due(_ context.Context, now time.Time, limit int, lease time.Duration) ([]*ScheduledMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*scheduleEntry
+	for _, e := range s.entries {
+		if e.leaseUntil.IsZero() && !e.sm.At.After(now) || !e.leaseUntil.IsZero() && !e.leaseUntil.After(now) {
+			due = append(due, e)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].sm.At.Before(due[j].sm.At) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	out := make([]*ScheduledMessage, len(due))
+	for i, e := range due {
+		e.leaseUntil = now.Add(lease)
+		out[i] = e.sm
+	}
+	return out, nil
+}
+
+func (s *memoryScheduleStore) claim(_ context.Context, id string, at time.Time, lease time.Duration) (*ScheduledMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[id]
+	if !ok || e.leaseUntil.IsZero() && e.sm.At.UnixMilli() != at.UnixMilli() {
+		return nil, nil
+	}
+	e.leaseUntil = time.Now().Add(lease)
+	return e.sm, nil
+}
+
+func (s *memoryScheduleStore) complete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, id)
+	return nil
+}
+
+func (s *memoryScheduleStore) list(_ context.Context, topic string) ([]*ScheduledMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*ScheduledMessage
+	for _, e := range s.entries {
+		if topic == "" || e.sm.Topic == topic {
+			out = append(out, &ScheduledMessage{Topic: e.sm.Topic, At: e.sm.At, Message: e.sm.Message.clone()})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].At.Before(out[j].At) })
+	return out, nil
+}
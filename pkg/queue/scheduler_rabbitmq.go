@@ -0,0 +1,290 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"project/pkg/config"
+	"project/pkg/logger"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+const (
+	// HeaderScheduledAt 延迟消息的计划发布时间（Unix 毫秒）
+	HeaderScheduledAt = "x-scheduled-at"
+	// HeaderScheduledTopic 延迟消息到期后发布的主题
+	HeaderScheduledTopic = "x-scheduled-topic"
+	// HeaderScheduledBy 计划该消息的调度器实例，未使用 Redis 时用于识别重启前计划的消息
+	HeaderScheduledBy = "x-scheduled-by"
+)
+
+// RabbitSchedulerOptions RabbitMQ 调度器配置
+type RabbitSchedulerOptions struct {
+	// Name 队列与交换机名前缀，默认 scheduler；到期消息进入 <Name>.due 队列
+	Name string
+	// Plugin 使用 rabbitmq_delayed_message_exchange 插件的 <Name>.delayed 交换机计时，
+	// 否则使用固定的一组 TTL 队列 <Name>.delay.<bucket>（1s、2s、4s……maxDelayBucket）分段计时
+	Plugin bool
+	// Redis 记录待发布消息以支持 Cancel 与 Pending；为空时记录在进程内，仅适用于单副本：
+	// 重启或由其他实例计划的消息没有记录，到期后按消息头 x-scheduled-topic 直接发布，此前的 Cancel 不再生效
+	Redis redis.UniversalClient
+	// Prefix Redis 键前缀，默认 {queue:schedule}:
+	Prefix string
+	// Concurrency 到期消息的并发处理数，默认 1
+	Concurrency int
+	// Lease 发布中消息的租约，默认 30s
+	Lease time.Duration
+}
+
+// maxDelayBucket 最长的 TTL 队列，更长的延迟分多段计时
+const maxDelayBucket = 1 << 17 * time.Second
+
+// RabbitScheduler 由 RabbitMQ 计时的调度器，实现 app.Worker
+//
+// 消息先进入不超过剩余延迟的最长 TTL 队列（或插件交换机），到期后转入 <Name>.due 队列，
+// 由调度器自身的消费者处理：尚未到期的消息进入下一段 TTL 队列，剩余不足 1s 时按 1s 计，因此最多晚 1s；
+// 到期的消息核对记录后发布到目标主题，已取消或已重新计划的消息直接丢弃。
+// 发布失败时沿用 Consumer 的重试队列机制。
+type RabbitScheduler struct {
+	mq       *RabbitMQ
+	pub      Publisher
+	opts     RabbitSchedulerOptions
+	store    scheduleStore
+	instance string
+	due      string
+	consumer *Consumer
+
+	setupMu  sync.Mutex
+	ready    bool
+	declared sync.Map
+}
+
+// NewRabbitScheduler 创建 RabbitMQ 调度器，到期消息通过 pub 发布（通常为 queue.Open 返回的驱动）
+func NewRabbitScheduler(mq *RabbitMQ, pub Publisher, opts RabbitSchedulerOptions) *RabbitScheduler {
+	if opts.Name == "" {
+		opts.Name = "scheduler"
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = "{queue:schedule}:"
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = 30 * time.Second
+	}
+
+	var store scheduleStore = newMemoryScheduleStore()
+	if opts.Redis != nil {
+		store = newRedisScheduleStore(opts.Redis, opts.Prefix)
+	}
+
+	s := &RabbitScheduler{
+		mq:       mq,
+		pub:      pub,
+		opts:     opts,
+		store:    store,
+		instance: uuid.NewString(),
+		due:      opts.Name + ".due",
+	}
+	s.consumer = mq.Consume(ConsumerOptions{Queue: s.due, Concurrency: opts.Concurrency}, s.handle)
+	return s
+}
+
+// Name 返回名称
+func (s *RabbitScheduler) Name() string {
+	return "rabbitmq-scheduler:" + s.opts.Name
+}
+
+// Start 声明到期队列并开始消费
+func (s *RabbitScheduler) Start() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.mq.publishTimeout)
+	defer cancel()
+	if err := s.setup(ctx); err != nil {
+		return err
+	}
+	return s.consumer.Start()
+}
+
+// Stop 停止消费到期队列，等待处理中的消息完成
+func (s *RabbitScheduler) Stop(ctx context.Context) error {
+	return s.consumer.Stop(ctx)
+}
+
+// Schedule 记录消息并发布到延迟队列
+func (s *RabbitScheduler) Schedule(ctx context.Context, topic string, at time.Time, msg *Message) error {
+	msg.prepare(ctx)
+	at = at.Truncate(time.Millisecond)
+	delay := time.Until(at)
+	if delay <= 0 {
+		// 已计划的相同 ID 不再发布
+		if _, err := s.store.cancel(ctx, msg.ID); err != nil {
+			return err
+		}
+		return s.pub.Publish(ctx, topic, msg)
+	}
+	if err := s.setup(ctx); err != nil {
+		return err
+	}
+
+	sm := &ScheduledMessage{Topic: topic, At: at, Message: msg.clone()}
+	if err := s.store.add(ctx, sm); err != nil {
+		return err
+	}
+
+	p := toPublishing(msg)
+	p.Headers[HeaderScheduledAt] = at.UnixMilli()
+	p.Headers[HeaderScheduledTopic] = topic
+	p.Headers[HeaderScheduledBy] = s.instance
+
+	var err error
+	if s.opts.Plugin {
+		p.Headers["x-delay"] = delay.Milliseconds()
+		// 插件交换机总是退回 mandatory 消息，因此不使用 mandatory
+		err = s.mq.Publish(ctx, s.opts.Name+".delayed", s.due, p)
+	} else {
+		err = s.delay(ctx, delay, p)
+	}
+	if err != nil {
+		if _, cancelErr := s.store.cancel(context.WithoutCancel(ctx), msg.ID); cancelErr != nil {
+			logger.Named("rabbitmq").Errorf("\t[rabbitmq] %s rollback %s failed: %v", s.Name(), msg.ID, cancelErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// Cancel 取消尚未到期的消息；延迟队列中的副本到期后被丢弃
+func (s *RabbitScheduler) Cancel(ctx context.Context, id string) (bool, error) {
+	return s.store.cancel(ctx, id)
+}
+
+// Pending 列出待发布的消息
+func (s *RabbitScheduler) Pending(ctx context.Context, topic string) ([]*ScheduledMessage, error) {
+	return s.store.list(ctx, topic)
+}
+
+// setup 声明到期队列与插件交换机（成功后不再重复声明）
+func (s *RabbitScheduler) setup(ctx context.Context) error {
+	s.setupMu.Lock()
+	defer s.setupMu.Unlock()
+	if s.ready {
+		return nil
+	}
+
+	t := &config.Topology{Queues: []config.Queue{{Name: s.due, Durable: true}}}
+	if s.opts.Plugin {
+		exchange := s.opts.Name + ".delayed"
+		t.Exchanges = []config.Exchange{{
+			Name:      exchange,
+			Type:      "x-delayed-message",
+			Durable:   true,
+			Arguments: map[string]interface{}{"x-delayed-type": "direct"},
+		}}
+		t.Bindings = []config.Binding{{Exchange: exchange, Queue: s.due, RoutingKey: s.due}}
+	}
+	if err := s.mq.DeclareTopology(ctx, t); err != nil {
+		return err
+	}
+	s.ready = true
+	return nil
+}
+
+// delay 发布到不超过 delay 的最长 TTL 队列
+func (s *RabbitScheduler) delay(ctx context.Context, delay time.Duration, p amqp.Publishing) error {
+	queue, err := s.delayQueue(ctx, delayBucket(delay))
+	if err != nil {
+		return err
+	}
+	return s.mq.Publish(ctx, "", queue, p, WithMandatory())
+}
+
+// delayBucket 返回不超过 delay 的最长 TTL，不足 1s 时为 1s
+func delayBucket(delay time.Duration) time.Duration {
+	bucket := time.Second
+	for bucket < maxDelayBucket && bucket*2 <= delay {
+		bucket *= 2
+	}
+	return bucket
+}
+
+// delayQueue 声明 TTL 队列，到期后转入到期队列
+func (s *RabbitScheduler) delayQueue(ctx context.Context, delay time.Duration) (string, error) {
+	name := fmt.Sprintf("%s.delay.%s", s.opts.Name, delay)
+	if _, ok := s.declared.Load(name); ok {
+		return name, nil
+	}
+
+	err := s.mq.DeclareTopology(ctx, &config.Topology{Queues: []config.Queue{{
+		Name:                 name,
+		Durable:              true,
+		MessageTTL:           delay,
+		DeadLetterRoutingKey: s.due,
+	}}})
+	if err != nil {
+		return "", err
+	}
+	s.declared.Store(name, struct{}{})
+	return name, nil
+}
+
+// handle 核对到期消息的记录后发布到目标主题
+func (s *RabbitScheduler) handle(ctx context.Context, d *amqp.Delivery) error {
+	at, ok := d.Headers[HeaderScheduledAt].(int64)
+	if !ok {
+		return Permanent(fmt.Errorf("missing %s header", HeaderScheduledAt))
+	}
+	if remaining := time.Until(time.UnixMilli(at)); remaining > 0 && !s.opts.Plugin {
+		return s.delay(ctx, remaining, deliveryPublishing(d))
+	}
+
+	sm, err := s.store.claim(ctx, d.MessageId, time.UnixMilli(at), s.opts.Lease)
+	if err != nil {
+		return err
+	}
+	if sm == nil {
+		// 进程内记录在重启后丢失，其他实例计划的消息同样没有记录，按消息头发布
+		if s.opts.Redis == nil && d.Headers[HeaderScheduledBy] != s.instance {
+			return s.publishDelivery(ctx, d)
+		}
+		logger.FromContext(ctx).Infof("\t[rabbitmq] %s drop cancelled or rescheduled message %s", s.Name(), d.MessageId)
+		return nil
+	}
+
+	if err := s.pub.Publish(ctx, sm.Topic, sm.Message); err != nil {
+		return err
+	}
+	return s.store.complete(ctx, sm.Message.ID)
+}
+
+// publishDelivery 按消息头 x-scheduled-topic 发布没有记录的到期消息
+func (s *RabbitScheduler) publishDelivery(ctx context.Context, d *amqp.Delivery) error {
+	topic, ok := d.Headers[HeaderScheduledTopic].(string)
+	if !ok || topic == "" {
+		return Permanent(fmt.Errorf("missing %s header", HeaderScheduledTopic))
+	}
+
+	msg := &Message{ID: d.MessageId, Type: d.Type, Timestamp: d.Timestamp, Payload: d.Body}
+	for k, v := range d.Headers {
+		// 消息信封的头都是字符串，调度器、重试与死信添加的头不再带到目标主题
+		value, ok := v.(string)
+		if !ok || isScheduleHeader(k) {
+			continue
+		}
+		msg.SetHeader(k, value)
+	}
+	logger.FromContext(ctx).Infof("\t[rabbitmq] %s publish unrecorded message %s to %s", s.Name(), d.MessageId, topic)
+	return s.pub.Publish(ctx, topic, msg)
+}
+
+// isScheduleHeader 调度过程中添加的消息头
+func isScheduleHeader(key string) bool {
+	switch key {
+	case HeaderScheduledTopic, HeaderScheduledBy, HeaderError, HeaderOriginalQueue:
+		return true
+	}
+	return strings.HasPrefix(key, "x-first-death-") || strings.HasPrefix(key, "x-last-death-")
+}
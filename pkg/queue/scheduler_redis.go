@@ -0,0 +1,185 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	// 认领到期的待发布消息与租约过期的发布中消息，返回消息内容
+	scheduleDueScript = redis.NewScript(`
+local ids = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, ARGV[2])
+local expired = redis.call("ZRANGEBYSCORE", KEYS[2], "-inf", ARGV[1], "LIMIT", 0, ARGV[2])
+for _, id in ipairs(expired) do
+	table.insert(ids, id)
+end
+local out = {}
+for i, id in ipairs(ids) do
+	if i > tonumber(ARGV[2]) then
+		break
+	end
+	redis.call("ZREM", KEYS[1], id)
+	local v = redis.call("HGET", KEYS[3], id)
+	if v then
+		redis.call("ZADD", KEYS[2], ARGV[3], id)
+		table.insert(out, v)
+	else
+		redis.call("ZREM", KEYS[2], id)
+	end
+end
+return out`)
+
+	// 认领指定消息：待发布消息的计划时间须一致，发布中的消息直接续租
+	scheduleClaimScript = redis.NewScript(`
+local at = redis.call("ZSCORE", KEYS[1], ARGV[1])
+if at then
+	if tonumber(at) ~= tonumber(ARGV[2]) then
+		return false
+	end
+	redis.call("ZREM", KEYS[1], ARGV[1])
+elseif not redis.call("ZSCORE", KEYS[2], ARGV[1]) then
+	return false
+end
+redis.call("ZADD", KEYS[2], ARGV[3], ARGV[1])
+return redis.call("HGET", KEYS[3], ARGV[1])`)
+
+	// 只删除待发布的消息
+	scheduleCancelScript = redis.NewScript(`
+if redis.call("ZREM", KEYS[1], ARGV[1]) == 1 then
+	redis.call("HDEL", KEYS[2], ARGV[1])
+	return 1
+end
+return 0`)
+)
+
+// NewRedisScheduler 创建基于 Redis 有序集合的调度器
+//
+// 待发布消息保存在 <prefix>due（按计划时间排序）、发布中消息保存在 <prefix>processing（按租约到期时间排序），
+// 消息内容保存在 <prefix>messages。集群模式下前缀需包含 hash tag，默认 {queue:schedule}:。
+func NewRedisScheduler(client redis.UniversalClient, pub Publisher, opts SchedulerOptions) *PollingScheduler {
+	if opts.Prefix == "" {
+		opts.Prefix = "{queue:schedule}:"
+	}
+	opts.setDefaults()
+	return newPollingScheduler("redis-scheduler:"+opts.Prefix, newRedisScheduleStore(client, opts.Prefix), pub, opts)
+}
+
+type redisScheduleStore struct {
+	client        redis.UniversalClient
+	dueKey        string
+	processingKey string
+	messagesKey   string
+}
+
+func newRedisScheduleStore(client redis.UniversalClient, prefix string) *redisScheduleStore {
+	return &redisScheduleStore{
+		client:        client,
+		dueKey:        prefix + "due",
+		processingKey: prefix + "processing",
+		messagesKey:   prefix + "messages",
+	}
+}
+
+func (s *redisScheduleStore) add(ctx context.Context, sm *ScheduledMessage) error {
+	data, err := json.Marshal(sm)
+	if err != nil {
+		return fmt.Errorf("queue: encode scheduled message: %w", err)
+	}
+	id := sm.Message.ID
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, s.messagesKey, id, data)
+		pipe.ZRem(ctx, s.processingKey, id)
+		pipe.ZAdd(ctx, s.dueKey, &redis.Z{Score: float64(sm.At.UnixMilli()), Member: id})
+		return nil
+	})
+	return err
+}
+
+func (s *redisScheduleStore) cancel(ctx context.Context, id string) (bool, error) {
+	n, err := scheduleCancelScript.Run(ctx, s.client, []string{s.dueKey, s.messagesKey}, id).Int64()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (s *redisScheduleStore) due(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*ScheduledMessage, error) {
+	vals, err := scheduleDueScript.Run(ctx, s.client, []string{s.dueKey, s.processingKey, s.messagesKey},
+		now.UnixMilli(), limit, now.Add(lease).UnixMilli()).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*ScheduledMessage, 0, len(vals))
+	for _, v := range vals {
+		sm, err := decodeScheduled(v)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, sm)
+	}
+	return out, nil
+}
+
+func (s *redisScheduleStore) claim(ctx context.Context, id string, at time.Time, lease time.Duration) (*ScheduledMessage, error) {
+	v, err := scheduleClaimScript.Run(ctx, s.client, []string{s.dueKey, s.processingKey, s.messagesKey},
+		id, at.UnixMilli(), time.Now().Add(lease).UnixMilli()).Text()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeScheduled(v)
+}
+
+func (s *redisScheduleStore) complete(ctx context.Context, id string) error {
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, s.processingKey, id)
+		pipe.HDel(ctx, s.messagesKey, id)
+		return nil
+	})
+	return err
+}
+
+func (s *redisScheduleStore) list(ctx context.Context, topic string) ([]*ScheduledMessage, error) {
+	var out []*ScheduledMessage
+	var cursor uint64
+	for {
+		kvs, next, err := s.client.HScan(ctx, s.messagesKey, cursor, "", 500).Result()
+		if err != nil {
+			return nil, err
+		}
+		for i := 1; i < len(kvs); i += 2 {
+			sm, err := decodeScheduled(kvs[i])
+			if err != nil {
+				return nil, err
+			}
+			if topic == "" || sm.Topic == topic {
+				out = append(out, sm)
+			}
+		}
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].At.Before(out[j].At) })
+	return out, nil
+}
+
+func decodeScheduled(v string) (*ScheduledMessage, error) {
+	var sm ScheduledMessage
+	if err := json.Unmarshal([]byte(v), &sm); err != nil {
+		return nil, fmt.Errorf("queue: decode scheduled message: %w", err)
+	}
+	if sm.Message == nil {
+		return nil, errors.New("queue: scheduled message without envelope")
+	}
+	return &sm, nil
+}
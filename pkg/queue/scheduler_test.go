@@ -0,0 +1,389 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"project/pkg/config"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ Scheduler = (*PollingScheduler)(nil)
+	_ Scheduler = (*RabbitScheduler)(nil)
+)
+
+func newTestRedis(t *testing.T) redis.UniversalClient {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+// testSchedulers 内存与 Redis 调度器使用相同的用例
+func testSchedulers() map[string]func(t *testing.T, pub Publisher, opts SchedulerOptions) *PollingScheduler {
+	return map[string]func(t *testing.T, pub Publisher, opts SchedulerOptions) *PollingScheduler{
+		"memory": func(t *testing.T, pub Publisher, opts SchedulerOptions) *PollingScheduler {
+			return NewMemoryScheduler(pub, opts)
+		},
+		"redis": func(t *testing.T, pub Publisher, opts SchedulerOptions) *PollingScheduler {
+			return NewRedisScheduler(newTestRedis(t), pub, opts)
+		},
+	}
+}
+
+func startScheduler(t *testing.T, s Subscription) {
+	require.NoError(t, s.Start())
+	t.Cleanup(func() { _ = s.Stop(context.Background()) })
+}
+
+func TestScheduler_PublishesWhenDue(t *testing.T) {
+	for name, open := range testSchedulers() {
+		t.Run(name, func(t *testing.T) {
+			broker := NewMemoryBroker(MemoryBrokerOptions{})
+			s := open(t, broker, SchedulerOptions{PollInterval: 10 * time.Millisecond})
+			startScheduler(t, s)
+			ctx := context.Background()
+
+			later, _ := NewMessage("order.cancel", "A2")
+			require.NoError(t, s.Schedule(ctx, "orders", time.Now().Add(time.Hour), later))
+			soon, _ := NewMessage("order.cancel", "A1")
+			soon.SetHeader("trace-id", "abc")
+			require.NoError(t, s.Schedule(ctx, "orders", time.Now().Add(50*time.Millisecond), soon))
+
+			pending, err := s.Pending(ctx, "orders")
+			require.NoError(t, err)
+			require.Len(t, pending, 2)
+			assert.Equal(t, soon.ID, pending[0].Message.ID)
+			assert.Equal(t, later.ID, pending[1].Message.ID)
+			pending, err = s.Pending(ctx, "other")
+			require.NoError(t, err)
+			assert.Empty(t, pending)
+
+			received := make(chan *Message, 2)
+			subscribe(t, broker, "orders", func(ctx context.Context, msg *Message) error {
+				received <- msg
+				return nil
+			})
+			select {
+			case msg := <-received:
+				assert.Equal(t, soon.ID, msg.ID)
+				assert.Equal(t, "abc", msg.Header("trace-id"))
+			case <-time.After(2 * time.Second):
+				t.Fatal("scheduled message not published")
+			}
+
+			// 发布成功后才从存储中删除
+			require.Eventually(t, func() bool {
+				pending, err = s.Pending(ctx, "")
+				return err == nil && len(pending) == 1 && pending[0].Message.ID == later.ID
+			}, time.Second, 10*time.Millisecond)
+		})
+	}
+}
+
+func TestScheduler_Cancel(t *testing.T) {
+	for name, open := range testSchedulers() {
+		t.Run(name, func(t *testing.T) {
+			broker := NewMemoryBroker(MemoryBrokerOptions{})
+			s := open(t, broker, SchedulerOptions{PollInterval: 10 * time.Millisecond})
+			startScheduler(t, s)
+			ctx := context.Background()
+
+			msg, _ := NewMessage("order.cancel", "A1")
+			msg.ID = "order.cancel:A1"
+			require.NoError(t, s.Schedule(ctx, "orders", time.Now().Add(50*time.Millisecond), msg))
+
+			ok, err := s.Cancel(ctx, "order.cancel:A1")
+			require.NoError(t, err)
+			assert.True(t, ok)
+			ok, err = s.Cancel(ctx, "order.cancel:A1")
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			time.Sleep(100 * time.Millisecond)
+			assert.Equal(t, 0, broker.Pending("orders"))
+		})
+	}
+}
+
+func TestScheduler_RescheduleAndPastDue(t *testing.T) {
+	for name, open := range testSchedulers() {
+		t.Run(name, func(t *testing.T) {
+			broker := NewMemoryBroker(MemoryBrokerOptions{})
+			s := open(t, broker, SchedulerOptions{})
+			ctx := context.Background()
+
+			// 已到期的消息立即发布
+			msg, _ := NewMessage("order.cancel", "A1")
+			require.NoError(t, s.Schedule(ctx, "orders", time.Now().Add(-time.Second), msg))
+			assert.Equal(t, 1, broker.Pending("orders"))
+
+			// 相同 ID 重新计划覆盖之前的时间
+			msg, _ = NewMessage("order.cancel", "A2")
+			at := time.Now().Add(time.Hour)
+			require.NoError(t, s.Schedule(ctx, "orders", time.Now().Add(time.Minute), msg))
+			require.NoError(t, s.Schedule(ctx, "orders", at, msg))
+			pending, err := s.Pending(ctx, "orders")
+			require.NoError(t, err)
+			require.Len(t, pending, 1)
+			assert.Equal(t, at.UnixMilli(), pending[0].At.UnixMilli())
+
+			// 重新计划到过去时立即发布，之前的计划作废
+			require.NoError(t, s.Schedule(ctx, "orders", time.Now().Add(-time.Second), msg))
+			assert.Equal(t, 2, broker.Pending("orders"))
+			pending, err = s.Pending(ctx, "orders")
+			require.NoError(t, err)
+			assert.Empty(t, pending)
+		})
+	}
+}
+
+func TestRedisScheduler_RetriesAfterLease(t *testing.T) {
+	client := newTestRedis(t)
+	var fail int32 = 1
+	pub := &funcPublisher{fn: func(topic string, msg *Message) error {
+		if atomic.CompareAndSwapInt32(&fail, 1, 0) {
+			return errors.New("broker unavailable")
+		}
+		return nil
+	}}
+	s := NewRedisScheduler(client, pub, SchedulerOptions{Lease: 50 * time.Millisecond})
+	ctx := context.Background()
+
+	msg, _ := NewMessage("order.cancel", "A1")
+	require.NoError(t, s.Schedule(ctx, "orders", time.Now().Add(10*time.Millisecond), msg))
+	time.Sleep(20 * time.Millisecond)
+
+	n, err := s.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Empty(t, pub.published())
+
+	// 发布中的消息不能取消，租约到期前不会被再次认领
+	ok, err := s.Cancel(ctx, msg.ID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	n, err = s.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	time.Sleep(60 * time.Millisecond)
+	n, err = s.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{msg.ID}, pub.published())
+
+	pending, err := s.Pending(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestRedisScheduler_MultipleReplicas(t *testing.T) {
+	client := newTestRedis(t)
+	pub := &funcPublisher{}
+	for i := 0; i < 3; i++ {
+		startScheduler(t, NewRedisScheduler(client, pub, SchedulerOptions{PollInterval: 5 * time.Millisecond, BatchSize: 3}))
+	}
+
+	s := NewRedisScheduler(client, pub, SchedulerOptions{})
+	for i := 0; i < 30; i++ {
+		msg, _ := NewMessage("tick", i)
+		require.NoError(t, s.Schedule(context.Background(), "ticks", time.Now().Add(20*time.Millisecond), msg))
+	}
+
+	require.Eventually(t, func() bool { return len(pub.published()) >= 30 }, 2*time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, pub.published(), 30)
+}
+
+func TestRabbitScheduler_TTLQueues(t *testing.T) {
+	broker := newFakeBroker()
+	mq := newTestRabbitMQ(t, broker, config.RabbitMQ{})
+	target := NewMemoryBroker(MemoryBrokerOptions{})
+	s := NewRabbitScheduler(mq, target, RabbitSchedulerOptions{Redis: newTestRedis(t)})
+	startScheduler(t, s)
+	ctx := context.Background()
+
+	keep, _ := NewMessage("order.cancel", "A1")
+	drop, _ := NewMessage("order.cancel", "A2")
+	require.NoError(t, s.Schedule(ctx, "orders", time.Now().Add(300*time.Millisecond), keep))
+	require.NoError(t, s.Schedule(ctx, "orders", time.Now().Add(200*time.Millisecond), drop))
+
+	// 不足 1s 的延迟进入最短的 TTL 队列
+	assert.True(t, broker.hasQueue("scheduler.delay.1s"))
+	pending, err := s.Pending(ctx, "orders")
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, drop.ID, pending[0].Message.ID)
+
+	ok, err := s.Cancel(ctx, drop.ID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.Eventually(t, func() bool { return target.Pending("orders") == 1 }, 3*time.Second, 20*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 1, target.Pending("orders"))
+
+	pending, err = s.Pending(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestRabbitScheduler_DelayBuckets(t *testing.T) {
+	broker := newFakeBroker()
+	mq := newTestRabbitMQ(t, broker, config.RabbitMQ{})
+	s := NewRabbitScheduler(mq, NewMemoryBroker(MemoryBrokerOptions{}), RabbitSchedulerOptions{})
+	ctx := context.Background()
+
+	// 任意延迟都只使用固定的一组 TTL 队列
+	for i := 0; i < 200; i++ {
+		msg, _ := NewMessage("order.cancel", i)
+		require.NoError(t, s.Schedule(ctx, "orders", time.Now().Add(time.Duration(i)*7919*time.Second+time.Duration(i)*time.Millisecond), msg))
+	}
+	var delayQueues []string
+	broker.mu.Lock()
+	for name := range broker.queues {
+		if strings.HasPrefix(name, "scheduler.delay.") {
+			delayQueues = append(delayQueues, name)
+		}
+	}
+	broker.mu.Unlock()
+	assert.LessOrEqual(t, len(delayQueues), 18)
+	assert.True(t, broker.hasQueue("scheduler.delay.36h24m32s"))
+
+	assert.Equal(t, time.Second, delayBucket(300*time.Millisecond))
+	assert.Equal(t, 2*time.Second, delayBucket(3500*time.Millisecond))
+	assert.Equal(t, 64*time.Second, delayBucket(100*time.Second))
+	assert.Equal(t, maxDelayBucket, delayBucket(30*24*time.Hour))
+}
+
+func TestRabbitScheduler_CascadesDelay(t *testing.T) {
+	broker := newFakeBroker()
+	mq := newTestRabbitMQ(t, broker, config.RabbitMQ{})
+	target := NewMemoryBroker(MemoryBrokerOptions{})
+	s := NewRabbitScheduler(mq, target, RabbitSchedulerOptions{})
+	startScheduler(t, s)
+	ctx := context.Background()
+
+	// 1.2s 的延迟先经过 1s 队列，剩余部分再进入 1s 队列
+	msg, _ := NewMessage("order.cancel", "A1")
+	start := time.Now()
+	require.NoError(t, s.Schedule(ctx, "orders", start.Add(1200*time.Millisecond), msg))
+
+	time.Sleep(1100 * time.Millisecond)
+	assert.Equal(t, 0, target.Pending("orders"))
+	require.Eventually(t, func() bool { return target.Pending("orders") == 1 }, 3*time.Second, 20*time.Millisecond)
+	assert.GreaterOrEqual(t, time.Since(start), 1200*time.Millisecond)
+}
+
+func TestRabbitScheduler_RescheduleToPast(t *testing.T) {
+	broker := newFakeBroker()
+	mq := newTestRabbitMQ(t, broker, config.RabbitMQ{})
+	target := NewMemoryBroker(MemoryBrokerOptions{})
+	s := NewRabbitScheduler(mq, target, RabbitSchedulerOptions{})
+	startScheduler(t, s)
+	ctx := context.Background()
+
+	msg, _ := NewMessage("order.cancel", "A1")
+	require.NoError(t, s.Schedule(ctx, "orders", time.Now().Add(300*time.Millisecond), msg))
+	require.NoError(t, s.Schedule(ctx, "orders", time.Now().Add(-time.Second), msg))
+	assert.Equal(t, 1, target.Pending("orders"))
+
+	pending, err := s.Pending(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	// 延迟队列中的副本到期后被丢弃
+	time.Sleep(1200 * time.Millisecond)
+	assert.Equal(t, 1, target.Pending("orders"))
+}
+
+func TestRabbitScheduler_RestartWithoutRedis(t *testing.T) {
+	broker := newFakeBroker()
+	ctx := context.Background()
+
+	// 第一个实例计划后退出，延迟队列中的消息仍在，进程内记录已丢失
+	old := NewRabbitScheduler(newTestRabbitMQ(t, broker, config.RabbitMQ{}), NewMemoryBroker(MemoryBrokerOptions{}), RabbitSchedulerOptions{})
+	msg, _ := NewMessage("order.cancel", "A1")
+	msg.SetHeader("tenant", "t1")
+	require.NoError(t, old.Schedule(ctx, "orders", time.Now().Add(300*time.Millisecond), msg))
+
+	var mu sync.Mutex
+	var got []*Message
+	pub := &funcPublisher{fn: func(topic string, m *Message) error {
+		assert.Equal(t, "orders", topic)
+		mu.Lock()
+		got = append(got, m)
+		mu.Unlock()
+		return nil
+	}}
+	s := NewRabbitScheduler(newTestRabbitMQ(t, broker, config.RabbitMQ{}), pub, RabbitSchedulerOptions{})
+	startScheduler(t, s)
+
+	// 新实例按消息头发布没有记录的到期消息
+	require.Eventually(t, func() bool { return len(pub.published()) == 1 }, 3*time.Second, 20*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, msg.ID, got[0].ID)
+	assert.Equal(t, msg.Type, got[0].Type)
+	assert.JSONEq(t, `"A1"`, string(got[0].Payload))
+	assert.Equal(t, map[string]string{"tenant": "t1"}, got[0].Headers)
+}
+
+func TestRabbitScheduler_Plugin(t *testing.T) {
+	broker := newFakeBroker()
+	mq := newTestRabbitMQ(t, broker, config.RabbitMQ{})
+	s := NewRabbitScheduler(mq, NewMemoryBroker(MemoryBrokerOptions{}), RabbitSchedulerOptions{Name: "delays", Plugin: true})
+	ctx := context.Background()
+
+	msg, _ := NewMessage("order.cancel", "A1")
+	require.NoError(t, s.Schedule(ctx, "orders", time.Now().Add(time.Minute), msg))
+
+	assert.Equal(t, "x-delayed-message", broker.exchanges["delays.delayed"].kind)
+	assert.Contains(t, broker.bindings, fakeBinding{exchange: "delays.delayed", queue: "delays.due", key: "delays.due"})
+
+	msgs := broker.messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "delays.delayed", msgs[0].Exchange)
+	assert.Equal(t, msg.ID, msgs[0].Msg.MessageId)
+	assert.InDelta(t, time.Minute.Milliseconds(), msgs[0].Msg.Headers["x-delay"], 1000)
+	assert.Equal(t, "orders", msgs[0].Msg.Headers[HeaderScheduledTopic])
+}
+
+// funcPublisher 记录发布的消息 ID，fn 返回错误时发布失败
+type funcPublisher struct {
+	fn func(topic string, msg *Message) error
+
+	mu  sync.Mutex
+	ids []string
+}
+
+func (p *funcPublisher) Publish(ctx context.Context, topic string, msgs ...*Message) error {
+	for _, m := range msgs {
+		if p.fn != nil {
+			if err := p.fn(topic, m); err != nil {
+				return err
+			}
+		}
+		p.mu.Lock()
+		p.ids = append(p.ids, m.ID)
+		p.mu.Unlock()
+	}
+	return nil
+}
+
+func (p *funcPublisher) published() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.ids...)
+}
@@ -0,0 +1,303 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"project/pkg/config"
+	"project/pkg/logger"
+
+	"github.com/streadway/amqp"
+)
+
+// TopologyDrift 配置与 broker 现有状态的差异
+type TopologyDrift struct {
+	// Kind exchange / queue / binding
+	Kind string
+	// Name 交换机或队列名，绑定为 exchange -> queue (key)
+	Name string
+	// Problem missing 表示不存在，mismatch 表示已存在但属性或参数不一致
+	Problem string
+	// Detail broker 返回的原因
+	Detail string
+}
+
+func (d TopologyDrift) String() string {
+	if d.Detail == "" {
+		return fmt.Sprintf("%s %s: %s", d.Kind, d.Name, d.Problem)
+	}
+	return fmt.Sprintf("%s %s: %s (%s)", d.Kind, d.Name, d.Problem, d.Detail)
+}
+
+// DeclareTopology 幂等声明配置中的交换机、队列与绑定
+//
+// 已存在的同名实体属性不一致时 broker 返回 406 PRECONDITION_FAILED，需要人工删除后重建。
+func (r *RabbitMQ) DeclareTopology(ctx context.Context, t *config.Topology) error {
+	conn, err := r.waitConnection(ctx)
+	if err != nil {
+		return err
+	}
+	ch, err := r.openChannel(conn)
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	for _, e := range t.Exchanges {
+		args, err := amqpTable(e.Arguments)
+		if err != nil {
+			return fmt.Errorf("rabbitmq: exchange %s: %w", e.Name, err)
+		}
+		if err := ch.ExchangeDeclare(e.Name, e.Type, e.Durable, e.AutoDelete, e.Internal, false, args); err != nil {
+			return fmt.Errorf("rabbitmq: declare exchange %s: %w", e.Name, err)
+		}
+	}
+	for _, q := range t.Queues {
+		args, err := queueArgs(q)
+		if err != nil {
+			return fmt.Errorf("rabbitmq: queue %s: %w", q.Name, err)
+		}
+		if _, err := ch.QueueDeclare(q.Name, q.Durable, q.AutoDelete, q.Exclusive, false, args); err != nil {
+			return fmt.Errorf("rabbitmq: declare queue %s: %w", q.Name, err)
+		}
+	}
+	for _, b := range t.Bindings {
+		args, err := amqpTable(b.Arguments)
+		if err != nil {
+			return fmt.Errorf("rabbitmq: binding %s: %w", bindingName(b), err)
+		}
+		if err := ch.QueueBind(b.Queue, b.RoutingKey, b.Exchange, false, args); err != nil {
+			return fmt.Errorf("rabbitmq: bind %s: %w", bindingName(b), err)
+		}
+	}
+	return nil
+}
+
+// CheckTopology 检查配置与 broker 现有状态的差异，不创建任何实体
+//
+// 先被动声明判断是否存在，存在时用相同参数重新声明（对一致的实体无副作用），
+// broker 返回 406 即为属性不一致。AMQP 无法查询绑定，只检查绑定两端是否存在。
+func (r *RabbitMQ) CheckTopology(ctx context.Context, t *config.Topology) ([]TopologyDrift, error) {
+	conn, err := r.waitConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []TopologyDrift
+	exists := map[string]bool{}
+
+	for _, e := range t.Exchanges {
+		args, err := amqpTable(e.Arguments)
+		if err != nil {
+			return nil, fmt.Errorf("rabbitmq: exchange %s: %w", e.Name, err)
+		}
+		drift, err := r.check(conn, "exchange", e.Name,
+			func(ch amqpChannel) error {
+				return ch.ExchangeDeclarePassive(e.Name, e.Type, e.Durable, e.AutoDelete, e.Internal, false, nil)
+			},
+			func(ch amqpChannel) error {
+				return ch.ExchangeDeclare(e.Name, e.Type, e.Durable, e.AutoDelete, e.Internal, false, args)
+			})
+		if err != nil {
+			return nil, err
+		}
+		if drift != nil {
+			drifts = append(drifts, *drift)
+		}
+		exists["exchange:"+e.Name] = drift == nil || drift.Problem != "missing"
+	}
+
+	for _, q := range t.Queues {
+		args, err := queueArgs(q)
+		if err != nil {
+			return nil, fmt.Errorf("rabbitmq: queue %s: %w", q.Name, err)
+		}
+		drift, err := r.check(conn, "queue", q.Name,
+			func(ch amqpChannel) error {
+				_, err := ch.QueueDeclarePassive(q.Name, q.Durable, q.AutoDelete, q.Exclusive, false, nil)
+				return err
+			},
+			func(ch amqpChannel) error {
+				_, err := ch.QueueDeclare(q.Name, q.Durable, q.AutoDelete, q.Exclusive, false, args)
+				return err
+			})
+		if err != nil {
+			return nil, err
+		}
+		if drift != nil {
+			drifts = append(drifts, *drift)
+		}
+		exists["queue:"+q.Name] = drift == nil || drift.Problem != "missing"
+	}
+
+	for _, b := range t.Bindings {
+		for _, end := range []struct{ kind, name string }{{"exchange", b.Exchange}, {"queue", b.Queue}} {
+			ok, known := exists[end.kind+":"+end.name]
+			if !known {
+				// 未在配置中声明的交换机或队列（如 amq.topic），单独检查
+				drift, err := r.check(conn, end.kind, end.name, func(ch amqpChannel) error {
+					if end.kind == "exchange" {
+						return ch.ExchangeDeclarePassive(end.name, "", false, false, false, false, nil)
+					}
+					_, err := ch.QueueDeclarePassive(end.name, false, false, false, false, nil)
+					return err
+				}, nil)
+				if err != nil {
+					return nil, err
+				}
+				ok = drift == nil
+				exists[end.kind+":"+end.name] = ok
+			}
+			if !ok {
+				drifts = append(drifts, TopologyDrift{
+					Kind:    "binding",
+					Name:    bindingName(b),
+					Problem: "missing",
+					Detail:  fmt.Sprintf("%s %s does not exist", end.kind, end.name),
+				})
+				break
+			}
+		}
+	}
+	return drifts, nil
+}
+
+// check 在独立 channel 上检查一个实体；通道异常会关闭 channel，因此每步都使用新 channel
+func (r *RabbitMQ) check(conn amqpConnection, kind, name string, passive, redeclare func(ch amqpChannel) error) (*TopologyDrift, error) {
+	for i, step := range []func(ch amqpChannel) error{passive, redeclare} {
+		if step == nil {
+			continue
+		}
+		ch, err := r.openChannel(conn)
+		if err != nil {
+			return nil, err
+		}
+		err = step(ch)
+		_ = ch.Close()
+
+		var amqpErr *amqp.Error
+		switch {
+		case err == nil:
+			continue
+		case errors.As(err, &amqpErr) && amqpErr.Code == amqp.NotFound && i == 0:
+			return &TopologyDrift{Kind: kind, Name: name, Problem: "missing"}, nil
+		case errors.As(err, &amqpErr) && amqpErr.Code == amqp.PreconditionFailed:
+			return &TopologyDrift{Kind: kind, Name: name, Problem: "mismatch", Detail: amqpErr.Reason}, nil
+		default:
+			return nil, fmt.Errorf("rabbitmq: check %s %s: %w", kind, name, err)
+		}
+	}
+	return nil, nil
+}
+
+// applyTopology 初始化或重连后处理配置中的拓扑
+func (r *RabbitMQ) applyTopology(dryRunAllowed bool) error {
+	t := r.topology
+	if t == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r.publishTimeout)
+	defer cancel()
+
+	if !t.DryRun {
+		return r.DeclareTopology(ctx, t)
+	}
+	if !dryRunAllowed {
+		return nil
+	}
+
+	drifts, err := r.CheckTopology(ctx, t)
+	if err != nil {
+		return err
+	}
+	for _, d := range drifts {
+		logger.Named("rabbitmq").Warnf("\t[rabbitmq] topology drift: %s", d)
+	}
+	if len(drifts) == 0 {
+		logger.Named("rabbitmq").Infof("\t[rabbitmq] topology matches broker state")
+	}
+	return nil
+}
+
+// queueArgs 把队列配置转换为声明参数，Arguments 中的同名项优先
+func queueArgs(q config.Queue) (amqp.Table, error) {
+	args := amqp.Table{}
+	if q.Quorum {
+		args["x-queue-type"] = "quorum"
+	}
+	if q.DeadLetterExchange != "" || q.DeadLetterRoutingKey != "" {
+		args["x-dead-letter-exchange"] = q.DeadLetterExchange
+	}
+	if q.DeadLetterRoutingKey != "" {
+		args["x-dead-letter-routing-key"] = q.DeadLetterRoutingKey
+	}
+	if q.MessageTTL > 0 {
+		args["x-message-ttl"] = int64(q.MessageTTL / time.Millisecond)
+	}
+	if q.MaxLength > 0 {
+		args["x-max-length"] = q.MaxLength
+	}
+	if q.MaxLengthBytes > 0 {
+		args["x-max-length-bytes"] = q.MaxLengthBytes
+	}
+	if q.Overflow != "" {
+		args["x-overflow"] = q.Overflow
+	}
+
+	extra, err := amqpTable(q.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range extra {
+		args[k] = v
+	}
+	if len(args) == 0 {
+		return nil, nil
+	}
+	return args, nil
+}
+
+// amqpTable 把配置中的参数转换为 amqp.Table（嵌套 map 转为 Table）
+func amqpTable(m map[string]interface{}) (amqp.Table, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	t := make(amqp.Table, len(m))
+	for k, v := range m {
+		t[k] = amqpValue(v)
+	}
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func amqpValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		t := make(amqp.Table, len(x))
+		for k, item := range x {
+			t[k] = amqpValue(item)
+		}
+		return t
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, item := range x {
+			out[i] = amqpValue(item)
+		}
+		return out
+	case int:
+		return int64(x)
+	case uint:
+		return int64(x)
+	case uint32:
+		return int64(x)
+	}
+	return v
+}
+
+func bindingName(b config.Binding) string {
+	return fmt.Sprintf("%s -> %s (%s)", b.Exchange, b.Queue, b.RoutingKey)
+}
@@ -0,0 +1,178 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"project/pkg/config"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTopology() *config.Topology {
+	return &config.Topology{
+		Exchanges: []config.Exchange{
+			{Name: "orders", Type: "topic", Durable: true},
+		},
+		Queues: []config.Queue{
+			{
+				Name:                 "orders.created",
+				Durable:              true,
+				Quorum:               true,
+				DeadLetterRoutingKey: "orders.created.dead",
+				MessageTTL:           time.Minute,
+				MaxLength:            1000,
+			},
+			{Name: "orders.created.dead", Durable: true},
+		},
+		Bindings: []config.Binding{
+			{Exchange: "orders", Queue: "orders.created", RoutingKey: "order.created"},
+		},
+	}
+}
+
+func TestInitComponent_DeclaresTopology(t *testing.T) {
+	broker := newFakeBroker()
+	mq := newTestRabbitMQ(t, broker, config.RabbitMQ{Topology: testTopology()})
+
+	assert.Equal(t, fakeExchange{kind: "topic", durable: true}, broker.exchanges["orders"])
+	q := broker.queues["orders.created"]
+	require.NotNil(t, q)
+	assert.True(t, q.durable)
+	assert.Equal(t, amqp.Table{
+		"x-queue-type":              "quorum",
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": "orders.created.dead",
+		"x-message-ttl":             int64(60000),
+		"x-max-length":              int64(1000),
+	}, q.args)
+	assert.Equal(t, []fakeBinding{{exchange: "orders", queue: "orders.created", key: "order.created"}}, broker.bindings)
+
+	// 重复声明是幂等的
+	require.NoError(t, mq.DeclareTopology(context.Background(), testTopology()))
+	assert.Len(t, broker.bindings, 1)
+	// 声明用的 channel 已关闭
+	assert.Eventually(t, func() bool { return mq.ChannelCount() == 0 }, time.Second, time.Millisecond)
+}
+
+func TestInitComponent_TopologyConflictFails(t *testing.T) {
+	broker := newFakeBroker()
+	broker.exchanges["orders"] = fakeExchange{kind: "direct", durable: true}
+
+	r := NewRabbitMQ(&config.RabbitMQ{URL: "amqp://fake", Topology: testTopology()})
+	r.dial = broker.dial
+	assert.False(t, r.InitComponent())
+	assert.False(t, r.IsInitialize())
+}
+
+func TestInitComponent_RedeclaresAfterReconnect(t *testing.T) {
+	broker := newFakeBroker()
+	newTestRabbitMQ(t, broker, config.RabbitMQ{Topology: testTopology()})
+
+	// 模拟 broker 重启丢失了全部实体
+	broker.set(func(b *fakeBroker) {
+		b.exchanges = make(map[string]fakeExchange)
+		b.queues = make(map[string]*fakeQueue)
+		b.bindings = nil
+	})
+	broker.drop()
+
+	require.Eventually(t, func() bool {
+		return broker.hasQueue("orders.created") && broker.hasQueue("orders.created.dead")
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestCheckTopology_ReportsDrift(t *testing.T) {
+	broker := newFakeBroker()
+	mq := newTestRabbitMQ(t, broker, config.RabbitMQ{})
+	ctx := context.Background()
+
+	drifts, err := mq.CheckTopology(ctx, testTopology())
+	require.NoError(t, err)
+	assert.Equal(t, []TopologyDrift{
+		{Kind: "exchange", Name: "orders", Problem: "missing"},
+		{Kind: "queue", Name: "orders.created", Problem: "missing"},
+		{Kind: "queue", Name: "orders.created.dead", Problem: "missing"},
+		{Kind: "binding", Name: "orders -> orders.created (order.created)", Problem: "missing", Detail: "exchange orders does not exist"},
+	}, drifts)
+	// dry-run 不创建任何实体
+	assert.Empty(t, broker.exchanges)
+	assert.False(t, broker.hasQueue("orders.created"))
+
+	require.NoError(t, mq.DeclareTopology(ctx, testTopology()))
+	drifts, err = mq.CheckTopology(ctx, testTopology())
+	require.NoError(t, err)
+	assert.Empty(t, drifts)
+
+	changed := testTopology()
+	changed.Queues[0].MaxLength = 5000
+	drifts, err = mq.CheckTopology(ctx, changed)
+	require.NoError(t, err)
+	require.Len(t, drifts, 1)
+	assert.Equal(t, "mismatch", drifts[0].Problem)
+	assert.Equal(t, "orders.created", drifts[0].Name)
+	assert.Contains(t, drifts[0].String(), "PRECONDITION_FAILED")
+	// 不一致的队列保持原参数
+	assert.Equal(t, int64(1000), broker.queues["orders.created"].args["x-max-length"])
+}
+
+func TestCheckTopology_ExternalBindingTarget(t *testing.T) {
+	broker := newFakeBroker()
+	broker.exchanges["amq.topic"] = fakeExchange{kind: "topic", durable: true}
+	mq := newTestRabbitMQ(t, broker, config.RabbitMQ{})
+
+	topology := &config.Topology{
+		Queues: []config.Queue{{Name: "audit", Durable: true}},
+		Bindings: []config.Binding{
+			{Exchange: "amq.topic", Queue: "audit", RoutingKey: "#"},
+			{Exchange: "amq.unknown", Queue: "audit", RoutingKey: "#"},
+		},
+	}
+	require.NoError(t, mq.DeclareTopology(context.Background(), &config.Topology{Queues: topology.Queues}))
+
+	drifts, err := mq.CheckTopology(context.Background(), topology)
+	require.NoError(t, err)
+	require.Len(t, drifts, 1)
+	assert.Equal(t, "binding", drifts[0].Kind)
+	assert.Equal(t, "exchange amq.unknown does not exist", drifts[0].Detail)
+}
+
+func TestInitComponent_DryRunDoesNotDeclare(t *testing.T) {
+	broker := newFakeBroker()
+	topology := testTopology()
+	topology.DryRun = true
+	newTestRabbitMQ(t, broker, config.RabbitMQ{Topology: topology})
+
+	assert.Empty(t, broker.exchanges)
+	assert.False(t, broker.hasQueue("orders.created"))
+}
+
+func TestQueueArgs(t *testing.T) {
+	args, err := queueArgs(config.Queue{Name: "q"})
+	require.NoError(t, err)
+	assert.Nil(t, args)
+
+	args, err = queueArgs(config.Queue{
+		Name:           "q",
+		MaxLengthBytes: 1 << 20,
+		Overflow:       "reject-publish",
+		Arguments: map[string]interface{}{
+			"x-max-length-bytes": 2048,
+			"x-single-active":    true,
+			"x-nested":           map[string]interface{}{"a": 1},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, amqp.Table{
+		"x-max-length-bytes": int64(2048),
+		"x-overflow":         "reject-publish",
+		"x-single-active":    true,
+		"x-nested":           amqp.Table{"a": int64(1)},
+	}, args)
+
+	_, err = queueArgs(config.Queue{Name: "q", Arguments: map[string]interface{}{"x-bad": struct{}{}}})
+	assert.Error(t, err)
+}
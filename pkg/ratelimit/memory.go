@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// memoryEntry 单个键的限流状态
+type memoryEntry struct {
+	// 令牌桶
+	tokens float64
+	last   time.Time
+	// 滑动窗口
+	window     int64
+	prev, curr int64
+
+	seen time.Time
+}
+
+// MemoryLimiter 进程内限流器，多副本部署时每个副本单独计数。
+// 长时间没有请求的键（状态已完全恢复）在后续请求时按 sweepInterval 批量删除，不需要后台协程
+type MemoryLimiter struct {
+	rule Rule
+	now  func() time.Time
+
+	mu            sync.Mutex
+	entries       map[string]*memoryEntry
+	sweepInterval time.Duration
+	lastSweep     time.Time
+}
+
+// NewMemoryLimiter 创建进程内限流器，rule 需已通过 Validate
+func NewMemoryLimiter(rule Rule) *MemoryLimiter {
+	rule = rule.withDefaults()
+	sweep := rule.idle()
+	if sweep < time.Minute {
+		sweep = time.Minute
+	}
+	return &MemoryLimiter{
+		rule:          rule,
+		now:           time.Now,
+		entries:       make(map[string]*memoryEntry),
+		sweepInterval: sweep,
+		lastSweep:     time.Now(),
+	}
+}
+
+// Allow 消耗 key 的一次配额
+func (l *MemoryLimiter) Allow(_ context.Context, key string) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= l.sweepInterval {
+		l.sweep(now)
+	}
+	e, ok := l.entries[key]
+	if !ok {
+		e = &memoryEntry{tokens: float64(l.rule.Burst), last: now}
+		l.entries[key] = e
+	}
+	e.seen = now
+
+	if l.rule.Algorithm == SlidingWindow {
+		return l.slidingWindow(e, now), nil
+	}
+	return l.tokenBucket(e, now), nil
+}
+
+func (l *MemoryLimiter) tokenBucket(e *memoryEntry, now time.Time) Result {
+	if elapsed := now.Sub(e.last); elapsed > 0 {
+		e.tokens = math.Min(float64(l.rule.Burst), e.tokens+float64(elapsed)*float64(l.rule.Limit)/float64(l.rule.Period))
+		e.last = now
+	}
+	allowed := e.tokens >= 1
+	if allowed {
+		e.tokens--
+	}
+	return tokenBucketResult(l.rule, e.tokens, allowed)
+}
+
+func (l *MemoryLimiter) slidingWindow(e *memoryEntry, now time.Time) Result {
+	period := int64(l.rule.Period)
+	window := now.UnixNano() / period
+	switch {
+	case window == e.window+1:
+		e.prev, e.curr = e.curr, 0
+	case window != e.window:
+		e.prev, e.curr = 0, 0
+	}
+	e.window = window
+
+	elapsed := time.Duration(now.UnixNano() - window*period)
+	allowed := slidingWindowAllowed(l.rule, e.prev, e.curr, elapsed)
+	if allowed {
+		e.curr++
+	}
+	return slidingWindowResult(l.rule, e.prev, e.curr, elapsed, allowed)
+}
+
+// sweep 删除空闲超过 rule.idle() 的键
+func (l *MemoryLimiter) sweep(now time.Time) {
+	idle := l.rule.idle()
+	for key, e := range l.entries {
+		if now.Sub(e.seen) > idle {
+			delete(l.entries, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// Len 返回当前保存的键数
+func (l *MemoryLimiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.entries)
+}
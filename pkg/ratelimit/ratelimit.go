@@ -0,0 +1,159 @@
+// Package ratelimit 按键限流，支持令牌桶与滑动窗口两种算法，提供进程内与基于 Redis 的集群级实现：
+//
+//	limiter := ratelimit.NewMemoryLimiter(ratelimit.Rule{Limit: 100, Period: time.Minute})
+//	res, err := limiter.Allow(ctx, "ip:"+c.ClientIP())
+//	if err == nil && !res.Allowed {
+//		// 返回 429，res.RetryAfter 后重试
+//	}
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// 限流算法
+const (
+	// TokenBucket 令牌桶：按 Limit/Period 的速率补充令牌，允许 Burst 个请求的突发
+	TokenBucket = "token_bucket"
+	// SlidingWindow 滑动窗口：按当前与上一个固定窗口的计数加权估算最近 Period 内的请求数
+	SlidingWindow = "sliding_window"
+)
+
+// Algorithms 支持的算法
+var Algorithms = []string{TokenBucket, SlidingWindow}
+
+// Rule 限流规则
+type Rule struct {
+	// Algorithm 算法，默认 token_bucket
+	Algorithm string
+	// Limit 每个 Period 允许的请求数
+	Limit int
+	// Period 周期，默认 1s
+	Period time.Duration
+	// Burst 令牌桶容量，默认同 Limit；滑动窗口忽略
+	Burst int
+}
+
+// Validate 校验限流规则
+func (r *Rule) Validate() error {
+	if r.Algorithm != "" && r.Algorithm != TokenBucket && r.Algorithm != SlidingWindow {
+		return fmt.Errorf("invalid rate limit algorithm: %s (must be %s/%s)", r.Algorithm, TokenBucket, SlidingWindow)
+	}
+	if r.Limit <= 0 {
+		return fmt.Errorf("rate limit must be > 0")
+	}
+	if r.Period != 0 && r.Period < time.Millisecond {
+		return fmt.Errorf("rate limit period must be >= 1ms")
+	}
+	if r.Burst < 0 {
+		return fmt.Errorf("rate limit burst must be >= 0")
+	}
+	return nil
+}
+
+func (r Rule) withDefaults() Rule {
+	if r.Algorithm == "" {
+		r.Algorithm = TokenBucket
+	}
+	if r.Period <= 0 {
+		r.Period = time.Second
+	}
+	if r.Burst <= 0 {
+		r.Burst = r.Limit
+	}
+	return r
+}
+
+// idle 键在该时长内没有请求时状态已完全恢复，可以删除
+func (r Rule) idle() time.Duration {
+	if r.Algorithm == TokenBucket {
+		return time.Duration(float64(r.Period) * float64(r.Burst) / float64(r.Limit))
+	}
+	return 2 * r.Period
+}
+
+// Result 限流结果
+type Result struct {
+	// Allowed 是否放行
+	Allowed bool
+	// Limit 配额
+	Limit int
+	// Remaining 剩余配额
+	Remaining int
+	// ResetAfter 配额完全恢复（令牌桶）或当前窗口结束（滑动窗口）的时间
+	ResetAfter time.Duration
+	// RetryAfter 被拒绝时到下一次可能放行的等待时间，放行时为 0
+	RetryAfter time.Duration
+}
+
+// Limiter 限流器
+type Limiter interface {
+	// Allow 消耗 key 的一次配额；返回错误时（如 Redis 不可用）由调用方决定放行或拒绝
+	Allow(ctx context.Context, key string) (Result, error)
+}
+
+// tokenBucketResult 由扣减后的令牌数计算结果
+func tokenBucketResult(r Rule, tokens float64, allowed bool) Result {
+	perToken := float64(r.Period) / float64(r.Limit)
+	res := Result{
+		Allowed:    allowed,
+		Limit:      r.Burst,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: time.Duration((float64(r.Burst) - tokens) * perToken),
+	}
+	if !allowed {
+		res.RetryAfter = ceilMillisecond(time.Duration((1 - tokens) * perToken))
+	}
+	return res
+}
+
+// slidingWindowResult 由上一个与当前窗口的计数（当前窗口已计入本次放行的请求）计算结果，elapsed 为当前窗口已经过的时间
+func slidingWindowResult(r Rule, prev, curr int64, elapsed time.Duration, allowed bool) Result {
+	weight := 1 - float64(elapsed)/float64(r.Period)
+	estimated := float64(prev)*weight + float64(curr)
+	res := Result{
+		Allowed:    allowed,
+		Limit:      r.Limit,
+		Remaining:  int(math.Max(0, math.Floor(float64(r.Limit)-estimated))),
+		ResetAfter: r.Period - elapsed,
+	}
+	if allowed {
+		return res
+	}
+
+	// 放行需要 prev*(1-t/Period) + curr <= Limit-1，t 为从当前窗口开始经过的时间
+	need := float64(r.Limit - 1)
+	if float64(curr) <= need && prev > 0 {
+		// 上一个窗口的权重下降后即可放行
+		t := time.Duration(float64(r.Period) * (1 - (need-float64(curr))/float64(prev)))
+		res.RetryAfter = t - elapsed
+	} else {
+		// 需等到下一个窗口，届时当前窗口的计数成为上一个窗口的计数
+		t := time.Duration(float64(r.Period) * (1 - need/float64(curr)))
+		res.RetryAfter = r.Period - elapsed + t
+	}
+	res.RetryAfter = ceilMillisecond(res.RetryAfter)
+	return res
+}
+
+// ceilMillisecond 向上取整到毫秒，消除浮点误差且至少为 1ms
+func ceilMillisecond(d time.Duration) time.Duration {
+	// 浮点误差在纳秒级，先去掉再取整
+	d = d.Round(time.Microsecond)
+	if r := d % time.Millisecond; r != 0 {
+		d += time.Millisecond - r
+	}
+	if d < time.Millisecond {
+		d = time.Millisecond
+	}
+	return d
+}
+
+// slidingWindowAllowed 加上本次请求后估算值不超过 Limit 时放行
+func slidingWindowAllowed(r Rule, prev, curr int64, elapsed time.Duration) bool {
+	weight := 1 - float64(elapsed)/float64(r.Period)
+	return float64(prev)*weight+float64(curr)+1 <= float64(r.Limit)
+}
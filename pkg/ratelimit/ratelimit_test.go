@@ -0,0 +1,178 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clock 测试中手动推进的时钟
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+func newClock() *clock                   { return &clock{t: time.UnixMilli(1_700_000_000_000)} }
+
+// testLimiters 各实现使用相同的用例
+func testLimiters(t *testing.T, rule Rule, c *clock) map[string]Limiter {
+	mem := NewMemoryLimiter(rule)
+	mem.now = c.now
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	rl := NewRedisLimiter(client, "test:", rule)
+	rl.now = c.now
+
+	return map[string]Limiter{"memory": mem, "redis": rl}
+}
+
+func allowN(t *testing.T, l Limiter, key string, n int) []Result {
+	t.Helper()
+	out := make([]Result, n)
+	for i := range out {
+		res, err := l.Allow(context.Background(), key)
+		require.NoError(t, err)
+		out[i] = res
+	}
+	return out
+}
+
+func TestRule_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr string
+	}{
+		{name: "defaults", rule: Rule{Limit: 10}},
+		{name: "sliding window", rule: Rule{Algorithm: SlidingWindow, Limit: 10, Period: time.Minute}},
+		{name: "unknown algorithm", rule: Rule{Algorithm: "leaky", Limit: 10}, wantErr: "invalid rate limit algorithm"},
+		{name: "zero limit", rule: Rule{}, wantErr: "must be > 0"},
+		{name: "sub-millisecond period", rule: Rule{Limit: 1, Period: time.Microsecond}, wantErr: ">= 1ms"},
+		{name: "negative burst", rule: Rule{Limit: 1, Burst: -1}, wantErr: "burst must be >= 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	c := newClock()
+	// 每秒 2 个，允许 4 个突发
+	for name, l := range testLimiters(t, Rule{Limit: 2, Period: time.Second, Burst: 4}, c) {
+		t.Run(name, func(t *testing.T) {
+			res := allowN(t, l, "a", 5)
+			for i := 0; i < 4; i++ {
+				assert.True(t, res[i].Allowed, "request %d", i)
+				assert.Equal(t, 4, res[i].Limit)
+				assert.Equal(t, 3-i, res[i].Remaining)
+			}
+			denied := res[4]
+			assert.False(t, denied.Allowed)
+			assert.Equal(t, 0, denied.Remaining)
+			assert.Equal(t, 500*time.Millisecond, denied.RetryAfter)
+			assert.Equal(t, 2*time.Second, denied.ResetAfter)
+
+			// 其他键不受影响
+			assert.True(t, allowN(t, l, "b", 1)[0].Allowed)
+
+			// 500ms 补充一个令牌
+			c.advance(500 * time.Millisecond)
+			res = allowN(t, l, "a", 2)
+			assert.True(t, res[0].Allowed)
+			assert.False(t, res[1].Allowed)
+
+			// 2s 后补满，但不超过容量
+			c.advance(10 * time.Second)
+			res = allowN(t, l, "a", 5)
+			assert.Equal(t, []bool{true, true, true, true, false},
+				[]bool{res[0].Allowed, res[1].Allowed, res[2].Allowed, res[3].Allowed, res[4].Allowed})
+		})
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	c := newClock()
+	// 对齐到窗口开始
+	c.t = c.t.Truncate(time.Minute)
+	for name, l := range testLimiters(t, Rule{Algorithm: SlidingWindow, Limit: 10, Period: time.Minute}, c) {
+		t.Run(name, func(t *testing.T) {
+			start := c.t
+			res := allowN(t, l, "a", 11)
+			for i := 0; i < 10; i++ {
+				assert.True(t, res[i].Allowed, "request %d", i)
+				assert.Equal(t, 9-i, res[i].Remaining)
+			}
+			denied := res[10]
+			assert.False(t, denied.Allowed)
+			assert.Equal(t, 10, denied.Limit)
+			assert.Equal(t, time.Minute, denied.ResetAfter)
+			// 下一个窗口经过 6s 时上一个窗口权重降到 0.9，估算值 9
+			assert.Equal(t, time.Minute+6*time.Second, denied.RetryAfter)
+
+			// 下一个窗口开始时上一个窗口权重为 1，仍被拒绝
+			c.t = start.Add(time.Minute)
+			assert.False(t, allowN(t, l, "a", 1)[0].Allowed)
+
+			// 经过半个窗口后，估算值 5，可再放行 5 个
+			c.t = start.Add(time.Minute + 30*time.Second)
+			res = allowN(t, l, "a", 6)
+			for i := 0; i < 5; i++ {
+				assert.True(t, res[i].Allowed, "request %d", i)
+			}
+			assert.False(t, res[5].Allowed)
+			assert.Equal(t, 30*time.Second, res[5].ResetAfter)
+			assert.Equal(t, 6*time.Second, res[5].RetryAfter)
+
+			// 两个窗口之后完全恢复
+			c.t = start.Add(3 * time.Minute)
+			assert.Equal(t, 9, allowN(t, l, "a", 1)[0].Remaining)
+			c.t = start
+		})
+	}
+}
+
+func TestMemoryLimiter_EvictsIdleKeys(t *testing.T) {
+	c := newClock()
+	l := NewMemoryLimiter(Rule{Limit: 10, Period: time.Second})
+	l.now = c.now
+	l.lastSweep = c.t
+
+	for _, key := range []string{"a", "b", "c"} {
+		allowN(t, l, key, 1)
+	}
+	assert.Equal(t, 3, l.Len())
+
+	// 未到清理间隔时不清理
+	c.advance(30 * time.Second)
+	allowN(t, l, "a", 1)
+	assert.Equal(t, 3, l.Len())
+
+	// 到达清理间隔，删除已完全恢复的键
+	c.advance(31 * time.Second)
+	allowN(t, l, "d", 1)
+	assert.Equal(t, 1, l.Len())
+}
+
+func TestRedisLimiter_Error(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	l := NewRedisLimiter(client, DefaultKeyPrefix, Rule{Limit: 1})
+
+	mr.Close()
+	_, err := l.Allow(context.Background(), "a")
+	assert.ErrorContains(t, err, "ratelimit:")
+}
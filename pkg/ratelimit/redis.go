@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// DefaultKeyPrefix Redis 中限流状态的默认键前缀
+const DefaultKeyPrefix = "ratelimit:"
+
+var (
+	// 令牌桶：补充令牌后扣减，返回 {是否放行, 剩余令牌数}；令牌数为小数，以字符串返回避免被截断
+	tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local per_ms = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil then
+	tokens = capacity
+	ts = now
+end
+if now > ts then
+	tokens = math.min(capacity, tokens + (now - ts) * per_ms)
+	ts = now
+end
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", ts)
+redis.call("PEXPIRE", KEYS[1], ttl)
+return {allowed, tostring(tokens)}`)
+
+	// 滑动窗口：KEYS[1] 当前窗口计数，KEYS[2] 上一个窗口计数；返回 {是否放行, 上一个窗口计数, 当前窗口计数}
+	slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local weight = tonumber(ARGV[2])
+local ttl = tonumber(ARGV[3])
+local curr = tonumber(redis.call("GET", KEYS[1]) or "0")
+local prev = tonumber(redis.call("GET", KEYS[2]) or "0")
+local allowed = 0
+if prev * weight + curr + 1 <= limit then
+	curr = redis.call("INCR", KEYS[1])
+	redis.call("PEXPIRE", KEYS[1], ttl)
+	allowed = 1
+end
+return {allowed, prev, curr}`)
+)
+
+// RedisLimiter 基于 Redis Lua 脚本的集群级限流器，所有副本共享计数。
+// 时间取本节点时钟，副本间的时钟偏差会使配额略有误差
+type RedisLimiter struct {
+	client redis.UniversalClient
+	prefix string
+	rule   Rule
+	now    func() time.Time
+}
+
+// NewRedisLimiter 创建 Redis 限流器，键为 prefix+key，rule 需已通过 Validate：
+//
+//	limiter := ratelimit.NewRedisLimiter(database.GetRedis().GetClient(), "ratelimit:login:", rule)
+func NewRedisLimiter(client redis.UniversalClient, prefix string, rule Rule) *RedisLimiter {
+	return &RedisLimiter{client: client, prefix: prefix, rule: rule.withDefaults(), now: time.Now}
+}
+
+// Allow 消耗 key 的一次配额
+func (l *RedisLimiter) Allow(ctx context.Context, key string) (Result, error) {
+	if l.rule.Algorithm == SlidingWindow {
+		return l.slidingWindow(ctx, key)
+	}
+	return l.tokenBucket(ctx, key)
+}
+
+func (l *RedisLimiter) tokenBucket(ctx context.Context, key string) (Result, error) {
+	perMs := float64(l.rule.Limit) / float64(l.rule.Period.Milliseconds())
+	ttl := l.rule.idle().Milliseconds() + 1000
+	vals, err := tokenBucketScript.Run(ctx, l.client, []string{l.prefix + key},
+		l.rule.Burst, strconv.FormatFloat(perMs, 'f', -1, 64), l.now().UnixMilli(), ttl).Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("ratelimit: %w", err)
+	}
+	if len(vals) != 2 {
+		return Result{}, fmt.Errorf("ratelimit: unexpected script result %v", vals)
+	}
+	allowed, _ := vals[0].(int64)
+	s, _ := vals[1].(string)
+	tokens, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("ratelimit: unexpected tokens %v", vals[1])
+	}
+	return tokenBucketResult(l.rule, tokens, allowed == 1), nil
+}
+
+func (l *RedisLimiter) slidingWindow(ctx context.Context, key string) (Result, error) {
+	period := l.rule.Period.Milliseconds()
+	now := l.now().UnixMilli()
+	window := now / period
+	elapsed := time.Duration(now-window*period) * time.Millisecond
+	weight := 1 - float64(elapsed)/float64(l.rule.Period)
+
+	// 两个窗口的键使用相同的 hash tag，集群模式下位于同一个 slot
+	base := l.prefix + "{" + key + "}:"
+	keys := []string{base + strconv.FormatInt(window, 10), base + strconv.FormatInt(window-1, 10)}
+	vals, err := slidingWindowScript.Run(ctx, l.client, keys,
+		l.rule.Limit, strconv.FormatFloat(weight, 'f', -1, 64), 2*period+1000).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("ratelimit: %w", err)
+	}
+	if len(vals) != 3 {
+		return Result{}, fmt.Errorf("ratelimit: unexpected script result %v", vals)
+	}
+	return slidingWindowResult(l.rule, vals[1], vals[2], elapsed, vals[0] == 1), nil
+}
@@ -0,0 +1,351 @@
+// Package redact 在写日志前脱敏敏感字段
+//
+// 字段按 struct tag 或字段名匹配：
+//
+//	type LoginRequest struct {
+//		Username string `json:"username"`
+//		Password string `json:"password" log:"redact"` // 输出 [REDACTED]
+//		Phone    string `json:"phone" log:"mask"`      // 输出 13*******78
+//	}
+//
+// 未加 tag 的字段（以及 map 的 key）名称包含 Keys 中任一模式（不区分大小写）时同样替换为 [REDACTED]。
+package redact
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Placeholder 脱敏后的值
+const Placeholder = "[REDACTED]"
+
+// struct tag 取值
+const (
+	TagName   = "log"
+	TagRedact = "redact"
+	TagMask   = "mask"
+)
+
+// DefaultKeys 默认的敏感字段名模式
+var DefaultKeys = []string{"password", "passwd", "token", "secret", "authorization", "cookie", "api_key"}
+
+var (
+	mu   sync.RWMutex
+	keys = normalizeKeys(DefaultKeys)
+)
+
+// SetKeys 替换敏感字段名模式，为空时恢复 DefaultKeys
+func SetKeys(patterns ...string) {
+	if len(patterns) == 0 {
+		patterns = DefaultKeys
+	}
+	normalized := normalizeKeys(patterns)
+	mu.Lock()
+	keys = normalized
+	mu.Unlock()
+}
+
+// IsSensitiveKey 字段名包含任一敏感模式时返回 true，比较时忽略大小写、- 和 _
+func IsSensitiveKey(key string) bool {
+	k := normalize(key)
+	if k == "" {
+		return false
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, p := range keys {
+		if strings.Contains(k, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Mask 保留首尾各 1/4（最多 4 个字符），其余替换为 *；不超过 4 个字符时全部替换
+func Mask(s string) string {
+	r := []rune(s)
+	if len(r) <= 4 {
+		return strings.Repeat("*", len(r))
+	}
+	keep := len(r) / 4
+	if keep > 4 {
+		keep = 4
+	}
+	return string(r[:keep]) + strings.Repeat("*", len(r)-2*keep) + string(r[len(r)-keep:])
+}
+
+// Value 返回 v 的脱敏副本，用于 JSON 编码；不修改 v。
+// 结构体按 json tag 输出字段（支持 omitempty 与 -），实现 json.Marshaler 的类型原样保留
+func Value(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	return walk(reflect.ValueOf(v), 0)
+}
+
+// JSON 编码 v 的脱敏副本
+func JSON(v interface{}) ([]byte, error) {
+	return json.Marshal(Value(v))
+}
+
+// String 脱敏 key=value 形式的敏感值，用于已拼接好的字符串（如请求头、查询参数）
+func String(key, value string) string {
+	if value != "" && IsSensitiveKey(key) {
+		return Placeholder
+	}
+	return value
+}
+
+// maxDepth 防止循环引用
+const maxDepth = 32
+
+var (
+	jsonMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func walk(v reflect.Value, depth int) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if depth > maxDepth {
+		return "[MAX DEPTH]"
+	}
+	if implementsMarshaler(v) {
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return walk(v.Elem(), depth+1)
+	case reflect.Struct:
+		return walkStruct(v, depth)
+	case reflect.Map:
+		return walkMap(v, depth)
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		fallthrough
+	case reflect.Array:
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i] = walk(v.Index(i), depth+1)
+		}
+		return out
+	}
+	if v.CanInterface() {
+		return v.Interface()
+	}
+	return nil
+}
+
+func implementsMarshaler(v reflect.Value) bool {
+	if !v.CanInterface() {
+		return false
+	}
+	t := v.Type()
+	if t.Kind() == reflect.Ptr && v.IsNil() {
+		return false
+	}
+	return t.Implements(jsonMarshaler) || t.Implements(textMarshaler)
+}
+
+func walkStruct(v reflect.Value, depth int) interface{} {
+	var out orderedObject
+	for _, f := range cachedFields(v.Type()) {
+		fv := v.FieldByIndex(f.index)
+		if f.omitEmpty && isEmpty(fv) {
+			continue
+		}
+		var value interface{}
+		switch {
+		case f.redact || (!f.mask && IsSensitiveKey(f.name)):
+			value = redactValue(fv, depth)
+		case f.mask:
+			value = maskValue(fv)
+		default:
+			value = walk(fv, depth+1)
+		}
+		out = append(out, member{key: f.name, value: value})
+	}
+	return out
+}
+
+func walkMap(v reflect.Value, depth int) interface{} {
+	if v.IsNil() {
+		return nil
+	}
+	if v.Type().Key().Kind() != reflect.String {
+		// 非字符串 key 交给 encoding/json 处理
+		return v.Interface()
+	}
+	out := make(map[string]interface{}, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		k := iter.Key().String()
+		if IsSensitiveKey(k) {
+			out[k] = redactValue(iter.Value(), depth)
+		} else {
+			out[k] = walk(iter.Value(), depth+1)
+		}
+	}
+	return out
+}
+
+// redactValue 空值原样输出，便于区分未设置与已脱敏
+func redactValue(v reflect.Value, depth int) interface{} {
+	if isEmpty(v) {
+		return walk(v, depth+1)
+	}
+	return Placeholder
+}
+
+func maskValue(v reflect.Value) interface{} {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.String {
+		return Mask(v.String())
+	}
+	if !v.CanInterface() {
+		return Placeholder
+	}
+	return Mask(fmt.Sprint(v.Interface()))
+}
+
+// field 结构体字段的编码信息
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	redact    bool
+	mask      bool
+}
+
+var fieldCache sync.Map // map[reflect.Type][]field
+
+func cachedFields(t reflect.Type) []field {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.([]field)
+	}
+	f, _ := fieldCache.LoadOrStore(t, typeFields(t, nil))
+	return f.([]field)
+}
+
+// typeFields 按 encoding/json 的规则收集导出字段，匿名结构体字段展开到外层
+func typeFields(t reflect.Type, index []int) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		idx := append(append([]int(nil), index...), i)
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct && sf.Type.Kind() != reflect.Ptr {
+			fields = append(fields, typeFields(ft, idx)...)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		logTag := sf.Tag.Get(TagName)
+		fields = append(fields, field{
+			name:      name,
+			index:     idx,
+			omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+			redact:    logTag == TagRedact,
+			mask:      logTag == TagMask,
+		})
+	}
+	return fields
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// orderedObject 按字段顺序编码的 JSON 对象
+type orderedObject []member
+
+type member struct {
+	key   string
+	value interface{}
+}
+
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(m.key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(m.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func normalizeKeys(patterns []string) []string {
+	out := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		if p = normalize(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// normalize 转小写并去掉 - 与 _，使 api_key、apiKey、API-Key 等价
+func normalize(s string) string {
+	s = strings.ToLower(s)
+	return strings.NewReplacer("_", "", "-", "").Replace(s)
+}
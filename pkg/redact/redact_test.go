@@ -0,0 +1,148 @@
+package redact
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password" log:"redact"`
+}
+
+type loginRequest struct {
+	credentials
+	Phone     string            `json:"phone" log:"mask"`
+	APIKey    string            `json:"apiKey"`
+	Code      int               `json:"code" log:"redact"`
+	Secret    string            `json:"-"`
+	Note      string            `json:"note,omitempty"`
+	Token     *string           `json:"token"`
+	Headers   map[string]string `json:"headers"`
+	CreatedAt time.Time         `json:"created_at"`
+	Items     []item            `json:"items"`
+	internal  string
+}
+
+type item struct {
+	Name         string `json:"name"`
+	ClientSecret string `json:"client_secret"`
+}
+
+func TestJSON(t *testing.T) {
+	token := "abc"
+	req := &loginRequest{
+		credentials: credentials{Username: "alice", Password: "p@ssw0rd"},
+		Phone:       "13812345678",
+		APIKey:      "key-1",
+		Code:        123456,
+		Secret:      "hidden",
+		Token:       &token,
+		Headers:     map[string]string{"Authorization": "Bearer x", "Accept": "*/*"},
+		CreatedAt:   time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC),
+		Items:       []item{{Name: "a", ClientSecret: "s"}},
+		internal:    "x",
+	}
+
+	data, err := JSON(req)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"username": "alice",
+		"password": "[REDACTED]",
+		"phone": "13*******78",
+		"apiKey": "[REDACTED]",
+		"code": "[REDACTED]",
+		"token": "[REDACTED]",
+		"headers": {"Authorization": "[REDACTED]", "Accept": "*/*"},
+		"created_at": "2024-01-15T10:00:00Z",
+		"items": [{"name": "a", "client_secret": "[REDACTED]"}]
+	}`, string(data))
+
+	// 字段顺序与 encoding/json 一致
+	assert.Regexp(t, `^\{"username":"alice","password":`, string(data))
+	// 原值不变
+	assert.Equal(t, "p@ssw0rd", req.Password)
+	assert.Equal(t, "Bearer x", req.Headers["Authorization"])
+}
+
+func TestJSON_EmptySensitiveValues(t *testing.T) {
+	data, err := JSON(loginRequest{})
+	require.NoError(t, err)
+
+	var out map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &out))
+	assert.Equal(t, "", out["password"])
+	assert.Nil(t, out["token"])
+	assert.NotContains(t, out, "note")
+}
+
+func TestValue_NonStructs(t *testing.T) {
+	tests := []struct {
+		name string
+		in   interface{}
+		want string
+	}{
+		{"nil", nil, `null`},
+		{"string", "password", `"password"`},
+		{"map", map[string]interface{}{"user": "bob", "nested": map[string]string{"secret_key": "x"}}, `{"nested":{"secret_key":"[REDACTED]"},"user":"bob"}`},
+		{"int keys", map[int]string{1: "a"}, `{"1":"a"}`},
+		{"bytes", []byte("hi"), `"aGk="`},
+		{"slice of pointers", []*credentials{{Username: "a", Password: "b"}, nil}, `[{"username":"a","password":"[REDACTED]"},null]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := JSON(tt.in)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(data))
+		})
+	}
+}
+
+func TestIsSensitiveKey(t *testing.T) {
+	tests := map[string]bool{
+		"password":      true,
+		"old_password":  true,
+		"AccessToken":   true,
+		"X-Api-Key":     true,
+		"apiKey":        true,
+		"Authorization": true,
+		"username":      false,
+		"":              false,
+	}
+	for key, want := range tests {
+		assert.Equal(t, want, IsSensitiveKey(key), key)
+	}
+}
+
+func TestSetKeys(t *testing.T) {
+	t.Cleanup(func() { SetKeys() })
+
+	SetKeys("ssn", "Card-No")
+	assert.True(t, IsSensitiveKey("user_ssn"))
+	assert.True(t, IsSensitiveKey("cardNo"))
+	assert.False(t, IsSensitiveKey("password"))
+	assert.Equal(t, Placeholder, String("SSN", "123"))
+	assert.Equal(t, "", String("SSN", ""))
+
+	SetKeys()
+	assert.True(t, IsSensitiveKey("password"))
+}
+
+func TestMask(t *testing.T) {
+	tests := map[string]string{
+		"":                    "",
+		"abcd":                "****",
+		"abcdefgh":            "ab****gh",
+		"13812345678":         "13*******78",
+		"alice@example.com":   "alic*********.com",
+		"张三丰先生":               "张***生",
+		"0123456789abcdefghi": "0123***********fghi",
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, Mask(in), in)
+	}
+}
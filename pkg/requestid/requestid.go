@@ -0,0 +1,120 @@
+// Package requestid 生成、保存与读取请求 ID，用于在 HTTP、出站调用与消息队列之间关联同一请求的日志
+package requestid
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"project/pkg/utils/idgen"
+	"project/pkg/utils/snowflake"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Header 默认的请求 ID 请求头
+const Header = "X-Request-ID"
+
+// ContextKey 请求 ID 在 gin.Context 中的键，与日志字段名一致
+const ContextKey = "request_id"
+
+// MaxLength 上游传入的请求 ID 的最大长度，超出或包含非法字符时重新生成
+const MaxLength = 128
+
+// 生成器名称
+const (
+	GeneratorUUID      = "uuid"
+	GeneratorSnowflake = "snowflake"
+	GeneratorRandom    = "random"
+)
+
+// Generators 支持的生成器名称
+var Generators = []string{GeneratorUUID, GeneratorSnowflake, GeneratorRandom}
+
+// Generator 生成请求 ID
+type Generator func() string
+
+// UUID 生成 UUID v4
+func UUID() string {
+	return uuid.NewString()
+}
+
+// Snowflake 使用雪花算法生成十进制 ID，生成失败（时钟回拨）时退回 UUID
+func Snowflake(w *snowflake.Worker) Generator {
+	return func() string {
+		id, err := w.NextID()
+		if err != nil {
+			return UUID()
+		}
+		return strconv.FormatUint(id, 10)
+	}
+}
+
+// Random 生成 length 位小写字母与数字，生成失败时退回 UUID
+func Random(length int) Generator {
+	return func() string {
+		id, err := idgen.GenerateID(length)
+		if err != nil {
+			return UUID()
+		}
+		return id
+	}
+}
+
+// NewGenerator 按名称创建生成器，名称为空时使用 uuid
+func NewGenerator(name string) (Generator, error) {
+	switch name {
+	case "", GeneratorUUID:
+		return UUID, nil
+	case GeneratorSnowflake:
+		return Snowflake(snowflake.NewWorker(snowflake.WorkerID, snowflake.WataCenterID)), nil
+	case GeneratorRandom:
+		return Random(20), nil
+	}
+	return nil, fmt.Errorf("invalid request id generator: %s (must be %s)", name, strings.Join(Generators, "/"))
+}
+
+// Valid 上游传入的请求 ID 只允许可见 ASCII 字符，且长度不超过 MaxLength
+func Valid(id string) bool {
+	if id == "" || len(id) > MaxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+type ctxKey struct{}
+
+// NewContext 返回携带请求 ID 的 ctx
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext 返回 ctx 中的请求 ID，不存在时返回空字符串
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// FromGin 返回当前请求的请求 ID，由 middleware.RequestID 写入
+func FromGin(c *gin.Context) string {
+	if c == nil {
+		return ""
+	}
+	if id := c.GetString(ContextKey); id != "" {
+		return id
+	}
+	if c.Request != nil {
+		return FromContext(c.Request.Context())
+	}
+	return ""
+}
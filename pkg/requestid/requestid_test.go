@@ -0,0 +1,93 @@
+package requestid
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"project/pkg/utils/snowflake"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewGenerator(t *testing.T) {
+	tests := []struct {
+		name    string
+		check   func(t *testing.T, id string)
+		wantErr bool
+	}{
+		{name: "", check: func(t *testing.T, id string) { assert.Len(t, id, 36) }},
+		{name: GeneratorUUID, check: func(t *testing.T, id string) { assert.Len(t, id, 36) }},
+		{name: GeneratorSnowflake, check: func(t *testing.T, id string) {
+			assert.NotEmpty(t, id)
+			assert.Empty(t, strings.Trim(id, "0123456789"))
+		}},
+		{name: GeneratorRandom, check: func(t *testing.T, id string) { assert.Len(t, id, 20) }},
+		{name: "ulid", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen, err := NewGenerator(tt.name)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			a, b := gen(), gen()
+			tt.check(t, a)
+			assert.NotEqual(t, a, b)
+			assert.True(t, Valid(a))
+		})
+	}
+}
+
+func TestSnowflake_Unique(t *testing.T) {
+	gen := Snowflake(snowflake.NewWorker(1, 1))
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id := gen()
+		require.False(t, seen[id], "duplicate id %s", id)
+		seen[id] = true
+	}
+}
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"abc-123", true},
+		{"", false},
+		{"has space", false},
+		{"line\nbreak", false},
+		{"中文", false},
+		{strings.Repeat("a", MaxLength), true},
+		{strings.Repeat("a", MaxLength+1), false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Valid(tt.id), "Valid(%q)", tt.id)
+	}
+}
+
+func TestContext(t *testing.T) {
+	assert.Empty(t, FromContext(context.Background()))
+	var nilCtx context.Context
+	assert.Empty(t, FromContext(nilCtx))
+
+	ctx := NewContext(context.Background(), "req-1")
+	assert.Equal(t, "req-1", FromContext(ctx))
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	assert.Empty(t, FromGin(c))
+	assert.Empty(t, FromGin(nil))
+
+	c.Request = httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+	assert.Equal(t, "req-1", FromGin(c))
+
+	c.Set(ContextKey, "req-2")
+	assert.Equal(t, "req-2", FromGin(c))
+}
@@ -2,10 +2,15 @@ package httpclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"time"
+
+	"project/pkg/logger"
+	"project/pkg/requestid"
 )
 
 type Response struct {
@@ -21,28 +26,44 @@ type Response struct {
 //	requestBody：请求数据
 //	contentType：请求数据格式（默认：application/json）
 func PostRequest(url string, requestBody interface{}, contentType string) ([]byte, error) {
+	return PostRequestContext(context.Background(), url, requestBody, contentType)
+}
+
+// PostRequestContext 同 PostRequest，请求随 ctx 取消，日志通过 ctx 中的 logger 输出（携带 request_id 等字段）；
+// ctx 中有请求 ID 时通过 X-Request-ID 请求头传给下游
+func PostRequestContext(ctx context.Context, url string, requestBody interface{}, contentType string) ([]byte, error) {
 	jsonBody, err := json.Marshal(requestBody)
 	if err != nil {
 		return nil, fmt.Errorf("无法封送请求正文: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("无法创建HTTP请求: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	req.Header.Set("Content-Type", contentType)
+	if id := requestid.FromContext(ctx); id != "" {
+		req.Header.Set(requestid.Header, id)
+	}
 
+	log := logger.FromContext(ctx).Named("http")
+	start := time.Now()
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
+		log.Warnf("\t[httpclient] POST %s failed after %v: %v", url, time.Since(start), err)
 		return nil, fmt.Errorf("无法执行HTTP请求: %w", err)
 	}
 	defer resp.Body.Close()
 
 	bodyBytes, err := io.ReadAll(resp.Body)
+	log.Debugf("\t[httpclient] POST %s %d %v", url, resp.StatusCode, time.Since(start))
 
 	if resp.StatusCode != 200 || err != nil {
-
+		log.Warnf("\t[httpclient] POST %s status %d", url, resp.StatusCode)
 		return nil, fmt.Errorf("收到未成功的状态码: %d, 响应体: %s", resp.StatusCode, string(bodyBytes))
 	}
 